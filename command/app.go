@@ -11,12 +11,14 @@ import (
 	"github.com/peak/s5cmd/v2/log"
 	"github.com/peak/s5cmd/v2/log/stat"
 	"github.com/peak/s5cmd/v2/parallel"
+	"github.com/peak/s5cmd/v2/ratelimit"
 	"github.com/peak/s5cmd/v2/storage"
 )
 
 const (
 	defaultWorkerCount = 256
 	defaultRetryCount  = 10
+	defaultIgnoreFile  = ".s5cmdignore"
 
 	appName = "s5cmd"
 )
@@ -78,6 +80,10 @@ var app = &cli.App{
 			Name:  "use-list-objects-v1",
 			Usage: "use ListObjectsV1 API for services that don't support ListObjectsV2",
 		},
+		&cli.IntFlag{
+			Name:  "list-partitions",
+			Usage: "list the objects in the given number of partitions of the key space concurrently, useful for huge flat buckets",
+		},
 		&cli.StringFlag{
 			Name:  "request-payer",
 			Usage: "who pays for request (access requester pays buckets)",
@@ -90,6 +96,23 @@ var app = &cli.App{
 			Name:  "credentials-file",
 			Usage: "use the specified credentials file instead of the default credentials file",
 		},
+		&cli.StringFlag{
+			Name:  "limit-rate",
+			Usage: "limit the total bandwidth of all uploads and downloads, e.g. 50MiB/s",
+		},
+		&cli.StringFlag{
+			Name:  "limit-upload-rate",
+			Usage: "limit the total bandwidth of uploads, e.g. 10MiB/s",
+		},
+		&cli.StringFlag{
+			Name:  "limit-download-rate",
+			Usage: "limit the total bandwidth of downloads, e.g. 500KB/s",
+		},
+		&cli.StringFlag{
+			Name:  "ignore-file",
+			Value: defaultIgnoreFile,
+			Usage: "name of the files in local directories which list the files to skip in gitignore syntax, empty to disable",
+		},
 	},
 	Before: func(c *cli.Context) error {
 		retryCount := c.Int("retry-count")
@@ -107,6 +130,11 @@ var app = &cli.App{
 			printError(commandFromContext(c), c.Command.Name, err)
 			return err
 		}
+		if c.Int("list-partitions") < 0 {
+			err := fmt.Errorf("list partitions cannot be a negative value")
+			printError(commandFromContext(c), c.Command.Name, err)
+			return err
+		}
 		if c.Bool("no-sign-request") && c.String("profile") != "" {
 			err := fmt.Errorf(`"no-sign-request" and "profile" flags cannot be used together`)
 			printError(commandFromContext(c), c.Command.Name, err)
@@ -118,6 +146,21 @@ var app = &cli.App{
 			return err
 		}
 
+		var rates [3]int64
+		for i, name := range []string{"limit-rate", "limit-upload-rate", "limit-download-rate"} {
+			if !c.IsSet(name) {
+				continue
+			}
+			rate, err := ratelimit.ParseRate(c.String(name))
+			if err != nil {
+				err = fmt.Errorf("bad value for --%v: %w", name, err)
+				printError(commandFromContext(c), c.Command.Name, err)
+				return err
+			}
+			rates[i] = rate
+		}
+		ratelimit.Init(rates[0], rates[1], rates[2])
+
 		if isStat {
 			stat.InitStat()
 		}
@@ -176,6 +219,12 @@ var app = &cli.App{
 	},
 }
 
+// listingCache is the cache of the listings of remote objects which is shared
+// by the storage clients of the commands, so the commands generated by sync
+// invalidate the cached listings on their writes. It is nil unless sync is
+// run with --cache-ttl flag.
+var listingCache *storage.ListingCache
+
 // NewStorageOpts creates storage.Options object from the given context.
 func NewStorageOpts(c *cli.Context) storage.Options {
 	return storage.Options{
@@ -186,13 +235,27 @@ func NewStorageOpts(c *cli.Context) storage.Options {
 		NoVerifySSL:            c.Bool("no-verify-ssl"),
 		RequestPayer:           c.String("request-payer"),
 		UseListObjectsV1:       c.Bool("use-list-objects-v1"),
+		ListPartitions:         c.Int("list-partitions"),
+		ListingCache:           listingCache,
 		Profile:                c.String("profile"),
 		CredentialFile:         c.String("credentials-file"),
 		LogLevel:               log.LevelFromString(c.String("log")),
 		NoSuchUploadRetryCount: c.Int("no-such-upload-retry-count"),
+		IgnoreFile:             c.String("ignore-file"),
+
+		SSECustomerKey:           storageSSECustomerKey(c, "sse-c-key"),
+		CopySourceSSECustomerKey: storageSSECustomerKey(c, "sse-c-copy-source-key"),
 	}
 }
 
+// storageSSECustomerKey returns the customer-provided key given by the flags.
+// The keys are validated by the commands before the storage options are
+// created.
+func storageSSECustomerKey(c *cli.Context, name string) string {
+	key, _ := sseCustomerKey(c, name)
+	return key
+}
+
 func Commands() []*cli.Command {
 	return []*cli.Command{
 		NewListCommand(),
@@ -207,10 +270,13 @@ func Commands() []*cli.Command {
 		NewPipeCommand(),
 		NewRunCommand(),
 		NewSyncCommand(),
+		NewBisyncCommand(),
 		NewVersionCommand(),
 		NewBucketVersionCommand(),
 		NewPresignCommand(),
 		NewHeadCommand(),
+		NewTagCommand(),
+		NewRestoreCommand(),
 	}
 }
 
@@ -0,0 +1,566 @@
+package command
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/klauspost/compress/zstd"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/ratelimit"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// formats of the archives created with --pack flag and extracted with
+// --unpack flag.
+const (
+	archiveTar    = "tar"
+	archiveTarGz  = "tar.gz"
+	archiveTarZst = "tar.zst"
+	archiveZip    = "zip"
+)
+
+var archiveFormats = []string{archiveTar, archiveTarGz, archiveTarZst, archiveZip}
+
+// errArchiveAborted is the error of the archive writer when the upload of the
+// archive is completed or skipped before the archive is fully written.
+var errArchiveAborted = errors.New("archive upload is aborted")
+
+// archiveFormatOf returns the format of the archive from the extension of its
+// name.
+func archiveFormatOf(name string) (string, bool) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar"):
+		return archiveTar, true
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return archiveTarGz, true
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return archiveTarZst, true
+	case strings.HasSuffix(name, ".zip"):
+		return archiveZip, true
+	default:
+		return "", false
+	}
+}
+
+// archiveWriter adds files to an archive.
+type archiveWriter interface {
+	Add(name string, fi os.FileInfo, r io.Reader) error
+	Close() error
+}
+
+func newArchiveWriter(format string, w io.Writer) (archiveWriter, error) {
+	switch format {
+	case archiveTar:
+		return &tarArchiveWriter{tw: tar.NewWriter(w)}, nil
+	case archiveTarGz:
+		gw := gzip.NewWriter(w)
+		return &tarArchiveWriter{tw: tar.NewWriter(gw), compressor: gw}, nil
+	case archiveTarZst:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		return &tarArchiveWriter{tw: tar.NewWriter(zw), compressor: zw}, nil
+	case archiveZip:
+		return &zipArchiveWriter{zw: zip.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+}
+
+type tarArchiveWriter struct {
+	tw         *tar.Writer
+	compressor io.WriteCloser
+}
+
+func (a *tarArchiveWriter) Add(name string, fi os.FileInfo, r io.Reader) error {
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(a.tw, r)
+	return err
+}
+
+func (a *tarArchiveWriter) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	if a.compressor != nil {
+		return a.compressor.Close()
+	}
+	return nil
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchiveWriter) Add(name string, fi os.FileInfo, r io.Reader) error {
+	hdr, err := zip.FileInfoHeader(fi)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	hdr.Method = zip.Deflate
+
+	w, err := a.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (a *zipArchiveWriter) Close() error {
+	return a.zw.Close()
+}
+
+// newPipe returns the pipe which uploads the archives to the destination with
+// the flags of the copy.
+func (c Copy) newPipe() Pipe {
+	return Pipe{
+		dst:                c.dst,
+		op:                 c.op,
+		fullCommand:        c.fullCommand,
+		noClobber:          c.noClobber,
+		storageClass:       c.storageClass,
+		encryptionMethod:   c.encryptionMethod,
+		encryptionKeyID:    c.encryptionKeyID,
+		masterKey:          c.masterKey,
+		acl:                c.acl,
+		cacheControl:       c.cacheControl,
+		expires:            c.expires,
+		contentType:        c.contentType,
+		contentEncoding:    c.contentEncoding,
+		contentDisposition: c.contentDisposition,
+		metadata:           c.metadata,
+		tags:               c.tags,
+		concurrency:        c.concurrency,
+		partSize:           c.partSize,
+		storageOpts:        c.storageOpts,
+	}
+}
+
+// packSources streams the source files into an archive, which is uploaded to
+// the destination object while it is being written. The files which can't be
+// read are reported and left out of the archive.
+func (c Copy) packSources(ctx context.Context) error {
+	client := storage.NewLocalClient(c.storageOpts)
+
+	isBatch := c.src.IsWildcard()
+	if !isBatch {
+		obj, err := client.Stat(ctx, c.src)
+		if err != nil {
+			printError(c.fullCommand, c.op, err)
+			return err
+		}
+		isBatch = obj.Type.IsDir()
+	}
+
+	objch, err := expandSource(ctx, client, c.followSymlinks, c.src)
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+
+	pr, pw := io.Pipe()
+
+	var (
+		merrorObjects error
+		writeErr      error
+		done          = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		merrorObjects, writeErr = c.writeArchive(pw, objch, isBatch)
+		pw.CloseWithError(writeErr)
+		// drain the channel to stop the listing.
+		for range objch {
+		}
+	}()
+
+	err = c.newPipe().upload(ctx, c.src, pr)
+	// the rest of the archive is not read if the upload is failed or skipped.
+	pr.CloseWithError(errArchiveAborted)
+	<-done
+
+	if err == nil && writeErr != nil && !errors.Is(writeErr, errArchiveAborted) {
+		err = writeErr
+	}
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+	return merrorObjects
+}
+
+// writeArchive writes the files read from the channel to the archive. The
+// errors of the files are returned separately from the error which fails the
+// whole archive.
+func (c Copy) writeArchive(w io.Writer, objch <-chan *storage.Object, isBatch bool) (error, error) {
+	archive, err := newArchiveWriter(c.pack, w)
+	if err != nil {
+		return nil, err
+	}
+
+	var merrorObjects error
+	for object := range objch {
+		if object.Type.IsDir() {
+			continue
+		}
+
+		if err := object.Err; err != nil {
+			merrorObjects = multierror.Append(merrorObjects, err)
+			printError(c.fullCommand, c.op, err)
+			continue
+		}
+
+		isExcluded, err := isObjectFiltered(object, c.excludePatterns, c.includePatterns, c.patternFiles, c.src.Prefix)
+		if err != nil {
+			printError(c.fullCommand, c.op, err)
+		}
+		if isExcluded {
+			continue
+		}
+
+		name := object.URL.Base()
+		if isBatch && !c.flatten {
+			name = object.URL.Relative()
+		}
+		name = filepath.ToSlash(name)
+
+		f, err := os.Open(object.URL.Absolute())
+		if err != nil {
+			merrorObjects = multierror.Append(merrorObjects, err)
+			printError(c.fullCommand, c.op, err)
+			continue
+		}
+
+		fi, err := f.Stat()
+		if err == nil && !fi.Mode().IsRegular() {
+			err = fmt.Errorf("object '%v' is not a regular file", object)
+		}
+		if err != nil {
+			f.Close()
+			merrorObjects = multierror.Append(merrorObjects, err)
+			printError(c.fullCommand, c.op, err)
+			continue
+		}
+
+		c.progressbar.AddTotalBytes(fi.Size())
+		c.progressbar.IncrementTotalObjects()
+
+		err = archive.Add(name, fi, f)
+		f.Close()
+		if err != nil {
+			return merrorObjects, err
+		}
+		c.progressbar.AddCompletedBytes(fi.Size())
+		c.progressbar.IncrementCompletedObjects()
+	}
+
+	return merrorObjects, archive.Close()
+}
+
+// unpackSource extracts the files of the source archive to the destination
+// directory. Tar archives are extracted while they are streamed, zip archives
+// and the archives encrypted on the client side are downloaded to a temporary
+// file first.
+func (c Copy) unpackSource(ctx context.Context) error {
+	err := c.extractArchive(ctx)
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+	}
+	return err
+}
+
+func (c Copy) extractArchive(ctx context.Context) error {
+	format, ok := archiveFormatOf(c.src.Path)
+	if !ok {
+		return fmt.Errorf("unable to detect the archive format of %q", c.src)
+	}
+
+	client, err := storage.NewClient(ctx, c.src, c.storageOpts)
+	if err != nil {
+		return err
+	}
+
+	if c.storageOpts.DryRun {
+		log.Info(log.InfoMessage{Operation: c.op, Source: c.src, Destination: c.dst})
+		return nil
+	}
+
+	var size int64
+	if reader, ok := client.(storage.Reader); ok && format != archiveZip && c.masterKey == nil {
+		rc, err := reader.Read(ctx, c.src)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+
+		counter := &countingReader{r: ratelimit.NewReader(rc, ratelimit.Download)}
+		err = c.extractTar(counter, format)
+		size = counter.n
+		if err != nil {
+			return err
+		}
+	} else {
+		f, n, err := c.downloadArchive(ctx, client)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		size = n
+		if format == archiveZip {
+			err = c.extractZip(f, n)
+		} else {
+			err = c.extractTar(f, format)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.deleteSource {
+		_ = client.Delete(ctx, c.src)
+	}
+
+	log.Info(log.InfoMessage{
+		Operation:   c.op,
+		Source:      c.src,
+		Destination: c.dst,
+		Object:      &storage.Object{Size: size},
+	})
+	return nil
+}
+
+// downloadArchive downloads the archive to a temporary file.
+func (c Copy) downloadArchive(ctx context.Context, client storage.Storage) (*os.File, int64, error) {
+	getter, ok := client.(storage.Getter)
+	if !ok {
+		return nil, 0, storage.NotSupported(c.src, "download")
+	}
+
+	f, err := os.CreateTemp("", "s5cmd-archive-*")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var size int64
+	if c.masterKey != nil {
+		size, err = getDecrypted(ctx, getter, c.masterKey, c.src, f, c.concurrency, c.partSize)
+	} else {
+		size, err = getter.Get(ctx, c.src, f, c.concurrency, c.partSize)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, 0, err
+	}
+	return f, size, nil
+}
+
+func (c Copy) extractTar(r io.Reader, format string) error {
+	switch format {
+	case archiveTarGz:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	case archiveTarZst:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	var merrorObjects error
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeDir:
+		default:
+			printDebug(c.op, fmt.Errorf("skipping %q, it is not a regular file", hdr.Name), c.src)
+			continue
+		}
+
+		err = c.extractFile(hdr.Name, hdr.FileInfo(), tr)
+		if err != nil {
+			merrorObjects = multierror.Append(merrorObjects, err)
+			printError(c.fullCommand, c.op, err)
+		}
+	}
+	return merrorObjects
+}
+
+func (c Copy) extractZip(f *os.File, size int64) error {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return err
+	}
+
+	var merrorObjects error
+	for _, file := range zr.File {
+		fi := file.FileInfo()
+		if !fi.Mode().IsRegular() && !fi.IsDir() {
+			printDebug(c.op, fmt.Errorf("skipping %q, it is not a regular file", file.Name), c.src)
+			continue
+		}
+
+		err := func() error {
+			rc, err := file.Open()
+			if err != nil {
+				return err
+			}
+			defer rc.Close()
+			return c.extractFile(file.Name, fi, rc)
+		}()
+		if err != nil {
+			merrorObjects = multierror.Append(merrorObjects, err)
+			printError(c.fullCommand, c.op, err)
+		}
+	}
+	return merrorObjects
+}
+
+// extractFile writes the file of the archive under the destination
+// directory, unless it is excluded by the filters.
+func (c Copy) extractFile(name string, fi os.FileInfo, r io.Reader) error {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if name == "." {
+		return nil
+	}
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("archive entry %q is outside of the destination", name)
+	}
+
+	entryurl, err := url.New(name, url.WithRaw(true))
+	if err != nil {
+		return err
+	}
+
+	object := &storage.Object{URL: entryurl}
+	isExcluded, err := isObjectFiltered(object, c.excludePatterns, c.includePatterns, c.patternFiles, "")
+	if err != nil {
+		return err
+	}
+	if isExcluded {
+		return nil
+	}
+
+	dsturl := c.dst.Join(name)
+	dst := dsturl.Absolute()
+	if fi.IsDir() {
+		return os.MkdirAll(dst, 0o755)
+	}
+
+	if c.noClobber {
+		if _, err := os.Stat(dst); err == nil {
+			printDebug(c.op, errorpkg.ErrObjectExists, c.src, dsturl)
+			return nil
+		}
+	}
+
+	c.progressbar.AddTotalBytes(fi.Size())
+	c.progressbar.IncrementTotalObjects()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	c.progressbar.AddCompletedBytes(fi.Size())
+	c.progressbar.IncrementCompletedObjects()
+	return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}
+
+// countingReader counts the bytes read from the reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func validateArchive(c *cli.Context, srcurl, dsturl *url.URL) error {
+	if c.String("pack") != "" {
+		if c.Bool("unpack") {
+			return fmt.Errorf("--pack and --unpack flags can not be used together")
+		}
+		if c.Command.Name != "cp" {
+			return fmt.Errorf("--pack flag is not supported by %v command", c.Command.Name)
+		}
+		if srcurl.IsRemote() || !dsturl.IsRemote() {
+			return fmt.Errorf("--pack flag is only supported for uploads")
+		}
+		if dsturl.IsBucket() || dsturl.IsPrefix() {
+			return fmt.Errorf("target %q must be an object", dsturl)
+		}
+	} else {
+		if !srcurl.IsRemote() || dsturl.IsRemote() {
+			return fmt.Errorf("--unpack flag is only supported for downloads")
+		}
+		if srcurl.IsWildcard() {
+			return fmt.Errorf("source %q can not contain glob characters with --unpack flag", srcurl)
+		}
+		if _, ok := archiveFormatOf(srcurl.Path); !ok {
+			return fmt.Errorf("unable to detect the archive format of %q", srcurl)
+		}
+	}
+
+	if c.Bool("resume") {
+		return fmt.Errorf("--resume flag can not be used with --pack and --unpack flags")
+	}
+	return nil
+}
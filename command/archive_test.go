@@ -0,0 +1,117 @@
+package command
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/progressbar"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestArchiveFormatOf(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		expected string
+	}{
+		{name: "dir/archive.tar", expected: archiveTar},
+		{name: "archive.tar.gz", expected: archiveTarGz},
+		{name: "ARCHIVE.TGZ", expected: archiveTarGz},
+		{name: "archive.tar.zst", expected: archiveTarZst},
+		{name: "archive.tzst", expected: archiveTarZst},
+		{name: "archive.zip", expected: archiveZip},
+		{name: "archive.gz", expected: ""},
+		{name: "archive", expected: ""},
+	}
+
+	for _, tc := range testcases {
+		format, ok := archiveFormatOf(tc.name)
+		assert.Equal(t, format, tc.expected, tc.name)
+		assert.Equal(t, ok, tc.expected != "", tc.name)
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	t.Parallel()
+	log.Init("error", false)
+
+	for _, format := range archiveFormats {
+		var buf bytes.Buffer
+		archive, err := newArchiveWriter(format, &buf)
+		assert.NilError(t, err)
+
+		files := map[string]string{
+			"a.txt":       "first file",
+			"dir/b.txt":   "second file",
+			"dir/c.log":   "excluded file",
+			"../evil.txt": "outside of the destination",
+		}
+		modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		for _, name := range []string{"a.txt", "dir/b.txt", "dir/c.log", "../evil.txt"} {
+			fi := fileInfo{name: filepath.Base(name), size: int64(len(files[name])), modTime: modTime}
+			assert.NilError(t, archive.Add(name, fi, bytes.NewBufferString(files[name])))
+		}
+		assert.NilError(t, archive.Close())
+
+		dir := t.TempDir()
+		dst, err := url.New(filepath.Join(dir, "out"))
+		assert.NilError(t, err)
+
+		excludePatterns, err := createRegexFromWildcard([]string{"*.log"})
+		assert.NilError(t, err)
+
+		c := Copy{
+			op:              "cp",
+			dst:             dst,
+			excludePatterns: excludePatterns,
+			progressbar:     &progressbar.NoOp{},
+		}
+
+		if format == archiveZip {
+			f, ferr := os.CreateTemp(dir, "archive")
+			assert.NilError(t, ferr)
+			_, ferr = f.Write(buf.Bytes())
+			assert.NilError(t, ferr)
+			err = c.extractZip(f, int64(buf.Len()))
+			f.Close()
+		} else {
+			err = c.extractTar(&buf, format)
+		}
+		assert.ErrorContains(t, err, `archive entry "../evil.txt" is outside of the destination`, format)
+
+		data, err := os.ReadFile(filepath.Join(dir, "out", "dir", "b.txt"))
+		assert.NilError(t, err, format)
+		assert.Equal(t, string(data), "second file")
+
+		fi, err := os.Stat(filepath.Join(dir, "out", "a.txt"))
+		assert.NilError(t, err, format)
+		assert.Assert(t, fi.ModTime().Equal(modTime), format)
+
+		_, err = os.Stat(filepath.Join(dir, "out", "dir", "c.log"))
+		assert.Assert(t, os.IsNotExist(err), format)
+		_, err = os.Stat(filepath.Join(dir, "evil.txt"))
+		assert.Assert(t, os.IsNotExist(err), format)
+	}
+}
+
+// fileInfo is the os.FileInfo of the regular files added to the test
+// archives.
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() os.FileMode  { return 0o644 }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return false }
+func (fi fileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,180 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const backupOp = "backup"
+
+// newBackupDir returns the url of the --backup-dir flag. It returns nil if the
+// flag is not given.
+func newBackupDir(c *cli.Context) (*url.URL, error) {
+	dir := c.String("backup-dir")
+	if dir == "" {
+		return nil, nil
+	}
+	return url.New(dir)
+}
+
+// backupObject keeps the object which is about to be overwritten or deleted
+// under the given backup location. Remote objects are copied on the server
+// side, the caller overwrites or deletes them afterwards. Local files are
+// moved to the backup location.
+func backupObject(ctx context.Context, obj *storage.Object, backupurl *url.URL, storageOpts storage.Options) (err error) {
+	defer stat.Collect(backupOp, &err)()
+
+	srcurl := obj.URL
+	if srcurl.IsRemote() {
+		err = backupRemoteObject(ctx, obj, backupurl, storageOpts)
+	} else {
+		err = backupLocalFile(srcurl, backupurl, storageOpts)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to back up %v to %v: %w", srcurl, backupurl, err)
+	}
+
+	msg := log.InfoMessage{
+		Operation:   backupOp,
+		Source:      srcurl,
+		Destination: backupurl,
+	}
+	log.Info(msg)
+	return nil
+}
+
+func backupRemoteObject(ctx context.Context, obj *storage.Object, backupurl *url.URL, storageOpts storage.Options) error {
+	metadata := storage.Metadata{
+		StorageClass: string(obj.StorageClass),
+		Directive:    metadataDirectiveCopy,
+	}
+
+	if backupurl.Scheme == "s3" && obj.Size > storage.MaxCopyObjectSize {
+		return multipartCopy(ctx, obj.URL, backupurl, storageOpts, storageOpts, metadata,
+			defaultCopyConcurrency, defaultPartSize*megabytes)
+	}
+
+	client, err := storage.NewClient(ctx, backupurl, storageOpts)
+	if err != nil {
+		return err
+	}
+	return client.Copy(ctx, obj.URL, backupurl, metadata)
+}
+
+func backupLocalFile(srcurl, backupurl *url.URL, storageOpts storage.Options) error {
+	client := storage.NewLocalClient(storageOpts)
+	if err := client.MkdirAll(filepath.Dir(backupurl.Absolute())); err != nil {
+		return err
+	}
+
+	file, err := client.Open(srcurl.Absolute())
+	if err != nil {
+		return err
+	}
+	file.Close()
+
+	return client.Rename(file, backupurl.Absolute())
+}
+
+// relativeDestination returns the path of the destination object relative to
+// the destination argument of the command, which is preserved under the
+// backup directory. The base name of the object is used if the destination
+// argument is the object itself.
+func relativeDestination(dst, dsturl *url.URL) string {
+	if dst.IsRemote() {
+		if (dst.IsPrefix() || dst.IsBucket()) && strings.HasPrefix(dsturl.Path, dst.Path) {
+			return strings.TrimPrefix(dsturl.Path, dst.Path)
+		}
+		return dsturl.Base()
+	}
+
+	rel, err := filepath.Rel(dst.Absolute(), dsturl.Absolute())
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return dsturl.Base()
+	}
+	return filepath.ToSlash(rel)
+}
+
+// relativeSource returns the path of the listed object relative to the source
+// argument of the command. The base name of the object is used if it is not
+// listed from a wildcard or a directory.
+func relativeSource(srcurl *url.URL) string {
+	if rel := srcurl.Relative(); rel != srcurl.Absolute() {
+		return filepath.ToSlash(rel)
+	}
+	return srcurl.Base()
+}
+
+// validateBackupDir checks the --backup-dir flag against the urls of the
+// objects which are backed up.
+func validateBackupDir(c *cli.Context, urls ...*url.URL) error {
+	backupurl, err := newBackupDir(c)
+	if err != nil || backupurl == nil {
+		return err
+	}
+
+	if backupurl.IsWildcard() {
+		return fmt.Errorf("--backup-dir %q can not contain glob characters", backupurl)
+	}
+
+	if backupurl.IsRemote() && !backupurl.IsPrefix() && !backupurl.IsBucket() {
+		return fmt.Errorf("--backup-dir %q must be a bucket or a prefix", backupurl)
+	}
+
+	for _, u := range urls {
+		if u.Scheme != backupurl.Scheme {
+			return fmt.Errorf("--backup-dir %q must be on the same storage as %q", backupurl, u)
+		}
+
+		if isUnderURL(backupurl, u) {
+			return fmt.Errorf("--backup-dir %q can not be under %q", backupurl, u)
+		}
+	}
+	return nil
+}
+
+// isUnderURL reports whether the backup directory is under the objects of the
+// given url, in which case the backups would be overwritten or deleted too.
+func isUnderURL(backupurl, u *url.URL) bool {
+	if u.IsRemote() {
+		if u.Bucket != backupurl.Bucket {
+			return false
+		}
+		switch {
+		case u.IsWildcard():
+			return strings.HasPrefix(backupurl.Path, u.Prefix)
+		case u.IsPrefix() || u.IsBucket():
+			return strings.HasPrefix(backupurl.Path, u.Path)
+		}
+		return false
+	}
+
+	dir := u.Absolute()
+	if u.IsWildcard() {
+		dir = path.Dir(u.Prefix)
+	} else if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+		return false
+	}
+
+	rel, err := filepath.Rel(absPath(dir), absPath(backupurl.Absolute()))
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+func absPath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	return abs
+}
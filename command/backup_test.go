@@ -0,0 +1,58 @@
+package command
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestRelativeDestination(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		dst      string
+		dsturl   string
+		expected string
+	}{
+		{name: "remote prefix", dst: "s3://bucket/prefix/", dsturl: "s3://bucket/prefix/dir/key", expected: "dir/key"},
+		{name: "remote bucket", dst: "s3://bucket", dsturl: "s3://bucket/dir/key", expected: "dir/key"},
+		{name: "remote object", dst: "s3://bucket/prefix/key", dsturl: "s3://bucket/prefix/key", expected: "key"},
+		{name: "local directory", dst: "folder/", dsturl: "folder/dir/file", expected: "dir/file"},
+		{name: "local file", dst: "folder/file", dsturl: "folder/file", expected: "file"},
+		{name: "local file out of destination", dst: "folder/", dsturl: "other/file", expected: "file"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dst, err := url.New(tc.dst)
+			assert.NilError(t, err)
+
+			dsturl, err := url.New(tc.dsturl)
+			assert.NilError(t, err)
+
+			assert.Equal(t, relativeDestination(dst, dsturl), tc.expected)
+		})
+	}
+}
+
+func TestRelativeSource(t *testing.T) {
+	t.Parallel()
+
+	src, err := url.New("s3://bucket/prefix/*")
+	assert.NilError(t, err)
+
+	listed, err := url.New("s3://bucket/prefix/dir/key")
+	assert.NilError(t, err)
+	listed.SetRelative(src)
+	assert.Equal(t, relativeSource(listed), "dir/key")
+
+	given, err := url.New("s3://bucket/prefix/dir/key")
+	assert.NilError(t, err)
+	assert.Equal(t, relativeSource(given), "key")
+}
@@ -0,0 +1,744 @@
+package command
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/ignore"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	conflictNewer    = "newer"
+	conflictKeepBoth = "keep-both"
+	conflictFail     = "fail"
+)
+
+var bisyncHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] directory remote-prefix
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	01. Synchronize local folder and s3 prefix in both directions
+		 > s5cmd {{.HelpName}} folder/ s3://bucket/prefix/
+
+	02. Synchronize local folder and s3 bucket but keep both versions of the files which are changed on both sides
+		 > s5cmd {{.HelpName}} --conflict keep-both folder/ s3://bucket/
+
+	03. Synchronize local folder and s3 prefix but do not change anything if a file is changed on both sides
+		 > s5cmd {{.HelpName}} --conflict fail folder/ s3://bucket/prefix/
+
+	04. Synchronize local folder and s3 prefix but exclude the files with log extension
+		 > s5cmd {{.HelpName}} --exclude "*.log" folder/ s3://bucket/prefix/
+
+	05. Synchronize local folder and s3 prefix and keep the state of the synchronization in the given directory
+		 > s5cmd {{.HelpName}} --state-dir /var/lib/s5cmd folder/ s3://bucket/prefix/
+`
+
+func NewBisyncCommandFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.GenericFlag{
+			Name:  "conflict",
+			Usage: "resolve the files which are changed on both sides by the given policy: (newer, keep-both, fail)",
+			Value: &EnumValue{
+				Enum:    []string{conflictNewer, conflictKeepBoth, conflictFail},
+				Default: conflictNewer,
+			},
+		},
+		&cli.StringFlag{
+			Name:  "state-dir",
+			Usage: "directory of the state files which hold the listings of the last synchronization (default: s5cmd/bisync directory in the user cache directory)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "exclude",
+			Usage: "exclude objects with given pattern",
+		},
+		&cli.StringSliceFlag{
+			Name:  "include",
+			Usage: "include objects with given pattern",
+		},
+	}
+}
+
+func NewBisyncCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:               "bisync",
+		HelpName:           "bisync",
+		Usage:              "synchronize a local directory and a remote prefix in both directions",
+		Flags:              NewBisyncCommandFlags(),
+		CustomHelpTemplate: bisyncHelpTemplate,
+		Before: func(c *cli.Context) error {
+			err := validateBisyncCommand(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			b, err := NewBisync(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+				return err
+			}
+			return b.Run(c)
+		},
+	}
+
+	cmd.BashComplete = getBashCompleteFn(cmd, false, false)
+	return cmd
+}
+
+// Bisync holds bisync operation flags and states.
+type Bisync struct {
+	local       *url.URL
+	remote      *url.URL
+	op          string
+	fullCommand string
+
+	// flags
+	conflict        string
+	stateDir        string
+	excludePatterns []*regexp.Regexp
+	includePatterns []*regexp.Regexp
+
+	// ignoreTree holds the ignore files of the local directory. The remote
+	// objects of the ignored files are skipped too, so that they are not
+	// taken as created on the remote side.
+	ignoreTree *ignore.Tree
+
+	storageOpts storage.Options
+}
+
+// NewBisync creates Bisync from cli.Context.
+func NewBisync(c *cli.Context) (Bisync, error) {
+	// the files are listed relative to the directory itself, not its parent.
+	localurl, err := url.New(strings.TrimSuffix(c.Args().Get(0), "/") + "/")
+	if err != nil {
+		return Bisync{}, err
+	}
+
+	remoteurl, err := url.New(c.Args().Get(1))
+	if err != nil {
+		return Bisync{}, err
+	}
+
+	stateDir := c.String("state-dir")
+	if stateDir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return Bisync{}, fmt.Errorf("unable to find the cache directory: %w", err)
+		}
+		stateDir = filepath.Join(cacheDir, "s5cmd", "bisync")
+	}
+
+	excludePatterns, err := createRegexFromWildcard(c.StringSlice("exclude"))
+	if err != nil {
+		return Bisync{}, err
+	}
+
+	includePatterns, err := createRegexFromWildcard(c.StringSlice("include"))
+	if err != nil {
+		return Bisync{}, err
+	}
+
+	storageOpts := NewStorageOpts(c)
+
+	return Bisync{
+		local:       localurl,
+		remote:      remoteurl,
+		op:          c.Command.Name,
+		fullCommand: commandFromContext(c),
+
+		conflict:        c.String("conflict"),
+		stateDir:        stateDir,
+		excludePatterns: excludePatterns,
+		includePatterns: includePatterns,
+		ignoreTree:      storage.NewLocalClient(storageOpts).IgnoreTree(localurl),
+
+		storageOpts: storageOpts,
+	}, nil
+}
+
+// Run lists both sides, classifies the changes on each side since the last
+// synchronization, propagates them in both directions and saves the listings
+// as the state of the next synchronization.
+func (b Bisync) Run(c *cli.Context) error {
+	ctx := c.Context
+
+	statePath, err := b.statePath()
+	if err != nil {
+		printError(b.fullCommand, b.op, err)
+		return err
+	}
+
+	prev, err := loadBisyncState(statePath)
+	if err != nil {
+		printError(b.fullCommand, b.op, err)
+		return err
+	}
+
+	localObjects, remoteObjects, err := b.listBothSides(ctx)
+	if err != nil {
+		printError(b.fullCommand, b.op, err)
+		return err
+	}
+
+	local, remote := bisyncEntries(localObjects), bisyncEntries(remoteObjects)
+	if err := checkEmptySides(prev, local, remote, statePath); err != nil {
+		printError(b.fullCommand, b.op, err)
+		return err
+	}
+
+	identical := b.identicalFunc(ctx, localObjects, remoteObjects)
+	plan := planBisync(prev, local, remote, b.conflict, identical)
+
+	if b.conflict == conflictFail && len(plan.conflicts) > 0 {
+		for _, key := range plan.conflicts {
+			printError(b.fullCommand, b.op, fmt.Errorf("%q is changed on both sides", key))
+		}
+		err := fmt.Errorf("%d files are changed on both sides, no changes are made", len(plan.conflicts))
+		printError(b.fullCommand, b.op, err)
+		return err
+	}
+
+	if err := b.renameConflicts(plan.renames); err != nil {
+		printError(b.fullCommand, b.op, err)
+		return err
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go b.planRun(c, plan, pipeWriter)
+
+	runErr := NewRun(c, pipeReader).Run(ctx)
+	if b.storageOpts.DryRun {
+		return runErr
+	}
+
+	// the listings of the touched files are refreshed. the other files keep
+	// the listings which the changes are classified against, so that the
+	// changes made during the synchronization are not missed.
+	next := bisyncState{Local: local, Remote: remote}
+	if touched := plan.touched(); len(touched) > 0 {
+		if runErr == nil {
+			localObjects, remoteObjects, err = b.listBothSides(ctx)
+			if err != nil {
+				printError(b.fullCommand, b.op, err)
+				return err
+			}
+			next.update(touched, bisyncState{
+				Local:  bisyncEntries(localObjects),
+				Remote: bisyncEntries(remoteObjects),
+			})
+		} else {
+			// the changes which are not propagated are classified again
+			// in the next synchronization.
+			next.update(touched, prev)
+		}
+	}
+
+	if err := next.save(statePath); err != nil {
+		printError(b.fullCommand, b.op, err)
+		return multierror.Append(runErr, err).ErrorOrNil()
+	}
+	return runErr
+}
+
+// statePath returns the path of the state file of the local directory and
+// the remote prefix pair.
+func (b Bisync) statePath() (string, error) {
+	local, err := filepath.Abs(b.local.Absolute())
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(local + "\n" + b.remote.Absolute() + "\n" + b.storageOpts.Endpoint))
+	return filepath.Join(b.stateDir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func (b Bisync) listBothSides(ctx context.Context) (map[string]*storage.Object, map[string]*storage.Object, error) {
+	localObjects, err := b.list(ctx, b.local)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	remoteObjects, err := b.list(ctx, b.remote)
+	if err != nil {
+		return nil, nil, err
+	}
+	return localObjects, remoteObjects, nil
+}
+
+// list returns the objects under the given directory or prefix by their
+// paths relative to it. Any listing error fails the synchronization, since
+// the missing objects would be taken as deleted.
+func (b Bisync) list(ctx context.Context, u *url.URL) (map[string]*storage.Object, error) {
+	// local directories are walked recursively, an empty directory is not
+	// reported as an error unlike an unmatched wildcard.
+	listurl := u
+	if u.IsRemote() {
+		var err error
+		listurl, err = url.New(strings.TrimSuffix(u.String(), "/") + "/*")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := storage.NewClient(ctx, listurl, b.storageOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := map[string]*storage.Object{}
+	for object := range client.List(ctx, listurl, true) {
+		if err := object.Err; err != nil {
+			if err == storage.ErrNoObjectFound {
+				continue
+			}
+			return nil, err
+		}
+
+		if object.Type.IsDir() {
+			continue
+		}
+
+		isExcluded, err := isObjectExcluded(object, b.excludePatterns, b.includePatterns, listurl.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		if isExcluded {
+			continue
+		}
+
+		key := filepath.ToSlash(object.URL.Relative())
+		ignored, err := b.ignoreTree.Ignored(key, false)
+		if err != nil {
+			return nil, err
+		}
+		if ignored {
+			continue
+		}
+
+		objects[key] = object
+	}
+	return objects, nil
+}
+
+// identicalFunc returns a function which reports whether the local file and
+// the remote object of the given key have the same content. The contents are
+// compared by their checksums, they are assumed to be different if the
+// checksums can't be compared.
+func (b Bisync) identicalFunc(ctx context.Context, localObjects, remoteObjects map[string]*storage.Object) func(string) bool {
+	strategy := &ChecksumStrategy{
+		partSize:   defaultPartSize * megabytes,
+		headObject: newHeadObjectFunc(ctx, b.storageOpts),
+		fallback:   &SizeOnlyStrategy{},
+	}
+
+	return func(key string) bool {
+		return strategy.ShouldSync(localObjects[key], remoteObjects[key]) == errorpkg.ErrObjectEtagsMatch
+	}
+}
+
+// renameConflicts renames the local files which are changed on both sides to
+// keep both versions of them.
+func (b Bisync) renameConflicts(renames map[string]string) error {
+	client := storage.NewLocalClient(b.storageOpts)
+
+	keys := make([]string, 0, len(renames))
+	for key := range renames {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		srcurl, dsturl := b.local.Join(key), b.local.Join(renames[key])
+
+		file, err := client.Open(srcurl.Absolute())
+		if err != nil {
+			return err
+		}
+		file.Close()
+
+		if err := client.Rename(file, dsturl.Absolute()); err != nil {
+			return err
+		}
+
+		msg := log.InfoMessage{
+			Operation:   "mv",
+			Source:      srcurl,
+			Destination: dsturl,
+		}
+		log.Info(msg)
+	}
+	return nil
+}
+
+// planRun writes the commands which propagate the changes to writer 'w'.
+func (b Bisync) planRun(c *cli.Context, plan bisyncPlan, w io.WriteCloser) {
+	defer w.Close()
+
+	// the flags of bisync command are not passed to the generated commands.
+	// their objects are already filtered and given in raw mode.
+	parent := c.Lineage()[1]
+	defaultFlags := map[string]interface{}{
+		"raw": true,
+	}
+
+	emit := func(cmd string, urls ...*url.URL) {
+		command, err := generateCommand(parent, cmd, defaultFlags, urls...)
+		if err != nil {
+			printDebug(b.op, err, urls...)
+			return
+		}
+		fmt.Fprintln(w, command)
+	}
+
+	for _, key := range plan.uploads {
+		emit("cp", b.local.Join(key), b.remote.Join(key))
+	}
+
+	for _, key := range plan.downloads {
+		emit("cp", b.remote.Join(key), b.local.Join(key))
+	}
+
+	if len(plan.remoteDeletes) > 0 {
+		urls := make([]*url.URL, 0, len(plan.remoteDeletes))
+		for _, key := range plan.remoteDeletes {
+			urls = append(urls, b.remote.Join(key))
+		}
+		emit("rm", urls...)
+	}
+
+	if len(plan.localDeletes) > 0 {
+		urls := make([]*url.URL, 0, len(plan.localDeletes))
+		for _, key := range plan.localDeletes {
+			urls = append(urls, b.local.Join(key))
+		}
+		emit("rm", urls...)
+	}
+}
+
+// bisyncEntry is the listing of a file in the last synchronization.
+type bisyncEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	ETag    string    `json:"etag,omitempty"`
+}
+
+// changed reports whether the file is changed since the listing. The ETags
+// are compared if both of them are known, otherwise the sizes and the
+// modification times are compared.
+func (e bisyncEntry) changed(current bisyncEntry) bool {
+	if e.ETag != "" && current.ETag != "" {
+		return e.ETag != current.ETag
+	}
+	return e.Size != current.Size || !e.ModTime.Equal(current.ModTime)
+}
+
+// bisyncState is the state of a local directory and remote prefix pair. It
+// holds the listings of both sides by the relative paths of the files.
+type bisyncState struct {
+	Local  map[string]bisyncEntry `json:"local"`
+	Remote map[string]bisyncEntry `json:"remote"`
+}
+
+// loadBisyncState reads the state from path. An empty state is returned if
+// there is no state file, which means that the pair is synchronized for the
+// first time.
+func loadBisyncState(path string) (bisyncState, error) {
+	state := bisyncState{
+		Local:  map[string]bisyncEntry{},
+		Remote: map[string]bisyncEntry{},
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("unable to read state file %q: %w", path, err)
+	}
+	if state.Local == nil {
+		state.Local = map[string]bisyncEntry{}
+	}
+	if state.Remote == nil {
+		state.Remote = map[string]bisyncEntry{}
+	}
+	return state, nil
+}
+
+// save writes the state to path. The state file is replaced atomically, so
+// that an interrupted write doesn't lose the previous state.
+func (st bisyncState) save(path string) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// update replaces the listings of the given keys with their listings in the
+// given state.
+func (st bisyncState) update(keys []string, from bisyncState) {
+	for _, key := range keys {
+		for _, side := range []struct{ dst, src map[string]bisyncEntry }{
+			{st.Local, from.Local},
+			{st.Remote, from.Remote},
+		} {
+			if entry, ok := side.src[key]; ok {
+				side.dst[key] = entry
+			} else {
+				delete(side.dst, key)
+			}
+		}
+	}
+}
+
+func bisyncEntries(objects map[string]*storage.Object) map[string]bisyncEntry {
+	entries := make(map[string]bisyncEntry, len(objects))
+	for key, object := range objects {
+		entry := bisyncEntry{Size: object.Size, ETag: object.Etag}
+		if object.ModTime != nil {
+			entry.ModTime = object.ModTime.UTC()
+		}
+		entries[key] = entry
+	}
+	return entries
+}
+
+// checkEmptySides refuses to synchronize if either side is empty while it
+// wasn't in the last synchronization, such as an unmounted directory or a
+// wrong endpoint, which would delete all the files on the other side.
+func checkEmptySides(prev bisyncState, local, remote map[string]bisyncEntry, statePath string) error {
+	if len(local) == 0 && len(prev.Local) > 0 {
+		return fmt.Errorf("local directory is empty but it had %d files in the last synchronization, remove the state file %q to synchronize from scratch", len(prev.Local), statePath)
+	}
+	if len(remote) == 0 && len(prev.Remote) > 0 {
+		return fmt.Errorf("remote prefix is empty but it had %d objects in the last synchronization, remove the state file %q to synchronize from scratch", len(prev.Remote), statePath)
+	}
+	return nil
+}
+
+type bisyncChange int
+
+const (
+	bisyncUnchanged bisyncChange = iota
+	bisyncCreated
+	bisyncModified
+	bisyncDeleted
+)
+
+// classifyChange classifies the change of a file on one side since the last
+// synchronization.
+func classifyChange(prev, current map[string]bisyncEntry, key string) bisyncChange {
+	prevEntry, prevOk := prev[key]
+	curEntry, curOk := current[key]
+
+	switch {
+	case !prevOk && curOk:
+		return bisyncCreated
+	case prevOk && !curOk:
+		return bisyncDeleted
+	case prevOk && curOk && prevEntry.changed(curEntry):
+		return bisyncModified
+	}
+	return bisyncUnchanged
+}
+
+// bisyncPlan holds the keys of the files to be propagated.
+type bisyncPlan struct {
+	uploads       []string
+	downloads     []string
+	localDeletes  []string
+	remoteDeletes []string
+
+	// renames maps the keys of the local files which are renamed to keep
+	// both versions of them to their new keys.
+	renames map[string]string
+
+	// conflicts are the keys of the files which are changed on both sides
+	// with different contents.
+	conflicts []string
+}
+
+// touched returns the keys of the files which are changed on either side by
+// the plan.
+func (p bisyncPlan) touched() []string {
+	var keys []string
+	for _, list := range [][]string{p.uploads, p.downloads, p.localDeletes, p.remoteDeletes} {
+		keys = append(keys, list...)
+	}
+	for key := range p.renames {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// planBisync decides how to propagate the changes on both sides since the
+// last synchronization. A change on one side is propagated to the other side
+// if the other side is unchanged. If a file is deleted on one side and
+// changed on the other side, the changed file is kept. If a file is changed
+// on both sides with different contents, the conflict is resolved by the
+// given policy. identical reports whether the local file and the remote
+// object of a key have the same content.
+func planBisync(prev bisyncState, local, remote map[string]bisyncEntry, policy string, identical func(string) bool) bisyncPlan {
+	plan := bisyncPlan{renames: map[string]string{}}
+
+	keys := map[string]struct{}{}
+	for _, m := range []map[string]bisyncEntry{prev.Local, prev.Remote, local, remote} {
+		for key := range m {
+			keys[key] = struct{}{}
+		}
+	}
+
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, key := range sortedKeys {
+		localChange := classifyChange(prev.Local, local, key)
+		remoteChange := classifyChange(prev.Remote, remote, key)
+		_, localOk := local[key]
+		_, remoteOk := remote[key]
+
+		switch {
+		case localChange == bisyncUnchanged && remoteChange == bisyncUnchanged:
+		case remoteChange == bisyncUnchanged:
+			if localOk {
+				plan.uploads = append(plan.uploads, key)
+			} else if remoteOk {
+				plan.remoteDeletes = append(plan.remoteDeletes, key)
+			}
+		case localChange == bisyncUnchanged:
+			if remoteOk {
+				plan.downloads = append(plan.downloads, key)
+			} else if localOk {
+				plan.localDeletes = append(plan.localDeletes, key)
+			}
+		case !localOk && !remoteOk:
+			// deleted on both sides.
+		case !remoteOk:
+			// deleted on remote but changed on local.
+			plan.uploads = append(plan.uploads, key)
+		case !localOk:
+			// deleted on local but changed on remote.
+			plan.downloads = append(plan.downloads, key)
+		case identical(key):
+		default:
+			plan.conflicts = append(plan.conflicts, key)
+			switch policy {
+			case conflictNewer:
+				if remote[key].ModTime.After(local[key].ModTime) {
+					plan.downloads = append(plan.downloads, key)
+				} else {
+					plan.uploads = append(plan.uploads, key)
+				}
+			case conflictKeepBoth:
+				newKey := conflictKey(key, func(k string) bool {
+					_, ok := keys[k]
+					return ok
+				})
+				keys[newKey] = struct{}{}
+				plan.renames[key] = newKey
+				plan.uploads = append(plan.uploads, newKey)
+				plan.downloads = append(plan.downloads, key)
+			}
+		}
+	}
+	return plan
+}
+
+// conflictKey returns the key which the local version of a conflicting file
+// is kept under, e.g. "dir/report.conflict.txt".
+func conflictKey(key string, taken func(string) bool) string {
+	ext := path.Ext(key)
+	if ext == path.Base(key) {
+		ext = ""
+	}
+	base := strings.TrimSuffix(key, ext)
+
+	newKey := base + ".conflict" + ext
+	for i := 2; taken(newKey); i++ {
+		newKey = base + ".conflict-" + strconv.Itoa(i) + ext
+	}
+	return newKey
+}
+
+func validateBisyncCommand(c *cli.Context) error {
+	if c.Args().Len() != 2 {
+		return fmt.Errorf("expected local directory and remote prefix arguments")
+	}
+
+	localurl, err := url.New(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	remoteurl, err := url.New(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	if localurl.IsRemote() || !remoteurl.IsRemote() {
+		return fmt.Errorf("expected a local directory and a remote prefix in order")
+	}
+
+	if localurl.IsWildcard() || remoteurl.IsWildcard() {
+		return fmt.Errorf("arguments can not contain glob characters")
+	}
+
+	if !remoteurl.IsPrefix() && !remoteurl.IsBucket() {
+		return fmt.Errorf("target %q must be a bucket or a prefix", remoteurl)
+	}
+
+	st, err := os.Stat(localurl.Absolute())
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("source %q must be a directory", localurl)
+	}
+	return nil
+}
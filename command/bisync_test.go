@@ -0,0 +1,162 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gotest.tools/v3/assert"
+)
+
+func TestPlanBisync(t *testing.T) {
+	t.Parallel()
+
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := old.Add(time.Hour)
+	newest := old.Add(2 * time.Hour)
+
+	entry := func(size int64, mod time.Time) bisyncEntry {
+		return bisyncEntry{Size: size, ModTime: mod}
+	}
+
+	prev := bisyncState{
+		Local: map[string]bisyncEntry{
+			"unchanged":          entry(1, old),
+			"modified-local":     entry(1, old),
+			"modified-remote":    entry(1, old),
+			"deleted-local":      entry(1, old),
+			"deleted-remote":     entry(1, old),
+			"deleted-both":       entry(1, old),
+			"deleted-local-mod":  entry(1, old),
+			"deleted-remote-mod": entry(1, old),
+			"conflict.txt":       entry(1, old),
+			"identical":          entry(1, old),
+		},
+		Remote: map[string]bisyncEntry{
+			"unchanged":          {Size: 1, ModTime: newer, ETag: "a"},
+			"modified-local":     {Size: 1, ModTime: newer, ETag: "a"},
+			"modified-remote":    {Size: 1, ModTime: newer, ETag: "a"},
+			"deleted-local":      {Size: 1, ModTime: newer, ETag: "a"},
+			"deleted-remote":     {Size: 1, ModTime: newer, ETag: "a"},
+			"deleted-both":       {Size: 1, ModTime: newer, ETag: "a"},
+			"deleted-local-mod":  {Size: 1, ModTime: newer, ETag: "a"},
+			"deleted-remote-mod": {Size: 1, ModTime: newer, ETag: "a"},
+			"conflict.txt":       {Size: 1, ModTime: newer, ETag: "a"},
+			"identical":          {Size: 1, ModTime: newer, ETag: "a"},
+		},
+	}
+
+	local := map[string]bisyncEntry{
+		"unchanged":          entry(1, old),
+		"modified-local":     entry(2, newer),
+		"modified-remote":    entry(1, old),
+		"deleted-remote":     entry(1, old),
+		"deleted-remote-mod": entry(2, newer),
+		"conflict.txt":       entry(2, newest),
+		"identical":          entry(2, newer),
+		"created-local":      entry(1, newer),
+		"created-both":       entry(1, newer),
+	}
+
+	remote := map[string]bisyncEntry{
+		"unchanged":         {Size: 1, ModTime: newer, ETag: "a"},
+		"modified-local":    {Size: 1, ModTime: newer, ETag: "a"},
+		"modified-remote":   {Size: 1, ModTime: newer, ETag: "b"},
+		"deleted-local":     {Size: 1, ModTime: newer, ETag: "a"},
+		"deleted-local-mod": {Size: 1, ModTime: newer, ETag: "b"},
+		"conflict.txt":      {Size: 3, ModTime: newer, ETag: "b"},
+		"identical":         {Size: 2, ModTime: newest, ETag: "b"},
+		"created-remote":    {Size: 1, ModTime: newer, ETag: "c"},
+		"created-both":      {Size: 2, ModTime: newest, ETag: "d"},
+	}
+
+	identical := func(key string) bool { return key == "identical" }
+
+	testcases := []struct {
+		policy   string
+		expected bisyncPlan
+	}{
+		{
+			policy: conflictNewer,
+			expected: bisyncPlan{
+				uploads:       []string{"conflict.txt", "created-local", "deleted-remote-mod", "modified-local"},
+				downloads:     []string{"created-both", "created-remote", "deleted-local-mod", "modified-remote"},
+				localDeletes:  []string{"deleted-remote"},
+				remoteDeletes: []string{"deleted-local"},
+				renames:       map[string]string{},
+				conflicts:     []string{"conflict.txt", "created-both"},
+			},
+		},
+		{
+			policy: conflictKeepBoth,
+			expected: bisyncPlan{
+				uploads:       []string{"conflict.conflict.txt", "created-both.conflict", "created-local", "deleted-remote-mod", "modified-local"},
+				downloads:     []string{"conflict.txt", "created-both", "created-remote", "deleted-local-mod", "modified-remote"},
+				localDeletes:  []string{"deleted-remote"},
+				remoteDeletes: []string{"deleted-local"},
+				renames: map[string]string{
+					"conflict.txt": "conflict.conflict.txt",
+					"created-both": "created-both.conflict",
+				},
+				conflicts: []string{"conflict.txt", "created-both"},
+			},
+		},
+		{
+			policy: conflictFail,
+			expected: bisyncPlan{
+				uploads:       []string{"created-local", "deleted-remote-mod", "modified-local"},
+				downloads:     []string{"created-remote", "deleted-local-mod", "modified-remote"},
+				localDeletes:  []string{"deleted-remote"},
+				remoteDeletes: []string{"deleted-local"},
+				renames:       map[string]string{},
+				conflicts:     []string{"conflict.txt", "created-both"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.policy, func(t *testing.T) {
+			t.Parallel()
+
+			plan := planBisync(prev, local, remote, tc.policy, identical)
+			if diff := cmp.Diff(tc.expected, plan, cmp.AllowUnexported(bisyncPlan{})); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestPlanBisyncFirstRun(t *testing.T) {
+	t.Parallel()
+
+	mod := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := bisyncState{Local: map[string]bisyncEntry{}, Remote: map[string]bisyncEntry{}}
+	local := map[string]bisyncEntry{"a": {Size: 1, ModTime: mod}, "b": {Size: 1, ModTime: mod}}
+	remote := map[string]bisyncEntry{"b": {Size: 1, ModTime: mod}, "c": {Size: 1, ModTime: mod}}
+
+	plan := planBisync(prev, local, remote, conflictFail, func(string) bool { return true })
+	expected := bisyncPlan{
+		uploads:   []string{"a"},
+		downloads: []string{"c"},
+		renames:   map[string]string{},
+	}
+	if diff := cmp.Diff(expected, plan, cmp.AllowUnexported(bisyncPlan{})); diff != "" {
+		t.Errorf("(-want +got):\n%v", diff)
+	}
+}
+
+func TestConflictKey(t *testing.T) {
+	t.Parallel()
+
+	taken := map[string]bool{
+		"dir/b.conflict.txt":   true,
+		"dir/b.conflict-2.txt": true,
+	}
+	isTaken := func(key string) bool { return taken[key] }
+
+	assert.Equal(t, conflictKey("dir/a.txt", isTaken), "dir/a.conflict.txt")
+	assert.Equal(t, conflictKey("dir/b.txt", isTaken), "dir/b.conflict-3.txt")
+	assert.Equal(t, conflictKey("dir/.env", isTaken), "dir/.env.conflict")
+	assert.Equal(t, conflictKey("noext", isTaken), "noext.conflict")
+}
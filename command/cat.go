@@ -7,8 +7,11 @@ import (
 
 	"github.com/urfave/cli/v2"
 
+	"github.com/peak/s5cmd/v2/cse"
 	"github.com/peak/s5cmd/v2/log/stat"
 	"github.com/peak/s5cmd/v2/orderedwriter"
+	"github.com/peak/s5cmd/v2/progressbar"
+	"github.com/peak/s5cmd/v2/ratelimit"
 	"github.com/peak/s5cmd/v2/storage"
 	"github.com/peak/s5cmd/v2/storage/url"
 )
@@ -31,6 +34,15 @@ Examples:
 
 	3. Concatenate multiple objects matching a prefix or wildcard and print to stdout
 		 > s5cmd {{.HelpName}} "s3://bucket/prefix/*"
+
+	4. Print the content of an object encrypted on the client side
+		 > s5cmd {{.HelpName}} --cse-key-file ~/.s5cmd/key s3://bucket/prefix/object
+
+	5. Print the content of an object encrypted on the server side with a customer-provided key (SSE-C)
+		 > s5cmd {{.HelpName}} --sse-c-key-file ~/.s5cmd/sse-c-key s3://bucket/prefix/object
+
+	6. Print the decompressed content of a gzip or zstd compressed object
+		 > s5cmd {{.HelpName}} --decompress s3://bucket/prefix/object.gz
 `
 
 func NewCatCommand() *cli.Command {
@@ -59,6 +71,26 @@ func NewCatCommand() *cli.Command {
 				Value:   defaultPartSize,
 				Usage:   "size of each part transferred between host and remote server, in MiB",
 			},
+			&cli.StringFlag{
+				Name:  "cse-key-file",
+				Usage: "decrypt the objects encrypted on the client side with the 256-bit key in the given file",
+			},
+			&cli.StringFlag{
+				Name:  "cse-passphrase-file",
+				Usage: "decrypt the objects encrypted on the client side with a key derived from the passphrase in the given file",
+			},
+			&cli.StringFlag{
+				Name:  "sse-c-key",
+				Usage: "read the objects encrypted on the server side with the given 256-bit customer-provided key (SSE-C), either raw or base64 encoded",
+			},
+			&cli.StringFlag{
+				Name:  "sse-c-key-file",
+				Usage: "read the objects encrypted on the server side with the customer-provided key (SSE-C) in the given file",
+			},
+			&cli.BoolFlag{
+				Name:  "decompress",
+				Usage: "decompress the objects compressed with gzip or zstd, detected by their content encoding or contents",
+			},
 		},
 		CustomHelpTemplate: catHelpTemplate,
 		Before: func(c *cli.Context) error {
@@ -81,6 +113,12 @@ func NewCatCommand() *cli.Command {
 				return err
 			}
 
+			masterKey, err := newMasterKey(c)
+			if err != nil {
+				printError(fullCommand, op, err)
+				return err
+			}
+
 			return Cat{
 				src:         src,
 				op:          op,
@@ -89,6 +127,8 @@ func NewCatCommand() *cli.Command {
 				storageOpts: NewStorageOpts(c),
 				concurrency: c.Int("concurrency"),
 				partSize:    c.Int64("part-size") * megabytes,
+				masterKey:   masterKey,
+				decompress:  c.Bool("decompress"),
 			}.Run(c.Context)
 		},
 	}
@@ -105,19 +145,28 @@ type Cat struct {
 	storageOpts storage.Options
 	concurrency int
 	partSize    int64
+	masterKey   *cse.MasterKey
+	decompress  bool
 }
 
 // Run prints content of given source to standard output.
 func (c Cat) Run(ctx context.Context) error {
-	client, err := storage.NewRemoteClient(ctx, c.src, c.storageOpts)
+	client, err := storage.NewClient(ctx, c.src, c.storageOpts)
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
 	}
 
+	getter, ok := client.(storage.Getter)
+	if !ok {
+		err := storage.NotSupported(c.src, "cat")
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+
 	if c.src.IsWildcard() || c.src.IsPrefix() || c.src.IsBucket() {
 		objectChan := client.List(ctx, c.src, false)
-		return c.processObjects(ctx, client, objectChan)
+		return c.processObjects(ctx, getter, objectChan)
 	}
 
 	_, err = client.Stat(ctx, c.src)
@@ -125,10 +174,15 @@ func (c Cat) Run(ctx context.Context) error {
 		printError(c.fullCommand, c.op, err)
 		return err
 	}
-	return c.processSingleObject(ctx, client, c.src)
+
+	err = c.processSingleObject(ctx, getter, c.src)
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+	}
+	return err
 }
 
-func (c Cat) processObjects(ctx context.Context, client *storage.S3, objectChan <-chan *storage.Object) error {
+func (c Cat) processObjects(ctx context.Context, client storage.Getter, objectChan <-chan *storage.Object) error {
 	for obj := range objectChan {
 		if obj.Err != nil {
 			printError(c.fullCommand, c.op, obj.Err)
@@ -148,8 +202,18 @@ func (c Cat) processObjects(ctx context.Context, client *storage.S3, objectChan
 	return nil
 }
 
-func (c Cat) processSingleObject(ctx context.Context, client *storage.S3, url *url.URL) error {
-	buf := orderedwriter.New(os.Stdout)
+func (c Cat) processSingleObject(ctx context.Context, client storage.Getter, url *url.URL) error {
+	if c.decompress {
+		_, err := getDecompressed(ctx, client, c.masterKey, url, os.Stdout, &progressbar.NoOp{}, c.concurrency, c.partSize)
+		return err
+	}
+
+	buf := ratelimit.NewWriterAt(orderedwriter.New(os.Stdout), ratelimit.Download)
+	if c.masterKey != nil {
+		_, err := getDecrypted(ctx, client, c.masterKey, url, buf, c.concurrency, c.partSize)
+		return err
+	}
+
 	_, err := client.Get(ctx, url, buf, c.concurrency, c.partSize)
 	return err
 }
@@ -179,5 +243,9 @@ func validateCatCommand(c *cli.Context) error {
 		}
 	}
 
-	return nil
+	if err := validateSSECustomerKey(c); err != nil {
+		return err
+	}
+
+	return validateClientSideEncryption(c)
 }
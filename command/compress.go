@@ -0,0 +1,283 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/cse"
+	"github.com/peak/s5cmd/v2/orderedwriter"
+	"github.com/peak/s5cmd/v2/progressbar"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// compression algorithms of the uploads, named after their Content-Encoding
+// values.
+const (
+	compressGzip = "gzip"
+	compressZstd = "zstd"
+)
+
+var compressionExtensions = map[string]string{
+	compressGzip: ".gz",
+	compressZstd: ".zst",
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compressReader returns a reader which compresses the contents of r while
+// they are read. Closing the reader stops the compression.
+func compressReader(r io.Reader, encoding string) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		var (
+			w   io.WriteCloser
+			err error
+		)
+		if encoding == compressZstd {
+			w, err = zstd.NewWriter(pw)
+		} else {
+			w = gzip.NewWriter(pw)
+		}
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		_, err = io.Copy(w, r)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
+
+// compressionOf returns the compression algorithm of the Content-Encoding
+// value, if it is known.
+func compressionOf(contentEncoding string) string {
+	for _, encoding := range strings.Split(contentEncoding, ",") {
+		switch strings.ToLower(strings.TrimSpace(encoding)) {
+		case "gzip", "x-gzip":
+			return compressGzip
+		case "zstd":
+			return compressZstd
+		}
+	}
+	return ""
+}
+
+// decompressReader returns a reader which decompresses the contents of r. The
+// compression is detected from the Content-Encoding of the object, or from
+// the magic bytes of the contents if the encoding is not known. The contents
+// which are not compressed are read as is.
+func decompressReader(r io.Reader, contentEncoding string) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	encoding := compressionOf(contentEncoding)
+	if encoding == "" {
+		magic, _ := br.Peek(len(zstdMagic))
+		switch {
+		case bytes.HasPrefix(magic, gzipMagic):
+			encoding = compressGzip
+		case bytes.HasPrefix(magic, zstdMagic):
+			encoding = compressZstd
+		}
+	}
+
+	switch encoding {
+	case compressGzip:
+		return gzip.NewReader(br)
+	case compressZstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// trimCompressionExtension removes the extension of the compressed files
+// from the path of the local destination.
+func trimCompressionExtension(dsturl *url.URL) *url.URL {
+	for _, ext := range compressionExtensions {
+		if strings.HasSuffix(dsturl.Path, ext) && len(dsturl.Path) > len(ext) {
+			trimmed := dsturl.Clone()
+			trimmed.Path = strings.TrimSuffix(dsturl.Path, ext)
+			return trimmed
+		}
+	}
+	return dsturl
+}
+
+// getDecompressed writes the decompressed contents of the object to the
+// writer. The object is downloaded with the concurrent range requests, which
+// are ordered before they are decompressed. It returns the size of the
+// decompressed contents.
+func getDecompressed(
+	ctx context.Context,
+	client storage.Getter,
+	masterKey *cse.MasterKey,
+	srcurl *url.URL,
+	to io.Writer,
+	pb progressbar.ProgressBar,
+	concurrency int,
+	partSize int64,
+) (int64, error) {
+	var contentEncoding string
+	if metadataReader, ok := client.(storage.MetadataReader); ok {
+		_, metadata, err := metadataReader.HeadObject(ctx, srcurl)
+		if err != nil {
+			return 0, err
+		}
+		contentEncoding = metadata.ContentEncoding
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		writer := newCountingWriter(orderedwriter.New(pw), pb)
+
+		var err error
+		if masterKey != nil {
+			_, err = getDecrypted(ctx, client, masterKey, srcurl, writer, concurrency, partSize)
+		} else {
+			_, err = client.Get(ctx, srcurl, writer, concurrency, partSize)
+		}
+		pw.CloseWithError(err)
+	}()
+
+	// the download is stopped if the contents can't be decompressed.
+	defer func() {
+		pr.Close()
+		<-done
+	}()
+
+	reader, err := decompressReader(pr, contentEncoding)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	return io.Copy(to, reader)
+}
+
+// doCompressedUpload compresses the contents of the reader while they are
+// uploaded. The compressed contents are encrypted on the client side if a
+// master key is given.
+func (c Copy) doCompressedUpload(
+	ctx context.Context,
+	putter storage.Putter,
+	reader io.Reader,
+	dsturl *url.URL,
+	metadata storage.Metadata,
+) error {
+	body := compressReader(reader, c.compress)
+	defer body.Close()
+
+	metadata.ContentEncoding = c.compress
+
+	var upload io.Reader = body
+	if c.masterKey != nil {
+		env, err := c.masterKey.NewEnvelope()
+		if err != nil {
+			return err
+		}
+
+		userDefined := env.Metadata()
+		for k, v := range metadata.UserDefined {
+			userDefined[k] = v
+		}
+		metadata.UserDefined = userDefined
+		upload = env.NewStreamReader(body)
+	}
+
+	return putter.Put(ctx, upload, dsturl, metadata, c.concurrency, c.partSize)
+}
+
+// doDecompressedDownload decompresses the object into a temporary file, which
+// is renamed to the destination once the download is completed.
+func (c Copy) doDecompressedDownload(
+	ctx context.Context,
+	srcClient storage.Getter,
+	dstClient *storage.Filesystem,
+	srcurl, dsturl *url.URL,
+) (int64, error) {
+	dstPath := filepath.Dir(dsturl.Absolute())
+	dstFile := filepath.Base(dsturl.Absolute())
+	file, err := dstClient.CreateTemp(dstPath, dstFile)
+	if err != nil {
+		return 0, err
+	}
+
+	masterKey := c.masterKey
+	if c.storageOpts.DryRun {
+		masterKey = nil
+	}
+
+	size, err := getDecompressed(ctx, srcClient, masterKey, srcurl, file, c.progressbar, c.concurrency, c.partSize)
+	file.Close()
+
+	if err != nil {
+		dErr := dstClient.Delete(ctx, &url.URL{Path: file.Name(), Type: dsturl.Type})
+		if dErr != nil {
+			printDebug(c.op, dErr, srcurl, dsturl)
+		}
+		return 0, err
+	}
+
+	if err := dstClient.Rename(file, dsturl.Absolute()); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+func validateCompression(c *cli.Context, srcurl, dsturl *url.URL) error {
+	if c.String("compress") != "" {
+		if srcurl.IsRemote() || !dsturl.IsRemote() {
+			return fmt.Errorf("--compress flag is only supported for uploads")
+		}
+		if c.String("content-encoding") != "" {
+			return fmt.Errorf("--compress and --content-encoding flags can not be used together")
+		}
+		if c.Bool("resume") {
+			return fmt.Errorf("--resume flag can not be used with --compress flag")
+		}
+		if c.String("pack") != "" {
+			return fmt.Errorf("--pack and --compress flags can not be used together")
+		}
+	} else if c.Bool("compress-extension") {
+		return fmt.Errorf("--compress-extension flag requires --compress flag")
+	}
+
+	if c.Bool("decompress") {
+		if !srcurl.IsRemote() || dsturl.IsRemote() {
+			return fmt.Errorf("--decompress flag is only supported for downloads")
+		}
+		if c.Bool("resume") {
+			return fmt.Errorf("--resume flag can not be used with --decompress flag")
+		}
+		if c.Bool("unpack") {
+			return fmt.Errorf("--unpack and --decompress flags can not be used together")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/progressbar"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestCompressionOf(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		contentEncoding string
+		expected        string
+	}{
+		{contentEncoding: "gzip", expected: compressGzip},
+		{contentEncoding: "X-GZIP", expected: compressGzip},
+		{contentEncoding: "zstd", expected: compressZstd},
+		{contentEncoding: "aws-chunked, gzip", expected: compressGzip},
+		{contentEncoding: "br", expected: ""},
+		{contentEncoding: "", expected: ""},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, compressionOf(tc.contentEncoding), tc.expected, tc.contentEncoding)
+	}
+}
+
+func TestCompressRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Repeat("s5cmd compresses the uploads\n", 10000)
+
+	for _, encoding := range []string{compressGzip, compressZstd} {
+		compressed, err := io.ReadAll(compressReader(strings.NewReader(content), encoding))
+		assert.NilError(t, err, encoding)
+		assert.Assert(t, len(compressed) < len(content), encoding)
+
+		// the encoding is given by the Content-Encoding of the object.
+		reader, err := decompressReader(bytes.NewReader(compressed), encoding)
+		assert.NilError(t, err, encoding)
+		data, err := io.ReadAll(reader)
+		assert.NilError(t, err, encoding)
+		assert.Equal(t, string(data), content, encoding)
+
+		// the encoding is detected from the contents.
+		reader, err = decompressReader(bytes.NewReader(compressed), "")
+		assert.NilError(t, err, encoding)
+		data, err = io.ReadAll(reader)
+		assert.NilError(t, err, encoding)
+		assert.Equal(t, string(data), content, encoding)
+	}
+
+	// the contents which are not compressed are read as is.
+	reader, err := decompressReader(strings.NewReader("plain"), "")
+	assert.NilError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "plain")
+
+	_, err = decompressReader(strings.NewReader("plain text, not compressed"), "gzip")
+	assert.ErrorContains(t, err, "invalid header")
+}
+
+func TestTrimCompressionExtension(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "dir/file.txt.gz", expected: "dir/file.txt"},
+		{path: "file.zst", expected: "file"},
+		{path: "file.tgz", expected: "file.tgz"},
+		{path: ".gz", expected: ".gz"},
+	}
+
+	for _, tc := range testcases {
+		u, err := url.New(tc.path)
+		assert.NilError(t, err)
+		assert.Equal(t, trimCompressionExtension(u).Path, tc.expected, tc.path)
+	}
+}
+
+func TestGetDecompressed(t *testing.T) {
+	t.Parallel()
+	log.Init("error", false)
+
+	ctx := context.Background()
+	content := strings.Repeat("0123456789", 100000)
+
+	for _, encoding := range []string{compressGzip, compressZstd} {
+		client := storage.NewMemoryClient(storage.Options{})
+		src, err := url.New("s3://bucket/object")
+		assert.NilError(t, err)
+
+		body := compressReader(strings.NewReader(content), encoding)
+		err = client.Put(ctx, body, src, storage.Metadata{ContentEncoding: encoding}, 1, 0)
+		assert.NilError(t, err, encoding)
+
+		var buf bytes.Buffer
+		n, err := getDecompressed(ctx, client, nil, src, &buf, &progressbar.NoOp{}, 4, 64*1024)
+		assert.NilError(t, err, encoding)
+		assert.Equal(t, n, int64(len(content)), encoding)
+		assert.Equal(t, buf.String(), content, encoding)
+	}
+}
@@ -11,12 +11,27 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
+// secretFlags are the flags whose values are not printed in the commands.
+var secretFlags = map[string]bool{
+	"sse-c-key":             true,
+	"sse-c-copy-source-key": true,
+}
+
+// commandFromContext returns the command given in the context to be printed.
+// The values of the secret flags are redacted.
 func commandFromContext(c *cli.Context) string {
+	return formatCommand(c, true)
+}
+
+func formatCommand(c *cli.Context, redact bool) string {
 	cmd := c.Command.FullName()
 
 	for _, f := range c.Command.Flags {
 		flagname := f.Names()[0]
 		for _, flagvalue := range contextValue(c, flagname) {
+			if redact && secretFlags[flagname] {
+				flagvalue = "REDACTED"
+			}
 			cmd = fmt.Sprintf("%s --%s=%v", cmd, flagname, flagvalue)
 		}
 	}
@@ -61,6 +76,17 @@ func contextValue(c *cli.Context, flagname string) []string {
 	return nil
 }
 
+// generatorFlags are the flags which are handled by the command which
+// generates the commands, such as the pattern files which are applied while
+// the objects are listed and the backup directory which differs for each of
+// the objects. They are not passed to the generated commands, the ones which
+// are needed are given explicitly with the default flags.
+var generatorFlags = map[string]struct{}{
+	"exclude-from": {},
+	"include-from": {},
+	"backup-dir":   {},
+}
+
 // generateCommand generates command string from given context, app command, default flags and urls.
 func generateCommand(c *cli.Context, cmd string, defaultFlags map[string]interface{}, urls ...*url.URL) (string, error) {
 	command := AppCommand(cmd)
@@ -86,6 +112,9 @@ func generateCommand(c *cli.Context, cmd string, defaultFlags map[string]interfa
 		if isDefaultFlag(flagname) || !c.IsSet(flagname) {
 			continue
 		}
+		if _, ok := generatorFlags[flagname]; ok {
+			continue
+		}
 
 		for _, flagvalue := range contextValue(c, flagname) {
 			flags = append(flags, fmt.Sprintf("--%s='%s'", flagname, flagvalue))
@@ -102,5 +131,5 @@ func generateCommand(c *cli.Context, cmd string, defaultFlags map[string]interfa
 	}
 
 	cmdCtx := cli.NewContext(c.App, flagset, c)
-	return strings.TrimSpace(commandFromContext(cmdCtx)), nil
+	return strings.TrimSpace(formatCommand(cmdCtx, false)), nil
 }
@@ -135,6 +135,44 @@ func TestGenerateCommand(t *testing.T) {
 			},
 			expectedCommand: `cp --exclude='*.log' --exclude='*.txt' "/source/dir" "s3://bucket/prefix/"`,
 		},
+		{
+			name: "generator-flags-are-not-passed",
+			cmd:  "cp",
+			flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "exclude-from",
+					Value: "exclude.txt",
+				},
+				&cli.StringFlag{
+					Name:  "include-from",
+					Value: "include.txt",
+				},
+				&cli.StringFlag{
+					Name:  "backup-dir",
+					Value: "s3://bucket/backup/",
+				},
+			},
+			urls: []*url.URL{
+				mustNewURL(t, "/source/dir/file"),
+				mustNewURL(t, "s3://bucket/prefix/file"),
+			},
+			expectedCommand: `cp "/source/dir/file" "s3://bucket/prefix/file"`,
+		},
+		{
+			name: "secret-flags-are-not-redacted",
+			cmd:  "cp",
+			flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "sse-c-key",
+					Value: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+				},
+			},
+			urls: []*url.URL{
+				mustNewURL(t, "s3://bucket/key1"),
+				mustNewURL(t, "s3://bucket/key2"),
+			},
+			expectedCommand: `cp --sse-c-key='MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=' "s3://bucket/key1" "s3://bucket/key2"`,
+		},
 		{
 			name:  "command-with-multiple-args",
 			cmd:   "rm",
@@ -1,7 +1,11 @@
 package command
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -16,11 +20,13 @@ import (
 	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
 
+	"github.com/peak/s5cmd/v2/cse"
 	errorpkg "github.com/peak/s5cmd/v2/error"
 	"github.com/peak/s5cmd/v2/log"
 	"github.com/peak/s5cmd/v2/log/stat"
 	"github.com/peak/s5cmd/v2/parallel"
 	"github.com/peak/s5cmd/v2/progressbar"
+	"github.com/peak/s5cmd/v2/ratelimit"
 	"github.com/peak/s5cmd/v2/storage"
 	"github.com/peak/s5cmd/v2/storage/url"
 )
@@ -37,6 +43,18 @@ const (
 	metadataDirectiveReplace = "REPLACE"
 )
 
+// properties of source objects which can be preserved on copies between
+// remote storages.
+const (
+	preserveMetadata     = "metadata"
+	preserveTags         = "tags"
+	preserveACL          = "acl"
+	preserveStorageClass = "storage-class"
+)
+
+// sseCustomerKeySize is the size of the customer-provided keys of SSE-C.
+const sseCustomerKeySize = 32
+
 var copyHelpTemplate = `Name:
 	{{.HelpName}} - {{.Usage}}
 
@@ -118,6 +136,54 @@ Examples:
 
 	24. Pass arbitrary metadata to the object during upload or copy
 		 > s5cmd {{.HelpName}} --metadata "camera=Nixon D750" --metadata "imageSize=6032x4032" flowers.png s3://bucket/prefix/flowers.png
+
+	25. Upload a large file to S3 bucket and continue from the last uploaded part if it is interrupted
+		 > s5cmd {{.HelpName}} --resume bigfile.tar s3://bucket/
+
+	26. Download a large S3 object and continue from the downloaded ranges if it is interrupted
+		 > s5cmd {{.HelpName}} --resume s3://bucket/bigfile.tar .
+
+	27. Copy S3 objects to another bucket with their metadata, tags, ACLs and storage classes
+		 > s5cmd {{.HelpName}} --preserve=metadata,tags,acl,storage-class "s3://bucket/*" s3://target-bucket/prefix/
+
+	28. Upload files to S3 bucket with tags
+		 > s5cmd {{.HelpName}} --tagging "project=s5cmd&team=storage" "dir/*" s3://bucket/prefix/
+
+	29. Upload files to S3 bucket encrypted on the client side with the key in a local file
+		 > s5cmd {{.HelpName}} --cse-key-file ~/.s5cmd/key "dir/*" s3://bucket/prefix/
+
+	30. Download and decrypt objects encrypted on the client side with a key derived from a passphrase
+		 > s5cmd {{.HelpName}} --cse-passphrase-file ~/.s5cmd/passphrase "s3://bucket/prefix/*" dir/
+
+	31. Upload files to S3 bucket encrypted on the server side with a customer-provided key (SSE-C)
+		 > s5cmd {{.HelpName}} --sse-c-key-file ~/.s5cmd/sse-c-key "dir/*" s3://bucket/prefix/
+
+	32. Copy S3 objects encrypted with a customer-provided key to another bucket with a new key
+		 > s5cmd {{.HelpName}} --sse-c-copy-source-key-file old-key --sse-c-key-file new-key "s3://bucket/*" s3://target-bucket/
+
+	33. Upload all files in a directory as a single zstd compressed tar archive
+		 > s5cmd {{.HelpName}} --pack tar.zst dir/ s3://bucket/dir.tar.zst
+
+	34. Download an archive and extract its files to a directory except the log files
+		 > s5cmd {{.HelpName}} --unpack --exclude "*.log" s3://bucket/dir.tar.zst dir/
+
+	35. Upload the log files compressed with gzip and append .gz to their names
+		 > s5cmd {{.HelpName}} --compress gzip --compress-extension "logs/*.log" s3://bucket/logs/
+
+	36. Download compressed objects and decompress them on the fly
+		 > s5cmd {{.HelpName}} --decompress "s3://bucket/logs/*.log.gz" logs/
+
+	37. Download the matching objects listed in an S3 Inventory report instead of listing the bucket
+		 > s5cmd {{.HelpName}} --from-inventory s3://inventory-bucket/bucket/config/2024-01-01T01-00Z/manifest.json "s3://bucket/logs/*" logs/
+
+	38. Download the objects of the keys in a file, one key per line
+		 > s5cmd {{.HelpName}} --files-from keys.txt "s3://bucket/*" dir/
+
+	39. Upload a directory but skip the files matched by the patterns in a file, in gitignore syntax
+		 > s5cmd {{.HelpName}} --exclude-from .gitignore dir/ s3://bucket/
+
+	40. Upload a directory without skipping the files listed in its .s5cmdignore files
+		 > s5cmd --ignore-file "" {{.HelpName}} dir/ s3://bucket/
 `
 
 func NewSharedFlags() []cli.Flag {
@@ -165,6 +231,30 @@ func NewSharedFlags() []cli.Flag {
 			Name:  "sse-kms-key-id",
 			Usage: "customer master key (CMK) id for SSE-KMS encryption; leave it out if server-side generated key is desired",
 		},
+		&cli.StringFlag{
+			Name:  "cse-key-file",
+			Usage: "encrypt uploads and decrypt downloads on the client side with the 256-bit key in the given file",
+		},
+		&cli.StringFlag{
+			Name:  "cse-passphrase-file",
+			Usage: "encrypt uploads and decrypt downloads on the client side with a key derived from the passphrase in the given file",
+		},
+		&cli.StringFlag{
+			Name:  "sse-c-key",
+			Usage: "perform server side encryption with the given 256-bit customer-provided key (SSE-C), either raw or base64 encoded",
+		},
+		&cli.StringFlag{
+			Name:  "sse-c-key-file",
+			Usage: "perform server side encryption with the customer-provided key (SSE-C) in the given file",
+		},
+		&cli.StringFlag{
+			Name:  "sse-c-copy-source-key",
+			Usage: "customer-provided key (SSE-C) of the source objects of copies between remote storages, either raw or base64 encoded",
+		},
+		&cli.StringFlag{
+			Name:  "sse-c-copy-source-key-file",
+			Usage: "customer-provided key (SSE-C) in the given file of the source objects of copies between remote storages",
+		},
 		&cli.StringFlag{
 			Name:  "acl",
 			Usage: "set acl for target: defines granted accesses and their types on different accounts/groups, e.g. cp --acl 'public-read'",
@@ -201,6 +291,14 @@ func NewSharedFlags() []cli.Flag {
 			Name:  "include",
 			Usage: "include objects with given pattern",
 		},
+		&cli.StringFlag{
+			Name:  "exclude-from",
+			Usage: "exclude objects with the patterns in given file, in gitignore syntax",
+		},
+		&cli.StringFlag{
+			Name:  "include-from",
+			Usage: "include objects with the patterns in given file, in gitignore syntax",
+		},
 		&cli.BoolFlag{
 			Name:  "raw",
 			Usage: "disable the wildcard operations, useful with filenames that contains glob characters",
@@ -217,6 +315,30 @@ func NewSharedFlags() []cli.Flag {
 			Name:  "content-disposition",
 			Usage: "set content disposition for target: defines content disposition header for object, e.g. --content-disposition 'attachment; filename=\"filename.jpg\"'",
 		},
+		&cli.StringFlag{
+			Name:  "tagging",
+			Usage: "set tags for target: defines URL encoded tag set of the object, e.g. --tagging 'key1=value1&key2=value2'",
+		},
+		&cli.StringFlag{
+			Name:  "from-inventory",
+			Usage: "list the source objects from the S3 Inventory report of the given manifest.json file instead of the bucket",
+		},
+		&cli.GenericFlag{
+			Name:  "compress",
+			Usage: "compress the uploads on the fly and set their content encoding: (gzip, zstd)",
+			Value: &EnumValue{
+				Enum:    []string{compressGzip, compressZstd, ""},
+				Default: "",
+			},
+		},
+		&cli.StringFlag{
+			Name:  "backup-dir",
+			Usage: "back up the destination objects which are overwritten or deleted under the given directory or prefix, preserving their relative paths",
+		},
+		&cli.StringSliceFlag{
+			Name:  "preserve",
+			Usage: "preserve properties of source objects on copies between remote storages: metadata, tags, acl, storage-class, e.g. --preserve=metadata,tags",
+		},
 		&cli.IntFlag{
 			Name:        "no-such-upload-retry-count",
 			Usage:       "number of times that a request will be retried on NoSuchUpload error; you should not use this unless you really know what you're doing",
@@ -257,6 +379,34 @@ func NewCopyCommandFlags() []cli.Flag {
 			Aliases: []string{"sp"},
 			Usage:   "show a progress bar",
 		},
+		&cli.BoolFlag{
+			Name:  "resume",
+			Usage: "record the progress of uploads and downloads and resume interrupted ones",
+		},
+		&cli.GenericFlag{
+			Name:  "pack",
+			Usage: "stream the source files into an archive uploaded to the destination object: (tar, tar.gz, tar.zst, zip)",
+			Value: &EnumValue{
+				Enum:    append(archiveFormats, ""),
+				Default: "",
+			},
+		},
+		&cli.BoolFlag{
+			Name:  "compress-extension",
+			Usage: "append the extension of the compression to the names of the uploaded objects, e.g. .gz or .zst",
+		},
+		&cli.BoolFlag{
+			Name:  "decompress",
+			Usage: "decompress the downloads compressed with gzip or zstd, detected by their content encoding or contents",
+		},
+		&cli.BoolFlag{
+			Name:  "unpack",
+			Usage: "extract the source archive to the destination directory while it is downloaded, the format is detected from its extension",
+		},
+		&cli.StringFlag{
+			Name:  "files-from",
+			Usage: "read the source objects from the given file of keys or 'ls --json' output instead of listing the bucket, '-' reads from stdin",
+		},
 	}
 	sharedFlags := NewSharedFlags()
 	return append(copyFlags, sharedFlags...)
@@ -310,11 +460,14 @@ type Copy struct {
 	storageClass          storage.StorageClass
 	encryptionMethod      string
 	encryptionKeyID       string
+	masterKey             *cse.MasterKey
 	acl                   string
 	forceGlacierTransfer  bool
 	ignoreGlacierWarnings bool
 	exclude               []string
 	include               []string
+	excludeFrom           string
+	includeFrom           string
 	cacheControl          string
 	expires               string
 	contentType           string
@@ -322,12 +475,24 @@ type Copy struct {
 	contentDisposition    string
 	metadata              map[string]string
 	metadataDirective     string
+	tags                  map[string]string
+	preserve              []string
 	showProgress          bool
 	progressbar           progressbar.ProgressBar
+	resume                bool
+	pack                  string
+	unpack                bool
+	compress              string
+	compressExtension     bool
+	decompress            bool
+	inventory             *url.URL
+	filesFrom             string
+	backupDir             *url.URL
 
 	// patterns
 	excludePatterns []*regexp.Regexp
 	includePatterns []*regexp.Regexp
+	patternFiles    patternFiles
 
 	// region settings
 	srcRegion string
@@ -371,6 +536,30 @@ func NewCopy(c *cli.Context, deleteSource bool) (*Copy, error) {
 		return nil, err
 	}
 
+	tags, err := parseTagging(c.String("tagging"))
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
+	masterKey, err := newMasterKey(c)
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
+	inventory, err := inventoryManifest(c)
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
+	backupDir, err := newBackupDir(c)
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
 	return &Copy{
 		src:          src,
 		dst:          dst,
@@ -388,11 +577,14 @@ func NewCopy(c *cli.Context, deleteSource bool) (*Copy, error) {
 		partSize:              c.Int64("part-size") * megabytes,
 		encryptionMethod:      c.String("sse"),
 		encryptionKeyID:       c.String("sse-kms-key-id"),
+		masterKey:             masterKey,
 		acl:                   c.String("acl"),
 		forceGlacierTransfer:  c.Bool("force-glacier-transfer"),
 		ignoreGlacierWarnings: c.Bool("ignore-glacier-warnings"),
 		exclude:               c.StringSlice("exclude"),
 		include:               c.StringSlice("include"),
+		excludeFrom:           c.String("exclude-from"),
+		includeFrom:           c.String("include-from"),
 		cacheControl:          c.String("cache-control"),
 		expires:               c.String("expires"),
 		contentType:           c.String("content-type"),
@@ -400,8 +592,19 @@ func NewCopy(c *cli.Context, deleteSource bool) (*Copy, error) {
 		contentDisposition:    c.String("content-disposition"),
 		metadata:              metadata,
 		metadataDirective:     c.String("metadata-directive"),
+		tags:                  tags,
+		preserve:              c.StringSlice("preserve"),
 		showProgress:          c.Bool("show-progress"),
 		progressbar:           commandProgressBar,
+		resume:                c.Bool("resume"),
+		pack:                  c.String("pack"),
+		unpack:                c.Bool("unpack"),
+		compress:              c.String("compress"),
+		compressExtension:     c.Bool("compress-extension"),
+		decompress:            c.Bool("decompress"),
+		inventory:             inventory,
+		filesFrom:             c.String("files-from"),
+		backupDir:             backupDir,
 
 		// region settings
 		srcRegion: c.String("source-region"),
@@ -424,7 +627,45 @@ func (c Copy) Run(ctx context.Context) error {
 		c.storageOpts.SetRegion(c.srcRegion)
 	}
 
-	client, err := storage.NewClient(ctx, c.src, c.storageOpts)
+	var err error
+	c.excludePatterns, err = createRegexFromWildcard(c.exclude)
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+
+	c.includePatterns, err = createRegexFromWildcard(c.include)
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+
+	c.patternFiles, err = newPatternFiles(c.excludeFrom, c.includeFrom)
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+
+	if c.pack != "" {
+		return c.packSources(ctx)
+	}
+	if c.unpack {
+		return c.unpackSource(ctx)
+	}
+
+	client, err := storage.NewClient(ctx, c.src, c.sourceStorageOpts(c.dst))
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+
+	client, err = withInventory(ctx, client, c.inventory, c.storageOpts)
+	if err != nil {
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+
+	client, err = withKeyList(client, c.filesFrom)
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
@@ -471,18 +712,6 @@ func (c Copy) Run(ctx context.Context) error {
 		isBatch = obj != nil && obj.Type.IsDir()
 	}
 
-	c.excludePatterns, err = createRegexFromWildcard(c.exclude)
-	if err != nil {
-		printError(c.fullCommand, c.op, err)
-		return err
-	}
-
-	c.includePatterns, err = createRegexFromWildcard(c.include)
-	if err != nil {
-		printError(c.fullCommand, c.op, err)
-		return err
-	}
-
 	for object := range objch {
 		if errorpkg.IsCancelation(object.Err) || object.Type.IsDir() {
 			continue
@@ -510,7 +739,7 @@ func (c Copy) Run(ctx context.Context) error {
 			continue
 		}
 
-		isExcluded, err := isObjectExcluded(object, c.excludePatterns, c.includePatterns, c.src.Prefix)
+		isExcluded, err := isObjectFiltered(object, c.excludePatterns, c.includePatterns, c.patternFiles, c.src.Prefix)
 		if err != nil {
 			printError(c.fullCommand, c.op, err)
 		}
@@ -521,7 +750,13 @@ func (c Copy) Run(ctx context.Context) error {
 		srcurl := object.URL
 		var task parallel.Task
 
-		if object.Size == 0 && !(srcurl.Type == c.dst.Type) {
+		// size of the remote objects is required to decide whether they can
+		// be copied with a single request. It is known for the listed
+		// objects, only the objects which are given as they are and the keys
+		// of the plain key lists lack it.
+		isListed := c.src.IsWildcard() || c.src.AllVersions
+		sizeUnknown := object.SizeUnknown() || (srcurl.IsRemote() && !isListed)
+		if object.Size == 0 && (srcurl.Type != c.dst.Type || sizeUnknown) {
 			obj, err := client.Stat(ctx, srcurl)
 			if err == nil {
 				object.Size = obj.Size
@@ -540,7 +775,7 @@ func (c Copy) Run(ctx context.Context) error {
 					c.metadataDirective = metadataDirectiveReplace
 				}
 			}
-			task = c.prepareCopyTask(ctx, srcurl, c.dst, isBatch, c.metadata)
+			task = c.prepareCopyTask(ctx, srcurl, c.dst, isBatch, c.metadata, object.Size)
 		case srcurl.IsRemote(): // remote->local
 			if c.metadataDirective != "" {
 				err := fmt.Errorf("metadata directive is not supported for download")
@@ -568,16 +803,27 @@ func (c Copy) Run(ctx context.Context) error {
 	return multierror.Append(merrorWaiter, merrorObjects).ErrorOrNil()
 }
 
+// sourceStorageOpts returns the storage options to access the source objects.
+// The source objects of the copies between remote storages are accessed with
+// the customer-provided key of the copy sources.
+func (c Copy) sourceStorageOpts(dsturl *url.URL) storage.Options {
+	if c.src.IsRemote() && dsturl.IsRemote() {
+		return c.storageOpts.CopySourceOptions()
+	}
+	return c.storageOpts
+}
+
 func (c Copy) prepareCopyTask(
 	ctx context.Context,
 	srcurl *url.URL,
 	dsturl *url.URL,
 	isBatch bool,
 	metadata map[string]string,
+	size int64,
 ) func() error {
 	return func() error {
 		dsturl = prepareRemoteDestination(srcurl, dsturl, c.flatten, isBatch)
-		err := c.doCopy(ctx, srcurl, dsturl, metadata)
+		err := c.doCopy(ctx, srcurl, dsturl, metadata, size)
 		if err != nil {
 			return &errorpkg.Error{
 				Op:  c.op,
@@ -602,6 +848,11 @@ func (c Copy) prepareDownloadTask(
 		if err != nil {
 			return err
 		}
+		// the decompressed files are named after the objects without the
+		// extensions of their compression.
+		if c.decompress && dsturl.Base() == srcurl.Base() {
+			dsturl = trimCompressionExtension(dsturl)
+		}
 		err = c.doDownload(ctx, srcurl, dsturl)
 		if err != nil {
 			return &errorpkg.Error{
@@ -625,6 +876,10 @@ func (c Copy) prepareUploadTask(
 ) func() error {
 	return func() error {
 		dsturl = prepareRemoteDestination(srcurl, dsturl, c.flatten, isBatch)
+		if c.compressExtension {
+			dsturl = dsturl.Clone()
+			dsturl.Path += compressionExtensions[c.compress]
+		}
 		err := c.doUpload(ctx, srcurl, dsturl, metadata)
 		if err != nil {
 			return &errorpkg.Error{
@@ -641,11 +896,16 @@ func (c Copy) prepareUploadTask(
 
 // doDownload is used to fetch a remote object and save as a local object.
 func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL) error {
-	srcClient, err := storage.NewRemoteClient(ctx, srcurl, c.storageOpts)
+	srcClient, err := storage.NewClient(ctx, srcurl, c.storageOpts)
 	if err != nil {
 		return err
 	}
 
+	getter, ok := srcClient.(storage.Getter)
+	if !ok {
+		return storage.NotSupported(srcurl, "download")
+	}
+
 	dstClient := storage.NewLocalClient(c.storageOpts)
 
 	err = c.shouldOverride(ctx, srcurl, dsturl)
@@ -657,22 +917,19 @@ func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL)
 		return err
 	}
 
-	dstPath := filepath.Dir(dsturl.Absolute())
-	dstFile := filepath.Base(dsturl.Absolute())
-	file, err := dstClient.CreateTemp(dstPath, dstFile)
-	if err != nil {
+	if err := c.backupDestination(ctx, dsturl); err != nil {
 		return err
 	}
 
-	writer := newCountingReaderWriter(file, c.progressbar)
-	size, err := srcClient.Get(ctx, srcurl, writer, c.concurrency, c.partSize)
-	file.Close()
-
+	var size int64
+	if c.resume {
+		size, err = c.doResumableDownload(ctx, srcClient, dstClient, srcurl, dsturl)
+	} else if c.decompress {
+		size, err = c.doDecompressedDownload(ctx, getter, dstClient, srcurl, dsturl)
+	} else {
+		size, err = c.doTempDownload(ctx, getter, dstClient, srcurl, dsturl)
+	}
 	if err != nil {
-		dErr := dstClient.Delete(ctx, &url.URL{Path: file.Name(), Type: dsturl.Type})
-		if dErr != nil {
-			printDebug(c.op, dErr, srcurl, dsturl)
-		}
 		return err
 	}
 
@@ -680,11 +937,6 @@ func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL)
 		_ = srcClient.Delete(ctx, srcurl)
 	}
 
-	err = dstClient.Rename(file, dsturl.Absolute())
-	if err != nil {
-		return err
-	}
-
 	if !c.showProgress {
 		msg := log.InfoMessage{
 			Operation:   c.op,
@@ -700,6 +952,151 @@ func (c Copy) doDownload(ctx context.Context, srcurl *url.URL, dsturl *url.URL)
 	return nil
 }
 
+// doTempDownload downloads the object into a temporary file, which is renamed
+// to the destination once the download is completed.
+func (c Copy) doTempDownload(
+	ctx context.Context,
+	srcClient storage.Getter,
+	dstClient *storage.Filesystem,
+	srcurl, dsturl *url.URL,
+) (int64, error) {
+	dstPath := filepath.Dir(dsturl.Absolute())
+	dstFile := filepath.Base(dsturl.Absolute())
+	file, err := dstClient.CreateTemp(dstPath, dstFile)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		writer io.WriterAt = newCountingReaderWriter(file, c.progressbar)
+		size   int64
+	)
+	if c.masterKey != nil && !c.storageOpts.DryRun {
+		size, err = getDecrypted(ctx, srcClient, c.masterKey, srcurl, writer, c.concurrency, c.partSize)
+	} else {
+		size, err = srcClient.Get(ctx, srcurl, writer, c.concurrency, c.partSize)
+	}
+	file.Close()
+
+	if err != nil {
+		dErr := dstClient.Delete(ctx, &url.URL{Path: file.Name(), Type: dsturl.Type})
+		if dErr != nil {
+			printDebug(c.op, dErr, srcurl, dsturl)
+		}
+		return 0, err
+	}
+
+	if err := dstClient.Rename(file, dsturl.Absolute()); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// getDecrypted downloads the object to the given writer by decrypting it with
+// the master key if the object is encrypted on the client side.
+func getDecrypted(
+	ctx context.Context,
+	client storage.Getter,
+	masterKey *cse.MasterKey,
+	srcurl *url.URL,
+	to io.WriterAt,
+	concurrency int,
+	partSize int64,
+) (int64, error) {
+	metadataReader, ok := client.(storage.MetadataReader)
+	if !ok {
+		return 0, storage.NotSupported(srcurl, "client-side encryption")
+	}
+
+	obj, metadata, err := metadataReader.HeadObject(ctx, srcurl)
+	if err != nil {
+		return 0, err
+	}
+
+	if !cse.IsEncrypted(metadata.UserDefined) {
+		return client.Get(ctx, srcurl, to, concurrency, partSize)
+	}
+
+	env, err := masterKey.OpenEnvelope(metadata.UserDefined)
+	if err != nil {
+		return 0, err
+	}
+
+	writer, err := env.NewWriterAt(to, obj.Size)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := client.Get(ctx, srcurl, writer, concurrency, partSize); err != nil {
+		return 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+
+	return cse.DecryptedSize(obj.Size)
+}
+
+// doResumableDownload downloads the object into a partial file next to the
+// destination, recording the downloaded byte ranges to a sidecar file. A
+// re-run of the same command fetches only the missing ranges. The partial
+// file is renamed to the destination once the download is completed.
+func (c Copy) doResumableDownload(
+	ctx context.Context,
+	srcClient storage.Storage,
+	dstClient *storage.Filesystem,
+	srcurl, dsturl *url.URL,
+) (int64, error) {
+	getter, ok := srcClient.(storage.ResumableGetter)
+	if !ok {
+		return 0, storage.NotSupported(srcurl, "resumable download")
+	}
+
+	partPath := dsturl.Absolute() + ".part"
+
+	// the object is downloaded from scratch if it is modified in the middle
+	// of the download.
+	for attempt := 0; ; attempt++ {
+		obj, err := srcClient.Stat(ctx, srcurl)
+		if err != nil {
+			return 0, err
+		}
+
+		state, err := storage.LoadDownloadState(partPath, obj)
+		if err != nil {
+			return 0, err
+		}
+
+		flag := os.O_WRONLY | os.O_CREATE
+		if state.IsFresh() {
+			flag |= os.O_TRUNC
+		}
+
+		file, err := dstClient.OpenFile(partPath, flag, 0644)
+		if err != nil {
+			return 0, err
+		}
+
+		writer := newCountingReaderWriter(file, c.progressbar)
+		size, err := getter.GetResumable(ctx, srcurl, writer, c.concurrency, c.partSize, state, c.progressbar.AddCompletedBytes)
+		file.Close()
+
+		if errors.Is(err, storage.ErrObjectModified) && attempt == 0 {
+			msg := log.DebugMessage{Err: fmt.Sprintf("Restarting download of %v: %v", srcurl, err)}
+			log.Debug(msg)
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if err := dstClient.Rename(file, dsturl.Absolute()); err != nil {
+			return 0, err
+		}
+		return size, nil
+	}
+}
+
 func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL, extradata map[string]string) error {
 	srcClient := storage.NewLocalClient(c.storageOpts)
 
@@ -722,11 +1119,20 @@ func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL, ex
 	if c.dstRegion != "" {
 		c.storageOpts.SetRegion(c.dstRegion)
 	}
-	dstClient, err := storage.NewRemoteClient(ctx, dsturl, c.storageOpts)
+
+	if err := c.backupDestination(ctx, dsturl); err != nil {
+		return err
+	}
+	dstClient, err := storage.NewClient(ctx, dsturl, c.storageOpts)
 	if err != nil {
 		return err
 	}
 
+	putter, ok := dstClient.(storage.Putter)
+	if !ok {
+		return storage.NotSupported(dsturl, "upload")
+	}
+
 	metadata := storage.Metadata{
 		UserDefined:        extradata,
 		ACL:                c.acl,
@@ -737,6 +1143,7 @@ func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL, ex
 		ContentDisposition: c.contentDisposition,
 		EncryptionMethod:   c.encryptionMethod,
 		EncryptionKeyID:    c.encryptionKeyID,
+		Tags:               c.tags,
 	}
 
 	if c.contentType != "" {
@@ -746,8 +1153,21 @@ func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL, ex
 	}
 
 	reader := newCountingReaderWriter(file, c.progressbar)
-	err = dstClient.Put(ctx, reader, dsturl, metadata, c.concurrency, c.partSize)
+	if c.masterKey != nil && c.compress == "" {
+		reader, err = c.encryptUpload(file, &metadata)
+		if err != nil {
+			return err
+		}
+	}
 
+	switch {
+	case c.compress != "":
+		err = c.doCompressedUpload(ctx, putter, reader, dsturl, metadata)
+	case c.resume:
+		err = c.doResumableUpload(ctx, srcClient, dstClient, reader, srcurl, dsturl, metadata)
+	default:
+		err = putter.Put(ctx, reader, dsturl, metadata, c.concurrency, c.partSize)
+	}
 	if err != nil {
 		return err
 	}
@@ -781,19 +1201,97 @@ func (c Copy) doUpload(ctx context.Context, srcurl *url.URL, dsturl *url.URL, ex
 	return nil
 }
 
-func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL, extradata map[string]string) error {
-	// override destination region if set
-	if c.dstRegion != "" {
-		c.storageOpts.SetRegion(c.dstRegion)
+// encryptUpload returns a reader which encrypts the file on the client side.
+// The parameters needed to decrypt the object are added to the user metadata.
+func (c Copy) encryptUpload(file *os.File, metadata *storage.Metadata) (*countingReaderWriter, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
 	}
-	dstClient, err := storage.NewClient(ctx, dsturl, c.storageOpts)
+
+	env, err := c.masterKey.NewEnvelope()
+	if err != nil {
+		return nil, err
+	}
+
+	// user metadata is shared by all the uploads, so it is copied before the
+	// encryption parameters are added.
+	userDefined := env.Metadata()
+	for k, v := range metadata.UserDefined {
+		userDefined[k] = v
+	}
+	metadata.UserDefined = userDefined
+
+	return newCountingReader(env.NewReader(file, info.Size()), c.progressbar), nil
+}
+
+// doResumableUpload uploads the file by recording its progress to a state
+// file, so that a re-run of the same command continues from where it stopped.
+func (c Copy) doResumableUpload(
+	ctx context.Context,
+	srcClient *storage.Filesystem,
+	dstClient storage.Storage,
+	reader io.ReaderAt,
+	srcurl, dsturl *url.URL,
+	metadata storage.Metadata,
+) error {
+	obj, err := srcClient.Stat(ctx, srcurl)
 	if err != nil {
 		return err
 	}
 
-	metadata := storage.Metadata{
-		UserDefined:        extradata,
-		ACL:                c.acl,
+	statePath, err := uploadStatePath(srcurl, dsturl)
+	if err != nil {
+		return err
+	}
+
+	state, err := storage.LoadUploadState(statePath, obj)
+	if err != nil {
+		return err
+	}
+
+	putter, ok := dstClient.(storage.ResumablePutter)
+	if !ok {
+		return storage.NotSupported(dsturl, "resumable upload")
+	}
+
+	return putter.PutResumable(ctx, reader, dsturl, metadata, c.concurrency, c.partSize, state, c.progressbar.AddCompletedBytes)
+}
+
+// uploadStatePath returns the path of the file which holds the state of the
+// resumable upload from srcurl to dsturl.
+func uploadStatePath(srcurl, dsturl *url.URL) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	src, err := filepath.Abs(srcurl.Absolute())
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(src + "\n" + dsturl.Absolute()))
+	return filepath.Join(cacheDir, "s5cmd", "uploads", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL, extradata map[string]string, size int64) error {
+	// source options are captured before the region is overridden for the
+	// destination.
+	srcOpts := c.sourceStorageOpts(dsturl)
+
+	// override destination region if set
+	if c.dstRegion != "" {
+		c.storageOpts.SetRegion(c.dstRegion)
+	}
+	dstClient, err := storage.NewClient(ctx, dsturl, c.storageOpts)
+	if err != nil {
+		return err
+	}
+
+	metadata := storage.Metadata{
+		UserDefined:        extradata,
+		ACL:                c.acl,
 		CacheControl:       c.cacheControl,
 		Expires:            c.expires,
 		StorageClass:       string(c.storageClass),
@@ -802,6 +1300,7 @@ func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL, extradata map
 		ContentDisposition: c.contentDisposition,
 		EncryptionMethod:   c.encryptionMethod,
 		EncryptionKeyID:    c.encryptionKeyID,
+		Tags:               c.tags,
 		Directive:          c.metadataDirective,
 	}
 
@@ -814,11 +1313,42 @@ func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL, extradata map
 		return err
 	}
 
-	err = dstClient.Copy(ctx, srcurl, dsturl, metadata)
+	if err := c.backupDestination(ctx, dsturl); err != nil {
+		return err
+	}
+
+	var acl *storage.ObjectACL
+	if len(c.preserve) > 0 {
+		acl, err = c.preserveSourceProperties(ctx, srcurl, srcOpts, &metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case srcurl.Scheme != dsturl.Scheme:
+		err = c.doStreamCopy(ctx, srcurl, dsturl, srcOpts, dstClient, metadata)
+	// the other storages copy the objects of any size with a single
+	// operation.
+	case dsturl.Scheme == "s3" && size > storage.MaxCopyObjectSize:
+		err = c.doMultipartCopy(ctx, srcurl, dsturl, srcOpts, metadata)
+	default:
+		err = dstClient.Copy(ctx, srcurl, dsturl, metadata)
+	}
 	if err != nil {
 		return err
 	}
 
+	if acl != nil {
+		aclAccessor, ok := dstClient.(storage.ACLAccessor)
+		if !ok {
+			return storage.NotSupported(dsturl, "preserving acl")
+		}
+		if err := aclAccessor.PutObjectACL(ctx, dsturl, acl); err != nil {
+			return err
+		}
+	}
+
 	if c.deleteSource {
 		srcClient, err := storage.NewClient(ctx, srcurl, c.storageOpts)
 		if err != nil {
@@ -835,7 +1365,7 @@ func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL, extradata map
 		Destination: dsturl,
 		Object: &storage.Object{
 			URL:          dsturl,
-			StorageClass: c.storageClass,
+			StorageClass: storage.StorageClass(metadata.StorageClass),
 		},
 	}
 	log.Info(msg)
@@ -843,6 +1373,211 @@ func (c Copy) doCopy(ctx context.Context, srcurl, dsturl *url.URL, extradata map
 	return nil
 }
 
+// doStreamCopy copies the objects between the storages of different schemes
+// by streaming the contents of the source object to the destination, since
+// the storages can not copy the objects of each other.
+func (c Copy) doStreamCopy(
+	ctx context.Context,
+	srcurl *url.URL,
+	dsturl *url.URL,
+	srcOpts storage.Options,
+	dstClient storage.Storage,
+	metadata storage.Metadata,
+) error {
+	srcClient, err := storage.NewClient(ctx, srcurl, srcOpts)
+	if err != nil {
+		return err
+	}
+
+	reader, ok := srcClient.(storage.Reader)
+	if !ok {
+		return storage.NotSupported(srcurl, "read")
+	}
+
+	putter, ok := dstClient.(storage.Putter)
+	if !ok {
+		return storage.NotSupported(dsturl, "upload")
+	}
+
+	if c.storageOpts.DryRun {
+		return nil
+	}
+
+	body, err := reader.Read(ctx, srcurl)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return putter.Put(ctx, newCountingStream(body, c.progressbar), dsturl, metadata, c.concurrency, c.partSize)
+}
+
+// doMultipartCopy copies the objects which are too large to be copied with a
+// single request. The properties of the source object which CopyObject would
+// carry over are passed explicitly, since multipart uploads are created from
+// scratch.
+func (c Copy) doMultipartCopy(
+	ctx context.Context,
+	srcurl *url.URL,
+	dsturl *url.URL,
+	srcOpts storage.Options,
+	metadata storage.Metadata,
+) error {
+	return multipartCopy(ctx, srcurl, dsturl, srcOpts, c.storageOpts, metadata, c.concurrency, c.partSize)
+}
+
+// multipartCopy copies the remote object with a multipart upload whose parts
+// are copied from the source object.
+func multipartCopy(
+	ctx context.Context,
+	srcurl *url.URL,
+	dsturl *url.URL,
+	srcOpts storage.Options,
+	dstOpts storage.Options,
+	metadata storage.Metadata,
+	concurrency int,
+	partSize int64,
+) error {
+	srcClient, err := storage.NewClient(ctx, srcurl, srcOpts)
+	if err != nil {
+		return err
+	}
+
+	metadataReader, ok := srcClient.(storage.MetadataReader)
+	if !ok {
+		return storage.NotSupported(srcurl, "multipart copy")
+	}
+
+	srcObj, srcMetadata, err := metadataReader.HeadObject(ctx, srcurl)
+	if err != nil {
+		return err
+	}
+
+	if metadata.Directive != metadataDirectiveReplace {
+		metadata.ContentType = srcMetadata.ContentType
+		metadata.CacheControl = srcMetadata.CacheControl
+		metadata.Expires = srcMetadata.Expires
+		metadata.ContentEncoding = srcMetadata.ContentEncoding
+		metadata.ContentDisposition = srcMetadata.ContentDisposition
+		metadata.ContentLanguage = srcMetadata.ContentLanguage
+		metadata.UserDefined = srcMetadata.UserDefined
+	}
+
+	// CopyObject copies the tags of the source object unless they are
+	// replaced.
+	if tagger, ok := srcClient.(storage.Tagger); ok && metadata.Tags == nil {
+		tags, err := tagger.GetObjectTagging(ctx, srcurl)
+		if err != nil {
+			return err
+		}
+		metadata.Tags = tags
+	}
+
+	dstClient, err := storage.NewClient(ctx, dsturl, dstOpts)
+	if err != nil {
+		return err
+	}
+
+	copier, ok := dstClient.(storage.MultipartCopier)
+	if !ok {
+		return storage.NotSupported(dsturl, "multipart copy")
+	}
+	return copier.MultipartCopy(ctx, srcObj, dsturl, metadata, concurrency, partSize)
+}
+
+// preserveSourceProperties fills the metadata with the properties of the
+// source object which are requested to be preserved. Properties which are
+// explicitly given with flags take precedence over the source ones. The
+// access control policy of the source object is returned to be applied to the
+// destination object once it is copied.
+func (c Copy) preserveSourceProperties(
+	ctx context.Context,
+	srcurl *url.URL,
+	srcOpts storage.Options,
+	metadata *storage.Metadata,
+) (*storage.ObjectACL, error) {
+	srcClient, err := storage.NewClient(ctx, srcurl, srcOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	preserves := func(property string) bool {
+		for _, p := range c.preserve {
+			if p == property {
+				return true
+			}
+		}
+		return false
+	}
+
+	if preserves(preserveMetadata) || preserves(preserveStorageClass) {
+		metadataReader, ok := srcClient.(storage.MetadataReader)
+		if !ok {
+			return nil, storage.NotSupported(srcurl, "preserving metadata")
+		}
+
+		_, srcMetadata, err := metadataReader.HeadObject(ctx, srcurl)
+		if err != nil {
+			return nil, err
+		}
+
+		setIfEmpty := func(dst *string, src string) {
+			if *dst == "" {
+				*dst = src
+			}
+		}
+
+		if preserves(preserveMetadata) {
+			setIfEmpty(&metadata.ContentType, srcMetadata.ContentType)
+			setIfEmpty(&metadata.CacheControl, srcMetadata.CacheControl)
+			setIfEmpty(&metadata.Expires, srcMetadata.Expires)
+			setIfEmpty(&metadata.ContentEncoding, srcMetadata.ContentEncoding)
+			setIfEmpty(&metadata.ContentDisposition, srcMetadata.ContentDisposition)
+			setIfEmpty(&metadata.ContentLanguage, srcMetadata.ContentLanguage)
+
+			// metadata keys are case insensitive.
+			userDefined := map[string]string{}
+			for k, v := range srcMetadata.UserDefined {
+				userDefined[strings.ToLower(k)] = v
+			}
+			for k, v := range metadata.UserDefined {
+				userDefined[strings.ToLower(k)] = v
+			}
+			metadata.UserDefined = userDefined
+
+			// all the metadata is given explicitly.
+			metadata.Directive = metadataDirectiveReplace
+		}
+
+		if preserves(preserveStorageClass) {
+			setIfEmpty(&metadata.StorageClass, srcMetadata.StorageClass)
+		}
+	}
+
+	if preserves(preserveTags) && metadata.Tags == nil {
+		tagger, ok := srcClient.(storage.Tagger)
+		if !ok {
+			return nil, storage.NotSupported(srcurl, "preserving tags")
+		}
+
+		tags, err := tagger.GetObjectTagging(ctx, srcurl)
+		if err != nil {
+			return nil, err
+		}
+		metadata.Tags = tags
+	}
+
+	if !preserves(preserveACL) {
+		return nil, nil
+	}
+
+	aclAccessor, ok := srcClient.(storage.ACLAccessor)
+	if !ok {
+		return nil, storage.NotSupported(srcurl, "preserving acl")
+	}
+	return aclAccessor.GetObjectACL(ctx, srcurl)
+}
+
 // shouldOverride function checks if the destination should be overridden if
 // the source-destination pair and given copy flags conform to the
 // override criteria. For example; "cp -n -s <src> <dst>" should not override
@@ -854,7 +1589,7 @@ func (c Copy) shouldOverride(ctx context.Context, srcurl *url.URL, dsturl *url.U
 		return nil
 	}
 
-	srcClient, err := storage.NewClient(ctx, srcurl, c.storageOpts)
+	srcClient, err := storage.NewClient(ctx, srcurl, c.sourceStorageOpts(dsturl))
 	if err != nil {
 		return err
 	}
@@ -905,6 +1640,26 @@ func (c Copy) shouldOverride(ctx context.Context, srcurl *url.URL, dsturl *url.U
 	return stickyErr
 }
 
+// backupDestination backs up the destination object under the backup
+// directory before it is overwritten, if it exists.
+func (c Copy) backupDestination(ctx context.Context, dsturl *url.URL) error {
+	if c.backupDir == nil {
+		return nil
+	}
+
+	client, err := storage.NewClient(ctx, dsturl, c.storageOpts)
+	if err != nil {
+		return err
+	}
+
+	obj, err := statObject(ctx, dsturl, client)
+	if err != nil || obj == nil {
+		return err
+	}
+
+	return backupObject(ctx, obj, c.backupDir.Join(relativeDestination(c.dst, dsturl)), c.storageOpts)
+}
+
 // prepareRemoteDestination will return a new destination URL for
 // remote->remote and local->remote copy operations.
 func prepareRemoteDestination(
@@ -1023,9 +1778,16 @@ func validateCopyCommand(c *cli.Context) error {
 		return fmt.Errorf("source argument must contain wildcard character")
 	}
 
+	isArchive := c.String("pack") != "" || c.Bool("unpack")
+	if isArchive {
+		if err := validateArchive(c, srcurl, dsturl); err != nil {
+			return err
+		}
+	}
+
 	// 'cp dir/* s3://bucket/prefix': expect a trailing slash to avoid any
 	// surprises.
-	if srcurl.IsWildcard() && dsturl.IsRemote() && !dsturl.IsPrefix() && !dsturl.IsBucket() {
+	if !isArchive && srcurl.IsWildcard() && dsturl.IsRemote() && !dsturl.IsPrefix() && !dsturl.IsBucket() {
 		return fmt.Errorf("target %q must be a bucket or a prefix", dsturl)
 	}
 
@@ -1033,11 +1795,70 @@ func validateCopyCommand(c *cli.Context) error {
 		return err
 	}
 
+	if c.Bool("resume") && srcurl.IsRemote() == dsturl.IsRemote() {
+		return fmt.Errorf("--resume flag is only supported for uploads and downloads")
+	}
+
+	if err := validatePreserve(c, srcurl, dsturl); err != nil {
+		return err
+	}
+
+	if err := validateCompression(c, srcurl, dsturl); err != nil {
+		return err
+	}
+
+	if err := validateInventory(c, srcurl); err != nil {
+		return err
+	}
+
+	if err := validateFilesFrom(c, srcurl); err != nil {
+		return err
+	}
+
+	if isArchive && c.String("backup-dir") != "" {
+		return fmt.Errorf("--backup-dir flag can not be used with --pack and --unpack flags")
+	}
+
+	if err := validateBackupDir(c, dsturl); err != nil {
+		return err
+	}
+
+	if err := validateClientSideEncryption(c); err != nil {
+		return err
+	}
+
+	if isClientSideEncryptionSet(c) {
+		if srcurl.IsRemote() == dsturl.IsRemote() {
+			return fmt.Errorf("client-side encryption is only supported for uploads and downloads")
+		}
+		if c.Bool("resume") {
+			return fmt.Errorf("--resume flag can not be used with client-side encryption")
+		}
+	}
+
+	if err := validateSSECustomerKey(c); err != nil {
+		return err
+	}
+
+	if isSSECopySourceKeySet(c) && (!srcurl.IsRemote() || !dsturl.IsRemote()) {
+		return fmt.Errorf("--sse-c-copy-source-key flag is only supported for copies between remote storages")
+	}
+
+	if c.IsSet("tagging") && !dsturl.IsRemote() {
+		return fmt.Errorf("--tagging flag is only supported for remote destinations")
+	}
+
+	if _, err := parseTagging(c.String("tagging")); err != nil {
+		return err
+	}
+
 	if err := checkVersioningWithGoogleEndpoint(c); err != nil {
 		return err
 	}
 
 	switch {
+	case isArchive:
+		return nil
 	case srcurl.Type == dsturl.Type:
 		return validateCopy(srcurl, dsturl)
 	case dsturl.IsRemote():
@@ -1047,6 +1868,126 @@ func validateCopyCommand(c *cli.Context) error {
 	}
 }
 
+func validatePreserve(c *cli.Context, srcurl, dsturl *url.URL) error {
+	properties := c.StringSlice("preserve")
+	if len(properties) == 0 {
+		return nil
+	}
+
+	for _, p := range properties {
+		switch p {
+		case preserveMetadata, preserveTags, preserveACL, preserveStorageClass:
+		default:
+			return fmt.Errorf("unknown property to preserve: %q, expected one of: %v, %v, %v, %v",
+				p, preserveMetadata, preserveTags, preserveACL, preserveStorageClass)
+		}
+
+		if p == preserveACL && c.String("acl") != "" {
+			return fmt.Errorf("--acl and --preserve=%v flags can not be used together", preserveACL)
+		}
+	}
+
+	if !srcurl.IsRemote() || !dsturl.IsRemote() {
+		return fmt.Errorf("--preserve flag is only supported for copies between remote storages")
+	}
+
+	return nil
+}
+
+// validateSSECustomerKey checks the customer-provided keys given by the SSE-C
+// flags.
+func validateSSECustomerKey(c *cli.Context) error {
+	for _, name := range []string{"sse-c-key", "sse-c-copy-source-key"} {
+		if c.String(name) != "" && c.String(name+"-file") != "" {
+			return fmt.Errorf("--%v and --%v-file flags can not be used together", name, name)
+		}
+		if _, err := sseCustomerKey(c, name); err != nil {
+			return err
+		}
+	}
+
+	if c.String("sse") != "" && (c.String("sse-c-key") != "" || c.String("sse-c-key-file") != "") {
+		return fmt.Errorf("--sse and --sse-c-key flags can not be used together")
+	}
+	return nil
+}
+
+// isSSECopySourceKeySet reports whether the customer-provided key of the copy
+// sources is given.
+func isSSECopySourceKeySet(c *cli.Context) bool {
+	return c.String("sse-c-copy-source-key") != "" || c.String("sse-c-copy-source-key-file") != ""
+}
+
+// sseCustomerKey returns the customer-provided key given by the flag with the
+// name or by its file variant. It returns an empty string if none of them is
+// given.
+func sseCustomerKey(c *cli.Context, name string) (string, error) {
+	if value := c.String(name); value != "" {
+		key, err := parseSSECustomerKey([]byte(value))
+		if err != nil {
+			return "", fmt.Errorf("invalid --%v: %w", name, err)
+		}
+		return key, nil
+	}
+
+	if path := c.String(name + "-file"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		key, err := parseSSECustomerKey(data)
+		if err != nil {
+			return "", fmt.Errorf("invalid key file %q: %w", path, err)
+		}
+		return key, nil
+	}
+	return "", nil
+}
+
+// parseSSECustomerKey decodes a 256-bit key, which is either raw or base64
+// encoded. Trailing whitespace of the encoded keys is ignored.
+func parseSSECustomerKey(data []byte) (string, error) {
+	if len(data) == sseCustomerKeySize {
+		return string(data), nil
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if key, err := base64.StdEncoding.DecodeString(string(trimmed)); err == nil && len(key) == sseCustomerKeySize {
+		return string(key), nil
+	}
+	if len(trimmed) == sseCustomerKeySize {
+		return string(trimmed), nil
+	}
+	return "", fmt.Errorf("key must be %d bytes, either raw or base64 encoded", sseCustomerKeySize)
+}
+
+// isClientSideEncryptionSet reports whether any of the client-side encryption
+// flags are given.
+func isClientSideEncryptionSet(c *cli.Context) bool {
+	return c.String("cse-key-file") != "" || c.String("cse-passphrase-file") != ""
+}
+
+func validateClientSideEncryption(c *cli.Context) error {
+	if c.String("cse-key-file") != "" && c.String("cse-passphrase-file") != "" {
+		return fmt.Errorf("--cse-key-file and --cse-passphrase-file flags can not be used together")
+	}
+
+	_, err := newMasterKey(c)
+	return err
+}
+
+// newMasterKey loads the master key of client-side encryption given by the
+// flags. It returns nil if client-side encryption is not enabled.
+func newMasterKey(c *cli.Context) (*cse.MasterKey, error) {
+	if path := c.String("cse-key-file"); path != "" {
+		return cse.LoadKeyFile(path)
+	}
+	if path := c.String("cse-passphrase-file"); path != "" {
+		return cse.LoadPassphraseFile(path)
+	}
+	return nil, nil
+}
+
 func validateCopy(srcurl, dsturl *url.URL) error {
 	if srcurl.IsRemote() || dsturl.IsRemote() {
 		return nil
@@ -1094,9 +2035,17 @@ func guessContentType(file *os.File) string {
 	return contentType
 }
 
+// readerAtSeeker is the interface of the readers which can be uploaded in
+// parts concurrently.
+type readerAtSeeker interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
 type countingReaderWriter struct {
 	pb      progressbar.ProgressBar
-	fp      *os.File
+	r       readerAtSeeker
+	w       io.WriterAt
 	signMap map[int64]struct{}
 	mu      sync.Mutex
 }
@@ -1104,37 +2053,85 @@ type countingReaderWriter struct {
 func newCountingReaderWriter(file *os.File, pb progressbar.ProgressBar) *countingReaderWriter {
 	return &countingReaderWriter{
 		pb:      pb,
-		fp:      file,
+		r:       file,
+		w:       file,
+		signMap: map[int64]struct{}{},
+	}
+}
+
+// newCountingWriter creates a countingReaderWriter which can only be written.
+func newCountingWriter(w io.WriterAt, pb progressbar.ProgressBar) *countingReaderWriter {
+	return &countingReaderWriter{
+		pb:      pb,
+		w:       w,
+		signMap: map[int64]struct{}{},
+	}
+}
+
+// newCountingReader creates a countingReaderWriter which can only be read.
+func newCountingReader(r readerAtSeeker, pb progressbar.ProgressBar) *countingReaderWriter {
+	return &countingReaderWriter{
+		pb:      pb,
+		r:       r,
 		signMap: map[int64]struct{}{},
 	}
 }
 
 func (r *countingReaderWriter) WriteAt(p []byte, off int64) (int, error) {
-	n, err := r.fp.WriteAt(p, off)
+	n, err := r.w.WriteAt(p, off)
 	r.pb.AddCompletedBytes(int64(n))
+	ratelimit.Wait(ratelimit.Download, n)
 	return n, err
 }
 
 func (r *countingReaderWriter) Read(p []byte) (int, error) {
-	n, err := r.fp.Read(p)
+	n, err := r.r.Read(p)
 	r.pb.AddCompletedBytes(int64(n))
+	ratelimit.Wait(ratelimit.Upload, n)
 	return n, err
 }
 
 func (r *countingReaderWriter) ReadAt(p []byte, off int64) (int, error) {
-	n, err := r.fp.ReadAt(p, off)
+	n, err := r.r.ReadAt(p, off)
 	r.mu.Lock()
 	// Ignore the first signature call
-	if _, ok := r.signMap[off]; ok {
+	_, ok := r.signMap[off]
+	if ok {
 		// Got the length have read (or means has uploaded)
 		r.pb.AddCompletedBytes(int64(n))
 	} else {
 		r.signMap[off] = struct{}{}
 	}
 	r.mu.Unlock()
+
+	// only the reads which are sent over the wire are limited.
+	if ok {
+		ratelimit.Wait(ratelimit.Upload, n)
+	}
 	return n, err
 }
 
 func (r *countingReaderWriter) Seek(offset int64, whence int) (int64, error) {
-	return r.fp.Seek(offset, whence)
+	return r.r.Seek(offset, whence)
+}
+
+// countingStream counts the bytes of a stream which can only be read
+// sequentially, such as the contents of the objects which are copied between
+// the storages of different schemes.
+type countingStream struct {
+	pb progressbar.ProgressBar
+	r  io.Reader
+}
+
+func newCountingStream(r io.Reader, pb progressbar.ProgressBar) *countingStream {
+	return &countingStream{pb: pb, r: r}
+}
+
+// Read limits the stream by the upload rate, since the bytes which are read
+// are sent to the destination storage.
+func (r *countingStream) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.pb.AddCompletedBytes(int64(n))
+	ratelimit.Wait(ratelimit.Upload, n)
+	return n, err
 }
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"encoding/base64"
 	"io"
 	"os"
 	"testing"
@@ -77,3 +78,33 @@ func TestGuessContentType(t *testing.T) {
 		os.Remove(f.Name())
 	}
 }
+
+func TestParseSSECustomerKey(t *testing.T) {
+	t.Parallel()
+
+	const key = "0123456789abcdef0123456789abcdef"
+	encoded := base64.StdEncoding.EncodeToString([]byte(key))
+
+	testcases := []struct {
+		name string
+		data string
+		err  bool
+	}{
+		{name: "raw", data: key},
+		{name: "raw with newline", data: key + "\n"},
+		{name: "base64", data: encoded},
+		{name: "base64 with newline", data: encoded + "\n"},
+		{name: "short", data: "0123456789abcdef", err: true},
+		{name: "short base64", data: base64.StdEncoding.EncodeToString([]byte("0123")), err: true},
+	}
+
+	for _, tc := range testcases {
+		got, err := parseSSECustomerKey([]byte(tc.data))
+		if tc.err {
+			assert.Error(t, err, "key must be 32 bytes, either raw or base64 encoded", tc.name)
+			continue
+		}
+		assert.NilError(t, err, tc.name)
+		assert.Equal(t, got, key, tc.name)
+	}
+}
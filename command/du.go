@@ -47,6 +47,15 @@ Examples:
 
 	7. Show disk usage of a specific version of an object in the bucket
 		 > s5cmd {{.HelpName}} --version-id VERSION_ID s3://bucket/object
+
+	8. Show disk usage of all objects in an S3 Inventory report instead of listing the bucket
+		 > s5cmd {{.HelpName}} --from-inventory s3://inventory-bucket/bucket/config/2024-01-01T01-00Z/manifest.json "s3://bucket/*"
+
+	9. Show disk usage of the objects of the keys in a file, one key per line
+		 > s5cmd {{.HelpName}} --files-from keys.txt "s3://bucket/*"
+
+	10. Show disk usage of all objects but exclude the ones matched by the patterns in a file, in gitignore syntax
+		 > s5cmd {{.HelpName}} --exclude-from patterns.txt "s3://bucket/*"
 `
 
 func NewSizeCommand() *cli.Command {
@@ -70,6 +79,10 @@ func NewSizeCommand() *cli.Command {
 				Name:  "exclude",
 				Usage: "exclude objects with given pattern",
 			},
+			&cli.StringFlag{
+				Name:  "exclude-from",
+				Usage: "exclude objects with the patterns in given file, in gitignore syntax",
+			},
 			&cli.BoolFlag{
 				Name:  "all-versions",
 				Usage: "list all versions of object(s)",
@@ -78,6 +91,14 @@ func NewSizeCommand() *cli.Command {
 				Name:  "version-id",
 				Usage: "use the specified version of an object",
 			},
+			&cli.StringFlag{
+				Name:  "from-inventory",
+				Usage: "count the objects from the S3 Inventory report of the given manifest.json file instead of listing the bucket",
+			},
+			&cli.StringFlag{
+				Name:  "files-from",
+				Usage: "count the objects from the given file of keys or 'ls --json' output instead of listing the bucket, '-' reads from stdin",
+			},
 		},
 		Before: func(c *cli.Context) error {
 			err := validateDUCommand(c)
@@ -99,6 +120,12 @@ func NewSizeCommand() *cli.Command {
 				return err
 			}
 
+			inventory, err := inventoryManifest(c)
+			if err != nil {
+				printError(fullCommand, c.Command.Name, err)
+				return err
+			}
+
 			return Size{
 				src:         srcurl,
 				op:          c.Command.Name,
@@ -107,6 +134,9 @@ func NewSizeCommand() *cli.Command {
 				groupByClass: c.Bool("group"),
 				humanize:     c.Bool("humanize"),
 				exclude:      c.StringSlice("exclude"),
+				excludeFrom:  c.String("exclude-from"),
+				inventory:    inventory,
+				filesFrom:    c.String("files-from"),
 
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
@@ -127,6 +157,9 @@ type Size struct {
 	groupByClass bool
 	humanize     bool
 	exclude      []string
+	excludeFrom  string
+	inventory    *url.URL
+	filesFrom    string
 
 	storageOpts storage.Options
 }
@@ -139,6 +172,18 @@ func (sz Size) Run(ctx context.Context) error {
 		return err
 	}
 
+	client, err = withInventory(ctx, client, sz.inventory, sz.storageOpts)
+	if err != nil {
+		printError(sz.fullCommand, sz.op, err)
+		return err
+	}
+
+	client, err = withKeyList(client, sz.filesFrom)
+	if err != nil {
+		printError(sz.fullCommand, sz.op, err)
+		return err
+	}
+
 	storageTotal := map[string]sizeAndCount{}
 	total := sizeAndCount{}
 
@@ -150,6 +195,12 @@ func (sz Size) Run(ctx context.Context) error {
 		return err
 	}
 
+	patternFiles, err := newPatternFiles(sz.excludeFrom, "")
+	if err != nil {
+		printError(sz.fullCommand, sz.op, err)
+		return err
+	}
+
 	for object := range client.List(ctx, sz.src, false) {
 		if object.Type.IsDir() || errorpkg.IsCancelation(object.Err) {
 			continue
@@ -161,10 +212,22 @@ func (sz Size) Run(ctx context.Context) error {
 			continue
 		}
 
-		if isURLMatched(excludePatterns, object.URL.Path, sz.src.Prefix) {
+		if isURLMatched(excludePatterns, object.URL.Path, sz.src.Prefix) ||
+			patternFiles.isExcluded(trimSourcePrefix(object.URL.Path, sz.src.Prefix)) {
 			continue
 		}
 
+		// sizes of the objects are not known if only their keys are listed.
+		if object.SizeUnknown() {
+			obj, err := client.Stat(ctx, object.URL)
+			if err != nil {
+				merror = multierror.Append(merror, err)
+				printError(sz.fullCommand, sz.op, err)
+				continue
+			}
+			object.Size, object.StorageClass = obj.Size, obj.StorageClass
+		}
+
 		storageClass := string(object.StorageClass)
 		s := storageTotal[storageClass]
 		s.addObject(object)
@@ -265,11 +328,15 @@ func validateDUCommand(c *cli.Context) error {
 	}
 
 	// the "all-versions" flag of du command works with GCS, because it does not
-	// depend on the generation numbers.
+	// depend on the generation numbers. gs:// URLs support the versions.
 	endpoint, err := urlpkg.Parse(c.String("endpoint-url"))
-	if err == nil && c.String("version-id") != "" && storage.IsGoogleEndpoint(*endpoint) {
+	if err == nil && c.String("version-id") != "" && srcurl.Scheme != "gs" && storage.IsGoogleEndpoint(*endpoint) {
 		return fmt.Errorf(versioningNotSupportedWarning, endpoint)
 	}
 
-	return nil
+	if err := validateInventory(c, srcurl); err != nil {
+		return err
+	}
+
+	return validateFilesFrom(c, srcurl)
 }
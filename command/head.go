@@ -53,6 +53,14 @@ func NewHeadCommand() *cli.Command {
 				Name:  "raw",
 				Usage: "disable the wildcard operations, useful with filenames that contains glob characters",
 			},
+			&cli.StringFlag{
+				Name:  "sse-c-key",
+				Usage: "read the metadata of the objects encrypted on the server side with the given 256-bit customer-provided key (SSE-C), either raw or base64 encoded",
+			},
+			&cli.StringFlag{
+				Name:  "sse-c-key-file",
+				Usage: "read the metadata of the objects encrypted on the server side with the customer-provided key (SSE-C) in the given file",
+			},
 		},
 
 		Before: func(c *cli.Context) error {
@@ -94,14 +102,21 @@ type Head struct {
 }
 
 func (h Head) Run(ctx context.Context) error {
-	client, err := storage.NewRemoteClient(ctx, h.src, h.storageOpts)
+	client, err := storage.NewClient(ctx, h.src, h.storageOpts)
 	if err != nil {
 		printError(h.fullCommand, h.op, err)
 		return err
 	}
 
 	if h.src.IsBucket() {
-		err := client.HeadBucket(ctx, h.src)
+		bucketHeader, ok := client.(storage.BucketHeader)
+		if !ok {
+			err := storage.NotSupported(h.src, "head bucket")
+			printError(h.fullCommand, h.op, err)
+			return err
+		}
+
+		err := bucketHeader.HeadBucket(ctx, h.src)
 		if err != nil {
 			printError(h.fullCommand, h.op, err)
 			return err
@@ -116,12 +131,30 @@ func (h Head) Run(ctx context.Context) error {
 		return nil
 	}
 
-	object, metadata, err := client.HeadObject(ctx, h.src)
+	metadataReader, ok := client.(storage.MetadataReader)
+	if !ok {
+		err := storage.NotSupported(h.src, "head")
+		printError(h.fullCommand, h.op, err)
+		return err
+	}
+
+	object, metadata, err := metadataReader.HeadObject(ctx, h.src)
 	if err != nil {
 		printError(h.fullCommand, h.op, err)
 		return err
 	}
 
+	// tags are not returned with the object metadata, they are fetched only
+	// if the object has any.
+	var tags map[string]string
+	if tagger, ok := client.(storage.Tagger); ok && metadata.TagCount > 0 {
+		tags, err = tagger.GetObjectTagging(ctx, h.src)
+		if err != nil {
+			printError(h.fullCommand, h.op, err)
+			return err
+		}
+	}
+
 	msg := HeadObjectMessage{
 		Key:                  object.URL.String(),
 		ContentType:          metadata.ContentType,
@@ -132,6 +165,7 @@ func (h Head) Run(ctx context.Context) error {
 		VersionID:            object.VersionID,
 		ETag:                 object.Etag,
 		Metadata:             metadata.UserDefined,
+		Tags:                 tags,
 	}
 
 	log.Info(msg)
@@ -149,6 +183,7 @@ type HeadObjectMessage struct {
 	VersionID            string            `json:"version_id,omitempty"`
 	ETag                 string            `json:"etag,omitempty"`
 	Metadata             map[string]string `json:"metadata"`
+	Tags                 map[string]string `json:"tags,omitempty"`
 }
 
 func (m HeadObjectMessage) String() string {
@@ -202,5 +237,5 @@ func validateHeadCommand(c *cli.Context) error {
 		return err
 	}
 
-	return nil
+	return validateSSECustomerKey(c)
 }
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// inventoryManifest returns the URL of the S3 Inventory manifest given with
+// --from-inventory flag. It returns nil if the flag is not set.
+func inventoryManifest(c *cli.Context) (*url.URL, error) {
+	manifest := c.String("from-inventory")
+	if manifest == "" {
+		return nil, nil
+	}
+	return url.New(manifest, url.WithRaw(true))
+}
+
+// withInventory returns a client which lists the objects from the inventory
+// report of the manifest instead of listing the storage. The client is
+// returned as is if there is no manifest.
+func withInventory(
+	ctx context.Context,
+	client storage.Storage,
+	manifest *url.URL,
+	opts storage.Options,
+) (storage.Storage, error) {
+	if manifest == nil {
+		return client, nil
+	}
+	return storage.NewInventoryClient(ctx, client, manifest, opts)
+}
+
+func validateInventory(c *cli.Context, srcurls ...*url.URL) error {
+	manifest, err := inventoryManifest(c)
+	if err != nil || manifest == nil {
+		return err
+	}
+
+	if !manifest.IsRemote() || manifest.IsBucket() || manifest.IsPrefix() {
+		return fmt.Errorf("--from-inventory flag expects the manifest.json file of an inventory report")
+	}
+
+	for _, srcurl := range srcurls {
+		if !srcurl.IsRemote() {
+			return fmt.Errorf("--from-inventory flag is only supported for remote sources")
+		}
+	}
+	return nil
+}
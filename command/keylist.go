@@ -0,0 +1,57 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// withKeyList returns a client which lists the objects from the keys in the
+// file given with --files-from flag instead of listing the storage. "-" reads
+// the keys from the standard input. The client is returned as is if there is
+// no file.
+func withKeyList(client storage.Storage, filesFrom string) (storage.Storage, error) {
+	switch filesFrom {
+	case "":
+		return client, nil
+	case "-":
+		return storage.NewKeyListClient(client, io.NopCloser(os.Stdin)), nil
+	}
+
+	f, err := os.Open(filesFrom)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewKeyListClient(client, f), nil
+}
+
+func validateFilesFrom(c *cli.Context, srcurls ...*url.URL) error {
+	if c.String("files-from") == "" {
+		return nil
+	}
+
+	if c.String("from-inventory") != "" {
+		return fmt.Errorf("--files-from and --from-inventory flags can not be used together")
+	}
+
+	if len(srcurls) != 1 {
+		return fmt.Errorf("--files-from flag expects a single source")
+	}
+
+	srcurl := srcurls[0]
+	if !srcurl.IsRemote() {
+		return fmt.Errorf("--files-from flag is only supported for remote sources")
+	}
+	if !srcurl.IsWildcard() {
+		return fmt.Errorf("--files-from flag expects a source with wildcard characters to match the keys, e.g. s3://bucket/*")
+	}
+	if srcurl.VersionID != "" || srcurl.AllVersions {
+		return fmt.Errorf("--files-from flag can not be used with --version-id or --all-versions flags")
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/urfave/cli/v2"
@@ -58,6 +59,21 @@ Examples:
 	11. List all files with their fullpaths
 		 > s5cmd {{.HelpName}} --show-fullpath "s3://bucket/*"
 
+	12. List all objects in a bucket with the restore status of the archived ones
+		 > s5cmd {{.HelpName}} --storage-class --restore-status "s3://bucket/*"
+
+	13. List all containers of an Azure Blob Storage account
+		 > s5cmd {{.HelpName}} az://
+
+	14. List all blobs in an Azure Blob Storage container
+		 > s5cmd {{.HelpName}} "az://container/*"
+
+	15. List the objects in an S3 Inventory report instead of listing the bucket
+		 > s5cmd {{.HelpName}} --from-inventory s3://inventory-bucket/bucket/config/2024-01-01T01-00Z/manifest.json "s3://bucket/*"
+
+	16. List all objects in a bucket but exclude the ones matched by the patterns in a file, in gitignore syntax
+		 > s5cmd {{.HelpName}} --exclude-from patterns.txt "s3://bucket/*"
+
 `
 
 func NewListCommand() *cli.Command {
@@ -86,6 +102,10 @@ func NewListCommand() *cli.Command {
 				Name:  "exclude",
 				Usage: "exclude objects with given pattern",
 			},
+			&cli.StringFlag{
+				Name:  "exclude-from",
+				Usage: "exclude objects with the patterns in given file, in gitignore syntax",
+			},
 			&cli.BoolFlag{
 				Name:  "all-versions",
 				Usage: "list all versions of object(s)",
@@ -94,6 +114,14 @@ func NewListCommand() *cli.Command {
 				Name:  "show-fullpath",
 				Usage: "shows only the fullpath names of the object(s)",
 			},
+			&cli.BoolFlag{
+				Name:  "restore-status",
+				Usage: "show restore status of the archived objects in the output",
+			},
+			&cli.StringFlag{
+				Name:  "from-inventory",
+				Usage: "list the objects from the S3 Inventory report of the given manifest.json file instead of the bucket",
+			},
 		},
 		Before: func(c *cli.Context) error {
 			err := validateLSCommand(c)
@@ -104,8 +132,9 @@ func NewListCommand() *cli.Command {
 		},
 		Action: func(c *cli.Context) (err error) {
 			defer stat.Collect(c.Command.FullName(), &err)()
-			if !c.Args().Present() {
-				err := ListBuckets(c.Context, NewStorageOpts(c))
+			scheme, isBucketListing := bucketListingScheme(c.Args().First())
+			if !c.Args().Present() || isBucketListing {
+				err := ListBuckets(c.Context, scheme, NewStorageOpts(c))
 				if err != nil {
 					printError(commandFromContext(c), c.Command.Name, err)
 				}
@@ -120,18 +149,30 @@ func NewListCommand() *cli.Command {
 				printError(fullCommand, c.Command.Name, err)
 				return err
 			}
+			inventory, err := inventoryManifest(c)
+			if err != nil {
+				printError(fullCommand, c.Command.Name, err)
+				return err
+			}
+
+			storageOpts := NewStorageOpts(c)
+			storageOpts.ListRestoreStatus = c.Bool("restore-status")
+
 			return List{
 				src:         srcurl,
 				op:          c.Command.Name,
 				fullCommand: fullCommand,
 				// flags
-				showEtag:         c.Bool("etag"),
-				humanize:         c.Bool("humanize"),
-				showStorageClass: c.Bool("storage-class"),
-				exclude:          c.StringSlice("exclude"),
-				showFullPath:     c.Bool("show-fullpath"),
-
-				storageOpts: NewStorageOpts(c),
+				showEtag:          c.Bool("etag"),
+				humanize:          c.Bool("humanize"),
+				showStorageClass:  c.Bool("storage-class"),
+				exclude:           c.StringSlice("exclude"),
+				excludeFrom:       c.String("exclude-from"),
+				showFullPath:      c.Bool("show-fullpath"),
+				showRestoreStatus: c.Bool("restore-status"),
+				inventory:         inventory,
+
+				storageOpts: storageOpts,
 			}.Run(c.Context)
 		},
 	}
@@ -147,25 +188,34 @@ type List struct {
 	fullCommand string
 
 	// flags
-	showEtag         bool
-	humanize         bool
-	showStorageClass bool
-	showFullPath     bool
-	exclude          []string
+	showEtag          bool
+	humanize          bool
+	showStorageClass  bool
+	showFullPath      bool
+	showRestoreStatus bool
+	exclude           []string
+	excludeFrom       string
+	inventory         *url.URL
 
 	storageOpts storage.Options
 }
 
-// ListBuckets prints all buckets.
-func ListBuckets(ctx context.Context, storageOpts storage.Options) error {
+// ListBuckets prints all buckets of the storage of the given scheme. S3
+// buckets are listed if the scheme is empty.
+func ListBuckets(ctx context.Context, scheme string, storageOpts storage.Options) error {
 	// set as remote storage
-	url := &url.URL{Type: 0}
-	client, err := storage.NewRemoteClient(ctx, url, storageOpts)
+	url := &url.URL{Type: 0, Scheme: scheme}
+	client, err := storage.NewClient(ctx, url, storageOpts)
 	if err != nil {
 		return err
 	}
 
-	buckets, err := client.ListBuckets(ctx, "")
+	lister, ok := client.(storage.BucketLister)
+	if !ok {
+		return storage.NotSupported(url, "listing buckets")
+	}
+
+	buckets, err := lister.ListBuckets(ctx, "")
 	if err != nil {
 		return err
 	}
@@ -186,6 +236,12 @@ func (l List) Run(ctx context.Context) error {
 		return err
 	}
 
+	client, err = withInventory(ctx, client, l.inventory, l.storageOpts)
+	if err != nil {
+		printError(l.fullCommand, l.op, err)
+		return err
+	}
+
 	var merror error
 
 	excludePatterns, err := createRegexFromWildcard(l.exclude)
@@ -194,6 +250,12 @@ func (l List) Run(ctx context.Context) error {
 		return err
 	}
 
+	patternFiles, err := newPatternFiles(l.excludeFrom, "")
+	if err != nil {
+		printError(l.fullCommand, l.op, err)
+		return err
+	}
+
 	for object := range client.List(ctx, l.src, false) {
 		if errorpkg.IsCancelation(object.Err) {
 			continue
@@ -205,16 +267,18 @@ func (l List) Run(ctx context.Context) error {
 			continue
 		}
 
-		if isURLMatched(excludePatterns, object.URL.Path, l.src.Prefix) {
+		if isURLMatched(excludePatterns, object.URL.Path, l.src.Prefix) ||
+			patternFiles.isExcluded(trimSourcePrefix(object.URL.Path, l.src.Prefix)) {
 			continue
 		}
 
 		msg := ListMessage{
-			Object:           object,
-			showEtag:         l.showEtag,
-			showHumanized:    l.humanize,
-			showStorageClass: l.showStorageClass,
-			showFullPath:     l.showFullPath,
+			Object:            object,
+			showEtag:          l.showEtag,
+			showHumanized:     l.humanize,
+			showStorageClass:  l.showStorageClass,
+			showFullPath:      l.showFullPath,
+			showRestoreStatus: l.showRestoreStatus,
 		}
 
 		log.Info(msg)
@@ -227,10 +291,11 @@ func (l List) Run(ctx context.Context) error {
 type ListMessage struct {
 	Object *storage.Object `json:"object"`
 
-	showEtag         bool
-	showHumanized    bool
-	showStorageClass bool
-	showFullPath     bool
+	showEtag          bool
+	showHumanized     bool
+	showStorageClass  bool
+	showFullPath      bool
+	showRestoreStatus bool
 }
 
 // humanize is a helper function to humanize bytes.
@@ -288,10 +353,18 @@ func (l ListMessage) String() string {
 		return s
 	}
 
-	stclass := ""
+	var columns []string
 	if l.showStorageClass {
-		stclass = fmt.Sprintf("%v", l.Object.StorageClass)
+		columns = append(columns, fmt.Sprintf("%v", l.Object.StorageClass))
+	}
+	if l.showRestoreStatus {
+		var restore string
+		if l.Object.Restore != nil {
+			restore = l.Object.Restore.String()
+		}
+		columns = append(columns, fmt.Sprintf("%-9s", restore))
 	}
+	stclass := strings.Join(columns, " ")
 
 	var path string
 	if l.showFullPath {
@@ -323,6 +396,13 @@ func validateLSCommand(c *cli.Context) error {
 		return fmt.Errorf("expected only 1 argument")
 	}
 
+	if _, ok := bucketListingScheme(c.Args().First()); ok || !c.Args().Present() {
+		if c.String("from-inventory") != "" {
+			return fmt.Errorf("--from-inventory flag can not be used for listing buckets")
+		}
+		return nil
+	}
+
 	srcurl, err := url.New(c.Args().First(),
 		url.WithAllVersions(c.Bool("all-versions")))
 	if err != nil {
@@ -337,5 +417,12 @@ func validateLSCommand(c *cli.Context) error {
 		return err
 	}
 
-	return nil
+	return validateInventory(c, srcurl)
+}
+
+// bucketListingScheme returns the scheme of the arguments without a bucket,
+// such as "az://", which list the buckets of the storage.
+func bucketListingScheme(arg string) (string, bool) {
+	scheme, rest, ok := strings.Cut(arg, "://")
+	return scheme, ok && rest == ""
 }
@@ -4,15 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"mime"
 	"os"
 	"path/filepath"
 
 	"github.com/urfave/cli/v2"
 
+	"github.com/peak/s5cmd/v2/cse"
 	errorpkg "github.com/peak/s5cmd/v2/error"
 	"github.com/peak/s5cmd/v2/log"
 	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/ratelimit"
 	"github.com/peak/s5cmd/v2/storage"
 	"github.com/peak/s5cmd/v2/storage/url"
 )
@@ -35,6 +38,12 @@ Examples:
 		> curl https://github.com/peak/s5cmd/ | s5cmd {{.HelpName}} s3://bucket/s5cmd.html
 	04. Compress an object and stream it to a bucket
 		> gzip -c file | s5cmd {{.HelpName}} s3://bucket/file.gz
+	05. Stream stdin to an object with tags
+		> tar -cz dir | s5cmd {{.HelpName}} --tagging "retention=short&team=storage" s3://bucket/dir.tar.gz
+	06. Stream stdin to an object encrypted on the client side
+		> tar -cz dir | s5cmd {{.HelpName}} --cse-key-file ~/.s5cmd/key s3://bucket/dir.tar.gz
+	07. Compress stdin with zstd while streaming it to an object
+		> pg_dump db | s5cmd {{.HelpName}} --compress zstd s3://bucket/db.sql.zst
 `
 
 func NewPipeCommandFlags() []cli.Flag {
@@ -67,6 +76,22 @@ func NewPipeCommandFlags() []cli.Flag {
 			Name:  "sse-kms-key-id",
 			Usage: "customer master key (CMK) id for SSE-KMS encryption; leave it out if server-side generated key is desired",
 		},
+		&cli.StringFlag{
+			Name:  "cse-key-file",
+			Usage: "encrypt the object on the client side with the 256-bit key in the given file",
+		},
+		&cli.StringFlag{
+			Name:  "cse-passphrase-file",
+			Usage: "encrypt the object on the client side with a key derived from the passphrase in the given file",
+		},
+		&cli.StringFlag{
+			Name:  "sse-c-key",
+			Usage: "perform server side encryption of the object with the given 256-bit customer-provided key (SSE-C), either raw or base64 encoded",
+		},
+		&cli.StringFlag{
+			Name:  "sse-c-key-file",
+			Usage: "perform server side encryption of the object with the customer-provided key (SSE-C) in the given file",
+		},
 		&cli.StringFlag{
 			Name:  "acl",
 			Usage: "set acl for target: defines granted accesses and their types on different accounts/groups, e.g. pipe --acl 'public-read'",
@@ -91,10 +116,22 @@ func NewPipeCommandFlags() []cli.Flag {
 			Name:  "content-encoding",
 			Usage: "set content encoding for target: defines content encoding header for object, e.g. --content-encoding gzip",
 		},
+		&cli.GenericFlag{
+			Name:  "compress",
+			Usage: "compress the object on the fly and set its content encoding: (gzip, zstd)",
+			Value: &EnumValue{
+				Enum:    []string{compressGzip, compressZstd, ""},
+				Default: "",
+			},
+		},
 		&cli.StringFlag{
 			Name:  "content-disposition",
 			Usage: "set content disposition for target: defines content disposition header for object, e.g. --content-disposition 'attachment; filename=\"filename.jpg\"'",
 		},
+		&cli.StringFlag{
+			Name:  "tagging",
+			Usage: "set tags for target: defines URL encoded tag set of the object, e.g. --tagging 'key1=value1&key2=value2'",
+		},
 		&cli.BoolFlag{
 			Name:    "no-clobber",
 			Aliases: []string{"n"},
@@ -146,13 +183,16 @@ type Pipe struct {
 	storageClass       storage.StorageClass
 	encryptionMethod   string
 	encryptionKeyID    string
+	masterKey          *cse.MasterKey
 	acl                string
 	cacheControl       string
 	expires            string
 	contentType        string
 	contentEncoding    string
 	contentDisposition string
+	compress           string
 	metadata           map[string]string
+	tags               map[string]string
 
 	// s3 options
 	concurrency int
@@ -177,6 +217,18 @@ func NewPipe(c *cli.Context, deleteSource bool) (*Pipe, error) {
 		return nil, err
 	}
 
+	tags, err := parseTagging(c.String("tagging"))
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
+	masterKey, err := newMasterKey(c)
+	if err != nil {
+		printError(fullCommand, c.Command.Name, err)
+		return nil, err
+	}
+
 	return &Pipe{
 		dst:          dst,
 		op:           c.Command.Name,
@@ -189,13 +241,16 @@ func NewPipe(c *cli.Context, deleteSource bool) (*Pipe, error) {
 		partSize:           c.Int64("part-size") * megabytes,
 		encryptionMethod:   c.String("sse"),
 		encryptionKeyID:    c.String("sse-kms-key-id"),
+		masterKey:          masterKey,
 		acl:                c.String("acl"),
 		cacheControl:       c.String("cache-control"),
 		expires:            c.String("expires"),
 		contentType:        c.String("content-type"),
 		contentEncoding:    c.String("content-encoding"),
 		contentDisposition: c.String("content-disposition"),
+		compress:           c.String("compress"),
 		metadata:           metadata,
+		tags:               tags,
 		// s3 options
 		storageOpts: NewStorageOpts(c),
 	}, nil
@@ -203,6 +258,12 @@ func NewPipe(c *cli.Context, deleteSource bool) (*Pipe, error) {
 
 // Run starts copying stdin output to destination.
 func (c Pipe) Run(ctx context.Context) error {
+	return c.upload(ctx, nil, &stdin{file: os.Stdin})
+}
+
+// upload streams the contents of the reader to the destination. src is the
+// source of the contents to be logged, it is nil for stdin.
+func (c Pipe) upload(ctx context.Context, src *url.URL, r io.Reader) error {
 	if c.dst.IsBucket() || c.dst.IsPrefix() {
 		return fmt.Errorf("target %q must be an object", c.dst)
 	}
@@ -210,17 +271,22 @@ func (c Pipe) Run(ctx context.Context) error {
 	err := c.shouldOverride(ctx, c.dst)
 	if err != nil {
 		if errorpkg.IsWarning(err) {
-			printDebug(c.op, err, nil, c.dst)
+			printDebug(c.op, err, src, c.dst)
 			return nil
 		}
 		return err
 	}
 
-	client, err := storage.NewRemoteClient(ctx, c.dst, c.storageOpts)
+	client, err := storage.NewClient(ctx, c.dst, c.storageOpts)
 	if err != nil {
 		return err
 	}
 
+	putter, ok := client.(storage.Putter)
+	if !ok {
+		return storage.NotSupported(c.dst, "upload")
+	}
+
 	metadata := storage.Metadata{
 		UserDefined:        c.metadata,
 		ACL:                c.acl,
@@ -231,6 +297,7 @@ func (c Pipe) Run(ctx context.Context) error {
 		ContentDisposition: c.contentDisposition,
 		EncryptionMethod:   c.encryptionMethod,
 		EncryptionKeyID:    c.encryptionKeyID,
+		Tags:               c.tags,
 	}
 
 	if c.contentType != "" {
@@ -239,14 +306,37 @@ func (c Pipe) Run(ctx context.Context) error {
 		metadata.ContentType = guessContentTypeByExtension(c.dst)
 	}
 
-	err = client.Put(ctx, &stdin{file: os.Stdin}, c.dst, metadata, c.concurrency, c.partSize)
+	reader := ratelimit.NewReader(r, ratelimit.Upload)
+	if c.compress != "" {
+		body := compressReader(reader, c.compress)
+		defer body.Close()
+
+		reader = body
+		metadata.ContentEncoding = c.compress
+	}
+
+	if c.masterKey != nil {
+		env, err := c.masterKey.NewEnvelope()
+		if err != nil {
+			return err
+		}
+
+		userDefined := env.Metadata()
+		for k, v := range c.metadata {
+			userDefined[k] = v
+		}
+		metadata.UserDefined = userDefined
+		reader = env.NewStreamReader(reader)
+	}
+
+	err = putter.Put(ctx, reader, c.dst, metadata, c.concurrency, c.partSize)
 	if err != nil {
 		return err
 	}
 
 	msg := log.InfoMessage{
 		Operation:   c.op,
-		Source:      nil,
+		Source:      src,
 		Destination: c.dst,
 		Object: &storage.Object{
 			StorageClass: c.storageClass,
@@ -313,7 +403,19 @@ func validatePipeCommand(c *cli.Context) error {
 		return fmt.Errorf("target %q can not contain glob characters", dst)
 	}
 
-	return nil
+	if _, err := parseTagging(c.String("tagging")); err != nil {
+		return err
+	}
+
+	if c.String("compress") != "" && c.String("content-encoding") != "" {
+		return fmt.Errorf("--compress and --content-encoding flags can not be used together")
+	}
+
+	if err := validateSSECustomerKey(c); err != nil {
+		return err
+	}
+
+	return validateClientSideEncryption(c)
 }
 
 func guessContentTypeByExtension(dsturl *url.URL) string {
@@ -27,6 +27,9 @@ Examples:
 
 	2. Print a remote object url with a specific expiration time to stdout
 		 > s5cmd {{.HelpName}} --expire 24h s3://bucket/prefix/object
+
+	3. Print a remote object url of an object encrypted with a customer-provided key (SSE-C), the SSE-C headers must be sent with the requests
+		 > s5cmd {{.HelpName}} --sse-c-key-file ~/.s5cmd/sse-c-key s3://bucket/prefix/object
 `
 
 func NewPresignCommand() *cli.Command {
@@ -44,6 +47,14 @@ func NewPresignCommand() *cli.Command {
 				Name:  "version-id",
 				Usage: "use the specified version of an object",
 			},
+			&cli.StringFlag{
+				Name:  "sse-c-key",
+				Usage: "sign the url of the object encrypted on the server side with the given 256-bit customer-provided key (SSE-C), either raw or base64 encoded",
+			},
+			&cli.StringFlag{
+				Name:  "sse-c-key-file",
+				Usage: "sign the url of the object encrypted on the server side with the customer-provided key (SSE-C) in the given file",
+			},
 		},
 		CustomHelpTemplate: presignHelpTemplate,
 		Before: func(c *cli.Context) error {
@@ -89,13 +100,20 @@ type Presign struct {
 
 // Run prints content of given source to standard output.
 func (c Presign) Run(ctx context.Context) error {
-	client, err := storage.NewRemoteClient(ctx, c.src, c.storageOpts)
+	client, err := storage.NewClient(ctx, c.src, c.storageOpts)
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
 	}
 
-	url, err := client.Presign(ctx, c.src, c.expire)
+	presigner, ok := client.(storage.Presigner)
+	if !ok {
+		err := storage.NotSupported(c.src, "presign")
+		printError(c.fullCommand, c.op, err)
+		return err
+	}
+
+	url, err := presigner.Presign(ctx, c.src, c.expire)
 	if err != nil {
 		printError(c.fullCommand, c.op, err)
 		return err
@@ -130,5 +148,5 @@ func validatePresignCommand(c *cli.Context) error {
 		return err
 	}
 
-	return nil
+	return validateSSECustomerKey(c)
 }
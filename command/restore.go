@@ -0,0 +1,411 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/parallel"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+var restoreHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] source
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Restore an archived object for a day
+		 > s5cmd {{.HelpName}} s3://bucket/prefix/object
+
+	2. Restore all archived objects with a prefix for a week using bulk retrieval
+		 > s5cmd {{.HelpName}} --days 7 --tier Bulk "s3://bucket/prefix/*"
+
+	3. Restore all archived objects that matches a wildcard and wait until they are available
+		 > s5cmd {{.HelpName}} --wait "s3://bucket/logs/2020/*.gz"
+
+	4. Restore an object using expedited retrieval and check its status every 30 seconds until it is available
+		 > s5cmd {{.HelpName}} --tier Expedited --wait --wait-interval 30s s3://bucket/prefix/object
+
+	5. Restore the specific version of an archived object
+		 > s5cmd {{.HelpName}} --version-id VERSION_ID s3://bucket/prefix/object
+`
+
+func NewRestoreCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:               "restore",
+		HelpName:           "restore",
+		Usage:              "restore archived objects",
+		CustomHelpTemplate: restoreHelpTemplate,
+		Flags: []cli.Flag{
+			&cli.Int64Flag{
+				Name:  "days",
+				Value: 1,
+				Usage: "number of days the restored copies of the objects are kept",
+			},
+			&cli.GenericFlag{
+				Name: "tier",
+				Value: &EnumValue{
+					Enum:    []string{"Expedited", "Standard", "Bulk"},
+					Default: "Standard",
+				},
+				Usage: "retrieval tier of the restore requests: (Expedited, Standard, Bulk)",
+			},
+			&cli.BoolFlag{
+				Name:  "wait",
+				Usage: "wait until all the objects are restored",
+			},
+			&cli.DurationFlag{
+				Name:  "wait-interval",
+				Value: time.Minute,
+				Usage: "interval of checking the restore status of the objects, used with --wait",
+			},
+			&cli.BoolFlag{
+				Name:  "raw",
+				Usage: "disable the wildcard operations, useful with filenames that contains glob characters",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude",
+				Usage: "exclude objects with given pattern",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "include objects with given pattern",
+			},
+			&cli.StringFlag{
+				Name:  "version-id",
+				Usage: "use the specified version of an object",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			err := validateRestoreCommand(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			op := c.Command.Name
+			fullCommand := commandFromContext(c)
+
+			src, err := url.New(c.Args().Get(0), url.WithVersion(c.String("version-id")),
+				url.WithRaw(c.Bool("raw")))
+			if err != nil {
+				printError(fullCommand, op, err)
+				return err
+			}
+
+			excludePatterns, err := createRegexFromWildcard(c.StringSlice("exclude"))
+			if err != nil {
+				printError(fullCommand, op, err)
+				return err
+			}
+
+			includePatterns, err := createRegexFromWildcard(c.StringSlice("include"))
+			if err != nil {
+				printError(fullCommand, op, err)
+				return err
+			}
+
+			return Restore{
+				src:         src,
+				op:          op,
+				fullCommand: fullCommand,
+
+				// flags
+				days:         c.Int64("days"),
+				tier:         c.String("tier"),
+				wait:         c.Bool("wait"),
+				waitInterval: c.Duration("wait-interval"),
+
+				// patterns
+				excludePatterns: excludePatterns,
+				includePatterns: includePatterns,
+
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+
+	cmd.BashComplete = getBashCompleteFn(cmd, false, false)
+	return cmd
+}
+
+// Restore holds restore operation flags and states.
+type Restore struct {
+	src         *url.URL
+	op          string
+	fullCommand string
+
+	// flag options
+	days         int64
+	tier         string
+	wait         bool
+	waitInterval time.Duration
+
+	// patterns
+	excludePatterns []*regexp.Regexp
+	includePatterns []*regexp.Regexp
+
+	// storage options
+	storageOpts storage.Options
+}
+
+// restoreClient is the storage which can restore the archived objects and
+// report their restore status.
+type restoreClient interface {
+	storage.Storage
+	storage.Restorer
+	storage.MetadataReader
+}
+
+// Run initiates restore requests for the given objects and optionally waits
+// until they are restored.
+func (r Restore) Run(ctx context.Context) error {
+	storageClient, err := storage.NewClient(ctx, r.src, r.storageOpts)
+	if err != nil {
+		printError(r.fullCommand, r.op, err)
+		return err
+	}
+
+	client, ok := storageClient.(restoreClient)
+	if !ok {
+		err := storage.NotSupported(r.src, "restore")
+		printError(r.fullCommand, r.op, err)
+		return err
+	}
+
+	objch, err := expandSource(ctx, client, false, r.src)
+	if err != nil {
+		printError(r.fullCommand, r.op, err)
+		return err
+	}
+
+	var (
+		merrorObjects error
+		pending       []*url.URL
+		mu            sync.Mutex
+	)
+
+	// objects which are still being restored are collected to be waited for.
+	inProgress := func(srcurl *url.URL) {
+		mu.Lock()
+		defer mu.Unlock()
+		pending = append(pending, srcurl)
+	}
+
+	merrorWaiter := r.runTasks(func(run func(parallel.Task)) {
+		for object := range objch {
+			if errorpkg.IsCancelation(object.Err) || object.Type.IsDir() {
+				continue
+			}
+
+			if err := object.Err; err != nil {
+				merrorObjects = multierror.Append(merrorObjects, err)
+				printError(r.fullCommand, r.op, err)
+				continue
+			}
+
+			// storage class of the objects is unknown unless they are listed.
+			if object.StorageClass != "" && !object.StorageClass.IsArchived() {
+				continue
+			}
+
+			isExcluded, err := isObjectExcluded(object, r.excludePatterns, r.includePatterns, r.src.Prefix)
+			if err != nil {
+				printError(r.fullCommand, r.op, err)
+			}
+			if isExcluded {
+				continue
+			}
+
+			srcurl := object.URL
+			run(func() error {
+				restoring, err := r.doRestore(ctx, client, srcurl)
+				if err != nil {
+					return &errorpkg.Error{Op: r.op, Src: srcurl, Err: err}
+				}
+				if restoring {
+					inProgress(srcurl)
+				}
+				return nil
+			})
+		}
+	})
+
+	merror := multierror.Append(merrorWaiter, merrorObjects).ErrorOrNil()
+	if !r.wait || len(pending) == 0 {
+		return merror
+	}
+
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return multierror.Append(merror, ctx.Err())
+		case <-time.After(r.waitInterval):
+		}
+
+		urls := pending
+		pending = nil
+		err := r.runTasks(func(run func(parallel.Task)) {
+			for _, srcurl := range urls {
+				srcurl := srcurl
+				run(func() error {
+					restoring, err := r.checkStatus(ctx, client, srcurl, false)
+					if err != nil {
+						return &errorpkg.Error{Op: r.op, Src: srcurl, Err: err}
+					}
+					if restoring {
+						inProgress(srcurl)
+					}
+					return nil
+				})
+			}
+		})
+		merror = multierror.Append(merror, err).ErrorOrNil()
+	}
+
+	return merror
+}
+
+// runTasks runs the tasks given by the dispatch function in parallel and
+// returns the errors of the tasks.
+func (r Restore) runTasks(dispatch func(run func(parallel.Task))) error {
+	waiter := parallel.NewWaiter()
+
+	var (
+		merror    error
+		errDoneCh = make(chan struct{})
+	)
+
+	go func() {
+		defer close(errDoneCh)
+		for err := range waiter.Err() {
+			printError(r.fullCommand, r.op, err)
+			merror = multierror.Append(merror, err)
+		}
+	}()
+
+	dispatch(func(task parallel.Task) {
+		parallel.Run(task, waiter)
+	})
+
+	waiter.Wait()
+	<-errDoneCh
+
+	return merror
+}
+
+// doRestore initiates the restore of the object and reports whether the
+// object is still being restored.
+func (r Restore) doRestore(ctx context.Context, client restoreClient, srcurl *url.URL) (bool, error) {
+	if err := client.RestoreObject(ctx, srcurl, r.days, r.tier); err != nil {
+		return false, err
+	}
+
+	if r.storageOpts.DryRun {
+		log.Info(RestoreMessage{Operation: r.op, Source: srcurl})
+		return false, nil
+	}
+
+	return r.checkStatus(ctx, client, srcurl, true)
+}
+
+// checkStatus prints the restore status of the object and reports whether
+// the object is still being restored. If verbose is false, objects which are
+// still being restored are not printed.
+func (r Restore) checkStatus(ctx context.Context, client restoreClient, srcurl *url.URL, verbose bool) (bool, error) {
+	object, _, err := client.HeadObject(ctx, srcurl)
+	if err != nil {
+		return false, err
+	}
+
+	restoring := object.Restore != nil && object.Restore.InProgress
+	if restoring && !verbose {
+		return true, nil
+	}
+
+	log.Info(RestoreMessage{
+		Operation: r.op,
+		Source:    srcurl,
+		Restore:   object.Restore,
+	})
+	return restoring, nil
+}
+
+// RestoreMessage is a structure for printing the restore status of an
+// object.
+type RestoreMessage struct {
+	Operation string                 `json:"operation"`
+	Success   bool                   `json:"success"`
+	Source    *url.URL               `json:"source"`
+	Restore   *storage.RestoreStatus `json:"restore,omitempty"`
+}
+
+// String is the string representation of RestoreMessage.
+func (m RestoreMessage) String() string {
+	if m.Restore == nil {
+		return fmt.Sprintf("%v %v", m.Operation, m.Source)
+	}
+	if m.Restore.ExpiryDate == nil {
+		return fmt.Sprintf("%v %v %v", m.Operation, m.Source, m.Restore)
+	}
+	return fmt.Sprintf("%v %v %v until %v", m.Operation, m.Source, m.Restore,
+		m.Restore.ExpiryDate.Format(time.RFC3339))
+}
+
+// JSON is the JSON representation of RestoreMessage.
+func (m RestoreMessage) JSON() string {
+	m.Success = true
+	return strutil.JSON(m)
+}
+
+func validateRestoreCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected only 1 argument")
+	}
+
+	if c.Int64("days") < 1 {
+		return fmt.Errorf("--days must be a positive number")
+	}
+
+	if c.Duration("wait-interval") <= 0 {
+		return fmt.Errorf("--wait-interval must be a positive duration")
+	}
+
+	srcurl, err := url.New(c.Args().Get(0), url.WithVersion(c.String("version-id")),
+		url.WithRaw(c.Bool("raw")))
+	if err != nil {
+		return err
+	}
+
+	if !srcurl.IsRemote() {
+		return fmt.Errorf("source must be a remote object")
+	}
+
+	if srcurl.IsBucket() || srcurl.IsPrefix() {
+		return fmt.Errorf("source argument must contain wildcard character")
+	}
+
+	if err := checkVersinoningURLRemote(srcurl); err != nil {
+		return err
+	}
+
+	return checkVersioningWithGoogleEndpoint(c)
+}
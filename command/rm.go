@@ -11,6 +11,7 @@ import (
 	errorpkg "github.com/peak/s5cmd/v2/error"
 	"github.com/peak/s5cmd/v2/log"
 	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/parallel"
 	"github.com/peak/s5cmd/v2/storage"
 	"github.com/peak/s5cmd/v2/storage/url"
 )
@@ -54,6 +55,22 @@ Examples:
 
 	10. Delete all versions of all objects in the bucket
 		 > s5cmd {{.HelpName}} --all-versions "s3://bucket/*"
+
+	11. Delete the matching objects listed in an S3 Inventory report instead of listing the bucket
+		 > s5cmd {{.HelpName}} --from-inventory s3://inventory-bucket/bucket/config/2024-01-01T01-00Z/manifest.json "s3://bucket/logs/*"
+
+	12. Delete the objects of the keys read from stdin, in batches of 1000 objects
+		 > cat keys.txt | s5cmd {{.HelpName}} --files-from - "s3://bucket/*"
+
+	13. Delete the object versions printed by ls command
+		 > s5cmd --json ls --all-versions "s3://bucket/tmp/*" > versions.json
+		 > s5cmd {{.HelpName}} --files-from versions.json "s3://bucket/*"
+
+	14. Delete all objects with a prefix but keep a copy of them under another prefix first
+		 > s5cmd {{.HelpName}} --backup-dir s3://bucket/trash/2026-10-16/ "s3://bucket/prefix/*"
+
+	15. Delete only the objects matched by the patterns in a file, in gitignore syntax
+		 > s5cmd {{.HelpName}} --include-from patterns.txt "s3://bucket/*"
 `
 
 func NewDeleteCommand() *cli.Command {
@@ -74,6 +91,14 @@ func NewDeleteCommand() *cli.Command {
 				Name:  "include",
 				Usage: "include objects with given pattern",
 			},
+			&cli.StringFlag{
+				Name:  "exclude-from",
+				Usage: "exclude objects with the patterns in given file, in gitignore syntax",
+			},
+			&cli.StringFlag{
+				Name:  "include-from",
+				Usage: "include objects with the patterns in given file, in gitignore syntax",
+			},
 			&cli.BoolFlag{
 				Name:  "all-versions",
 				Usage: "list all versions of object(s)",
@@ -82,6 +107,18 @@ func NewDeleteCommand() *cli.Command {
 				Name:  "version-id",
 				Usage: "use the specified version of an object",
 			},
+			&cli.StringFlag{
+				Name:  "from-inventory",
+				Usage: "list the objects from the S3 Inventory report of the given manifest.json file instead of the bucket",
+			},
+			&cli.StringFlag{
+				Name:  "files-from",
+				Usage: "read the objects from the given file of keys or 'ls --json' output instead of listing the bucket, '-' reads from stdin",
+			},
+			&cli.StringFlag{
+				Name:  "backup-dir",
+				Usage: "back up the objects under the given directory or prefix before they are deleted, preserving their relative paths",
+			},
 		},
 		CustomHelpTemplate: deleteHelpTemplate,
 		Before: func(c *cli.Context) error {
@@ -114,6 +151,24 @@ func NewDeleteCommand() *cli.Command {
 				return err
 			}
 
+			patternFiles, err := newPatternFiles(c.String("exclude-from"), c.String("include-from"))
+			if err != nil {
+				printError(fullCommand, c.Command.Name, err)
+				return err
+			}
+
+			inventory, err := inventoryManifest(c)
+			if err != nil {
+				printError(fullCommand, c.Command.Name, err)
+				return err
+			}
+
+			backupDir, err := newBackupDir(c)
+			if err != nil {
+				printError(fullCommand, c.Command.Name, err)
+				return err
+			}
+
 			return Delete{
 				src:         srcUrls,
 				op:          c.Command.Name,
@@ -126,6 +181,11 @@ func NewDeleteCommand() *cli.Command {
 				// patterns
 				excludePatterns: excludePatterns,
 				includePatterns: includePatterns,
+				patternFiles:    patternFiles,
+
+				inventory: inventory,
+				filesFrom: c.String("files-from"),
+				backupDir: backupDir,
 
 				storageOpts: NewStorageOpts(c),
 			}.Run(c.Context)
@@ -149,6 +209,18 @@ type Delete struct {
 	// patterns
 	excludePatterns []*regexp.Regexp
 	includePatterns []*regexp.Regexp
+	patternFiles    patternFiles
+
+	// inventory is the manifest of the S3 Inventory report which the objects
+	// are listed from.
+	inventory *url.URL
+
+	// filesFrom is the file of the keys which the objects are listed from.
+	filesFrom string
+
+	// backupDir is the directory or prefix which the objects are backed up
+	// under before they are deleted.
+	backupDir *url.URL
 
 	// storage options
 	storageOpts storage.Options
@@ -165,17 +237,42 @@ func (d Delete) Run(ctx context.Context) error {
 		return err
 	}
 
+	client, err = withInventory(ctx, client, d.inventory, d.storageOpts)
+	if err != nil {
+		printError(d.fullCommand, d.op, err)
+		return err
+	}
+
+	client, err = withKeyList(client, d.filesFrom)
+	if err != nil {
+		printError(d.fullCommand, d.op, err)
+		return err
+	}
+
 	objch := expandSources(ctx, client, false, d.src...)
 
 	var (
 		merrorObjects error
 		merrorResult  error
+		merrorBackup  error
+		waiter        = parallel.NewWaiter()
+		errDoneCh     = make(chan struct{})
 	)
 
+	go func() {
+		defer close(errDoneCh)
+		for err := range waiter.Err() {
+			printError(d.fullCommand, d.op, err)
+			merrorBackup = multierror.Append(merrorBackup, err)
+		}
+	}()
+
 	// do object->url transformation
 	urlch := make(chan *url.URL)
 	go func() {
 		defer close(urlch)
+		// the objects are deleted once they are backed up.
+		defer waiter.Wait()
 
 		for object := range objch {
 			if object.Type.IsDir() || errorpkg.IsCancelation(object.Err) {
@@ -188,7 +285,7 @@ func (d Delete) Run(ctx context.Context) error {
 				continue
 			}
 
-			isExcluded, err := isObjectExcluded(object, d.excludePatterns, d.includePatterns, srcurl.Prefix)
+			isExcluded, err := isObjectFiltered(object, d.excludePatterns, d.includePatterns, d.patternFiles, srcurl.Prefix)
 			if err != nil {
 				printError(d.fullCommand, d.op, err)
 			}
@@ -196,6 +293,11 @@ func (d Delete) Run(ctx context.Context) error {
 				continue
 			}
 
+			if d.backupDir != nil {
+				parallel.Run(d.prepareBackupTask(ctx, object, urlch), waiter)
+				continue
+			}
+
 			urlch <- object.URL
 		}
 	}()
@@ -219,8 +321,37 @@ func (d Delete) Run(ctx context.Context) error {
 		}
 		log.Info(msg)
 	}
+	<-errDoneCh
+
+	return multierror.Append(merrorResult, merrorObjects, merrorBackup).ErrorOrNil()
+}
+
+// prepareBackupTask returns a task which backs up the object and sends it to
+// be deleted. The local files are moved to the backup directory, so they are
+// not deleted afterwards.
+func (d Delete) prepareBackupTask(ctx context.Context, object *storage.Object, urlch chan<- *url.URL) func() error {
+	return func() error {
+		backupurl := d.backupDir.Join(relativeSource(object.URL))
+		if err := backupObject(ctx, object, backupurl, d.storageOpts); err != nil {
+			return &errorpkg.Error{
+				Op:  d.op,
+				Src: object.URL,
+				Err: err,
+			}
+		}
+
+		if object.URL.IsRemote() {
+			urlch <- object.URL
+			return nil
+		}
 
-	return multierror.Append(merrorResult, merrorObjects).ErrorOrNil()
+		msg := log.InfoMessage{
+			Operation: d.op,
+			Source:    object.URL,
+		}
+		log.Info(msg)
+		return nil
+	}
 }
 
 // newSources creates object URL list from given sources.
@@ -297,5 +428,17 @@ func validateRMCommand(c *cli.Context) error {
 		}
 	}
 
-	return nil
+	if err := validateInventory(c, srcurls...); err != nil {
+		return err
+	}
+
+	if c.String("backup-dir") != "" && (c.Bool("all-versions") || c.String("version-id") != "") {
+		return fmt.Errorf("--backup-dir flag can not be used with versioning flags")
+	}
+
+	if err := validateBackupDir(c, srcurls...); err != nil {
+		return err
+	}
+
+	return validateFilesFrom(c, srcurls...)
 }
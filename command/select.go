@@ -129,6 +129,14 @@ func NewSelectCommand() *cli.Command {
 			Name:  "version-id",
 			Usage: "use the specified version of the object",
 		},
+		&cli.StringFlag{
+			Name:  "sse-c-key",
+			Usage: "query the objects encrypted on the server side with the given 256-bit customer-provided key (SSE-C), either raw or base64 encoded",
+		},
+		&cli.StringFlag{
+			Name:  "sse-c-key-file",
+			Usage: "query the objects encrypted on the server side with the customer-provided key (SSE-C) in the given file",
+		},
 	}
 
 	cmd := &cli.Command{
@@ -289,12 +297,19 @@ type Select struct {
 
 // Run starts copying given source objects to destination.
 func (s Select) Run(ctx context.Context) error {
-	client, err := storage.NewRemoteClient(ctx, s.src, s.storageOpts)
+	client, err := storage.NewClient(ctx, s.src, s.storageOpts)
 	if err != nil {
 		printError(s.fullCommand, s.op, err)
 		return err
 	}
 
+	selector, ok := client.(storage.Selector)
+	if !ok {
+		err := storage.NotSupported(s.src, "select")
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -373,7 +388,7 @@ func (s Select) Run(ctx context.Context) error {
 			continue
 		}
 
-		task := s.prepareTask(ctx, client, object.URL, resultCh)
+		task := s.prepareTask(ctx, selector, object.URL, resultCh)
 		parallel.Run(task, waiter)
 
 	}
@@ -386,7 +401,7 @@ func (s Select) Run(ctx context.Context) error {
 	return multierror.Append(merrorWaiter, merrorObjects).ErrorOrNil()
 }
 
-func (s Select) prepareTask(ctx context.Context, client *storage.S3, url *url.URL, resultCh chan<- json.RawMessage) func() error {
+func (s Select) prepareTask(ctx context.Context, selector storage.Selector, url *url.URL, resultCh chan<- json.RawMessage) func() error {
 	return func() error {
 		query := &storage.SelectQuery{
 			ExpressionType:        "SQL",
@@ -398,7 +413,7 @@ func (s Select) prepareTask(ctx context.Context, client *storage.S3, url *url.UR
 			CompressionType:       s.compressionType,
 		}
 
-		return client.Select(ctx, url, query, resultCh)
+		return selector.Select(ctx, url, query, resultCh)
 	}
 }
 
@@ -434,5 +449,5 @@ func validateSelectCommand(c *cli.Context) error {
 		return fmt.Errorf("query must be non-empty")
 	}
 
-	return nil
+	return validateSSECustomerKey(c)
 }
@@ -2,12 +2,16 @@ package command
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/hashicorp/go-multierror"
@@ -15,6 +19,7 @@ import (
 	"github.com/urfave/cli/v2"
 
 	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/ignore"
 	"github.com/peak/s5cmd/v2/log"
 	"github.com/peak/s5cmd/v2/log/stat"
 	"github.com/peak/s5cmd/v2/parallel"
@@ -69,6 +74,30 @@ Examples:
 
 	11. Sync all files to S3 bucket but include the only ones with txt and gz extension
 		 > s5cmd {{.HelpName}} --include "*.txt" --include "*.gz" dir/ s3://bucket
+
+	12. Sync local folder to s3 bucket but compare objects by their checksums
+		 > s5cmd {{.HelpName}} --checksum folder/ s3://bucket/
+
+	13. Sync local folder to s3 bucket and tag the uploaded objects
+		 > s5cmd {{.HelpName}} --tagging "retention=short" folder/ s3://bucket/
+
+	14. Sync local folder to s3 bucket repeatedly and reuse the listing of the bucket for an hour
+		 > s5cmd {{.HelpName}} --cache-ttl 1h folder/ s3://bucket/
+
+	15. Sync S3 bucket to local folder but do not delete any files if more than 100 files are to be deleted
+		 > s5cmd {{.HelpName}} --delete --max-delete 100 "s3://bucket/*" folder/
+
+	16. Sync local folder to s3 bucket but do not delete any objects if more than 10 percent of the bucket is to be deleted
+		 > s5cmd {{.HelpName}} --delete --max-delete-percent 10 folder/ s3://bucket/
+
+	17. Sync local folder to s3 bucket but keep a copy of the overwritten and deleted objects under another prefix
+		 > s5cmd {{.HelpName}} --delete --backup-dir s3://bucket/trash/2026-10-16/ folder/ s3://bucket/static/
+
+	18. Sync local folder to s3 bucket and keep uploading the changed files until interrupted
+		 > s5cmd {{.HelpName}} --watch --delete folder/ s3://bucket/
+
+	19. Sync local folder to s3 bucket but skip the files matched by the patterns in a file, in gitignore syntax
+		 > s5cmd {{.HelpName}} --delete --exclude-from .gitignore folder/ s3://bucket/
 `
 
 func NewSyncCommandFlags() []cli.Flag {
@@ -77,14 +106,48 @@ func NewSyncCommandFlags() []cli.Flag {
 			Name:  "delete",
 			Usage: "delete objects in destination but not in source",
 		},
+		&cli.IntFlag{
+			Name:  "max-delete",
+			Usage: "do not delete any objects if more than given number of objects are to be deleted with --delete flag",
+		},
+		&cli.IntFlag{
+			Name:  "max-delete-percent",
+			Usage: "do not delete any objects if more than given percentage of destination objects are to be deleted with --delete flag",
+		},
 		&cli.BoolFlag{
 			Name:  "size-only",
 			Usage: "make size of object only criteria to decide whether an object should be synced",
 		},
+		&cli.BoolFlag{
+			Name:  "checksum",
+			Usage: "make checksum of object criteria to decide whether an object should be synced, falls back to size and modification time if checksum can not be compared",
+		},
 		&cli.BoolFlag{
 			Name:  "exit-on-error",
 			Usage: "stops the sync process if an error is received",
 		},
+		&cli.BoolFlag{
+			Name:  "watch",
+			Usage: "keep running after the sync and upload the changes of the local directory as they happen until interrupted",
+		},
+		&cli.DurationFlag{
+			Name:  "watch-debounce",
+			Value: defaultWatchDebounce,
+			Usage: "wait until no changes are made for the given duration before uploading the changed files with --watch flag",
+		},
+		&cli.DurationFlag{
+			Name:  "reconcile-interval",
+			Value: defaultReconcileInterval,
+			Usage: "sync the whole directory in the given interval to catch the missed changes with --watch flag",
+		},
+		&cli.DurationFlag{
+			Name:  "cache-ttl",
+			Usage: "cache the listing of the destination locally and revalidate it after the given duration, e.g. 1h",
+		},
+		&cli.StringFlag{
+			Name:  "cache-dir",
+			Usage: "directory of the listing cache (default: s5cmd directory in the user cache directory)",
+		},
 	}
 	sharedFlags := NewSharedFlags()
 	return append(syncFlags, sharedFlags...)
@@ -99,7 +162,7 @@ func NewSyncCommand() *cli.Command {
 		CustomHelpTemplate: syncHelpTemplate,
 		Before: func(c *cli.Context) error {
 			// sync command share same validation method as copy command
-			err := validateCopyCommand(c)
+			err := validateSyncCommand(c)
 			if err != nil {
 				printError(commandFromContext(c), c.Command.Name, err)
 			}
@@ -108,7 +171,12 @@ func NewSyncCommand() *cli.Command {
 		Action: func(c *cli.Context) (err error) {
 			defer stat.Collect(c.Command.FullName(), &err)()
 
-			return NewSync(c).Run(c)
+			s, err := NewSync(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+				return err
+			}
+			return s.Run(c)
 		},
 	}
 
@@ -130,7 +198,41 @@ type Sync struct {
 	// flags
 	delete      bool
 	sizeOnly    bool
+	checksum    bool
 	exitOnError bool
+	partSize    int64
+	compress    string
+	inventory   string
+
+	// maxDelete and maxDeletePercent are negative if they are not set.
+	maxDelete        int
+	maxDeletePercent int
+
+	// backupDir is the directory or prefix which the overwritten and deleted
+	// objects are backed up under.
+	backupDir *url.URL
+
+	// patternFiles are applied to the objects of both sides while they are
+	// listed, instead of the generated commands.
+	patternFiles patternFiles
+
+	// dirExcludePatterns are the --exclude patterns which end with "*". All
+	// files under a directory are matched by such a pattern if the directory
+	// is matched, so the directory is not watched in watch mode.
+	dirExcludePatterns []*regexp.Regexp
+
+	// watch mode settings
+	watch             bool
+	watchDebounce     time.Duration
+	reconcileInterval time.Duration
+
+	// listing cache settings of the destination
+	cacheTTL time.Duration
+	cacheDir string
+
+	// clientSideEncryption reports whether the remote objects are encrypted
+	// on the client side.
+	clientSideEncryption bool
 
 	// s3 options
 	storageOpts storage.Options
@@ -144,7 +246,36 @@ type Sync struct {
 }
 
 // NewSync creates Sync from cli.Context
-func NewSync(c *cli.Context) Sync {
+func NewSync(c *cli.Context) (Sync, error) {
+	maxDelete, maxDeletePercent := -1, -1
+	if c.IsSet("max-delete") {
+		maxDelete = c.Int("max-delete")
+	}
+	if c.IsSet("max-delete-percent") {
+		maxDeletePercent = c.Int("max-delete-percent")
+	}
+
+	backupDir, err := newBackupDir(c)
+	if err != nil {
+		return Sync{}, err
+	}
+
+	patternFiles, err := newPatternFiles(c.String("exclude-from"), c.String("include-from"))
+	if err != nil {
+		return Sync{}, err
+	}
+
+	var dirExcludes []string
+	for _, pattern := range c.StringSlice("exclude") {
+		if strings.HasSuffix(pattern, "*") {
+			dirExcludes = append(dirExcludes, pattern)
+		}
+	}
+	dirExcludePatterns, err := createRegexFromWildcard(dirExcludes)
+	if err != nil {
+		return Sync{}, err
+	}
+
 	return Sync{
 		src:         c.Args().Get(0),
 		dst:         c.Args().Get(1),
@@ -154,7 +285,27 @@ func NewSync(c *cli.Context) Sync {
 		// flags
 		delete:      c.Bool("delete"),
 		sizeOnly:    c.Bool("size-only"),
+		checksum:    c.Bool("checksum"),
 		exitOnError: c.Bool("exit-on-error"),
+		partSize:    c.Int64("part-size") * megabytes,
+		compress:    c.String("compress"),
+		inventory:   c.String("from-inventory"),
+
+		maxDelete:        maxDelete,
+		maxDeletePercent: maxDeletePercent,
+		backupDir:        backupDir,
+		patternFiles:     patternFiles,
+
+		dirExcludePatterns: dirExcludePatterns,
+
+		watch:             c.Bool("watch"),
+		watchDebounce:     c.Duration("watch-debounce"),
+		reconcileInterval: c.Duration("reconcile-interval"),
+
+		cacheTTL: c.Duration("cache-ttl"),
+		cacheDir: c.String("cache-dir"),
+
+		clientSideEncryption: isClientSideEncryptionSet(c),
 
 		// flags
 		followSymlinks: !c.Bool("no-follow-symlinks"),
@@ -164,12 +315,53 @@ func NewSync(c *cli.Context) Sync {
 		srcRegion:   c.String("source-region"),
 		dstRegion:   c.String("destination-region"),
 		storageOpts: NewStorageOpts(c),
+	}, nil
+}
+
+// Run syncs source to destination. In watch mode, it keeps syncing the
+// changes until it is interrupted.
+func (s Sync) Run(c *cli.Context) error {
+	if s.cacheTTL > 0 {
+		closeCache, err := s.openListingCache()
+		if err != nil {
+			printError(s.fullCommand, s.op, err)
+			return err
+		}
+		defer closeCache()
+	}
+
+	if s.watch {
+		return s.runWatch(c)
 	}
+	return s.run(c)
 }
 
-// Run compares files, plans necessary s5cmd commands to execute
+// openListingCache opens the listing cache of the destination. The cache is
+// shared with the generated commands, so their writes invalidate the cached
+// listing. The destination is listed without the cache if the cache is used
+// by another process.
+func (s *Sync) openListingCache() (func(), error) {
+	cache, err := storage.OpenListingCache(s.cacheDir, s.cacheTTL)
+	if errors.Is(err, storage.ErrListingCacheLocked) {
+		printDebug(s.op, err)
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	listingCache = cache
+	s.storageOpts.ListingCache = cache
+
+	return func() {
+		listingCache = nil
+		cache.Close()
+	}, nil
+}
+
+// run compares files, plans necessary s5cmd commands to execute
 // and executes them in order to sync source to destination.
-func (s Sync) Run(c *cli.Context) error {
+func (s Sync) run(c *cli.Context) error {
 	srcurl, err := url.New(s.src, url.WithRaw(s.raw))
 	if err != nil {
 		return err
@@ -228,14 +420,27 @@ func (s Sync) Run(c *cli.Context) error {
 		}
 	}()
 
-	strategy := NewStrategy(s.sizeOnly) // create comparison strategy.
+	// create comparison strategy.
+	strategy := NewStrategy(s.sizeOnly, s.checksum, s.partSize, newHeadObjectFunc(ctx, s.storageOpts))
+	if s.compress != "" {
+		strategy = &CompressionStrategy{}
+	}
+	if s.clientSideEncryption {
+		strategy = &ClientSideEncryptionStrategy{
+			strategy:   strategy,
+			headObject: newHeadObjectFunc(ctx, s.storageOpts),
+		}
+	}
 	pipeReader, pipeWriter := io.Pipe() // create a reader, writer pipe to pass commands to run
 
 	// Create commands in background.
-	go s.planRun(c, onlySource, onlyDest, commonObjects, dsturl, strategy, pipeWriter, isBatch)
+	planErrCh := make(chan error, 1)
+	go func() {
+		planErrCh <- s.planRun(c, onlySource, onlyDest, commonObjects, dsturl, strategy, pipeWriter, isBatch)
+	}()
 
 	err = NewRun(c, pipeReader).Run(ctx)
-	return multierror.Append(err, merrorWaiter).ErrorOrNil()
+	return multierror.Append(err, merrorWaiter, <-planErrCh).ErrorOrNil()
 }
 
 // compareObjects compares source and destination objects. It assumes that
@@ -306,7 +511,24 @@ func (s Sync) getSourceAndDestinationObjects(ctx context.Context, cancel context
 		return nil, nil, err
 	}
 
-	destClient, err := storage.NewClient(ctx, dsturl, s.storageOpts)
+	if s.inventory != "" {
+		manifest, err := url.New(s.inventory, url.WithRaw(true))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sourceClient, err = withInventory(ctx, sourceClient, manifest, s.storageOpts)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// only the listing of the destination is cached, the source is listed
+	// in each run.
+	destOpts := s.storageOpts
+	destOpts.CacheListings = true
+
+	destClient, err := storage.NewClient(ctx, dsturl, destOpts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -324,6 +546,13 @@ func (s Sync) getSourceAndDestinationObjects(ctx context.Context, cancel context
 		return nil, nil, err
 	}
 
+	// the ignore files of a local side hide its files from the listing. The
+	// objects of the other side are skipped too, so that the ignored files
+	// are neither overwritten nor deleted.
+	localClient := storage.NewLocalClient(s.storageOpts)
+	srcIgnoreTree := localClient.IgnoreTree(destObjectsURL)
+	dstIgnoreTree := localClient.IgnoreTree(srcurl)
+
 	var (
 		sourceObjects = make(chan *storage.Object, extsortChannelBufferSize)
 		destObjects   = make(chan *storage.Object, extsortChannelBufferSize)
@@ -357,7 +586,7 @@ func (s Sync) getSourceAndDestinationObjects(ctx context.Context, cancel context
 					log.Error(msg)
 					cancel()
 				}
-				if s.shouldSkipSrcObject(st, true) {
+				if s.shouldSkipSrcObject(st, true) || s.isExcludedFrom(st, srcIgnoreTree) {
 					continue
 				}
 				filteredSrcObjectChannel <- *st
@@ -404,7 +633,7 @@ func (s Sync) getSourceAndDestinationObjects(ctx context.Context, cancel context
 					log.Error(msg)
 					cancel()
 				}
-				if s.shouldSkipDstObject(dt, false) {
+				if s.shouldSkipDstObject(dt, false) || s.isExcludedFrom(dt, dstIgnoreTree) {
 					continue
 				}
 				filteredDstObjectChannel <- *dt
@@ -435,7 +664,8 @@ func (s Sync) getSourceAndDestinationObjects(ctx context.Context, cancel context
 	return sourceObjects, destObjects, nil
 }
 
-// planRun prepares the commands and writes them to writer 'w'.
+// planRun prepares the commands and writes them to writer 'w'. It returns an
+// error if the deletions are skipped because they exceed the limits.
 func (s Sync) planRun(
 	c *cli.Context,
 	onlySource, onlyDest chan *url.URL,
@@ -444,7 +674,7 @@ func (s Sync) planRun(
 	strategy SyncStrategy,
 	w io.WriteCloser,
 	isBatch bool,
-) {
+) error {
 	defer w.Close()
 
 	// Always use raw mode since sync command generates commands
@@ -458,10 +688,21 @@ func (s Sync) planRun(
 	// are completed before closing the WriteCloser w to ensure that all URLs are processed.
 	var wg sync.WaitGroup
 
+	// the number of objects both in source and destination is required to
+	// check the percentage of the deletions.
+	var (
+		commonCount int
+		commonDone  = make(chan struct{})
+		deleteErr   error
+	)
+
 	// only in source
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+
+		// the objects only in source are not expected to be in destination,
+		// they are not looked up to be backed up.
 		for srcurl := range onlySource {
 			curDestURL := generateDestinationURL(srcurl, dsturl, isBatch)
 			command, err := generateCommand(c, "cp", defaultFlags, srcurl, curDestURL)
@@ -477,7 +718,9 @@ func (s Sync) planRun(
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		defer close(commonDone)
 		for commonObject := range common {
+			commonCount++
 			sourceObject, destObject := commonObject.src, commonObject.dst
 			curSourceURL, curDestURL := sourceObject.URL, destObject.URL
 			err := strategy.ShouldSync(sourceObject, destObject) // check if object should be copied.
@@ -486,7 +729,12 @@ func (s Sync) planRun(
 				continue
 			}
 
-			command, err := generateCommand(c, "cp", defaultFlags, curSourceURL, curDestURL)
+			flags := defaultFlags
+			if s.backupDir != nil {
+				flags = withBackupDir(defaultFlags, s.backupDirOf(curDestURL))
+			}
+
+			command, err := generateCommand(c, "cp", flags, curSourceURL, curDestURL)
 			if err != nil {
 				printDebug(s.op, err, curSourceURL, curDestURL)
 				continue
@@ -512,6 +760,21 @@ func (s Sync) planRun(
 				return
 			}
 
+			<-commonDone
+			if err := s.checkDeleteLimits(len(dstURLs), len(dstURLs)+commonCount); err != nil {
+				for _, dsturl := range dstURLs {
+					printError(fmt.Sprintf("rm %v", dsturl), "rm", errDeleteLimitExceeded)
+				}
+				printError(s.fullCommand, s.op, err)
+				deleteErr = err
+				return
+			}
+
+			if s.backupDir != nil {
+				s.emitBackupDeletes(c, w, defaultFlags, dstURLs)
+				return
+			}
+
 			command, err := generateCommand(c, "rm", defaultFlags, dstURLs...)
 			if err != nil {
 				printDebug(s.op, err, dstURLs...)
@@ -528,6 +791,120 @@ func (s Sync) planRun(
 	}()
 
 	wg.Wait()
+	return deleteErr
+}
+
+// emitBackupDeletes writes the rm commands of the objects which are backed up
+// before they are deleted. The objects are grouped by the directories of their
+// relative paths, since rm command backs up the given objects under their base
+// names.
+func (s Sync) emitBackupDeletes(c *cli.Context, w io.Writer, defaultFlags map[string]interface{}, dstURLs []*url.URL) {
+	var (
+		dirs   []string
+		groups = map[string][]*url.URL{}
+	)
+	for _, dsturl := range dstURLs {
+		dir := s.backupDirOf(dsturl)
+		if _, ok := groups[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		groups[dir] = append(groups[dir], dsturl)
+	}
+
+	for _, dir := range dirs {
+		command, err := generateCommand(c, "rm", withBackupDir(defaultFlags, dir), groups[dir]...)
+		if err != nil {
+			printDebug(s.op, err, groups[dir]...)
+			continue
+		}
+		fmt.Fprintln(w, command)
+	}
+}
+
+// backupDirOf returns the backup directory of the destination object for the
+// generated commands, which preserves the directory of its path relative to
+// the destination.
+func (s Sync) backupDirOf(dsturl *url.URL) string {
+	dir := path.Dir(relativeSource(dsturl))
+	if dir == "." {
+		return s.backupDir.String()
+	}
+	return s.backupDir.Join(dir + "/").String()
+}
+
+// withBackupDir returns a copy of the flags of the generated commands with
+// the given backup directory.
+func withBackupDir(flags map[string]interface{}, dir string) map[string]interface{} {
+	withBackup := map[string]interface{}{"backup-dir": dir}
+	for k, v := range flags {
+		withBackup[k] = v
+	}
+	return withBackup
+}
+
+// errDeleteLimitExceeded is reported for each of the objects which are not
+// deleted because the deletions exceed the limits.
+var errDeleteLimitExceeded = fmt.Errorf("not deleted, the number of deletions exceeds the limit")
+
+// checkDeleteLimits checks the number of the objects to be deleted against
+// --max-delete and --max-delete-percent flags. total is the number of the
+// objects in destination.
+func (s Sync) checkDeleteLimits(deletes, total int) error {
+	if s.maxDelete >= 0 && deletes > s.maxDelete {
+		return fmt.Errorf(
+			"%d objects are to be deleted which exceeds --max-delete %d, no objects are deleted",
+			deletes, s.maxDelete,
+		)
+	}
+
+	if s.maxDeletePercent >= 0 && deletes*100 > total*s.maxDeletePercent {
+		return fmt.Errorf(
+			"%d of %d objects in destination are to be deleted which exceeds --max-delete-percent %d, no objects are deleted",
+			deletes, total, s.maxDeletePercent,
+		)
+	}
+
+	return nil
+}
+
+func validateSyncCommand(c *cli.Context) error {
+	if c.Bool("size-only") && c.Bool("checksum") {
+		return fmt.Errorf("--size-only and --checksum flags can not be used together")
+	}
+
+	if c.Bool("checksum") && isClientSideEncryptionSet(c) {
+		return fmt.Errorf("--checksum flag can not be used with client-side encryption")
+	}
+
+	if c.String("compress") != "" && (c.Bool("size-only") || c.Bool("checksum")) {
+		return fmt.Errorf("--compress flag can not be used with --size-only or --checksum flags")
+	}
+
+	for _, flag := range []string{"max-delete", "max-delete-percent"} {
+		if !c.IsSet(flag) {
+			continue
+		}
+		if !c.Bool("delete") {
+			return fmt.Errorf("--%v flag can only be used with --delete flag", flag)
+		}
+		if c.Int(flag) < 0 {
+			return fmt.Errorf("--%v flag can not be a negative value", flag)
+		}
+	}
+
+	if c.Int("max-delete-percent") > 100 {
+		return fmt.Errorf("--max-delete-percent flag can not be greater than 100")
+	}
+
+	if c.Duration("cache-ttl") < 0 {
+		return fmt.Errorf("--cache-ttl flag can not be a negative value")
+	}
+
+	if err := validateCopyCommand(c); err != nil {
+		return err
+	}
+
+	return validateSyncWatch(c)
 }
 
 // generateDestinationURL generates destination url for given
@@ -587,6 +964,23 @@ func (s Sync) shouldSkipDstObject(object *storage.Object, verbose bool) bool {
 	return false
 }
 
+// isExcludedFrom reports whether the object is excluded by the pattern files
+// or ignored by the given ignore files. The paths relative to the listed
+// directories are matched, so that the same objects are skipped on both sides.
+func (s Sync) isExcludedFrom(object *storage.Object, tree *ignore.Tree) bool {
+	relpath := filepath.ToSlash(object.URL.Relative())
+	if s.patternFiles.isExcluded(relpath) {
+		return true
+	}
+
+	ignored, err := tree.Ignored(relpath, false)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return true
+	}
+	return ignored
+}
+
 // shouldStopSync determines whether a sync process should be stopped or not.
 func (s Sync) shouldStopSync(err error) bool {
 	if err == storage.ErrNoObjectFound {
@@ -1,17 +1,64 @@
 package command
 
 import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/peak/s5cmd/v2/cse"
 	errorpkg "github.com/peak/s5cmd/v2/error"
 	"github.com/peak/s5cmd/v2/storage"
 )
 
+const (
+	// maxUploadParts is the maximum number of parts allowed in a multipart
+	// upload. The uploader increases the part size if the object cannot fit
+	// into this many parts, so the same adjustment is needed to reproduce
+	// multipart ETags locally.
+	maxUploadParts = 10000
+)
+
 // SyncStrategy is the interface to make decision whether given source object should be synced
 // to destination object
 type SyncStrategy interface {
 	ShouldSync(srcObject, dstObject *storage.Object) error
 }
 
-func NewStrategy(sizeOnly bool) SyncStrategy {
+// headObjectFunc returns the metadata of the given remote object.
+type headObjectFunc func(obj *storage.Object) (*storage.Metadata, error)
+
+// newHeadObjectFunc returns the headObjectFunc which retrieves the metadata
+// with the client of the storage of the object.
+func newHeadObjectFunc(ctx context.Context, opts storage.Options) headObjectFunc {
+	return func(obj *storage.Object) (*storage.Metadata, error) {
+		client, err := storage.NewClient(ctx, obj.URL, opts)
+		if err != nil {
+			return nil, err
+		}
+		metadataReader, ok := client.(storage.MetadataReader)
+		if !ok {
+			return nil, storage.NotSupported(obj.URL, "object metadata")
+		}
+		_, metadata, err := metadataReader.HeadObject(ctx, obj.URL)
+		return metadata, err
+	}
+}
+
+func NewStrategy(sizeOnly, checksum bool, partSize int64, headObject headObjectFunc) SyncStrategy {
+	if checksum {
+		return &ChecksumStrategy{
+			partSize:   partSize,
+			headObject: headObject,
+			fallback:   &SizeAndModificationStrategy{},
+		}
+	}
 	if sizeOnly {
 		return &SizeOnlyStrategy{}
 	} else {
@@ -50,3 +97,270 @@ func (sm *SizeAndModificationStrategy) ShouldSync(srcObj, dstObj *storage.Object
 
 	return errorpkg.ErrObjectIsNewerAndSizesMatch
 }
+
+// ChecksumStrategy determines to sync based on objects' content hashes. If the
+// CRC32C checksum of the remote object is known, such as the objects of
+// Google Cloud Storage, it is compared since it does not depend on how the
+// object is uploaded. Otherwise local files are hashed with MD5 and compared
+// against the ETag of the remote object. Multipart ETags are reproduced by
+// hashing the file in parts of the given part size. If the ETag of the remote
+// object can't be reproduced, such as objects encrypted with SSE-KMS or
+// uploaded with a different part size, the decision is delegated to the
+// fallback strategy.
+type ChecksumStrategy struct {
+	partSize   int64
+	headObject headObjectFunc
+	fallback   SyncStrategy
+}
+
+func (cs *ChecksumStrategy) ShouldSync(srcObj, dstObj *storage.Object) error {
+	if srcObj.Size != dstObj.Size {
+		return nil
+	}
+
+	if srcSum, dstSum, ok := cs.crc32c(srcObj, dstObj); ok {
+		if srcSum == dstSum {
+			return errorpkg.ErrObjectEtagsMatch
+		}
+		return nil
+	}
+
+	srcEtag, srcOk := cs.etag(srcObj, dstObj)
+	dstEtag, dstOk := cs.etag(dstObj, srcObj)
+	if !srcOk || !dstOk {
+		return cs.fallback.ShouldSync(srcObj, dstObj)
+	}
+
+	if srcEtag == dstEtag {
+		return errorpkg.ErrObjectEtagsMatch
+	}
+
+	// ETags of the objects which are uploaded in multiple parts depend on the
+	// part size. Different ETags don't mean different contents unless both
+	// of them are plain MD5 hashes.
+	if isMultipartEtag(srcEtag) || isMultipartEtag(dstEtag) {
+		return cs.fallback.ShouldSync(srcObj, dstObj)
+	}
+
+	// ETags of the objects which are encrypted with SSE-KMS are not MD5
+	// hashes of their contents.
+	if cs.isKMSEncrypted(srcObj) || cs.isKMSEncrypted(dstObj) {
+		return cs.fallback.ShouldSync(srcObj, dstObj)
+	}
+
+	return nil
+}
+
+func (cs *ChecksumStrategy) isKMSEncrypted(obj *storage.Object) bool {
+	if cs.headObject == nil || !obj.URL.IsRemote() {
+		return false
+	}
+
+	metadata, err := cs.headObject(obj)
+	if err != nil {
+		// encryption method is unknown, don't trust the ETag.
+		return true
+	}
+	return strings.HasPrefix(metadata.EncryptionMethod, "aws:kms")
+}
+
+// etag returns the ETag of the given object. For local files, the ETag is
+// calculated in the same format with the ETag of its remote counterpart. It
+// reports false if the ETag is unknown or can't be reproduced.
+func (cs *ChecksumStrategy) etag(obj, counterpart *storage.Object) (string, bool) {
+	if obj.URL.IsRemote() {
+		return obj.Etag, isValidEtag(obj.Etag)
+	}
+
+	// local->local is not permitted, so the counterpart is remote.
+	remoteEtag := counterpart.Etag
+	if !isValidEtag(remoteEtag) {
+		return "", false
+	}
+
+	partSize := int64(0)
+	if isMultipartEtag(remoteEtag) {
+		partSize = adjustPartSize(obj.Size, cs.partSize)
+		_, parts, _ := strings.Cut(remoteEtag, "-")
+		if strconv.FormatInt(numberOfParts(obj.Size, partSize), 10) != parts {
+			// object was uploaded with an unknown part size.
+			return "", false
+		}
+	}
+
+	etag, err := calculateEtag(obj.URL.Absolute(), partSize)
+	if err != nil {
+		return "", false
+	}
+	return etag, true
+}
+
+// crc32c returns the CRC32C checksums of the given objects. The checksum of a
+// local file is calculated if its remote counterpart reports one. It reports
+// false if the checksum of either object is unknown.
+func (cs *ChecksumStrategy) crc32c(srcObj, dstObj *storage.Object) (string, string, bool) {
+	remote := srcObj
+	if !remote.URL.IsRemote() {
+		remote = dstObj
+	}
+	if remote.CRC32C == "" {
+		return "", "", false
+	}
+
+	checksum := func(obj *storage.Object) (string, bool) {
+		if obj.URL.IsRemote() {
+			return obj.CRC32C, obj.CRC32C != ""
+		}
+		sum, err := calculateCRC32C(obj.URL.Absolute())
+		return sum, err == nil
+	}
+
+	srcSum, srcOk := checksum(srcObj)
+	dstSum, dstOk := checksum(dstObj)
+	return srcSum, dstSum, srcOk && dstOk
+}
+
+// calculateCRC32C calculates the CRC32C checksum of the file at given path in
+// the format of Google Cloud Storage, which is the base64 encoding of the
+// checksum in big-endian order.
+func calculateCRC32C(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// calculateEtag calculates the ETag of the file at given path in the same way
+// S3 does. If partSize is zero, the MD5 hash of the whole file is returned.
+// Otherwise MD5 hash of the concatenated MD5 hashes of each part is returned
+// with a suffix of number of parts.
+func calculateEtag(path string, partSize int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if partSize <= 0 {
+		h := md5.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	var (
+		sums  []byte
+		parts int
+	)
+	for {
+		h := md5.New()
+		n, err := io.CopyN(h, f, partSize)
+		if n > 0 {
+			sums = append(sums, h.Sum(nil)...)
+			parts++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	sum := md5.Sum(sums)
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), parts), nil
+}
+
+// adjustPartSize returns the part size that the uploader would use for an
+// object of the given size.
+func adjustPartSize(size, partSize int64) int64 {
+	if size/partSize >= maxUploadParts {
+		return size/maxUploadParts + 1
+	}
+	return partSize
+}
+
+// numberOfParts returns the number of parts an object of the given size would
+// be uploaded with.
+func numberOfParts(size, partSize int64) int64 {
+	return (size + partSize - 1) / partSize
+}
+
+// isValidEtag reports whether the given ETag is an MD5 hash, optionally
+// followed by the number of parts of a multipart upload.
+func isValidEtag(etag string) bool {
+	hash, parts, isMultipart := strings.Cut(etag, "-")
+	if len(hash) != md5.Size*2 {
+		return false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return false
+	}
+	if isMultipart {
+		n, err := strconv.Atoi(parts)
+		return err == nil && n > 0
+	}
+	return true
+}
+
+func isMultipartEtag(etag string) bool {
+	return strings.Contains(etag, "-")
+}
+
+// ClientSideEncryptionStrategy compares the remote objects which are encrypted
+// on the client side by their plaintext sizes, since their ciphertexts are
+// larger than the local files. The decision is delegated to the underlying
+// strategy.
+type ClientSideEncryptionStrategy struct {
+	strategy   SyncStrategy
+	headObject headObjectFunc
+}
+
+func (s *ClientSideEncryptionStrategy) ShouldSync(srcObj, dstObj *storage.Object) error {
+	return s.strategy.ShouldSync(s.decryptedObject(srcObj), s.decryptedObject(dstObj))
+}
+
+// decryptedObject returns a copy of the remote object with its plaintext
+// size. Only the objects which have the envelope metadata of client-side
+// encryption are adjusted, the others are returned as is.
+func (s *ClientSideEncryptionStrategy) decryptedObject(obj *storage.Object) *storage.Object {
+	if s.headObject == nil || !obj.URL.IsRemote() {
+		return obj
+	}
+
+	// objects which can not be a ciphertext are not encrypted, there is no
+	// need to check their metadata.
+	size, err := cse.DecryptedSize(obj.Size)
+	if err != nil {
+		return obj
+	}
+
+	metadata, err := s.headObject(obj)
+	if err != nil || !cse.IsEncrypted(metadata.UserDefined) {
+		return obj
+	}
+
+	decrypted := *obj
+	decrypted.Size = size
+	return &decrypted
+}
+
+// CompressionStrategy determines to sync based on objects' modification times
+// only, since the sizes of the objects which are compressed while they are
+// uploaded differ from the sizes of the local files.
+type CompressionStrategy struct{}
+
+func (s *CompressionStrategy) ShouldSync(srcObj, dstObj *storage.Object) error {
+	if srcObj.ModTime.After(*dstObj.ModTime) {
+		return nil
+	}
+	return errorpkg.ErrObjectIsNewer
+}
@@ -1,11 +1,20 @@
 package command
 
 import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/peak/s5cmd/v2/cse"
 	errorpkg "github.com/peak/s5cmd/v2/error"
 	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
 )
 
 func TestSizeAndModificationStrategy_ShouldSync(t *testing.T) {
@@ -147,3 +156,318 @@ func TestSizeOnlyStrategy_ShouldSync(t *testing.T) {
 		})
 	}
 }
+
+func TestClientSideEncryptionStrategy_ShouldSync(t *testing.T) {
+	object := func(path string, size int64) *storage.Object {
+		u, err := url.New(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &storage.Object{URL: u, Size: size}
+	}
+	local := func(size int64) *storage.Object {
+		return object("file.txt", size)
+	}
+	remote := func(size int64) *storage.Object {
+		return object("s3://bucket/file.txt", size)
+	}
+
+	testcases := []struct {
+		name     string
+		src      *storage.Object
+		dst      *storage.Object
+		expected error
+	}{
+		{
+			name:     "upload, plaintext sizes are same",
+			src:      local(100),
+			dst:      remote(cse.EncryptedSize(100)),
+			expected: errorpkg.ErrObjectSizesMatch,
+		},
+		{
+			name:     "upload, plaintext sizes are different",
+			src:      local(100),
+			dst:      remote(cse.EncryptedSize(99)),
+			expected: nil,
+		},
+		{
+			name:     "download, plaintext sizes are same",
+			src:      remote(cse.EncryptedSize(3 * cse.SegmentSize)),
+			dst:      local(3 * cse.SegmentSize),
+			expected: errorpkg.ErrObjectSizesMatch,
+		},
+		{
+			name:     "remote object is not a ciphertext",
+			src:      local(10),
+			dst:      remote(10),
+			expected: errorpkg.ErrObjectSizesMatch,
+		},
+		{
+			name:     "remote object is not encrypted",
+			src:      local(100),
+			dst:      object("s3://bucket/plain.txt", cse.EncryptedSize(100)),
+			expected: nil,
+		},
+		{
+			name:     "remote object is not encrypted, sizes are same",
+			src:      local(cse.EncryptedSize(100)),
+			dst:      object("s3://bucket/plain.txt", cse.EncryptedSize(100)),
+			expected: errorpkg.ErrObjectSizesMatch,
+		},
+	}
+
+	// the objects named plain.txt are uploaded without client-side
+	// encryption.
+	headObject := func(obj *storage.Object) (*storage.Metadata, error) {
+		if obj.URL.Base() == "plain.txt" {
+			return &storage.Metadata{}, nil
+		}
+		return &storage.Metadata{
+			UserDefined: map[string]string{cse.MetadataAlgorithm: cse.Algorithm},
+		}, nil
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy := &ClientSideEncryptionStrategy{
+				strategy:   &SizeOnlyStrategy{},
+				headObject: headObject,
+			}
+			if got := strategy.ShouldSync(tc.src, tc.dst); got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestCompressionStrategy_ShouldSync(t *testing.T) {
+	ft := time.Now()
+	timePtr := func(tt time.Time) *time.Time {
+		return &tt
+	}
+	testcases := []struct {
+		name     string
+		src      *storage.Object
+		dst      *storage.Object
+		expected error
+	}{
+		{
+			name:     "source is newer",
+			src:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 10},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 10},
+			expected: nil,
+		},
+		{
+			name:     "source is older, sizes are different",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 100},
+			dst:      &storage.Object{ModTime: timePtr(ft.Add(time.Minute)), Size: 20},
+			expected: errorpkg.ErrObjectIsNewer,
+		},
+		{
+			name:     "files have same age",
+			src:      &storage.Object{ModTime: timePtr(ft), Size: 100},
+			dst:      &storage.Object{ModTime: timePtr(ft), Size: 20},
+			expected: errorpkg.ErrObjectIsNewer,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy := &CompressionStrategy{}
+			if got := strategy.ShouldSync(tc.src, tc.dst); got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestChecksumStrategy_ShouldSync(t *testing.T) {
+	ft := time.Now()
+	timePtr := func(tt time.Time) *time.Time {
+		return &tt
+	}
+
+	const content = "hello"
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	md5sum := func(data string) string {
+		sum := md5.Sum([]byte(data))
+		return hex.EncodeToString(sum[:])
+	}
+	multipartEtag := func(parts ...string) string {
+		var sums []byte
+		for _, part := range parts {
+			sum := md5.Sum([]byte(part))
+			sums = append(sums, sum[:]...)
+		}
+		sum := md5.Sum(sums)
+		return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(parts))
+	}
+
+	localObj := func(modTime time.Time) *storage.Object {
+		u, err := url.New(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &storage.Object{URL: u, ModTime: timePtr(modTime), Size: int64(len(content))}
+	}
+	remoteObj := func(etag string, modTime time.Time, size int64) *storage.Object {
+		u, err := url.New("s3://bucket/file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &storage.Object{URL: u, ModTime: timePtr(modTime), Size: size, Etag: etag}
+	}
+	crc32cSum := func(data string) string {
+		h := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+		h.Write([]byte(data))
+		return base64.StdEncoding.EncodeToString(h.Sum(nil))
+	}
+	gcsObj := func(crc32c string, modTime time.Time) *storage.Object {
+		u, err := url.New("gs://bucket/file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// composite objects don't have MD5 checksums.
+		return &storage.Object{URL: u, ModTime: timePtr(modTime), Size: int64(len(content)), CRC32C: crc32c}
+	}
+
+	testcases := []struct {
+		name       string
+		src        *storage.Object
+		dst        *storage.Object
+		partSize   int64
+		encryption string
+		expected   error
+	}{
+		{
+			name:     "local source is newer, checksums match",
+			src:      localObj(ft.Add(time.Minute)),
+			dst:      remoteObj(md5sum(content), ft, 5),
+			partSize: 2,
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "local source is older, checksums are different",
+			src:      localObj(ft),
+			dst:      remoteObj(md5sum("world"), ft.Add(time.Minute), 5),
+			partSize: 2,
+			expected: nil,
+		},
+		{
+			name:     "remote source is newer, checksums match",
+			src:      remoteObj(md5sum(content), ft.Add(time.Minute), 5),
+			dst:      localObj(ft),
+			partSize: 2,
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "sizes are different",
+			src:      localObj(ft),
+			dst:      remoteObj(md5sum(content), ft.Add(time.Minute), 10),
+			partSize: 2,
+			expected: nil,
+		},
+		{
+			name:     "multipart checksums match",
+			src:      localObj(ft.Add(time.Minute)),
+			dst:      remoteObj(multipartEtag("he", "ll", "o"), ft, 5),
+			partSize: 2,
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "multipart checksums are different",
+			src:      localObj(ft.Add(time.Minute)),
+			dst:      remoteObj(multipartEtag("wo", "rl", "d"), ft, 5),
+			partSize: 2,
+			expected: nil,
+		},
+		{
+			name:     "multipart checksum with unknown part size falls back to size and modification time",
+			src:      localObj(ft),
+			dst:      remoteObj(multipartEtag("hel", "lo"), ft.Add(time.Minute), 5),
+			partSize: 2,
+			expected: errorpkg.ErrObjectIsNewerAndSizesMatch,
+		},
+		{
+			name:     "remote checksum is missing, falls back to size and modification time",
+			src:      localObj(ft.Add(time.Minute)),
+			dst:      remoteObj("", ft, 5),
+			partSize: 2,
+			expected: nil,
+		},
+		{
+			name:       "remote object is encrypted with SSE-KMS, falls back to size and modification time",
+			src:        localObj(ft),
+			dst:        remoteObj(md5sum("world"), ft.Add(time.Minute), 5),
+			partSize:   2,
+			encryption: "aws:kms",
+			expected:   errorpkg.ErrObjectIsNewerAndSizesMatch,
+		},
+		{
+			name:     "remote objects have same checksums",
+			src:      remoteObj(md5sum(content), ft.Add(time.Minute), 5),
+			dst:      remoteObj(md5sum(content), ft, 5),
+			partSize: 2,
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "remote objects have different multipart checksums, falls back to size and modification time",
+			src:      remoteObj(multipartEtag("hel", "lo"), ft, 5),
+			dst:      remoteObj(md5sum(content), ft.Add(time.Minute), 5),
+			partSize: 2,
+			expected: errorpkg.ErrObjectIsNewerAndSizesMatch,
+		},
+		{
+			name:     "crc32c checksums match",
+			src:      localObj(ft.Add(time.Minute)),
+			dst:      gcsObj(crc32cSum(content), ft),
+			partSize: 2,
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+		{
+			name:     "crc32c checksums are different",
+			src:      localObj(ft),
+			dst:      gcsObj(crc32cSum("world"), ft.Add(time.Minute)),
+			partSize: 2,
+			expected: nil,
+		},
+		{
+			name:     "remote objects have same crc32c checksums",
+			src:      gcsObj(crc32cSum(content), ft.Add(time.Minute)),
+			dst:      gcsObj(crc32cSum(content), ft),
+			partSize: 2,
+			expected: errorpkg.ErrObjectEtagsMatch,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			headObject := func(*storage.Object) (*storage.Metadata, error) {
+				return &storage.Metadata{EncryptionMethod: tc.encryption}, nil
+			}
+			strategy := NewStrategy(false, true, tc.partSize, headObject)
+			if got := strategy.ShouldSync(tc.src, tc.dst); got != tc.expected {
+				t.Fatalf("expected: %q(%T), got: %q(%T)", tc.expected, tc.expected, got, got)
+			}
+		})
+	}
+}
+
+func TestAdjustPartSize(t *testing.T) {
+	const megabyte = 1024 * 1024
+	testcases := []struct {
+		size     int64
+		partSize int64
+		expected int64
+	}{
+		{size: 100 * megabyte, partSize: 5 * megabyte, expected: 5 * megabyte},
+		{size: 10000 * 5 * megabyte, partSize: 5 * megabyte, expected: 5*megabyte + 1},
+	}
+	for _, tc := range testcases {
+		if got := adjustPartSize(tc.size, tc.partSize); got != tc.expected {
+			t.Errorf("adjustPartSize(%d, %d): expected %d, got %d", tc.size, tc.partSize, tc.expected, got)
+		}
+	}
+}
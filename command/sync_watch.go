@@ -0,0 +1,454 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/urfave/cli/v2"
+
+	"github.com/peak/s5cmd/v2/ignore"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	defaultWatchDebounce     = time.Second
+	defaultReconcileInterval = 10 * time.Minute
+)
+
+// runWatch syncs the local directory to the remote destination, then keeps
+// uploading the changed files and deleting the removed ones as they happen.
+// The whole directory is synced periodically to catch the changes which are
+// missed by the watcher. It runs until the context of the command is
+// canceled, such as by an interrupt, then the pending changes are flushed
+// before it returns. The work in progress is not canceled by the first
+// interrupt, a second interrupt cancels it.
+func (s Sync) runWatch(c *cli.Context) error {
+	srcurl, err := url.New(s.src)
+	if err != nil {
+		return err
+	}
+
+	dsturl, err := url.New(s.dst)
+	if err != nil {
+		return err
+	}
+
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+
+	go func() {
+		select {
+		case <-c.Context.Done():
+		case <-workCtx.Done():
+			return
+		}
+
+		sigch := make(chan os.Signal, 1)
+		signal.Notify(sigch, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigch)
+
+		select {
+		case <-sigch:
+			cancelWork()
+		case <-workCtx.Done():
+		}
+	}()
+
+	workc := *c
+	workc.Context = workCtx
+
+	// the ignore files are read again before each flush, since they may
+	// have changed too.
+	tree := storage.NewLocalClient(s.storageOpts).IgnoreTree(srcurl)
+	isExcluded := func(dir string) bool {
+		return s.isDirExcluded(srcurl, tree, dir)
+	}
+
+	watcher, err := newDirWatcher(srcurl.Absolute(), isExcluded)
+	if err != nil {
+		printError(s.fullCommand, s.op, err)
+		return err
+	}
+	defer watcher.Close()
+
+	// the watches are added before the initial sync, so that the changes
+	// made during the sync are not missed.
+	var watchErr error
+	if err := s.run(&workc); err != nil {
+		watchErr = err
+	}
+
+	ticker := time.NewTicker(s.reconcileInterval)
+	defer ticker.Stop()
+
+	var (
+		pending    = map[string]struct{}{}
+		debounceCh <-chan time.Time
+		reconcile  bool
+	)
+
+	flush := func() {
+		tree = storage.NewLocalClient(s.storageOpts).IgnoreTree(srcurl)
+
+		uploads, deletes, needsReconcile := watcher.resolve(pending, s.delete)
+		pending = map[string]struct{}{}
+		debounceCh = nil
+
+		uploads = s.filterExcluded(srcurl, tree, uploads)
+		deletes = s.filterExcluded(srcurl, tree, deletes)
+
+		// the limits of the deletions are checked against the whole
+		// destination by a full sync.
+		if len(deletes) > 0 && (s.maxDelete >= 0 || s.maxDeletePercent >= 0) {
+			deletes = nil
+			needsReconcile = true
+		}
+
+		if err := s.runWatchCommands(&workc, srcurl, dsturl, uploads, deletes); err != nil {
+			watchErr = err
+		}
+		reconcile = reconcile || needsReconcile
+	}
+
+	for {
+		if reconcile && c.Context.Err() == nil {
+			reconcile = false
+			pending = map[string]struct{}{}
+			debounceCh = nil
+			if err := s.run(&workc); err != nil {
+				watchErr = err
+			}
+		}
+
+		select {
+		case <-c.Context.Done():
+			if len(pending) > 0 && workCtx.Err() == nil {
+				flush()
+			}
+			return watchErr
+		case <-workCtx.Done():
+			return watchErr
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return watchErr
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			pending[event.Name] = struct{}{}
+			debounceCh = time.After(s.watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return watchErr
+			}
+			// the events are dropped if the queue of the watcher is full.
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				reconcile = true
+				continue
+			}
+			printError(s.fullCommand, s.op, err)
+		case <-debounceCh:
+			flush()
+		case <-ticker.C:
+			reconcile = true
+		}
+	}
+}
+
+// runWatchCommands uploads the given local files and deletes the remote
+// counterparts of the removed ones.
+func (s Sync) runWatchCommands(c *cli.Context, srcurl, dsturl *url.URL, uploads, deletes []string) error {
+	if len(uploads) == 0 && len(deletes) == 0 {
+		return nil
+	}
+
+	// the destination objects are given their paths relative to the
+	// destination, as if they are listed by a full sync.
+	dstListPath := s.dst + "/*"
+	if strings.HasSuffix(s.dst, "/") {
+		dstListPath = s.dst + "*"
+	}
+
+	dstListURL, err := url.New(dstListPath)
+	if err != nil {
+		return err
+	}
+
+	destinationURL := func(path string) (*url.URL, *url.URL, error) {
+		fileurl, err := url.New(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		fileurl.SetRelative(srcurl)
+
+		objurl := generateDestinationURL(fileurl, dsturl, true)
+		objurl.SetRelative(dstListURL)
+		return fileurl, objurl, nil
+	}
+
+	defaultFlags := map[string]interface{}{
+		"raw": true,
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		defer pipeWriter.Close()
+
+		for _, path := range uploads {
+			fileurl, objurl, err := destinationURL(path)
+			if err != nil {
+				printError(s.fullCommand, s.op, err)
+				continue
+			}
+
+			flags := defaultFlags
+			if s.backupDir != nil {
+				flags = withBackupDir(defaultFlags, s.backupDirOf(objurl))
+			}
+
+			command, err := generateCommand(c, "cp", flags, fileurl, objurl)
+			if err != nil {
+				printDebug(s.op, err, fileurl, objurl)
+				continue
+			}
+			fmt.Fprintln(pipeWriter, command)
+		}
+
+		if len(deletes) == 0 {
+			return
+		}
+
+		objurls := make([]*url.URL, 0, len(deletes))
+		for _, path := range deletes {
+			_, objurl, err := destinationURL(path)
+			if err != nil {
+				printError(s.fullCommand, s.op, err)
+				continue
+			}
+			objurls = append(objurls, objurl)
+		}
+
+		if s.backupDir != nil {
+			s.emitBackupDeletes(c, pipeWriter, defaultFlags, objurls)
+			return
+		}
+
+		command, err := generateCommand(c, "rm", defaultFlags, objurls...)
+		if err != nil {
+			printDebug(s.op, err, objurls...)
+			return
+		}
+		fmt.Fprintln(pipeWriter, command)
+	}()
+
+	return NewRun(c, pipeReader).Run(c.Context)
+}
+
+// filterExcluded drops the files which are excluded by the pattern files or
+// ignored by the ignore files, as a full sync does.
+func (s Sync) filterExcluded(srcurl *url.URL, tree *ignore.Tree, paths []string) []string {
+	var filtered []string
+	for _, path := range paths {
+		fileurl, err := url.New(path)
+		if err != nil {
+			printError(s.fullCommand, s.op, err)
+			continue
+		}
+		fileurl.SetRelative(srcurl)
+
+		if s.isExcludedFrom(&storage.Object{URL: fileurl}, tree) {
+			continue
+		}
+		filtered = append(filtered, path)
+	}
+	return filtered
+}
+
+// isDirExcluded reports whether all files under the given local directory are
+// excluded by the pattern files, the ignore files or the --exclude flags, as
+// walkDir skips the ignored directories. The --exclude patterns are matched
+// against the paths of the files as the generated commands do.
+func (s Sync) isDirExcluded(srcurl *url.URL, tree *ignore.Tree, dir string) bool {
+	dirurl, err := url.New(dir)
+	if err != nil {
+		return false
+	}
+	dirurl.SetRelative(srcurl)
+	relpath := filepath.ToSlash(dirurl.Relative())
+
+	if s.patternFiles.exclude.Match(relpath, true) {
+		return true
+	}
+	if isURLMatched(s.dirExcludePatterns, filepath.ToSlash(dir)+"/", "") {
+		return true
+	}
+
+	ignored, err := tree.Ignored(relpath, true)
+	return err == nil && ignored
+}
+
+// dirWatcher watches the directories of a local directory tree, except the
+// excluded ones. An excluded directory is not watched even if it is not
+// excluded later, the full syncs catch its changes.
+type dirWatcher struct {
+	*fsnotify.Watcher
+	dirs       map[string]struct{}
+	isExcluded func(dir string) bool
+}
+
+func newDirWatcher(root string, isExcluded func(dir string) bool) (*dirWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &dirWatcher{Watcher: watcher, dirs: map[string]struct{}{}, isExcluded: isExcluded}
+	if _, err := w.addTree(root); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// addTree watches the given directory and its subdirectories which are not
+// excluded. It returns the files in them.
+func (w *dirWatcher) addTree(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// the directories which are removed while they are walked are
+			// reported by their events.
+			if errors.Is(err, fs.ErrNotExist) && path != root {
+				return nil
+			}
+			return err
+		}
+
+		if !d.IsDir() {
+			if d.Type().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		}
+
+		if _, ok := w.dirs[path]; ok {
+			return nil
+		}
+		if path != root && w.isExcluded(path) {
+			return filepath.SkipDir
+		}
+		if err := w.Add(path); err != nil {
+			return err
+		}
+		w.dirs[path] = struct{}{}
+		return nil
+	})
+	return files, err
+}
+
+// resolve decides what to do with the paths which are changed since the last
+// flush. The existing files are uploaded and the files in the new
+// directories are uploaded too. The removed files are deleted if
+// deleteRemoved is set. A removed or renamed directory requires a full sync,
+// since the files under it are not known.
+func (w *dirWatcher) resolve(paths map[string]struct{}, deleteRemoved bool) (uploads, deletes []string, reconcile bool) {
+	toUpload := map[string]struct{}{}
+
+	for path := range paths {
+		st, err := os.Stat(path)
+		switch {
+		case err == nil && st.IsDir():
+			if _, ok := w.dirs[path]; ok || w.isExcluded(path) {
+				continue
+			}
+			files, err := w.addTree(path)
+			if err != nil {
+				// the directory is synced as a whole if it can't be
+				// watched.
+				reconcile = true
+			}
+			for _, file := range files {
+				toUpload[file] = struct{}{}
+			}
+		case err == nil && st.Mode().IsRegular():
+			toUpload[path] = struct{}{}
+		case errors.Is(err, fs.ErrNotExist):
+			if _, ok := w.dirs[path]; ok {
+				delete(w.dirs, path)
+				_ = w.Remove(path)
+				reconcile = true
+				continue
+			}
+			if deleteRemoved {
+				deletes = append(deletes, path)
+			}
+		}
+	}
+
+	for path := range toUpload {
+		uploads = append(uploads, path)
+	}
+	sort.Strings(uploads)
+	sort.Strings(deletes)
+	return uploads, deletes, reconcile
+}
+
+// validateSyncWatch validates the flags of the watch mode of sync command.
+func validateSyncWatch(c *cli.Context) error {
+	watchFlags := []string{"watch-debounce", "reconcile-interval"}
+
+	if !c.Bool("watch") {
+		for _, flag := range watchFlags {
+			if c.IsSet(flag) {
+				return fmt.Errorf("--%v flag can only be used with --watch flag", flag)
+			}
+		}
+		return nil
+	}
+
+	for _, flag := range watchFlags {
+		if c.Duration(flag) <= 0 {
+			return fmt.Errorf("--%v flag must be a positive duration", flag)
+		}
+	}
+
+	srcurl, err := url.New(c.Args().Get(0))
+	if err != nil {
+		return err
+	}
+
+	dsturl, err := url.New(c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	errWatch := fmt.Errorf("--watch flag can only be used to sync a local directory to a bucket or a prefix")
+	if srcurl.IsRemote() || srcurl.IsWildcard() || !dsturl.IsRemote() {
+		return errWatch
+	}
+
+	st, err := os.Stat(srcurl.Absolute())
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() || (!dsturl.IsPrefix() && !dsturl.IsBucket()) {
+		return errWatch
+	}
+
+	if c.String("from-inventory") != "" {
+		return fmt.Errorf("--watch flag can not be used with --from-inventory flag")
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+
+	"github.com/peak/s5cmd/v2/ignore"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestDirWatcherResolve(t *testing.T) {
+	t.Parallel()
+
+	workdir := fs.NewDir(t, "watch",
+		fs.WithFile("removed.txt", "content"),
+		fs.WithFile("modified.txt", "content"),
+		fs.WithDir("dir", fs.WithFile("file.txt", "content")),
+		fs.WithDir("removed-dir", fs.WithFile("file.txt", "content")),
+	)
+	defer workdir.Remove()
+
+	watcher, err := newDirWatcher(workdir.Path(), func(string) bool { return false })
+	assert.NilError(t, err)
+	defer watcher.Close()
+
+	assert.NilError(t, os.Remove(workdir.Join("removed.txt")))
+	assert.NilError(t, os.WriteFile(workdir.Join("modified.txt"), []byte("modified"), 0o644))
+	assert.NilError(t, os.MkdirAll(workdir.Join("new", "sub"), 0o755))
+	assert.NilError(t, os.WriteFile(workdir.Join("new", "sub", "file.txt"), []byte("content"), 0o644))
+
+	changed := map[string]struct{}{
+		workdir.Join("removed.txt"):  {},
+		workdir.Join("modified.txt"): {},
+		workdir.Join("dir"):          {},
+		workdir.Join("new"):          {},
+	}
+
+	uploads, deletes, reconcile := watcher.resolve(changed, true)
+	assert.DeepEqual(t, uploads, []string{workdir.Join("modified.txt"), workdir.Join("new", "sub", "file.txt")})
+	assert.DeepEqual(t, deletes, []string{workdir.Join("removed.txt")})
+	assert.Assert(t, !reconcile)
+
+	// the new directories are watched.
+	_, ok := watcher.dirs[filepath.Join(workdir.Path(), "new", "sub")]
+	assert.Assert(t, ok)
+
+	// the files under the removed directories are not known.
+	assert.NilError(t, os.RemoveAll(workdir.Join("removed-dir")))
+	changed = map[string]struct{}{
+		workdir.Join("removed-dir"): {},
+		workdir.Join("removed.txt"): {},
+	}
+
+	uploads, deletes, reconcile = watcher.resolve(changed, false)
+	assert.Equal(t, len(uploads), 0)
+	assert.Equal(t, len(deletes), 0)
+	assert.Assert(t, reconcile)
+}
+
+func TestDirWatcherExcludedDirs(t *testing.T) {
+	t.Parallel()
+
+	workdir := fs.NewDir(t, "watch",
+		fs.WithDir("src", fs.WithFile("file.txt", "content")),
+		fs.WithDir("node_modules", fs.WithDir("pkg", fs.WithFile("index.js", "content"))),
+	)
+	defer workdir.Remove()
+
+	isExcluded := func(dir string) bool { return filepath.Base(dir) == "node_modules" }
+	watcher, err := newDirWatcher(workdir.Path(), isExcluded)
+	assert.NilError(t, err)
+	defer watcher.Close()
+
+	_, ok := watcher.dirs[workdir.Join("src")]
+	assert.Assert(t, ok)
+	_, ok = watcher.dirs[workdir.Join("node_modules")]
+	assert.Assert(t, !ok)
+	_, ok = watcher.dirs[workdir.Join("node_modules", "pkg")]
+	assert.Assert(t, !ok)
+
+	// the excluded directories which are created later are not watched.
+	assert.NilError(t, os.MkdirAll(workdir.Join("new", "node_modules"), 0o755))
+	assert.NilError(t, os.WriteFile(workdir.Join("new", "node_modules", "index.js"), []byte("content"), 0o644))
+
+	uploads, _, reconcile := watcher.resolve(map[string]struct{}{
+		workdir.Join("new"):                 {},
+		workdir.Join("new", "node_modules"): {},
+	}, false)
+	assert.Equal(t, len(uploads), 0)
+	assert.Assert(t, !reconcile)
+
+	_, ok = watcher.dirs[workdir.Join("new")]
+	assert.Assert(t, ok)
+	_, ok = watcher.dirs[workdir.Join("new", "node_modules")]
+	assert.Assert(t, !ok)
+}
+
+func TestSyncIsDirExcluded(t *testing.T) {
+	t.Parallel()
+
+	workdir := fs.NewDir(t, "watch",
+		fs.WithFile(".s5cmdignore", "node_modules/\n"),
+		fs.WithFile("exclude.txt", "build/\n"),
+	)
+	defer workdir.Remove()
+
+	excludeFrom, err := ignore.ReadFile(workdir.Join("exclude.txt"))
+	assert.NilError(t, err)
+	dirExcludePatterns, err := createRegexFromWildcard([]string{"*/cache/*"})
+	assert.NilError(t, err)
+
+	s := Sync{
+		patternFiles:       patternFiles{exclude: excludeFrom},
+		dirExcludePatterns: dirExcludePatterns,
+	}
+	srcurl, err := url.New(workdir.Path() + "/")
+	assert.NilError(t, err)
+	tree := ignore.NewTree(workdir.Path(), ".s5cmdignore")
+
+	testcases := []struct {
+		dir      string
+		expected bool
+	}{
+		{dir: "src", expected: false},
+		{dir: "node_modules", expected: true},
+		{dir: "src/node_modules", expected: true},
+		{dir: "build", expected: true},
+		{dir: "src/cache", expected: true},
+		{dir: "src/cached", expected: false},
+	}
+	for _, tc := range testcases {
+		dir := filepath.Join(workdir.Path(), filepath.FromSlash(tc.dir))
+		assert.Equal(t, s.isDirExcluded(srcurl, tree, dir), tc.expected, tc.dir)
+	}
+}
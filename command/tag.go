@@ -0,0 +1,369 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/log/stat"
+	"github.com/peak/s5cmd/v2/parallel"
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/peak/s5cmd/v2/strutil"
+)
+
+const (
+	// maxObjectTags is the maximum number of tags an object can have.
+	maxObjectTags = 10
+	// maxTagKeyLength and maxTagValueLength are the length limits of the tag
+	// keys and values, in unicode characters.
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+var tagHelpTemplate = `Name:
+	{{.HelpName}} - {{.Usage}}
+
+Usage:
+	{{.HelpName}} [options] source
+
+Options:
+	{{range .VisibleFlags}}{{.}}
+	{{end}}
+Examples:
+	1. Print the tags of a remote object
+		 > s5cmd {{.HelpName}} s3://bucket/prefix/object
+
+	2. Print the tags of all objects with a prefix
+		 > s5cmd {{.HelpName}} "s3://bucket/prefix/*"
+
+	3. Replace the tags of a remote object
+		 > s5cmd {{.HelpName}} --set "project=s5cmd&team=storage" s3://bucket/prefix/object
+
+	4. Replace the tags of all objects that matches a wildcard
+		 > s5cmd {{.HelpName}} --set "retention=short" "s3://bucket/logs/*.gz"
+
+	5. Delete the tags of all objects with a prefix, except the ones with .txt extension
+		 > s5cmd {{.HelpName}} --delete --exclude "*.txt" "s3://bucket/prefix/*"
+
+	6. Replace the tags of the specific version of a remote object
+		 > s5cmd {{.HelpName}} --version-id VERSION_ID --set "project=s5cmd" s3://bucket/prefix/object
+`
+
+func NewTagCommand() *cli.Command {
+	cmd := &cli.Command{
+		Name:               "tag",
+		HelpName:           "tag",
+		Usage:              "print, replace or delete object tags",
+		CustomHelpTemplate: tagHelpTemplate,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "set",
+				Usage: "replace the tags of the objects with the given URL encoded tag set, e.g. --set 'key1=value1&key2=value2'",
+			},
+			&cli.BoolFlag{
+				Name:  "delete",
+				Usage: "delete the tags of the objects",
+			},
+			&cli.BoolFlag{
+				Name:  "raw",
+				Usage: "disable the wildcard operations, useful with filenames that contains glob characters",
+			},
+			&cli.StringSliceFlag{
+				Name:  "exclude",
+				Usage: "exclude objects with given pattern",
+			},
+			&cli.StringSliceFlag{
+				Name:  "include",
+				Usage: "include objects with given pattern",
+			},
+			&cli.StringFlag{
+				Name:  "version-id",
+				Usage: "use the specified version of an object",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			err := validateTagCommand(c)
+			if err != nil {
+				printError(commandFromContext(c), c.Command.Name, err)
+			}
+			return err
+		},
+		Action: func(c *cli.Context) (err error) {
+			defer stat.Collect(c.Command.FullName(), &err)()
+
+			op := c.Command.Name
+			fullCommand := commandFromContext(c)
+
+			src, err := url.New(c.Args().Get(0), url.WithVersion(c.String("version-id")),
+				url.WithRaw(c.Bool("raw")))
+			if err != nil {
+				printError(fullCommand, op, err)
+				return err
+			}
+
+			tags, err := parseTagging(c.String("set"))
+			if err != nil {
+				printError(fullCommand, op, err)
+				return err
+			}
+
+			excludePatterns, err := createRegexFromWildcard(c.StringSlice("exclude"))
+			if err != nil {
+				printError(fullCommand, op, err)
+				return err
+			}
+
+			includePatterns, err := createRegexFromWildcard(c.StringSlice("include"))
+			if err != nil {
+				printError(fullCommand, op, err)
+				return err
+			}
+
+			return Tag{
+				src:         src,
+				op:          op,
+				fullCommand: fullCommand,
+
+				// flags
+				tags:   tags,
+				delete: c.Bool("delete"),
+
+				// patterns
+				excludePatterns: excludePatterns,
+				includePatterns: includePatterns,
+
+				storageOpts: NewStorageOpts(c),
+			}.Run(c.Context)
+		},
+	}
+
+	cmd.BashComplete = getBashCompleteFn(cmd, false, false)
+	return cmd
+}
+
+// Tag holds tag operation flags and states.
+type Tag struct {
+	src         *url.URL
+	op          string
+	fullCommand string
+
+	// flag options
+	tags   map[string]string
+	delete bool
+
+	// patterns
+	excludePatterns []*regexp.Regexp
+	includePatterns []*regexp.Regexp
+
+	// storage options
+	storageOpts storage.Options
+}
+
+// Run prints, replaces or deletes the tags of the given objects.
+func (t Tag) Run(ctx context.Context) error {
+	client, err := storage.NewClient(ctx, t.src, t.storageOpts)
+	if err != nil {
+		printError(t.fullCommand, t.op, err)
+		return err
+	}
+
+	tagger, ok := client.(storage.Tagger)
+	if !ok {
+		err := storage.NotSupported(t.src, "tagging")
+		printError(t.fullCommand, t.op, err)
+		return err
+	}
+
+	objch, err := expandSource(ctx, client, false, t.src)
+	if err != nil {
+		printError(t.fullCommand, t.op, err)
+		return err
+	}
+
+	waiter := parallel.NewWaiter()
+
+	var (
+		merrorWaiter  error
+		merrorObjects error
+		errDoneCh     = make(chan struct{})
+	)
+
+	go func() {
+		defer close(errDoneCh)
+		for err := range waiter.Err() {
+			printError(t.fullCommand, t.op, err)
+			merrorWaiter = multierror.Append(merrorWaiter, err)
+		}
+	}()
+
+	for object := range objch {
+		if errorpkg.IsCancelation(object.Err) || object.Type.IsDir() {
+			continue
+		}
+
+		if err := object.Err; err != nil {
+			merrorObjects = multierror.Append(merrorObjects, err)
+			printError(t.fullCommand, t.op, err)
+			continue
+		}
+
+		isExcluded, err := isObjectExcluded(object, t.excludePatterns, t.includePatterns, t.src.Prefix)
+		if err != nil {
+			printError(t.fullCommand, t.op, err)
+		}
+		if isExcluded {
+			continue
+		}
+
+		parallel.Run(t.prepareTask(ctx, tagger, object.URL), waiter)
+	}
+
+	waiter.Wait()
+	<-errDoneCh
+
+	return multierror.Append(merrorWaiter, merrorObjects).ErrorOrNil()
+}
+
+func (t Tag) prepareTask(ctx context.Context, tagger storage.Tagger, srcurl *url.URL) func() error {
+	return func() error {
+		err := t.doTag(ctx, tagger, srcurl)
+		if err != nil {
+			return &errorpkg.Error{
+				Op:  t.op,
+				Src: srcurl,
+				Err: err,
+			}
+		}
+		return nil
+	}
+}
+
+func (t Tag) doTag(ctx context.Context, tagger storage.Tagger, srcurl *url.URL) error {
+	switch {
+	case t.delete:
+		if err := tagger.DeleteObjectTagging(ctx, srcurl); err != nil {
+			return err
+		}
+	case t.tags != nil:
+		if err := tagger.PutObjectTagging(ctx, srcurl, t.tags); err != nil {
+			return err
+		}
+	default:
+		tags, err := tagger.GetObjectTagging(ctx, srcurl)
+		if err != nil {
+			return err
+		}
+
+		log.Info(TagMessage{
+			Key:       srcurl.String(),
+			VersionID: srcurl.VersionID,
+			Tags:      tags,
+		})
+		return nil
+	}
+
+	msg := log.InfoMessage{
+		Operation: t.op,
+		Source:    srcurl,
+	}
+	log.Info(msg)
+
+	return nil
+}
+
+// TagMessage is a structure for printing the tags of an object.
+type TagMessage struct {
+	Key       string            `json:"key"`
+	VersionID string            `json:"version_id,omitempty"`
+	Tags      map[string]string `json:"tags"`
+}
+
+func (m TagMessage) String() string {
+	return m.JSON()
+}
+
+func (m TagMessage) JSON() string {
+	return strutil.JSON(m)
+}
+
+// parseTagging parses the URL encoded tag set, e.g. "key1=value1&key2=value2",
+// which is the format S3 expects in x-amz-tagging header. A nil map is
+// returned for the empty tag set.
+func parseTagging(tagging string) (map[string]string, error) {
+	if tagging == "" {
+		return nil, nil
+	}
+
+	values, err := neturl.ParseQuery(tagging)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tagging %q: %w", tagging, err)
+	}
+
+	if len(values) > maxObjectTags {
+		return nil, fmt.Errorf("invalid tagging %q: an object can have at most %d tags", tagging, maxObjectTags)
+	}
+
+	tags := make(map[string]string, len(values))
+	for k, v := range values {
+		if k == "" {
+			return nil, fmt.Errorf("invalid tagging %q: tag keys can not be empty", tagging)
+		}
+		if len(v) > 1 {
+			return nil, fmt.Errorf("invalid tagging %q: tag key %q is given more than once", tagging, k)
+		}
+		if len([]rune(k)) > maxTagKeyLength {
+			return nil, fmt.Errorf("invalid tagging %q: tag key %q is longer than %d characters", tagging, k, maxTagKeyLength)
+		}
+		if len([]rune(v[0])) > maxTagValueLength {
+			return nil, fmt.Errorf("invalid tagging %q: value of tag %q is longer than %d characters", tagging, k, maxTagValueLength)
+		}
+		tags[k] = v[0]
+	}
+	return tags, nil
+}
+
+func validateTagCommand(c *cli.Context) error {
+	if c.Args().Len() != 1 {
+		return fmt.Errorf("expected only 1 argument")
+	}
+
+	if c.IsSet("set") && c.Bool("delete") {
+		return fmt.Errorf("--set and --delete flags can not be used together")
+	}
+
+	if c.IsSet("set") && strings.TrimSpace(c.String("set")) == "" {
+		return fmt.Errorf("--set flag requires at least one tag")
+	}
+
+	if _, err := parseTagging(c.String("set")); err != nil {
+		return err
+	}
+
+	srcurl, err := url.New(c.Args().Get(0), url.WithVersion(c.String("version-id")),
+		url.WithRaw(c.Bool("raw")))
+	if err != nil {
+		return err
+	}
+
+	if !srcurl.IsRemote() {
+		return fmt.Errorf("source must be a remote object")
+	}
+
+	if srcurl.IsBucket() || srcurl.IsPrefix() {
+		return fmt.Errorf("source argument must contain wildcard character")
+	}
+
+	if err := checkVersinoningURLRemote(srcurl); err != nil {
+		return err
+	}
+
+	return checkVersioningWithGoogleEndpoint(c)
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTagging(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		tagging  string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			tagging:  "",
+			expected: nil,
+		},
+		{
+			name:     "single tag",
+			tagging:  "key=value",
+			expected: map[string]string{"key": "value"},
+		},
+		{
+			name:     "multiple tags with escaped characters",
+			tagging:  "project=s5cmd&team=data+%26+ml&empty=",
+			expected: map[string]string{"project": "s5cmd", "team": "data & ml", "empty": ""},
+		},
+		{
+			name:    "invalid escape",
+			tagging: "key=%zz",
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			tagging: "=value",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate key",
+			tagging: "key=a&key=b",
+			wantErr: true,
+		},
+		{
+			name:    "too many tags",
+			tagging: "a=1&b=2&c=3&d=4&e=5&f=6&g=7&h=8&i=9&j=10&k=11",
+			wantErr: true,
+		},
+		{
+			name:    "long key",
+			tagging: strings.Repeat("k", maxTagKeyLength+1) + "=value",
+			wantErr: true,
+		},
+		{
+			name:    "long value",
+			tagging: "key=" + strings.Repeat("v", maxTagValueLength+1),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseTagging(tc.tagging)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got tags: %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Errorf("(-want +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -10,7 +10,7 @@ import (
 )
 
 const (
-	versioningNotSupportedWarning = "versioning related features are not supported with the given endpoint %q"
+	versioningNotSupportedWarning = "versioning related features are not supported with the given endpoint %q, use gs:// urls instead"
 	allVersionsFlagName           = "all-versions"
 	versionIDFlagName             = "version-id"
 )
@@ -36,10 +36,11 @@ func checkVersioningFlagCompatibility(ctx *cli.Context) error {
 
 // checkVersioningWithGoogleEndpoint checks if the versioning flags are used with
 // the Google Endpoint. Because the s3 versioning operations are not compatible with
-// GCS's versioning API.
+// GCS's versioning API. The gs:// URLs use the JSON API of GCS, which supports
+// the versions of the objects, so they are not restricted.
 func checkVersioningWithGoogleEndpoint(ctx *cli.Context) error {
 	endpoint := ctx.String("endpoint-url")
-	if endpoint == "" {
+	if endpoint == "" || !hasS3Argument(ctx) {
 		return nil
 	}
 
@@ -55,6 +56,21 @@ func checkVersioningWithGoogleEndpoint(ctx *cli.Context) error {
 	return nil
 }
 
+// hasS3Argument reports whether any of the arguments is an s3 URL, which is
+// sent to the endpoint given with the "endpoint-url" flag.
+func hasS3Argument(ctx *cli.Context) bool {
+	for _, arg := range ctx.Args().Slice() {
+		u, err := url.New(arg)
+		if err != nil {
+			continue
+		}
+		if u.Scheme == "s3" {
+			return true
+		}
+	}
+	return false
+}
+
 // checkNumberOfArguments checks if the number of the arguments is valid.
 // if the max is negative then there is no upper limit of arguments.
 func checkNumberOfArguments(ctx *cli.Context, min, max int) error {
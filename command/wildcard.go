@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/peak/s5cmd/v2/ignore"
 	"github.com/peak/s5cmd/v2/storage"
 	"github.com/peak/s5cmd/v2/strutil"
 )
@@ -31,18 +32,25 @@ func isURLMatched(regexPatterns []*regexp.Regexp, urlPath, sourcePrefix string)
 	if len(regexPatterns) == 0 {
 		return false
 	}
-	if !strings.HasSuffix(sourcePrefix, "/") {
-		sourcePrefix += "/"
-	}
-	sourcePrefix = filepath.ToSlash(sourcePrefix)
+	relpath := trimSourcePrefix(urlPath, sourcePrefix)
 	for _, regexPattern := range regexPatterns {
-		if regexPattern.MatchString(strings.TrimPrefix(urlPath, sourcePrefix)) {
+		if regexPattern.MatchString(relpath) {
 			return true
 		}
 	}
 	return false
 }
 
+// trimSourcePrefix returns the path of the object relative to the prefix of
+// the source.
+func trimSourcePrefix(urlPath, sourcePrefix string) string {
+	if !strings.HasSuffix(sourcePrefix, "/") {
+		sourcePrefix += "/"
+	}
+	sourcePrefix = filepath.ToSlash(sourcePrefix)
+	return strings.TrimPrefix(urlPath, sourcePrefix)
+}
+
 func isObjectExcluded(object *storage.Object, excludePatterns []*regexp.Regexp, includePatterns []*regexp.Regexp, prefix string) (bool, error) {
 	if err := object.Err; err != nil {
 		return true, err
@@ -55,3 +63,62 @@ func isObjectExcluded(object *storage.Object, excludePatterns []*regexp.Regexp,
 	}
 	return false, nil
 }
+
+// patternFiles holds the patterns of gitignore syntax which are read from the
+// files given by --exclude-from and --include-from flags.
+type patternFiles struct {
+	exclude *ignore.Matcher
+	include *ignore.Matcher
+}
+
+// newPatternFiles reads the given pattern files. Empty file names are skipped.
+func newPatternFiles(excludeFrom, includeFrom string) (patternFiles, error) {
+	var (
+		files patternFiles
+		err   error
+	)
+	if excludeFrom != "" {
+		files.exclude, err = ignore.ReadFile(excludeFrom)
+		if err != nil {
+			return patternFiles{}, err
+		}
+	}
+	if includeFrom != "" {
+		files.include, err = ignore.ReadFile(includeFrom)
+		if err != nil {
+			return patternFiles{}, err
+		}
+	}
+	return files, nil
+}
+
+// isExcluded reports whether the given path, which is relative to the listed
+// prefix, is excluded by the pattern files.
+func (p patternFiles) isExcluded(relpath string) bool {
+	if p.exclude.Match(relpath, false) {
+		return true
+	}
+	if p.include != nil {
+		return !p.include.Match(relpath, false)
+	}
+	return false
+}
+
+// isObjectFiltered is isObjectExcluded which also takes the pattern files into
+// account. An object is excluded if it is matched by any of the exclude
+// patterns. If there are include patterns, the object is excluded unless it is
+// matched by one of them.
+func isObjectFiltered(object *storage.Object, excludePatterns, includePatterns []*regexp.Regexp, files patternFiles, prefix string) (bool, error) {
+	if err := object.Err; err != nil {
+		return true, err
+	}
+
+	relpath := trimSourcePrefix(object.URL.Path, prefix)
+	if isURLMatched(excludePatterns, object.URL.Path, prefix) || files.exclude.Match(relpath, false) {
+		return true, nil
+	}
+	if len(includePatterns) == 0 && files.include == nil {
+		return false, nil
+	}
+	return !isURLMatched(includePatterns, object.URL.Path, prefix) && !files.include.Match(relpath, false), nil
+}
@@ -1,6 +1,8 @@
 package command
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/peak/s5cmd/v2/storage"
@@ -84,3 +86,90 @@ func TestIsObjectExcluded(t *testing.T) {
 		assert.DeepEqual(t, tc.filteredObjects, filteredObjects)
 	}
 }
+
+func TestIsObjectFiltered(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writePatterns := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		assert.NilError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	excludeFrom := writePatterns("exclude", "*.log\n!keep.log\n/build/\n")
+	includeFrom := writePatterns("include", "docs/**\n")
+
+	testcases := []struct {
+		name            string
+		excludePatterns []string
+		includePatterns []string
+		excludeFrom     string
+		includeFrom     string
+		objects         []string
+		filteredObjects []string
+	}{
+		{
+			name:            "exclude from",
+			excludeFrom:     excludeFrom,
+			objects:         []string{"prefix/a.log", "prefix/keep.log", "prefix/build/a.txt", "prefix/src/build/a.txt", "prefix/a.txt"},
+			filteredObjects: []string{"prefix/keep.log", "prefix/src/build/a.txt", "prefix/a.txt"},
+		},
+		{
+			name:            "include from",
+			includeFrom:     includeFrom,
+			objects:         []string{"prefix/docs/a.md", "prefix/docs/x/b.md", "prefix/src/docs/c.md", "prefix/d.md"},
+			filteredObjects: []string{"prefix/docs/a.md", "prefix/docs/x/b.md"},
+		},
+		{
+			name:            "exclude from with include pattern",
+			excludeFrom:     excludeFrom,
+			includePatterns: []string{"*.log"},
+			objects:         []string{"prefix/a.log", "prefix/keep.log", "prefix/a.txt"},
+			filteredObjects: []string{"prefix/keep.log"},
+		},
+		{
+			name:            "include from with include pattern",
+			includeFrom:     includeFrom,
+			includePatterns: []string{"*.txt"},
+			objects:         []string{"prefix/docs/a.md", "prefix/a.txt", "prefix/a.md"},
+			filteredObjects: []string{"prefix/docs/a.md", "prefix/a.txt"},
+		},
+		{
+			name:            "exclude pattern with include from",
+			excludePatterns: []string{"*.tmp"},
+			includeFrom:     includeFrom,
+			objects:         []string{"prefix/docs/a.md", "prefix/docs/a.tmp"},
+			filteredObjects: []string{"prefix/docs/a.md"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			excludeRegex, err := createRegexFromWildcard(tc.excludePatterns)
+			assert.NilError(t, err)
+
+			includeRegex, err := createRegexFromWildcard(tc.includePatterns)
+			assert.NilError(t, err)
+
+			files, err := newPatternFiles(tc.excludeFrom, tc.includeFrom)
+			assert.NilError(t, err)
+
+			var filteredObjects []string
+			for _, object := range tc.objects {
+				skip, err := isObjectFiltered(&storage.Object{URL: &url.URL{Path: object}}, excludeRegex, includeRegex, files, "prefix")
+				assert.NilError(t, err)
+				if skip {
+					continue
+				}
+				filteredObjects = append(filteredObjects, object)
+			}
+
+			assert.DeepEqual(t, tc.filteredObjects, filteredObjects)
+		})
+	}
+}
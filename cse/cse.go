@@ -0,0 +1,435 @@
+// Package cse implements client-side envelope encryption of objects.
+//
+// Every object is encrypted with a random data key using AES-256-GCM. The
+// data key is wrapped with a master key, which is either read from a key file
+// or derived from a passphrase, and is stored in the user metadata of the
+// object along with the parameters needed for decryption.
+//
+// The content is split into segments of SegmentSize bytes which are sealed
+// separately. Segments can be encrypted and decrypted independently of each
+// other, so objects can be transferred in parts concurrently.
+package cse
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// Algorithm is the identifier of the encryption scheme.
+	Algorithm = "AES-256-GCM-64K"
+
+	// SegmentSize is the size of the plaintext segments.
+	SegmentSize = 64 * 1024
+
+	// Overhead is the number of bytes added to each segment.
+	Overhead = 16
+
+	keySize   = 32
+	nonceSize = 12
+	saltSize  = 16
+
+	kdfName       = "pbkdf2-sha256"
+	kdfIterations = 600000
+	// maxIterations protects against the objects which would take forever
+	// to open.
+	maxIterations = 10000000
+)
+
+// Metadata keys of encrypted objects.
+const (
+	MetadataAlgorithm = "s5cmd-cse-algorithm"
+	MetadataKey       = "s5cmd-cse-key"
+	MetadataIV        = "s5cmd-cse-iv"
+	MetadataKDF       = "s5cmd-cse-kdf"
+)
+
+var (
+	// ErrWrongKey is returned if the data key of an object can't be
+	// unwrapped with the master key.
+	ErrWrongKey = errors.New("object is encrypted with a different key")
+
+	keys sync.Map
+)
+
+// MasterKey wraps and unwraps the data keys of the objects.
+type MasterKey struct {
+	// key is the master key given by a key file.
+	key []byte
+
+	// passphrase is used to derive the master key, using a random salt for
+	// the new objects. Derived keys are cached by salt since the derivation
+	// is slow on purpose.
+	passphrase []byte
+	salt       []byte
+	mu         sync.Mutex
+	derived    map[string][]byte
+}
+
+// LoadKeyFile reads a 256-bit master key from the given file. The key can be
+// stored as raw bytes, or hex or base64 encoded. Keys are cached by path.
+func LoadKeyFile(path string) (*MasterKey, error) {
+	return load("key", path, func() (*MasterKey, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key file %q: %w", path, err)
+		}
+		return &MasterKey{key: key}, nil
+	})
+}
+
+// LoadPassphraseFile reads the passphrase to derive the master key from the
+// given file. Trailing newlines of the file are ignored. Keys are cached by
+// path.
+func LoadPassphraseFile(path string) (*MasterKey, error) {
+	return load("passphrase", path, func() (*MasterKey, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		passphrase := bytes.TrimRight(data, "\r\n")
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("invalid passphrase file %q: passphrase is empty", path)
+		}
+		return NewPassphraseKey(passphrase), nil
+	})
+}
+
+// NewPassphraseKey creates a master key derived from the given passphrase.
+func NewPassphraseKey(passphrase []byte) *MasterKey {
+	return &MasterKey{
+		passphrase: passphrase,
+		derived:    map[string][]byte{},
+	}
+}
+
+func load(kind, path string, fn func() (*MasterKey, error)) (*MasterKey, error) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	id := kind + ":" + path
+	if key, ok := keys.Load(id); ok {
+		return key.(*MasterKey), nil
+	}
+
+	key, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := keys.LoadOrStore(id, key)
+	return actual.(*MasterKey), nil
+}
+
+func parseKey(data []byte) ([]byte, error) {
+	if len(data) == keySize {
+		return data, nil
+	}
+
+	str := strings.TrimSpace(string(data))
+	if key, err := hex.DecodeString(str); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(str); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key must be %d bytes, either raw or hex/base64 encoded", keySize)
+}
+
+// IsEncrypted reports whether the object with the given user metadata is
+// encrypted on the client side.
+func IsEncrypted(metadata map[string]string) bool {
+	_, ok := lookup(metadata, MetadataAlgorithm)
+	return ok
+}
+
+// NewEnvelope creates a new envelope with a random data key to encrypt an
+// object.
+func (m *MasterKey) NewEnvelope() (*Envelope, error) {
+	dataKey := make([]byte, keySize)
+	iv := make([]byte, nonceSize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{
+		MetadataAlgorithm: Algorithm,
+		MetadataIV:        base64.StdEncoding.EncodeToString(iv),
+	}
+
+	masterKey := m.key
+	if m.passphrase != nil {
+		salt, err := m.sessionSalt()
+		if err != nil {
+			return nil, err
+		}
+		masterKey = m.derive(salt, kdfIterations)
+		metadata[MetadataKDF] = fmt.Sprintf("%v:%v:%v", kdfName, kdfIterations,
+			base64.StdEncoding.EncodeToString(salt))
+	}
+
+	wrapped, err := seal(masterKey, dataKey, []byte(Algorithm))
+	if err != nil {
+		return nil, err
+	}
+	metadata[MetadataKey] = base64.StdEncoding.EncodeToString(wrapped)
+
+	return newEnvelope(dataKey, iv, metadata)
+}
+
+// OpenEnvelope unwraps the data key of an encrypted object with the given
+// user metadata.
+func (m *MasterKey) OpenEnvelope(metadata map[string]string) (*Envelope, error) {
+	algorithm, _ := lookup(metadata, MetadataAlgorithm)
+	if algorithm != Algorithm {
+		return nil, fmt.Errorf("unsupported client-side encryption algorithm %q", algorithm)
+	}
+
+	wrapped, err := decodeMetadata(metadata, MetadataKey)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := decodeMetadata(metadata, MetadataIV)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != nonceSize {
+		return nil, fmt.Errorf("invalid %v metadata", MetadataIV)
+	}
+
+	kdf, hasKDF := lookup(metadata, MetadataKDF)
+	if hasKDF != (m.passphrase != nil) {
+		return nil, ErrWrongKey
+	}
+
+	masterKey := m.key
+	if hasKDF {
+		salt, iterations, err := parseKDF(kdf)
+		if err != nil {
+			return nil, err
+		}
+		masterKey = m.derive(salt, iterations)
+	}
+
+	dataKey, err := open(masterKey, wrapped, []byte(Algorithm))
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+
+	return newEnvelope(dataKey, iv, metadata)
+}
+
+func (m *MasterKey) sessionSalt() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		m.salt = salt
+	}
+	return m.salt, nil
+}
+
+func (m *MasterKey) derive(salt []byte, iterations int) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := fmt.Sprintf("%x:%v", salt, iterations)
+	if key, ok := m.derived[id]; ok {
+		return key
+	}
+
+	key := pbkdf2.Key(m.passphrase, salt, iterations, keySize, sha256.New)
+	m.derived[id] = key
+	return key
+}
+
+func parseKDF(kdf string) ([]byte, int, error) {
+	fields := strings.Split(kdf, ":")
+	if len(fields) != 3 || fields[0] != kdfName {
+		return nil, 0, fmt.Errorf("unsupported key derivation function %q", kdf)
+	}
+
+	iterations, err := strconv.Atoi(fields[1])
+	if err != nil || iterations < 1 || iterations > maxIterations {
+		return nil, 0, fmt.Errorf("invalid key derivation function %q", kdf)
+	}
+
+	// the salts which are shorter than the ones generated for the new
+	// objects are rejected, they would weaken the derived keys.
+	salt, err := base64.StdEncoding.DecodeString(fields[2])
+	if err != nil || len(salt) < saltSize {
+		return nil, 0, fmt.Errorf("invalid key derivation function %q", kdf)
+	}
+	return salt, iterations, nil
+}
+
+// Envelope encrypts and decrypts the content of a single object.
+type Envelope struct {
+	aead     cipher.AEAD
+	iv       []byte
+	metadata map[string]string
+}
+
+func newEnvelope(dataKey, iv []byte, metadata map[string]string) (*Envelope, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{aead: aead, iv: iv, metadata: metadata}, nil
+}
+
+// Metadata returns the user metadata to be stored with the encrypted object.
+func (e *Envelope) Metadata() map[string]string {
+	metadata := make(map[string]string, len(e.metadata))
+	for k, v := range e.metadata {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// EncryptedSize returns the size of the ciphertext of a plaintext with the
+// given size. Empty plaintexts are encrypted as a single empty segment.
+func EncryptedSize(size int64) int64 {
+	return size + segmentCount(size)*Overhead
+}
+
+// DecryptedSize returns the size of the plaintext of a ciphertext with the
+// given size.
+func DecryptedSize(size int64) (int64, error) {
+	segments := (size + encryptedSegmentSize - 1) / encryptedSegmentSize
+	last := size - (segments-1)*encryptedSegmentSize
+	if segments == 0 || last < Overhead || (last == Overhead && segments > 1) {
+		return 0, fmt.Errorf("invalid encrypted object size %d", size)
+	}
+	return size - segments*Overhead, nil
+}
+
+func segmentCount(size int64) int64 {
+	if size == 0 {
+		return 1
+	}
+	return (size + SegmentSize - 1) / SegmentSize
+}
+
+// seal encrypts the plaintext of the segment with the given index. The last
+// segment is authenticated differently than the others so that truncated
+// objects can be detected.
+func (e *Envelope) seal(dst, plaintext []byte, index int64, last bool) []byte {
+	return e.aead.Seal(dst, e.nonce(index), plaintext, segmentAD(last))
+}
+
+func (e *Envelope) open(dst, ciphertext []byte, index int64, last bool) ([]byte, error) {
+	plaintext, err := e.aead.Open(dst, e.nonce(index), ciphertext, segmentAD(last))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt segment %d: %w", index, err)
+	}
+	return plaintext, nil
+}
+
+func (e *Envelope) nonce(index int64) []byte {
+	nonce := make([]byte, nonceSize)
+	copy(nonce, e.iv)
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(index))
+	for i := range counter {
+		nonce[nonceSize-8+i] ^= counter[i]
+	}
+	return nonce
+}
+
+func segmentAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext with a random nonce which is prepended to the
+// ciphertext.
+func seal(key, plaintext, ad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize, nonceSize+len(plaintext)+Overhead)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+func open(key, ciphertext, ad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("ciphertext is too short")
+	}
+	return aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], ad)
+}
+
+// lookup finds the value of the given key in user metadata. Keys are
+// compared case insensitively since the header names are canonicalized by
+// HTTP clients.
+func lookup(metadata map[string]string, key string) (string, bool) {
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func decodeMetadata(metadata map[string]string, key string) ([]byte, error) {
+	value, ok := lookup(metadata, key)
+	if !ok {
+		return nil, fmt.Errorf("%v metadata is missing", key)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v metadata: %w", key, err)
+	}
+	return data, nil
+}
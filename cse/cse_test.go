@@ -0,0 +1,254 @@
+package cse
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+func newTestKey(t *testing.T) *MasterKey {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "key")
+	err := os.WriteFile(path, []byte(hex.EncodeToString(randomBytes(keySize))+"\n"), 0600)
+	assert.NilError(t, err)
+
+	key, err := LoadKeyFile(path)
+	assert.NilError(t, err)
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Parallel()
+
+	sizes := []int{0, 1, SegmentSize - 1, SegmentSize, SegmentSize + 1, 3*SegmentSize + 100}
+
+	for _, size := range sizes {
+		plaintext := randomBytes(size)
+
+		key := newTestKey(t)
+		env, err := key.NewEnvelope()
+		assert.NilError(t, err)
+
+		reader := env.NewReader(bytes.NewReader(plaintext), int64(size))
+		assert.Equal(t, reader.Size(), EncryptedSize(int64(size)))
+
+		ciphertext, err := io.ReadAll(reader)
+		assert.NilError(t, err)
+		assert.Equal(t, int64(len(ciphertext)), EncryptedSize(int64(size)))
+
+		// the stream reader must produce the same ciphertext.
+		streamed, err := io.ReadAll(env.NewStreamReader(bytes.NewReader(plaintext)))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, streamed, ciphertext)
+
+		decryptedSize, err := DecryptedSize(int64(len(ciphertext)))
+		assert.NilError(t, err)
+		assert.Equal(t, decryptedSize, int64(size))
+
+		opened, err := key.OpenEnvelope(env.Metadata())
+		assert.NilError(t, err)
+
+		// write the ciphertext in shuffled chunks as concurrent downloads do.
+		const chunkSize = 10000
+		var offsets []int
+		for off := 0; off < len(ciphertext); off += chunkSize {
+			offsets = append(offsets, off)
+		}
+		rand.Shuffle(len(offsets), func(i, j int) {
+			offsets[i], offsets[j] = offsets[j], offsets[i]
+		})
+
+		dst := &bufferWriterAt{}
+		writer, err := opened.NewWriterAt(dst, int64(len(ciphertext)))
+		assert.NilError(t, err)
+		for _, off := range offsets {
+			end := off + chunkSize
+			if end > len(ciphertext) {
+				end = len(ciphertext)
+			}
+			_, err := writer.WriteAt(ciphertext[off:end], int64(off))
+			assert.NilError(t, err)
+		}
+		assert.NilError(t, writer.Close())
+		assert.DeepEqual(t, dst.Bytes(), plaintext)
+	}
+}
+
+func TestReaderReadAt(t *testing.T) {
+	t.Parallel()
+
+	plaintext := randomBytes(2*SegmentSize + 10)
+
+	env, err := newTestKey(t).NewEnvelope()
+	assert.NilError(t, err)
+
+	reader := env.NewReader(bytes.NewReader(plaintext), int64(len(plaintext)))
+	ciphertext, err := io.ReadAll(reader)
+	assert.NilError(t, err)
+
+	// ranges across the segment boundaries
+	p := make([]byte, SegmentSize)
+	n, err := reader.ReadAt(p, SegmentSize/2)
+	assert.NilError(t, err)
+	assert.Equal(t, n, len(p))
+	assert.DeepEqual(t, p, ciphertext[SegmentSize/2:SegmentSize/2+SegmentSize])
+
+	n, err = reader.ReadAt(p, int64(len(ciphertext)-100))
+	assert.Equal(t, err, io.EOF)
+	assert.DeepEqual(t, p[:n], ciphertext[len(ciphertext)-100:])
+}
+
+func TestDecryptTampered(t *testing.T) {
+	t.Parallel()
+
+	plaintext := randomBytes(2 * SegmentSize)
+
+	key := newTestKey(t)
+	env, err := key.NewEnvelope()
+	assert.NilError(t, err)
+
+	ciphertext, err := io.ReadAll(env.NewReader(bytes.NewReader(plaintext), int64(len(plaintext))))
+	assert.NilError(t, err)
+
+	t.Run("modified", func(t *testing.T) {
+		modified := append([]byte(nil), ciphertext...)
+		modified[100] ^= 1
+
+		writer, err := env.NewWriterAt(&bufferWriterAt{}, int64(len(modified)))
+		assert.NilError(t, err)
+		_, err = writer.WriteAt(modified, 0)
+		assert.ErrorContains(t, err, "unable to decrypt segment 0")
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		truncated := ciphertext[:SegmentSize+Overhead]
+
+		writer, err := env.NewWriterAt(&bufferWriterAt{}, int64(len(truncated)))
+		assert.NilError(t, err)
+		_, err = writer.WriteAt(truncated, 0)
+		assert.ErrorContains(t, err, "unable to decrypt segment 0")
+	})
+
+	t.Run("incomplete", func(t *testing.T) {
+		writer, err := env.NewWriterAt(&bufferWriterAt{}, int64(len(ciphertext)))
+		assert.NilError(t, err)
+		_, err = writer.WriteAt(ciphertext[:SegmentSize+Overhead], 0)
+		assert.NilError(t, err)
+		assert.Error(t, writer.Close(), "cse: encrypted object is incomplete")
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, err := newTestKey(t).OpenEnvelope(env.Metadata())
+		assert.Equal(t, err, ErrWrongKey)
+	})
+}
+
+func TestPassphraseKey(t *testing.T) {
+	t.Parallel()
+
+	key := NewPassphraseKey([]byte("correct horse battery staple"))
+	env, err := key.NewEnvelope()
+	assert.NilError(t, err)
+
+	metadata := env.Metadata()
+	assert.Assert(t, IsEncrypted(metadata))
+	assert.Assert(t, metadata[MetadataKDF] != "")
+
+	// keys are derived with the salt in the metadata.
+	other := NewPassphraseKey([]byte("correct horse battery staple"))
+	_, err = other.OpenEnvelope(metadata)
+	assert.NilError(t, err)
+
+	wrong := NewPassphraseKey([]byte("wrong passphrase"))
+	_, err = wrong.OpenEnvelope(metadata)
+	assert.Equal(t, err, ErrWrongKey)
+}
+
+func TestParseKey(t *testing.T) {
+	t.Parallel()
+
+	raw := randomBytes(keySize)
+
+	testcases := []struct {
+		name string
+		data []byte
+		err  bool
+	}{
+		{name: "raw", data: raw},
+		{name: "hex", data: []byte(hex.EncodeToString(raw) + "\n")},
+		{name: "short", data: []byte("0123456789abcdef"), err: true},
+	}
+
+	for _, tc := range testcases {
+		key, err := parseKey(tc.data)
+		if tc.err {
+			assert.ErrorContains(t, err, "key must be 32 bytes")
+			continue
+		}
+		assert.NilError(t, err, tc.name)
+		assert.DeepEqual(t, key, raw)
+	}
+}
+
+func TestParseKDF(t *testing.T) {
+	t.Parallel()
+
+	salt := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, saltSize))
+	shortSalt := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, saltSize-1))
+
+	testcases := []struct {
+		name      string
+		kdf       string
+		expectErr bool
+	}{
+		{name: "valid", kdf: "pbkdf2-sha256:600000:" + salt},
+		{name: "unknown function", kdf: "scrypt:600000:" + salt, expectErr: true},
+		{name: "invalid iterations", kdf: "pbkdf2-sha256:0:" + salt, expectErr: true},
+		{name: "too many iterations", kdf: "pbkdf2-sha256:10000001:" + salt, expectErr: true},
+		{name: "invalid salt", kdf: "pbkdf2-sha256:600000:???", expectErr: true},
+		{name: "empty salt", kdf: "pbkdf2-sha256:600000:", expectErr: true},
+		{name: "short salt", kdf: "pbkdf2-sha256:600000:" + shortSalt, expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		_, iterations, err := parseKDF(tc.kdf)
+		if tc.expectErr {
+			assert.Assert(t, err != nil, tc.name)
+			continue
+		}
+		assert.NilError(t, err, tc.name)
+		assert.Equal(t, iterations, 600000, tc.name)
+	}
+}
+
+type bufferWriterAt struct {
+	buf []byte
+}
+
+func (w *bufferWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(w.buf) {
+		w.buf = append(w.buf, make([]byte, end-len(w.buf))...)
+	}
+	return copy(w.buf[off:], p), nil
+}
+
+func (w *bufferWriterAt) Bytes() []byte {
+	if w.buf == nil {
+		return []byte{}
+	}
+	return w.buf
+}
@@ -0,0 +1,279 @@
+package cse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const encryptedSegmentSize = SegmentSize + Overhead
+
+// Reader encrypts a plaintext which supports random access. Any range of the
+// ciphertext can be read independently, which makes it suitable for
+// concurrent multipart uploads.
+type Reader struct {
+	env    *Envelope
+	src    io.ReaderAt
+	size   int64
+	offset int64
+	mu     sync.Mutex
+}
+
+// NewReader returns a Reader which encrypts size bytes of src.
+func (e *Envelope) NewReader(src io.ReaderAt, size int64) *Reader {
+	return &Reader{env: e, src: src, size: size}
+}
+
+// Size returns the size of the ciphertext.
+func (r *Reader) Size() int64 {
+	return EncryptedSize(r.size)
+}
+
+// ReadAt reads the ciphertext at the given offset.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("cse: negative offset")
+	}
+
+	var (
+		n    int
+		size = r.Size()
+	)
+	for n < len(p) && off < size {
+		index := off / encryptedSegmentSize
+		segment, err := r.segment(index)
+		if err != nil {
+			return n, err
+		}
+
+		c := copy(p[n:], segment[off-index*encryptedSegmentSize:])
+		n += c
+		off += int64(c)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Read reads the ciphertext from the current offset.
+func (r *Reader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, err := r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek sets the offset for the next Read.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.Size()
+	default:
+		return 0, errors.New("cse: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("cse: negative position")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *Reader) segment(index int64) ([]byte, error) {
+	start := index * SegmentSize
+	end := start + SegmentSize
+	if end > r.size {
+		end = r.size
+	}
+
+	plaintext := make([]byte, end-start, encryptedSegmentSize)
+	if _, err := r.src.ReadAt(plaintext, start); err != nil && !(err == io.EOF && end == r.size) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	last := end == r.size
+	return r.env.seal(plaintext[:0], plaintext, index, last), nil
+}
+
+// streamReader encrypts a plaintext of unknown size which can only be read
+// sequentially.
+type streamReader struct {
+	env   *Envelope
+	src   io.Reader
+	index int64
+	// next is the first byte of the next segment, which is read ahead to
+	// find out if the current segment is the last one.
+	next    []byte
+	pending []byte
+	done    bool
+	err     error
+}
+
+// NewStreamReader returns an io.Reader which encrypts the plaintext read from
+// src.
+func (e *Envelope) NewStreamReader(src io.Reader) io.Reader {
+	return &streamReader{env: e, src: src}
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.fill()
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// fill encrypts the next segment.
+func (r *streamReader) fill() {
+	plaintext := make([]byte, SegmentSize+1, encryptedSegmentSize+1)
+	n := copy(plaintext, r.next)
+
+	m, err := io.ReadFull(r.src, plaintext[n:])
+	n += m
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		r.err = err
+		return
+	}
+
+	last := n <= SegmentSize
+	if last {
+		r.next = nil
+		r.done = true
+	} else {
+		r.next = []byte{plaintext[SegmentSize]}
+		n = SegmentSize
+	}
+
+	r.pending = r.env.seal(plaintext[:0], plaintext[:n], r.index, last)
+	r.index++
+}
+
+// WriterAt decrypts a ciphertext which is written in arbitrary order, as the
+// concurrent multipart downloads do. Segments are buffered until they are
+// complete, then decrypted and written to the destination.
+type WriterAt struct {
+	env  *Envelope
+	dst  io.WriterAt
+	size int64
+
+	mu        sync.Mutex
+	segments  map[int64]*segmentBuffer
+	completed int64
+}
+
+type segmentBuffer struct {
+	data    []byte
+	written int
+}
+
+// NewWriterAt returns a WriterAt which decrypts a ciphertext of the given
+// size and writes the plaintext to dst.
+func (e *Envelope) NewWriterAt(dst io.WriterAt, size int64) (*WriterAt, error) {
+	if _, err := DecryptedSize(size); err != nil {
+		return nil, err
+	}
+	return &WriterAt{
+		env:      e,
+		dst:      dst,
+		size:     size,
+		segments: map[int64]*segmentBuffer{},
+	}, nil
+}
+
+// WriteAt writes the ciphertext at the given offset.
+func (w *WriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(p)) > w.size {
+		return 0, fmt.Errorf("cse: write at offset %d exceeds the object size %d", off, w.size)
+	}
+
+	written := 0
+	for written < len(p) {
+		index := off / encryptedSegmentSize
+		segment := w.segment(index)
+
+		n := copy(segment.data[off-index*encryptedSegmentSize:], p[written:])
+		written += n
+		off += int64(n)
+
+		if !w.complete(index, segment, n) {
+			continue
+		}
+
+		last := (index+1)*encryptedSegmentSize >= w.size
+		plaintext, err := w.env.open(segment.data[:0], segment.data, index, last)
+		if err != nil {
+			return written, err
+		}
+		if _, err := w.dst.WriteAt(plaintext, index*SegmentSize); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Close checks that the whole ciphertext is written.
+func (w *WriterAt) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.completed != (w.size+encryptedSegmentSize-1)/encryptedSegmentSize {
+		return errors.New("cse: encrypted object is incomplete")
+	}
+	return nil
+}
+
+func (w *WriterAt) segment(index int64) *segmentBuffer {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	segment, ok := w.segments[index]
+	if !ok {
+		size := w.size - index*encryptedSegmentSize
+		if size > encryptedSegmentSize {
+			size = encryptedSegmentSize
+		}
+		segment = &segmentBuffer{data: make([]byte, size)}
+		w.segments[index] = segment
+	}
+	return segment
+}
+
+// complete records n bytes written to the segment and reports whether the
+// segment is complete.
+func (w *WriterAt) complete(index int64, segment *segmentBuffer, n int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	segment.written += n
+	if segment.written < len(segment.data) {
+		return false
+	}
+
+	delete(w.segments, index)
+	w.completed++
+	return true
+}
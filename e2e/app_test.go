@@ -7,10 +7,12 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/peak/s5cmd/v2/command"
 
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
 	"gotest.tools/v3/icmd"
 )
 
@@ -72,6 +74,21 @@ func TestAppRetryCount(t *testing.T) {
 	}
 }
 
+func TestAppNegativeListPartitions(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("--list-partitions", "-1")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals("ERROR list partitions cannot be a negative value"),
+	})
+}
+
 // Checks if the stats are written in necessary conditions.
 // 1. Print with every log level when there is an operation
 // 2. Do not print when used with help & version commands.
@@ -312,3 +329,78 @@ func TestAppEndpointShouldHaveScheme(t *testing.T) {
 		})
 	}
 }
+
+func TestAppLimitRateValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "invalid rate",
+			args:     []string{"--limit-rate", "fast"},
+			expected: `ERROR bad value for --limit-rate: invalid rate "fast"`,
+		},
+		{
+			name:     "unknown unit",
+			args:     []string{"--limit-upload-rate", "10TB/s"},
+			expected: `ERROR bad value for --limit-upload-rate: invalid rate "10TB/s": unknown unit "TB"`,
+		},
+		{
+			name:     "zero rate",
+			args:     []string{"--limit-download-rate", "0"},
+			expected: `ERROR bad value for --limit-download-rate: invalid rate "0": rate must be positive`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// --limit-rate 1KB/s cp s3://bucket/object .
+func TestAppLimitRateDownload(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const filename = "file.txt"
+	content := strings.Repeat("s", 2500)
+	putFile(t, s3client, bucket, filename, content)
+
+	cmd := s5cmd("--limit-rate", "1KB/s", "cp", fmt.Sprintf("s3://%v/%v", bucket, filename), ".")
+
+	start := time.Now()
+	result := icmd.RunCmd(cmd)
+	elapsed := time.Since(start)
+
+	result.Assert(t, icmd.Success)
+
+	// the first second worth of bytes are allowed immediately, the rest
+	// takes 1.5 seconds.
+	if elapsed < 1500*time.Millisecond {
+		t.Errorf("expected the download to be limited, took %v", elapsed)
+	}
+
+	expected := fs.Expected(t, fs.WithFile(filename, content, fs.WithMode(0644)))
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
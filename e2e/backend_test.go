@@ -0,0 +1,134 @@
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/icmd"
+)
+
+func newHTTPSource(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, content)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// cp http://host/dir/file.txt .
+func TestCopyHTTPObjectToLocal(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	const content = "content served over http"
+	src := newHTTPSource(t, map[string]string{"/dir/file.txt": content}) + "/dir/file.txt"
+
+	cmd := s5cmd("cp", src, ".")
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v file.txt`, src),
+	})
+
+	expected := fs.Expected(t, fs.WithFile("file.txt", content, fs.WithMode(0644)))
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
+// cp http://host/file.txt s3://bucket/
+func TestCopyHTTPObjectToS3(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const content = "streamed from http to s3"
+	src := newHTTPSource(t, map[string]string{"/file.txt": content}) + "/file.txt"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", src, dst)
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %vfile.txt`, src, dst),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "file.txt", content))
+}
+
+// rm http://host/file.txt
+// cp file.txt http://host/
+func TestHTTPBackendIsReadOnly(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	base := newHTTPSource(t, map[string]string{"/file.txt": "content"})
+
+	cmd := s5cmd("rm", base+"/file.txt")
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`delete is not supported by http:// storage`),
+	})
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile("file.txt", "content"))
+
+	cmd = s5cmd("cp", workdir.Join("file.txt"), base+"/")
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`upload is not supported by http:// storage`),
+	})
+}
+
+// head http://host/file.txt
+func TestHeadHTTPObject(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	src := newHTTPSource(t, map[string]string{"/file.txt": "content"}) + "/file.txt"
+
+	cmd := s5cmd("head", src)
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: contains(`"key":"%v"`, src),
+	})
+}
+
+// tag http://host/file.txt
+func TestTagHTTPObjectIsNotSupported(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	src := newHTTPSource(t, map[string]string{"/file.txt": "content"}) + "/file.txt"
+
+	cmd := s5cmd("tag", src)
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`tagging is not supported by http:// storage`),
+	})
+}
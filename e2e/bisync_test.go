@@ -0,0 +1,344 @@
+package e2e
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/icmd"
+)
+
+// bisync folder/ s3://bucket/prefix/
+func TestBisyncFirstRun(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("local.txt", "this is a local file"),
+		fs.WithFile("common.txt", "this is a common file"),
+		fs.WithDir("dir", fs.WithFile("sub.txt", "this is a local file in a directory")),
+	)
+	defer workdir.Remove()
+
+	putFile(t, s3client, bucket, "prefix/remote.txt", "this is a remote file")
+	putFile(t, s3client, bucket, "prefix/common.txt", "this is a common file")
+	putFile(t, s3client, bucket, "other/object.txt", "this is an object out of the prefix")
+
+	src := filepath.ToSlash(workdir.Path())
+	dst := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+	cmd := s5cmd("bisync", "--state-dir", t.TempDir(), src+"/", dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/dir/sub.txt %vdir/sub.txt`, src, dst),
+		1: equals(`cp %v/local.txt %vlocal.txt`, src, dst),
+		2: equals(`cp %vremote.txt %v/remote.txt`, dst, src),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithFile("local.txt", "this is a local file"),
+		fs.WithFile("common.txt", "this is a common file"),
+		fs.WithFile("remote.txt", "this is a remote file"),
+		fs.WithDir("dir", fs.WithFile("sub.txt", "this is a local file in a directory")),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	expectedS3Content := map[string]string{
+		"prefix/local.txt":   "this is a local file",
+		"prefix/common.txt":  "this is a common file",
+		"prefix/remote.txt":  "this is a remote file",
+		"prefix/dir/sub.txt": "this is a local file in a directory",
+		"other/object.txt":   "this is an object out of the prefix",
+	}
+	for key, content := range expectedS3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+}
+
+// bisync folder/ s3://bucket/
+func TestBisyncPropagateChanges(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("modified-local.txt", "this is a file"),
+		fs.WithFile("deleted-local.txt", "this is a file"),
+		fs.WithFile("modified-remote.txt", "this is a file"),
+		fs.WithFile("deleted-remote.txt", "this is a file"),
+		fs.WithFile("unchanged.txt", "this is a file"),
+	)
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(workdir.Path())
+	dst := fmt.Sprintf("s3://%v/", bucket)
+	stateDir := t.TempDir()
+
+	result := icmd.RunCmd(s5cmd("bisync", "--state-dir", stateDir, src, dst))
+	result.Assert(t, icmd.Success)
+
+	// change both sides since the first synchronization.
+	assert.NilError(t, os.WriteFile(workdir.Join("modified-local.txt"), []byte("this is a modified local file"), 0o644))
+	assert.NilError(t, os.WriteFile(workdir.Join("created-local.txt"), []byte("this is a created local file"), 0o644))
+	assert.NilError(t, os.Remove(workdir.Join("deleted-local.txt")))
+	putFile(t, s3client, bucket, "modified-remote.txt", "this is a modified remote file")
+	putFile(t, s3client, bucket, "created-remote.txt", "this is a created remote file")
+	_, err := s3client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String("deleted-remote.txt"),
+	})
+	assert.NilError(t, err)
+
+	result = icmd.RunCmd(s5cmd("bisync", "--state-dir", stateDir, src, dst))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/created-local.txt %vcreated-local.txt`, src, dst),
+		1: equals(`cp %v/modified-local.txt %vmodified-local.txt`, src, dst),
+		2: equals(`cp %vcreated-remote.txt %v/created-remote.txt`, dst, src),
+		3: equals(`cp %vmodified-remote.txt %v/modified-remote.txt`, dst, src),
+		4: equals(`rm %v/deleted-remote.txt`, src),
+		5: equals(`rm %vdeleted-local.txt`, dst),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithFile("modified-local.txt", "this is a modified local file"),
+		fs.WithFile("created-local.txt", "this is a created local file"),
+		fs.WithFile("modified-remote.txt", "this is a modified remote file"),
+		fs.WithFile("created-remote.txt", "this is a created remote file"),
+		fs.WithFile("unchanged.txt", "this is a file"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	expectedS3Content := map[string]string{
+		"modified-local.txt":  "this is a modified local file",
+		"created-local.txt":   "this is a created local file",
+		"modified-remote.txt": "this is a modified remote file",
+		"created-remote.txt":  "this is a created remote file",
+		"unchanged.txt":       "this is a file",
+	}
+	for key, content := range expectedS3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+
+	err = ensureS3Object(s3client, bucket, "deleted-local.txt", "this is a file")
+	assertError(t, err, errS3NoSuchKey)
+
+	// nothing is changed since the last synchronization.
+	result = icmd.RunCmd(s5cmd("bisync", "--state-dir", stateDir, src, dst))
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+}
+
+func TestBisyncConflict(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	testcases := []struct {
+		name            string
+		policy          string
+		localModTime    time.Time
+		expectedStdout  map[int]compareFunc
+		expectedStderr  map[int]compareFunc
+		expectedLocal   []fs.PathOp
+		expectedContent map[string]string
+	}{
+		{
+			name:         "newer local",
+			policy:       "newer",
+			localModTime: now.Add(time.Hour),
+			expectedStdout: map[int]compareFunc{
+				0: match(`^cp .*/conflict.txt s3://.*/conflict.txt$`),
+			},
+			expectedLocal: []fs.PathOp{
+				fs.WithFile("conflict.txt", "this is a local change"),
+			},
+			expectedContent: map[string]string{
+				"conflict.txt": "this is a local change",
+			},
+		},
+		{
+			name:         "newer remote",
+			policy:       "newer",
+			localModTime: now.Add(-time.Hour),
+			expectedStdout: map[int]compareFunc{
+				0: match(`^cp s3://.*/conflict.txt .*/conflict.txt$`),
+			},
+			expectedLocal: []fs.PathOp{
+				fs.WithFile("conflict.txt", "this is a remote change"),
+			},
+			expectedContent: map[string]string{
+				"conflict.txt": "this is a remote change",
+			},
+		},
+		{
+			name:         "keep both",
+			policy:       "keep-both",
+			localModTime: now.Add(time.Hour),
+			expectedStdout: map[int]compareFunc{
+				0: match(`^cp .*/conflict.conflict.txt s3://.*/conflict.conflict.txt$`),
+				1: match(`^cp s3://.*/conflict.txt .*/conflict.txt$`),
+				2: match(`^mv .*/conflict.txt .*/conflict.conflict.txt$`),
+			},
+			expectedLocal: []fs.PathOp{
+				fs.WithFile("conflict.txt", "this is a remote change"),
+				fs.WithFile("conflict.conflict.txt", "this is a local change"),
+			},
+			expectedContent: map[string]string{
+				"conflict.txt":          "this is a remote change",
+				"conflict.conflict.txt": "this is a local change",
+			},
+		},
+		{
+			name:           "fail",
+			policy:         "fail",
+			localModTime:   now.Add(time.Hour),
+			expectedStdout: map[int]compareFunc{},
+			expectedStderr: map[int]compareFunc{
+				0: match(`^ERROR "bisync .*": "conflict.txt" is changed on both sides$`),
+				1: match(`^ERROR "bisync .*": 1 files are changed on both sides, no changes are made$`),
+			},
+			expectedLocal: []fs.PathOp{
+				fs.WithFile("conflict.txt", "this is a local change"),
+			},
+			expectedContent: map[string]string{
+				"conflict.txt": "this is a remote change",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd := setup(t)
+
+			bucket := s3BucketFromTestName(t)
+			createBucket(t, s3client, bucket)
+
+			workdir := fs.NewDir(t, "somedir", fs.WithFile("conflict.txt", "this is a file"))
+			defer workdir.Remove()
+
+			src := filepath.ToSlash(workdir.Path())
+			dst := fmt.Sprintf("s3://%v/", bucket)
+			stateDir := t.TempDir()
+
+			result := icmd.RunCmd(s5cmd("bisync", "--state-dir", stateDir, src, dst))
+			result.Assert(t, icmd.Success)
+
+			path := workdir.Join("conflict.txt")
+			assert.NilError(t, os.WriteFile(path, []byte("this is a local change"), 0o644))
+			assert.NilError(t, os.Chtimes(path, tc.localModTime, tc.localModTime))
+			putFile(t, s3client, bucket, "conflict.txt", "this is a remote change")
+
+			result = icmd.RunCmd(s5cmd("bisync", "--conflict", tc.policy, "--state-dir", stateDir, src, dst))
+			if tc.expectedStderr != nil {
+				result.Assert(t, icmd.Expected{ExitCode: 1})
+				assertLines(t, result.Stderr(), tc.expectedStderr)
+			} else {
+				result.Assert(t, icmd.Success)
+			}
+
+			assertLines(t, result.Stdout(), tc.expectedStdout, sortInput(true))
+
+			assert.Assert(t, fs.Equal(workdir.Path(), fs.Expected(t, tc.expectedLocal...)))
+			for key, content := range tc.expectedContent {
+				assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+			}
+		})
+	}
+}
+
+func TestBisyncEmptySide(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("file.txt", "this is a file"))
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(workdir.Path())
+	dst := fmt.Sprintf("s3://%v/", bucket)
+	stateDir := t.TempDir()
+
+	result := icmd.RunCmd(s5cmd("bisync", "--state-dir", stateDir, src, dst))
+	result.Assert(t, icmd.Success)
+
+	assert.NilError(t, os.Remove(workdir.Join("file.txt")))
+
+	result = icmd.RunCmd(s5cmd("bisync", "--state-dir", stateDir, src, dst))
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`local directory is empty but it had 1 files in the last synchronization`),
+	})
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "file.txt", "this is a file"))
+}
+
+func TestBisyncWrongArguments(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	workdir := fs.NewDir(t, "somedir")
+	defer workdir.Remove()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "remote to local",
+			args:     []string{"s3://bucket/prefix/", workdir.Path()},
+			expected: `expected a local directory and a remote prefix in order`,
+		},
+		{
+			name:     "wildcard",
+			args:     []string{workdir.Path(), "s3://bucket/prefix/*"},
+			expected: `arguments can not contain glob characters`,
+		},
+		{
+			name:     "remote object",
+			args:     []string{workdir.Path(), "s3://bucket/object"},
+			expected: `target "s3://bucket/object" must be a bucket or a prefix`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			result := icmd.RunCmd(s5cmd(append([]string{"bisync"}, tc.args...)...))
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: contains(tc.expected),
+			})
+		})
+	}
+}
@@ -548,3 +548,25 @@ func TestPrefixWildcardFail(t *testing.T) {
 		})
 	}
 }
+
+func TestCatDecompress(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// the encoding of the object without a content encoding is detected
+	// from its contents, the objects which are not compressed are printed as
+	// is.
+	putFile(t, s3client, bucket, "a.gz", gzipContent(t, "compressed content, "))
+	putFile(t, s3client, bucket, "b.txt", "plain content")
+
+	cmd := s5cmd("cat", "--decompress", fmt.Sprintf("s3://%v/*", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("compressed content, plain content"),
+	}, alignment(true))
+}
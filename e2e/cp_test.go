@@ -23,6 +23,9 @@
 package e2e
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	jsonpkg "encoding/json"
 	"fmt"
 	"net"
 	"net/http"
@@ -35,6 +38,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
 	"gotest.tools/v3/icmd"
@@ -844,6 +848,194 @@ func TestCopySingleFileToS3WithArbitraryMetadata(t *testing.T) {
 	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureArbitraryMetadata(metadata)))
 }
 
+// cp --preserve=metadata,storage-class --metadata key2=val2 s3://bucket/obj s3://bucket/obj_cp
+func TestCopyS3ToS3WithPreserve(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename     = "index"
+		content      = "things"
+		storageClass = "STANDARD_IA"
+	)
+
+	srcmetadata := map[string]*string{
+		"Key1": aws.String("value1"),
+		"Key2": aws.String("value2"),
+	}
+
+	// metadata given with flags takes precedence over the preserved one.
+	dstmetadata := map[string]*string{
+		"Key1": aws.String("value1"),
+		"Key2": aws.String("bar"),
+	}
+
+	srcpath := fmt.Sprintf("s3://%v/%v", bucket, filename)
+	dstpath := fmt.Sprintf("s3://%v/%v_cp", bucket, filename)
+
+	putFile(t, s3client, bucket, filename, content, putArbitraryMetadata(srcmetadata), putStorageClass(storageClass))
+	cmd := s5cmd("cp", "--preserve=metadata,storage-class", "--metadata", "Key2=bar", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// assert S3
+	assert.Assert(t, ensureS3Object(s3client, bucket, fmt.Sprintf("%s_cp", filename), content,
+		ensureArbitraryMetadata(dstmetadata), ensureStorageClass(storageClass)))
+}
+
+func TestCopyWithPreserveValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "unknown property",
+			args:     []string{"cp", "--preserve=owner", "s3://bucket/object", "s3://bucket/copy"},
+			expected: `ERROR "cp --preserve=owner s3://bucket/object s3://bucket/copy": unknown property to preserve: "owner", expected one of: metadata, tags, acl, storage-class`,
+		},
+		{
+			name:     "acl is given explicitly",
+			args:     []string{"cp", "--preserve=acl", "--acl=public-read", "s3://bucket/object", "s3://bucket/copy"},
+			expected: `ERROR "cp --acl=public-read --preserve=acl s3://bucket/object s3://bucket/copy": --acl and --preserve=acl flags can not be used together`,
+		},
+		{
+			name:     "download",
+			args:     []string{"cp", "--preserve=tags", "s3://bucket/object", "."},
+			expected: `ERROR "cp --preserve=tags s3://bucket/object .": --preserve flag is only supported for copies between remote storages`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// cp --tagging "key1=val1&key2=val2" file s3://bucket/
+func TestCopySingleFileToS3WithTagging(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "content"
+	)
+
+	workdir := fs.NewDir(t, bucket, fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--tagging", "team=storage&project=s5 cmd", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(`cp %v %v%v`, srcpath, dstpath, filename),
+	})
+
+	// assert S3
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureTagging("project=s5+cmd&team=storage")))
+}
+
+// cp --tagging "key1=val1" s3://bucket/object s3://bucket/copy
+func TestCopyS3ToS3WithTagging(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "index"
+		content  = "things"
+	)
+
+	srcpath := fmt.Sprintf("s3://%v/%v", bucket, filename)
+	dstpath := fmt.Sprintf("s3://%v/%v_cp", bucket, filename)
+
+	putFile(t, s3client, bucket, filename, content)
+	cmd := s5cmd("cp", "--tagging", "retention=short", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// assert S3
+	assert.Assert(t, ensureS3Object(s3client, bucket, fmt.Sprintf("%s_cp", filename), content, ensureTagging("retention=short")))
+}
+
+func TestCopyWithTaggingValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "invalid tagging",
+			args:     []string{"cp", "--tagging=key=%zz", "file", "s3://bucket/object"},
+			expected: `ERROR "cp --tagging=key=%%zz file s3://bucket/object": invalid tagging "key=%%zz": invalid URL escape "%%zz"`,
+		},
+		{
+			name:     "duplicate tag key",
+			args:     []string{"cp", "--tagging=key=a&key=b", "file", "s3://bucket/object"},
+			expected: `ERROR "cp --tagging=key=a&key=b file s3://bucket/object": invalid tagging "key=a&key=b": tag key "key" is given more than once`,
+		},
+		{
+			name:     "download",
+			args:     []string{"cp", "--tagging=key=value", "s3://bucket/object", "."},
+			expected: `ERROR "cp --tagging=key=value s3://bucket/object .": --tagging flag is only supported for remote destinations`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
 // cp s3://bucket2/obj2 s3://bucket1/obj1 --metadata key1=val1 --metadata key2=val2 ...
 func TestCopyS3ToS3WithArbitraryMetadataWithDefaultDirective(t *testing.T) {
 	t.Parallel()
@@ -4602,106 +4794,302 @@ func TestCopyS3ObjectsWithIncludeExcludeFilter2(t *testing.T) {
 	assert.Assert(t, fs.Equal(cmd.Dir, expected))
 }
 
-// cp --content-type "video/mp4" file s3://bucket/
-func TestCopySingleLocalFileToS3WithContentType(t *testing.T) {
+// cp dir/ s3://bucket/prefix/ (dir/.s5cmdignore and dir/a/.s5cmdignore)
+func TestCopyLocalDirectoryToS3WithIgnoreFile(t *testing.T) {
 	t.Parallel()
 
-	s3client, s5cmd := setup(t)
+	testcases := []struct {
+		name            string
+		directoryPrefix string
+	}{
+		{
+			name:            "folder with /",
+			directoryPrefix: "/",
+		},
+		{
+			name:            "folder with / and glob *",
+			directoryPrefix: "/*",
+		},
+	}
 
-	bucket := s3BucketFromTestName(t)
-	createBucket(t, s3client, bucket)
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
 
-	const (
-		filename = "testfile.txt"
-		content  = "this is a test file"
-	)
+			bucket := s3BucketFromTestName(t)
 
-	workdir := fs.NewDir(t, t.Name(), fs.WithFile(filename, content))
-	defer workdir.Remove()
+			s3client, s5cmd := setup(t)
 
-	srcpath := workdir.Join(filename)
-	dstpath := fmt.Sprintf("s3://%v/", bucket)
+			createBucket(t, s3client, bucket)
 
-	cmd := s5cmd("cp", "--content-type", "video/mp4", srcpath, dstpath)
+			folderLayout := []fs.PathOp{
+				fs.WithFile(".s5cmdignore", "*.log\n/readme.md\nbuild/\n**/cache/**\n"),
+				fs.WithFile("readme.md", "this is a readme file"),
+				fs.WithFile("debug.log", "this is a log file"),
+				fs.WithFile("main.go", "this is a go file"),
+				fs.WithDir(
+					"a",
+					fs.WithFile(".s5cmdignore", "!keep.log\n"),
+					fs.WithFile("readme.md", "this is another readme file"),
+					fs.WithFile("keep.log", "this log file is kept"),
+					fs.WithFile("other.log", "this is another log file"),
+					fs.WithDir("cache", fs.WithFile("data.bin", "this is a cached file")),
+				),
+				fs.WithDir(
+					"build",
+					fs.WithFile("out.bin", "this is a build output"),
+				),
+			}
 
-	result := icmd.RunCmd(cmd)
-	result.Assert(t, icmd.Success)
+			workdir := fs.NewDir(t, "somedir", folderLayout...)
+			defer workdir.Remove()
 
-	expected := fs.Expected(t, fs.WithFile(filename, content))
-	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+			src := fmt.Sprintf("%v%v", workdir.Path(), tc.directoryPrefix)
+			src = filepath.ToSlash(src)
+			dst := fmt.Sprintf("s3://%v/prefix/", bucket)
 
-	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureContentType("video/mp4")))
+			cmd := s5cmd("cp", src, dst)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			expectedS3Content := map[string]string{
+				"prefix/.s5cmdignore":   "*.log\n/readme.md\nbuild/\n**/cache/**\n",
+				"prefix/main.go":        "this is a go file",
+				"prefix/a/.s5cmdignore": "!keep.log\n",
+				"prefix/a/readme.md":    "this is another readme file",
+				"prefix/a/keep.log":     "this log file is kept",
+			}
+
+			nonExpectedS3Content := map[string]string{
+				"prefix/readme.md":        "this is a readme file",
+				"prefix/debug.log":        "this is a log file",
+				"prefix/a/other.log":      "this is another log file",
+				"prefix/a/cache/data.bin": "this is a cached file",
+				"prefix/build/out.bin":    "this is a build output",
+			}
+
+			// assert objects should be in S3
+			for key, content := range expectedS3Content {
+				assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+			}
+
+			// assert objects should not be in S3.
+			for key, content := range nonExpectedS3Content {
+				err := ensureS3Object(s3client, bucket, key, content)
+				assertError(t, err, errS3NoSuchKey)
+			}
+		})
+	}
 }
 
-// cp --content-type "video/avi" dir s3://bucket/
-func TestCopyMultipleFilesToS3BucketWithContentType(t *testing.T) {
+// --ignore-file "" cp dir/ s3://bucket/
+func TestCopyLocalDirectoryToS3WithIgnoreFileDisabled(t *testing.T) {
 	t.Parallel()
-	if runtime.GOOS == "windows" {
-		t.Skip("Files in Windows cannot contain glob(*) characters")
-	}
+
+	bucket := s3BucketFromTestName(t)
 
 	s3client, s5cmd := setup(t)
 
-	bucket := s3BucketFromTestName(t)
 	createBucket(t, s3client, bucket)
 
 	folderLayout := []fs.PathOp{
-		fs.WithFile("file1.html", "<html><body><h1>file1</h1></body></html>"),
-		fs.WithDir(
-			"a",
-			fs.WithFile("another_test_file.txt", "yet another txt file. yatf."),
-		),
+		fs.WithFile(".s5cmdignore", "*.log\n"),
+		fs.WithFile("debug.log", "this is a log file"),
 	}
 
 	workdir := fs.NewDir(t, "somedir", folderLayout...)
 	defer workdir.Remove()
 
-	src := fmt.Sprintf("%v/*", workdir.Path())
+	src := filepath.ToSlash(workdir.Path() + "/")
 	dst := fmt.Sprintf("s3://%v/", bucket)
 
-	cmd := s5cmd("cp", "--content-type", "video/avi", src, dst)
-
+	cmd := s5cmd("--ignore-file", "", "cp", src, dst)
 	result := icmd.RunCmd(cmd)
-	result.Assert(t, icmd.Success)
-
-	expected := fs.Expected(t, folderLayout...)
-	assert.Assert(t, fs.Equal(workdir.Path(), expected))
 
-	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals("cp %v/a/another_test_file.txt %sa/another_test_file.txt", workdir.Path(), dst),
-		1: equals("cp %v/file1.html %sfile1.html", workdir.Path(), dst),
-	}, sortInput(true))
+	result.Assert(t, icmd.Success)
 
-	assert.Assert(t, ensureS3Object(s3client, bucket, "file1.html", "<html><body><h1>file1</h1></body></html>", ensureContentType("video/avi")))
-	assert.Assert(t, ensureS3Object(s3client, bucket, "a/another_test_file.txt", "yet another txt file. yatf.", ensureContentType("video/avi")))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "debug.log", "this is a log file"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, ".s5cmdignore", "*.log\n"))
 }
 
-// cp --content-type "video/avi" s3://srcbucket/object s3://dstbucket/
-func TestCopySingleS3ObjectToAnotherBucketWithContentType(t *testing.T) {
+// cp --exclude-from exclude.txt --include-from include.txt s3://bucket/* .
+func TestCopyS3ObjectsWithExcludeFromAndIncludeFrom(t *testing.T) {
 	t.Parallel()
 
-	srcbucket := s3BucketFromTestNameWithPrefix(t, "src")
-	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
-
 	s3client, s5cmd := setup(t)
 
-	createBucket(t, s3client, srcbucket)
-	createBucket(t, s3client, dstbucket)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
 
-	const (
-		filename = "testfile.txt"
-		content  = "this is a test file"
-	)
+	const fileContent = "content"
 
-	putFile(t, s3client, srcbucket, filename, content)
+	files := [...]string{
+		"src/main.go",
+		"src/main_test.go",
+		"src/vendor/lib.go",
+		"docs/readme.md",
+		"main.go",
+		"vendor/lib.go",
+	}
 
-	src := fmt.Sprintf("s3://%v/%v", srcbucket, filename)
-	dst := fmt.Sprintf("s3://%v/", dstbucket)
+	for _, filename := range files {
+		putFile(t, s3client, bucket, filename, fileContent)
+	}
 
-	cmd := s5cmd("cp", "--content-type", "video/avi", src, dst)
-	result := icmd.RunCmd(cmd)
+	excludeFrom := fs.NewFile(t, "exclude", fs.WithContent("# tests and vendored files\n*_test.go\n/vendor/\n"))
+	defer excludeFrom.Remove()
 
-	result.Assert(t, icmd.Success)
+	includeFrom := fs.NewFile(t, "include", fs.WithContent("*.go\n"))
+	defer includeFrom.Remove()
+
+	srcpath := fmt.Sprintf("s3://%s", bucket)
+
+	cmd := s5cmd("cp", "--exclude-from", excludeFrom.Path(), "--include-from", includeFrom.Path(), srcpath+"/*", ".")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("cp %v/main.go main.go", srcpath),
+		1: equals("cp %v/src/main.go src/main.go", srcpath),
+		2: equals("cp %v/src/vendor/lib.go src/vendor/lib.go", srcpath),
+	}, sortInput(true))
+
+	expectedFileSystem := []fs.PathOp{
+		fs.WithFile("main.go", fileContent),
+		fs.WithDir(
+			"src",
+			fs.WithFile("main.go", fileContent),
+			fs.WithDir("vendor", fs.WithFile("lib.go", fileContent)),
+		),
+	}
+	// assert local filesystem
+	expected := fs.Expected(t, expectedFileSystem...)
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
+// cp --exclude-from missing.txt s3://bucket/* .
+func TestCopyWithMissingExcludeFromFile(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	cmd := s5cmd("cp", "--exclude-from", "missing.txt", "s3://"+bucket+"/*", ".")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`open missing.txt: no such file or directory`),
+	})
+}
+
+// cp --content-type "video/mp4" file s3://bucket/
+func TestCopySingleLocalFileToS3WithContentType(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "this is a test file"
+	)
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := workdir.Join(filename)
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--content-type", "video/mp4", srcpath, dstpath)
+
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	expected := fs.Expected(t, fs.WithFile(filename, content))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureContentType("video/mp4")))
+}
+
+// cp --content-type "video/avi" dir s3://bucket/
+func TestCopyMultipleFilesToS3BucketWithContentType(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("Files in Windows cannot contain glob(*) characters")
+	}
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	folderLayout := []fs.PathOp{
+		fs.WithFile("file1.html", "<html><body><h1>file1</h1></body></html>"),
+		fs.WithDir(
+			"a",
+			fs.WithFile("another_test_file.txt", "yet another txt file. yatf."),
+		),
+	}
+
+	workdir := fs.NewDir(t, "somedir", folderLayout...)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("%v/*", workdir.Path())
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--content-type", "video/avi", src, dst)
+
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	expected := fs.Expected(t, folderLayout...)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("cp %v/a/another_test_file.txt %sa/another_test_file.txt", workdir.Path(), dst),
+		1: equals("cp %v/file1.html %sfile1.html", workdir.Path(), dst),
+	}, sortInput(true))
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "file1.html", "<html><body><h1>file1</h1></body></html>", ensureContentType("video/avi")))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "a/another_test_file.txt", "yet another txt file. yatf.", ensureContentType("video/avi")))
+}
+
+// cp --content-type "video/avi" s3://srcbucket/object s3://dstbucket/
+func TestCopySingleS3ObjectToAnotherBucketWithContentType(t *testing.T) {
+	t.Parallel()
+
+	srcbucket := s3BucketFromTestNameWithPrefix(t, "src")
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	s3client, s5cmd := setup(t)
+
+	createBucket(t, s3client, srcbucket)
+	createBucket(t, s3client, dstbucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "this is a test file"
+	)
+
+	putFile(t, s3client, srcbucket, filename, content)
+
+	src := fmt.Sprintf("s3://%v/%v", srcbucket, filename)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("cp", "--content-type", "video/avi", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
 
 	assert.Assert(t, ensureS3Object(s3client, dstbucket, filename, content, ensureContentType("video/avi")))
 }
@@ -4739,3 +5127,568 @@ func TestCopyMultipleS3ObjectsToAnotherBucketWithContentType(t *testing.T) {
 		assert.Assert(t, ensureS3Object(s3client, dstbucket, filename, content, ensureContentType("video/avi")))
 	}
 }
+
+// cp --resume --part-size 5 file s3://bucket/
+func TestCopySingleFileToS3WithResume(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const filename = "testfile.txt"
+	content := strings.Repeat("0123456789abcdef", 12*1024*1024/16) // 3 parts of 5 MiB
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	cachedir := fs.NewDir(t, "cachedir")
+	defer cachedir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--resume", "--part-size", "5", srcpath, dstpath)
+	result := icmd.RunCmd(cmd, withEnv("XDG_CACHE_HOME", cachedir.Path()))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(`cp %v %v%v`, srcpath, dstpath, filename),
+	})
+
+	// assert S3
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+
+	// assert the state file is removed after the upload is completed
+	states, err := filepath.Glob(filepath.Join(cachedir.Path(), "s5cmd", "uploads", "*.json"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(states), 0)
+}
+
+// cp --resume --part-size 5 file s3://bucket/ continues an interrupted upload.
+func TestCopySingleFileToS3ResumesInterruptedUpload(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		partSize = 5 * 1024 * 1024
+	)
+	content := strings.Repeat("0123456789abcdef", 12*1024*1024/16) // 3 parts of 5 MiB
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	cachedir := fs.NewDir(t, "cachedir")
+	defer cachedir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	// upload the first part and leave the upload incomplete, as if the
+	// previous run is interrupted.
+	upload, err := s3client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(filename),
+	})
+	assert.NilError(t, err)
+
+	part, err := s3client.UploadPart(&s3.UploadPartInput{
+		Bucket:     aws.String(bucket),
+		Key:        aws.String(filename),
+		UploadId:   upload.UploadId,
+		PartNumber: aws.Int64(1),
+		Body:       strings.NewReader(content[:partSize]),
+	})
+	assert.NilError(t, err)
+
+	fi, err := os.Stat(workdir.Join(filename))
+	assert.NilError(t, err)
+
+	state, err := jsonpkg.Marshal(map[string]interface{}{
+		"source":    workdir.Join(filename),
+		"size":      fi.Size(),
+		"mod_time":  fi.ModTime().UTC(),
+		"bucket":    bucket,
+		"key":       filename,
+		"upload_id": aws.StringValue(upload.UploadId),
+		"part_size": partSize,
+		"parts": []map[string]interface{}{
+			{"part_number": 1, "etag": aws.StringValue(part.ETag), "size": partSize},
+		},
+	})
+	assert.NilError(t, err)
+
+	sum := sha256.Sum256([]byte(workdir.Join(filename) + "\n" + dstpath + filename))
+	statedir := filepath.Join(cachedir.Path(), "s5cmd", "uploads")
+	assert.NilError(t, os.MkdirAll(statedir, 0o700))
+	assert.NilError(t, os.WriteFile(filepath.Join(statedir, hex.EncodeToString(sum[:])+".json"), state, 0o600))
+
+	cmd := s5cmd("--log", "debug", "cp", "--resume", "--part-size", "5", srcpath, dstpath+filename)
+	result := icmd.RunCmd(cmd, withEnv("XDG_CACHE_HOME", cachedir.Path()))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(`cp %v %v%v`, srcpath, dstpath, filename),
+	})
+
+	// assert S3
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+
+	// assert the state file is removed after the upload is completed
+	states, err := filepath.Glob(filepath.Join(statedir, "*.json"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(states), 0)
+}
+
+// cp --resume s3://bucket/object s3://bucket/
+func TestCopyS3ToS3WithResumeMustFail(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("cp", "--resume", "s3://bucket/object", "s3://bucket/copy")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "cp --resume=true s3://bucket/object s3://bucket/copy": --resume flag is only supported for uploads and downloads`),
+	})
+}
+
+// cp --resume s3://bucket/object . continues an interrupted download.
+func TestCopySingleS3ObjectToLocalResumesInterruptedDownload(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const filename = "testfile.txt"
+	content := strings.Repeat("0123456789abcdef", 12*1024*1024/16)
+	putFile(t, s3client, bucket, filename, content)
+
+	object, err := s3client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(filename),
+	})
+	assert.NilError(t, err)
+
+	// the first 5 MiB is downloaded by the interrupted run. The rest of the
+	// partial file contains garbage that must be overwritten.
+	const downloaded = 5 * 1024 * 1024
+	partial := content[:downloaded] + strings.Repeat("x", 1024)
+
+	state, err := jsonpkg.Marshal(map[string]interface{}{
+		"source": fmt.Sprintf("s3://%v/%v", bucket, filename),
+		"etag":   strings.Trim(aws.StringValue(object.ETag), `"`),
+		"size":   len(content),
+		"ranges": []map[string]interface{}{
+			{"start": 0, "end": downloaded},
+		},
+	})
+	assert.NilError(t, err)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile(filename+".part", partial),
+		fs.WithFile(filename+".part.json", string(state)),
+	)
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/%v", bucket, filename)
+
+	cmd := s5cmd("cp", "--resume", "--part-size", "5", srcpath, ".")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v`, srcpath, filename),
+	})
+
+	// the partial file and its state are replaced by the downloaded file.
+	expected := fs.Expected(t, fs.WithFile(filename, content, fs.WithMode(0644)))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// cp --resume s3://bucket/object . restarts the download if the object is
+// modified after the interrupted run.
+func TestCopySingleS3ObjectToLocalWithResumeRestartsModifiedObject(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "this is the new content of the object"
+	)
+	putFile(t, s3client, bucket, filename, content)
+
+	state, err := jsonpkg.Marshal(map[string]interface{}{
+		"source": fmt.Sprintf("s3://%v/%v", bucket, filename),
+		"etag":   "0123456789abcdef0123456789abcdef",
+		"size":   len(content),
+		"ranges": []map[string]interface{}{
+			{"start": 0, "end": 10},
+		},
+	})
+	assert.NilError(t, err)
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile(filename+".part", "0123456789"),
+		fs.WithFile(filename+".part.json", string(state)),
+	)
+	defer workdir.Remove()
+
+	srcpath := fmt.Sprintf("s3://%v/%v", bucket, filename)
+
+	cmd := s5cmd("cp", "--resume", srcpath, ".")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v`, srcpath, filename),
+	})
+
+	expected := fs.Expected(t, fs.WithFile(filename, content, fs.WithMode(0644)))
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// cp --pack FORMAT dir/ s3://bucket/archive
+// cp --unpack s3://bucket/archive dir/
+func TestCopyPackDirToS3AndUnpack(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		format string
+		name   string
+	}{
+		{format: "tar", name: "archive.tar"},
+		{format: "tar.gz", name: "archive.tar.gz"},
+		{format: "tar.zst", name: "archive.tar.zst"},
+		{format: "zip", name: "archive.zip"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			s3client, s5cmd := setup(t)
+
+			bucket := s3BucketFromTestName(t)
+			createBucket(t, s3client, bucket)
+
+			workdir := fs.NewDir(t, "packdir",
+				fs.WithFile("file1.txt", "this is the first test file"),
+				fs.WithFile("debug.log", "this file is excluded"),
+				fs.WithDir("c",
+					fs.WithFile("file2.txt", "this is the second test file"),
+					fs.WithDir("d", fs.WithFile("file3.txt", "this is the third test file")),
+				),
+			)
+			defer workdir.Remove()
+
+			srcpath := filepath.ToSlash(workdir.Path()) + "/"
+			dstpath := fmt.Sprintf("s3://%v/%v", bucket, tc.name)
+
+			cmd := s5cmd("cp", "--pack", tc.format, "--exclude", "*.log", srcpath, dstpath)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals(`cp %v %v`, srcpath, dstpath),
+			})
+
+			outdir := fs.NewDir(t, "unpackdir")
+			defer outdir.Remove()
+
+			cmd = s5cmd("cp", "--unpack", "--exclude", "c/d/*", dstpath, "out/")
+			result = icmd.RunCmd(cmd, withWorkingDir(outdir))
+
+			result.Assert(t, icmd.Success)
+
+			assertLines(t, result.Stdout(), map[int]compareFunc{
+				0: equals(`cp %v out/`, dstpath),
+			})
+
+			expected := fs.Expected(t,
+				fs.WithDir("out",
+					fs.WithMode(0755),
+					fs.WithFile("file1.txt", "this is the first test file", fs.MatchAnyFileMode),
+					fs.WithDir("c",
+						fs.WithMode(0755),
+						fs.WithFile("file2.txt", "this is the second test file", fs.MatchAnyFileMode),
+					),
+				),
+			)
+			assert.Assert(t, fs.Equal(outdir.Path(), expected))
+		})
+	}
+}
+
+// cp --pack tar.gz 'dir/*.txt' s3://bucket/prefix/
+func TestCopyPackToS3PrefixMustFail(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "packdir", fs.WithFile("file1.txt", "content"))
+	defer workdir.Remove()
+
+	cmd := s5cmd("cp", "--pack", "tar.gz", workdir.Path()+"/*.txt", fmt.Sprintf("s3://%v/prefix/", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`must be an object`),
+	})
+}
+
+// cp --unpack s3://bucket/object.bin dir/
+func TestCopyUnpackUnknownFormatMustFail(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "object.bin", "content")
+
+	cmd := s5cmd("cp", "--unpack", fmt.Sprintf("s3://%v/object.bin", bucket), "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`unable to detect the archive format`),
+	})
+}
+
+// cp --compress gzip --compress-extension file s3://bucket/
+// cp --decompress s3://bucket/file.gz dir/
+func TestCopyCompressedFileToS3AndDecompress(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "this is a file which is compressed while it is uploaded"
+	)
+
+	workdir := fs.NewDir(t, "compressdir", fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Join(filename))
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("cp", "--compress", "gzip", "--compress-extension", srcpath, dstpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v%v.gz`, srcpath, dstpath, filename),
+	})
+
+	// the HTTP client of the SDK decompresses the objects with gzip content
+	// encoding, so the stored contents are read with cat.
+	assert.Equal(t, contentEncodingOf(t, s3client, bucket, filename+".gz"), "gzip")
+
+	result = icmd.RunCmd(s5cmd("cat", dstpath+filename+".gz"))
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), gzipContent(t, content))
+
+	cmd = s5cmd("cp", "--decompress", dstpath+filename+".gz", "out/")
+	result = icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v%v.gz out/%v`, dstpath, filename, filename),
+	})
+
+	expected := fs.Expected(t,
+		fs.WithFile(filename, content, fs.MatchAnyFileMode),
+		fs.WithDir("out",
+			fs.WithMode(0755),
+			fs.WithFile(filename, content, fs.MatchAnyFileMode),
+		),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// cp --compress gzip s3://bucket/object dir/
+func TestCopyCompressDownloadMustFail(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "object.txt", "content")
+
+	cmd := s5cmd("cp", "--compress", "gzip", fmt.Sprintf("s3://%v/object.txt", bucket), "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`--compress flag is only supported for uploads`),
+	})
+}
+
+// cp --from-inventory s3://inventory/manifest.json s3://bucket/* dir/
+func TestCopyFromInventory(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	inventory := bucket + "-inventory"
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, inventory)
+
+	putFile(t, s3client, bucket, "testfile1.txt", "this is a test file 1")
+	putFile(t, s3client, bucket, "a/readme.md", "this is a readme file")
+	// not in the inventory report
+	putFile(t, s3client, bucket, "b/another_test_file.txt", "yet another txt file")
+
+	manifest := putInventory(t, s3client, inventory, bucket, "testfile1.txt", "a/readme.md")
+
+	cmd := s5cmd("cp", "--from-inventory", manifest, "s3://"+bucket+"/*", "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/a/readme.md dir/a/readme.md`, bucket),
+		1: equals(`cp s3://%v/testfile1.txt dir/testfile1.txt`, bucket),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithDir("dir",
+			fs.WithFile("testfile1.txt", "this is a test file 1"),
+			fs.WithDir("a",
+				fs.WithFile("readme.md", "this is a readme file"),
+			),
+		),
+	)
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
+// cp --files-from keys.txt s3://bucket/* dir/
+func TestCopyFilesFrom(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "testfile1.txt", "this is a test file 1")
+	putFile(t, s3client, bucket, "a/readme.md", "this is a readme file")
+	putFile(t, s3client, bucket, "a/file with *.txt", "a file with glob characters")
+	// not in the key list
+	putFile(t, s3client, bucket, "b/another_test_file.txt", "yet another txt file")
+
+	keys := "testfile1.txt\na/readme.md\ns3://" + bucket + "/a/file with *.txt\n"
+	workdir := fs.NewDir(t, bucket, fs.WithFile("keys.txt", keys))
+	defer workdir.Remove()
+
+	cmd := s5cmd("cp", "--files-from", workdir.Join("keys.txt"), "s3://"+bucket+"/*", "dir/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%v/a/file with *.txt dir/a/file with *.txt`, bucket),
+		1: equals(`cp s3://%v/a/readme.md dir/a/readme.md`, bucket),
+		2: equals(`cp s3://%v/testfile1.txt dir/testfile1.txt`, bucket),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithDir("dir",
+			fs.WithFile("testfile1.txt", "this is a test file 1"),
+			fs.WithDir("a",
+				fs.WithFile("readme.md", "this is a readme file"),
+				fs.WithFile("file with *.txt", "a file with glob characters"),
+			),
+		),
+	)
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+}
+
+// cp --files-from keys.txt s3://bucket/prefix/ dir/
+func TestCopyFilesFromWithoutWildcardMustFail(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	cmd := s5cmd("cp", "--files-from", "-", "s3://"+bucket+"/prefix/object", "dir/")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(strings.NewReader("prefix/object\n")))
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`--files-from flag expects a source with wildcard characters`),
+	})
+}
+
+// --log trace cp --files-from keys.txt s3://bucket/* s3://bucket/copy/
+func TestCopyFilesFromPlainKeysS3ToS3(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "testfile1.txt", "this is a test file 1")
+	putFile(t, s3client, bucket, "a/readme.md", "this is a readme file")
+
+	keys := "testfile1.txt\na/readme.md\n"
+	workdir := fs.NewDir(t, bucket, fs.WithFile("keys.txt", keys))
+	defer workdir.Remove()
+
+	dst := fmt.Sprintf("s3://%v/copy/", bucket)
+	cmd := s5cmd("--log", "trace", "cp", "--files-from", workdir.Join("keys.txt"), "s3://"+bucket+"/*", dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// sizes of the plain keys are not known, they are required to decide
+	// whether the objects can be copied with a single request.
+	output := result.Combined()
+	for _, key := range []string{"testfile1.txt", "a/readme.md"} {
+		assert.Assert(t, strings.Contains(output, fmt.Sprintf("HEAD /%v/%v", bucket, key)), key)
+	}
+
+	assert.Assert(t, ensureS3Object(s3client, bucket, "copy/testfile1.txt", "this is a test file 1"))
+	assert.Assert(t, ensureS3Object(s3client, bucket, "copy/a/readme.md", "this is a readme file"))
+}
@@ -0,0 +1,229 @@
+package e2e
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/icmd"
+)
+
+const (
+	cseKey        = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	cseOtherKey   = "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100"
+	csePassphrase = "correct horse battery staple"
+)
+
+// getRawObject returns the content and the user metadata of the object as
+// stored in the bucket.
+func getRawObject(t *testing.T, client *s3.S3, bucket, key string) (string, map[string]*string) {
+	t.Helper()
+
+	output, err := client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	assert.NilError(t, err)
+	defer output.Body.Close()
+
+	body, err := io.ReadAll(output.Body)
+	assert.NilError(t, err)
+	return string(body), output.Metadata
+}
+
+// cp --cse-key-file key file s3://bucket/
+// cp --cse-key-file key s3://bucket/file file
+// cat --cse-key-file key s3://bucket/file
+func TestCopyWithClientSideEncryption(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// larger than a segment to cover the segment boundaries.
+	content := strings.Repeat("s5cmd client-side encryption\n", 5000)
+
+	keys := fs.NewDir(t, "keys", fs.WithFile("key", cseKey), fs.WithFile("other", cseOtherKey))
+	defer keys.Remove()
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile("file.txt", content))
+	defer workdir.Remove()
+
+	dst := fmt.Sprintf("s3://%v/file.txt", bucket)
+
+	cmd := s5cmd("cp", "--cse-key-file", keys.Join("key"), workdir.Join("file.txt"), dst)
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	// the object is stored encrypted.
+	raw, metadata := getRawObject(t, s3client, bucket, "file.txt")
+	assert.Assert(t, raw != content)
+	assert.Assert(t, !strings.Contains(raw, "client-side encryption"))
+	assert.Equal(t, aws.StringValue(metadata["S5cmd-Cse-Algorithm"]), "AES-256-GCM-64K")
+
+	cmd = s5cmd("cp", "--cse-key-file", keys.Join("key"), dst, "downloaded.txt")
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v downloaded.txt`, dst),
+	})
+
+	expected := fs.Expected(t, fs.WithFile("downloaded.txt", content, fs.WithMode(0644)))
+	assert.Assert(t, fs.Equal(cmd.Dir, expected))
+
+	cmd = s5cmd("cat", "--cse-key-file", keys.Join("key"), dst)
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), content)
+
+	// objects are printed as is without the key.
+	cmd = s5cmd("cat", dst)
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), raw)
+
+	cmd = s5cmd("cat", "--cse-key-file", keys.Join("other"), dst)
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`object is encrypted with a different key`),
+	})
+}
+
+// pipe --cse-passphrase-file passphrase s3://bucket/object
+// cat --cse-passphrase-file passphrase s3://bucket/object
+func TestPipeWithClientSideEncryption(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	content := strings.Repeat("0123456789", 10000)
+
+	keys := fs.NewDir(t, "keys", fs.WithFile("passphrase", csePassphrase+"\n"))
+	defer keys.Remove()
+
+	dst := fmt.Sprintf("s3://%v/object", bucket)
+
+	cmd := s5cmd("pipe", "--cse-passphrase-file", keys.Join("passphrase"), dst)
+	result := icmd.RunCmd(cmd, icmd.WithStdin(strings.NewReader(content)))
+	result.Assert(t, icmd.Success)
+
+	raw, metadata := getRawObject(t, s3client, bucket, "object")
+	assert.Assert(t, raw != content)
+	assert.Assert(t, aws.StringValue(metadata["S5cmd-Cse-Kdf"]) != "")
+
+	cmd = s5cmd("cat", "--cse-passphrase-file", keys.Join("passphrase"), dst)
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), content)
+}
+
+// sync --cse-key-file key dir/ s3://bucket/
+func TestSyncWithClientSideEncryption(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	keys := fs.NewDir(t, "keys", fs.WithFile("key", cseKey))
+	defer keys.Remove()
+
+	workdir := fs.NewDir(t, t.Name(),
+		fs.WithFile("a.txt", "content of a"),
+		fs.WithFile("b.txt", strings.Repeat("b", 100000)),
+	)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("%v/", workdir.Path())
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--cse-key-file", keys.Join("key"), src, dst)
+	result := icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va.txt %va.txt`, src, dst),
+		1: equals(`cp %vb.txt %vb.txt`, src, dst),
+	}, sortInput(true))
+
+	// encrypted objects are compared by their plaintext sizes.
+	cmd = s5cmd("sync", "--size-only", "--cse-key-file", keys.Join("key"), src, dst)
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+}
+
+func TestClientSideEncryptionValidation(t *testing.T) {
+	t.Parallel()
+
+	// the directory is removed after the parallel subtests are completed.
+	keys := fs.NewDir(t, "keys", fs.WithFile("key", cseKey), fs.WithFile("short", "0123"))
+
+	key := keys.Join("key")
+	short := keys.Join("short")
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "key file and passphrase file",
+			args:     []string{"cp", "--cse-key-file=" + key, "--cse-passphrase-file=" + key, "file.txt", "s3://bucket/"},
+			expected: fmt.Sprintf(`ERROR "cp --cse-key-file=%v --cse-passphrase-file=%v file.txt s3://bucket/": --cse-key-file and --cse-passphrase-file flags can not be used together`, key, key),
+		},
+		{
+			name:     "remote to remote",
+			args:     []string{"cp", "--cse-key-file=" + key, "s3://bucket/file.txt", "s3://bucket/copy.txt"},
+			expected: fmt.Sprintf(`ERROR "cp --cse-key-file=%v s3://bucket/file.txt s3://bucket/copy.txt": client-side encryption is only supported for uploads and downloads`, key),
+		},
+		{
+			name:     "resume",
+			args:     []string{"cp", "--resume", "--cse-key-file=" + key, "s3://bucket/file.txt", "file.txt"},
+			expected: fmt.Sprintf(`ERROR "cp --resume=true --cse-key-file=%v s3://bucket/file.txt file.txt": --resume flag can not be used with client-side encryption`, key),
+		},
+		{
+			name:     "invalid key",
+			args:     []string{"cat", "--cse-key-file=" + short, "s3://bucket/file.txt"},
+			expected: fmt.Sprintf(`ERROR "cat --cse-key-file=%v s3://bucket/file.txt": invalid key file "%v": key must be 32 bytes, either raw or hex/base64 encoded`, short, short),
+		},
+		{
+			name:     "checksum",
+			args:     []string{"sync", "--checksum", "--cse-key-file=" + key, "dir/", "s3://bucket/"},
+			expected: fmt.Sprintf(`ERROR "sync --checksum=true --cse-key-file=%v dir/ s3://bucket/": --checksum flag can not be used with client-side encryption`, key),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
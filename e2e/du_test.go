@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"gotest.tools/v3/fs"
 	"gotest.tools/v3/icmd"
 )
 
@@ -198,6 +199,34 @@ func TestDiskUsageWildcardWithExcludeFilter(t *testing.T) {
 	})
 }
 
+// du --exclude-from exclude.txt s3://bucket/*
+func TestDiskUsageWildcardWithExcludeFrom(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd := setup(t)
+
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "testfile1.txt", "this is a file content")
+	putFile(t, s3client, bucket, "main.txt", "this is also a file content")
+	putFile(t, s3client, bucket, "main.py", "this is a python file")
+	putFile(t, s3client, bucket, "foo/testfile3.txt", "this is also a file content somehow")
+	putFile(t, s3client, bucket, "bar/testfile3.gz", "this is also a file content somehow")
+
+	excludeFrom := fs.NewFile(t, "exclude", fs.WithContent("main*\nbar/\n"))
+	defer excludeFrom.Remove()
+
+	cmd := s5cmd("du", "--exclude-from", excludeFrom.Path(), "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(`57 bytes in 2 objects: s3://%v/*`, bucket),
+	})
+}
+
 // du --exclude "main*" --exclude "*.gz" s3://bucket/*
 func TestDiskUsageWildcardWithExcludeFilters(t *testing.T) {
 	t.Parallel()
@@ -318,3 +347,48 @@ func TestDiskUsageEmptyBucket(t *testing.T) {
 		0: suffix(`0 bytes in 0 objects: s3://%v`, bucket),
 	})
 }
+
+// du --from-inventory s3://inventory/manifest.json s3://bucket/*
+func TestDiskUsageFromInventory(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	inventory := bucket + "-inventory"
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, inventory)
+
+	manifest := putInventory(t, s3client, inventory, bucket, "testfile1.txt", "testfile2.txt", "testfile3.gz")
+
+	cmd := s5cmd("du", "--from-inventory", manifest, "s3://"+bucket+"/*.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(`2 bytes in 2 objects: s3://%v/*.txt`, bucket),
+	})
+}
+
+// du --files-from - s3://bucket/*
+func TestDiskUsageFilesFrom(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "testfile1.txt", "this is a file content")
+	putFile(t, s3client, bucket, "testfile2.txt", "this is also a file content")
+	putFile(t, s3client, bucket, "testfile3.txt", "this is not in the key list")
+
+	cmd := s5cmd("du", "--files-from", "-", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd, icmd.WithStdin(strings.NewReader("testfile1.txt\ntestfile2.txt\n")))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix(`49 bytes in 2 objects: s3://%v/*`, bucket),
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
 	"gotest.tools/v3/icmd"
 )
@@ -188,6 +189,27 @@ func TestListS3ObjectsWithDashS(t *testing.T) {
 	// TODO: test if full form of storage class is displayed (it can be done when and if gofakes3 supports storage classes)
 }
 
+// ls --restore-status bucket/*
+func TestListS3ObjectsWithRestoreStatus(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "testfile1.txt", "this is a file content", putStorageClass("GLACIER"))
+
+	cmd := s5cmd("ls", "--restore-status", "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the fake S3 server doesn't report restore status of the objects.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("22 testfile1.txt"),
+	})
+}
+
 // ls bucket/*/object*.ext
 func TestListMultipleWildcardS3Object(t *testing.T) {
 	t.Parallel()
@@ -668,6 +690,81 @@ func TestListLocalFilesWithExcludeFilter(t *testing.T) {
 	}
 }
 
+// ls directory/ (directory/.s5cmdignore)
+func TestListLocalFilesWithIgnoreFile(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	folderLayout := []fs.PathOp{
+		fs.WithFile(".s5cmdignore", "/.s5cmdignore\n*.pyc\nmain/\n"),
+		fs.WithDir(
+			"main",
+			fs.WithFile("try.txt", "this is a txt file"),
+		),
+		fs.WithDir(
+			"lib",
+			fs.WithFile(".s5cmdignore", "!/cache.pyc\n"),
+			fs.WithFile("cache.pyc", "this is a compiled python file"),
+			fs.WithFile("util.pyc", "this is a compiled python file"),
+		),
+		fs.WithFile("main.py", "this is a python file"),
+		fs.WithFile("main.pyc", "this is a compiled python file"),
+	}
+
+	workdir := fs.NewDir(t, t.Name(), folderLayout...)
+	defer workdir.Remove()
+
+	srcpath := filepath.ToSlash(workdir.Path() + "/")
+
+	cmd := s5cmd("ls", srcpath)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match("lib/.s5cmdignore"),
+		1: match("lib/cache.pyc"),
+		2: match("main.py"),
+	}, trimMatch(dateRe), alignment(true))
+}
+
+// ls --exclude-from exclude.txt s3://bucket/*
+func TestListS3ObjectsWithExcludeFrom(t *testing.T) {
+	t.Parallel()
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd := setup(t)
+
+	createBucket(t, s3client, bucket)
+
+	filenames := []string{
+		"file.txt",
+		"file.py",
+		"a/try.txt",
+		"a/try.py",
+		"b/c/file.txt",
+	}
+
+	for _, filename := range filenames {
+		putFile(t, s3client, bucket, filename, "content")
+	}
+
+	excludeFrom := fs.NewFile(t, "exclude", fs.WithContent("*.txt\n!/file.txt\na/\n"))
+	defer excludeFrom.Remove()
+
+	cmd := s5cmd("ls", "--exclude-from", excludeFrom.Path(), "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: match(`file.py`),
+		1: match(`file.txt`),
+	}, trimMatch(dateRe), alignment(false))
+}
+
 // ls --exclude "main*" --exclude ".txt" directory/
 func TestListLocalFilesWithExcludeFilters(t *testing.T) {
 	t.Parallel()
@@ -802,3 +899,91 @@ func TestEmptyBucket(t *testing.T) {
 
 	assertLines(t, result.Stdout(), nil)
 }
+
+// ls --from-inventory s3://inventory/manifest.json s3://bucket/*
+func TestListFromInventory(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	inventory := bucket + "-inventory"
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, inventory)
+
+	putFile(t, s3client, bucket, "a/testfile1.txt", "content")
+	putFile(t, s3client, bucket, "a/test file2.txt", "content")
+	putFile(t, s3client, bucket, "b/testfile3.txt", "content")
+	// not in the inventory report
+	putFile(t, s3client, bucket, "a/testfile4.txt", "content")
+
+	manifest := putInventory(t, s3client, inventory, bucket, "a/testfile1.txt", "a/test file2.txt", "b/testfile3.txt")
+
+	cmd := s5cmd("ls", "--from-inventory", manifest, "s3://"+bucket+"/a/*.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("1 testfile1.txt"),
+		1: suffix("1 test file2.txt"),
+	})
+
+	cmd = s5cmd("ls", "--from-inventory", manifest, "s3://"+bucket)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: suffix("DIR a/"),
+		1: suffix("DIR b/"),
+	}, alignment(true))
+}
+
+// ls --from-inventory s3://inventory/manifest.json s3://other-bucket/*
+func TestListFromInventoryOfAnotherBucket(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	inventory := bucket + "-inventory"
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, inventory)
+
+	manifest := putInventory(t, s3client, inventory, "other-bucket", "testfile.txt")
+
+	cmd := s5cmd("ls", "--from-inventory", manifest, "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`is the report of bucket "other-bucket", not %q`, bucket),
+	})
+}
+
+// --list-partitions ls bucket/*
+func TestListWithListPartitions(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	for i := 0; i < 20; i++ {
+		putFile(t, s3client, bucket, fmt.Sprintf("%c/testfile%d.txt", 'a'+i%5, i), "content")
+		putFile(t, s3client, bucket, fmt.Sprintf("%x.log", i*7919), "content")
+	}
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+
+	expected := icmd.RunCmd(s5cmd("ls", src))
+	expected.Assert(t, icmd.Success)
+
+	result := icmd.RunCmd(s5cmd("--list-partitions", "4", "ls", src))
+	result.Assert(t, icmd.Success)
+
+	assert.Equal(t, strings.Count(result.Stdout(), "\n"), 40)
+	assert.Equal(t, result.Stdout(), expected.Stdout())
+}
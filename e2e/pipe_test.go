@@ -529,3 +529,70 @@ func TestUploadStdinToToS3WithAllMetadataFlags(t *testing.T) {
 		ensureEncryptionKeyID(EncryptionKeyID),
 	))
 }
+
+func TestPipeToS3WithTagging(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "index.txt"
+		content  = "content"
+	)
+
+	reader := bytes.NewBufferString(content)
+
+	dstpath := fmt.Sprintf("s3://%v/%v", bucket, filename)
+
+	cmd := s5cmd("pipe", "--tagging", "team=storage&retention=short", dstpath)
+	result := icmd.RunCmd(cmd, icmd.WithStdin(reader))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`pipe %v`, dstpath),
+	})
+
+	// assert S3
+	assert.Assert(t, ensureS3Object(s3client, bucket, filename, content, ensureTagging("retention=short&team=storage")))
+}
+
+// pipe --compress zstd s3://bucket/object.zst
+func TestPipeCompressedToS3(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "stdin.txt.zst"
+		content  = "this is the content which is compressed with zstd"
+	)
+
+	dstpath := fmt.Sprintf("s3://%v/%v", bucket, filename)
+
+	cmd := s5cmd("pipe", "--compress", "zstd", dstpath)
+	result := icmd.RunCmd(cmd, icmd.WithStdin(bytes.NewBufferString(content)))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`pipe %v`, dstpath),
+	})
+
+	cmd = s5cmd("cat", dstpath)
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+	assert.Assert(t, result.Stdout() != content)
+
+	cmd = s5cmd("cat", "--decompress", dstpath)
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(content),
+	}, alignment(true))
+}
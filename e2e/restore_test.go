@@ -0,0 +1,96 @@
+package e2e
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/v3/icmd"
+)
+
+// --dry-run restore "s3://bucket/*"
+func TestRestoreWithWildcardDryRun(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "archived.txt", "content", putStorageClass("GLACIER"))
+	putFile(t, s3client, bucket, "deep.txt", "content", putStorageClass("DEEP_ARCHIVE"))
+	putFile(t, s3client, bucket, "standard.txt", "content")
+
+	cmd := s5cmd("--dry-run", "restore", "--days", "7", "--tier", "Bulk", fmt.Sprintf("s3://%v/*", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// objects which are not archived are skipped.
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`restore s3://%v/archived.txt`, bucket),
+		1: equals(`restore s3://%v/deep.txt`, bucket),
+	}, sortInput(true))
+}
+
+func TestRestoreValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "no source",
+			args:     []string{"restore"},
+			expected: `ERROR "restore": expected only 1 argument`,
+		},
+		{
+			name:     "non-positive days",
+			args:     []string{"restore", "--days=0", "s3://bucket/object"},
+			expected: `ERROR "restore --days=0 s3://bucket/object": --days must be a positive number`,
+		},
+		{
+			name:     "local source",
+			args:     []string{"restore", "file.txt"},
+			expected: `ERROR "restore file.txt": source must be a remote object`,
+		},
+		{
+			name:     "bucket",
+			args:     []string{"restore", "s3://bucket"},
+			expected: `ERROR "restore s3://bucket": source argument must contain wildcard character`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+func TestRestoreWithInvalidTier(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("restore", "--tier", "Fast", "s3://bucket/object")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{
+		ExitCode: 1,
+		Out:      `invalid value "Fast" for flag -tier: allowed values: [Expedited, Standard, Bulk]`,
+	})
+}
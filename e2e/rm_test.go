@@ -851,6 +851,55 @@ func TestRemoveMultipleS3ObjectsWithExcludeFilter(t *testing.T) {
 	}
 }
 
+// rm --exclude-from exclude.txt --include-from include.txt s3://bucket/*
+func TestRemoveMultipleS3ObjectsWithExcludeFromAndIncludeFrom(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	filesToContent := map[string]string{
+		"tmp/a.txt":      "this is a temporary file",
+		"tmp/keep.txt":   "this is a temporary file to keep",
+		"tmp/x/b.txt":    "this is another temporary file",
+		"data/tmp/c.txt": "this is a data file",
+		"readme.md":      "this is a readme file",
+	}
+
+	excludeFrom := fs.NewFile(t, "exclude", fs.WithContent("keep.txt\n"))
+	defer excludeFrom.Remove()
+
+	includeFrom := fs.NewFile(t, "include", fs.WithContent("/tmp/**\n"))
+	defer includeFrom.Remove()
+
+	for filename, content := range filesToContent {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	cmd := s5cmd("rm", "--exclude-from", excludeFrom.Path(), "--include-from", includeFrom.Path(), "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm s3://%v/tmp/a.txt`, bucket),
+		1: equals(`rm s3://%v/tmp/x/b.txt`, bucket),
+	}, sortInput(true))
+
+	for _, filename := range []string{"tmp/keep.txt", "data/tmp/c.txt", "readme.md"} {
+		assert.Assert(t, ensureS3Object(s3client, bucket, filename, filesToContent[filename]))
+	}
+
+	for _, filename := range []string{"tmp/a.txt", "tmp/x/b.txt"} {
+		err := ensureS3Object(s3client, bucket, filename, filesToContent[filename])
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
 // rm --exclude "*.txt" "*.gz" s3://bucket/*
 func TestRemoveMultipleS3ObjectsWithExcludeFilters(t *testing.T) {
 	t.Parallel()
@@ -1453,3 +1502,197 @@ func TestRemoveS3ObjectsWithIncludeExcludeFilter2(t *testing.T) {
 		assert.Assert(t, ensureS3Object(s3client, bucket, f, fileContent))
 	}
 }
+
+// rm --from-inventory s3://inventory/manifest.json s3://bucket/*
+func TestRemoveFromInventory(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	inventory := bucket + "-inventory"
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, inventory)
+
+	const content = "content"
+
+	filesRemoved := []string{"testfile1.txt", "dir/testfile2.txt"}
+	// not in the inventory report
+	filesKept := []string{"testfile3.txt"}
+
+	for _, filename := range append(filesRemoved, filesKept...) {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	manifest := putInventory(t, s3client, inventory, bucket, filesRemoved...)
+
+	cmd := s5cmd("rm", "--from-inventory", manifest, "s3://"+bucket+"/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm s3://%v/dir/testfile2.txt`, bucket),
+		1: equals(`rm s3://%v/testfile1.txt`, bucket),
+	}, sortInput(true))
+
+	// assert s3 objects
+	for _, filename := range filesRemoved {
+		err := ensureS3Object(s3client, bucket, filename, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+	for _, filename := range filesKept {
+		assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+	}
+}
+
+// ls --json s3://bucket/* | rm --files-from - s3://bucket/*
+func TestRemoveFilesFromStdin(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const content = "content"
+
+	filesRemoved := []string{"testfile1.txt", "dir/testfile2.txt"}
+	filesKept := []string{"testfile3.gz", "dir/testfile4.gz"}
+
+	for _, filename := range append(filesRemoved, filesKept...) {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	cmd := s5cmd("--json", "ls", "s3://"+bucket+"/*.txt")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	// the keys are read both from the JSON output of ls and as plain keys.
+	keys := result.Stdout() + "testfile3.gz\n"
+
+	cmd = s5cmd("rm", "--files-from", "-", "s3://"+bucket+"/*.txt")
+	result = icmd.RunCmd(cmd, icmd.WithStdin(strings.NewReader(keys)))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm s3://%v/dir/testfile2.txt`, bucket),
+		1: equals(`rm s3://%v/testfile1.txt`, bucket),
+	}, sortInput(true))
+
+	// assert s3 objects
+	for _, filename := range filesRemoved {
+		err := ensureS3Object(s3client, bucket, filename, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+	for _, filename := range filesKept {
+		assert.Assert(t, ensureS3Object(s3client, bucket, filename, content))
+	}
+}
+
+// rm --backup-dir s3://bucket/trash/ s3://bucket/data/*
+func TestRemoveS3ObjectsWithBackupDir(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	s3Content := map[string]string{
+		"data/testfile1.txt":     "this is the first test file",
+		"data/dir/testfile2.txt": "this is the second test file",
+		"other/testfile3.txt":    "this is the third test file",
+	}
+	for filename, content := range s3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	cmd := s5cmd("--json", "--stat", "rm", "--backup-dir", "s3://"+bucket+"/trash/", "s3://"+bucket+"/data/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`{"operation":"backup","success":2,"error":0}`),
+		1: equals(`{"operation":"backup","success":true,"source":"s3://%v/data/dir/testfile2.txt","destination":"s3://%v/trash/dir/testfile2.txt"}`, bucket, bucket),
+		2: equals(`{"operation":"backup","success":true,"source":"s3://%v/data/testfile1.txt","destination":"s3://%v/trash/testfile1.txt"}`, bucket, bucket),
+		3: equals(`{"operation":"rm","success":1,"error":0}`),
+		4: equals(`{"operation":"rm","success":true,"source":"s3://%v/data/dir/testfile2.txt"}`, bucket),
+		5: equals(`{"operation":"rm","success":true,"source":"s3://%v/data/testfile1.txt"}`, bucket),
+	}, sortInput(true))
+
+	// the objects are deleted after they are backed up.
+	for _, filename := range []string{"data/testfile1.txt", "data/dir/testfile2.txt"} {
+		err := ensureS3Object(s3client, bucket, filename, s3Content[filename])
+		assertError(t, err, errS3NoSuchKey)
+	}
+
+	expectedS3Content := map[string]string{
+		"trash/testfile1.txt":     "this is the first test file",
+		"trash/dir/testfile2.txt": "this is the second test file",
+		"other/testfile3.txt":     "this is the third test file",
+	}
+	for key, content := range expectedS3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+}
+
+// rm --backup-dir trash/ data/
+func TestRemoveLocalDirectoryWithBackupDir(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	folderLayout := []fs.PathOp{
+		fs.WithDir(
+			"data",
+			fs.WithFile("file1.txt", "this is the first test file"),
+			fs.WithDir("dir", fs.WithFile("file2.txt", "this is the second test file")),
+		),
+	}
+
+	workdir := fs.NewDir(t, t.Name(), folderLayout...)
+	defer workdir.Remove()
+
+	cmd := s5cmd("rm", "--backup-dir", "trash/", "data/")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals("backup data/dir/file2.txt trash/dir/file2.txt"),
+		1: equals("backup data/file1.txt trash/file1.txt"),
+		2: equals("rm data/dir/file2.txt"),
+		3: equals("rm data/file1.txt"),
+	}, sortInput(true))
+
+	expected := fs.Expected(
+		t,
+		fs.WithDir("data", fs.WithDir("dir")),
+		fs.WithDir(
+			"trash",
+			fs.WithFile("file1.txt", "this is the first test file"),
+			fs.WithDir("dir", fs.WithFile("file2.txt", "this is the second test file")),
+		),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// rm --backup-dir s3://bucket/data/trash/ s3://bucket/data/*
+func TestRemoveS3ObjectsWithBackupDirUnderSource(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("rm", "--backup-dir", "s3://bucket/data/trash/", "s3://bucket/data/*")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "rm --backup-dir=s3://bucket/data/trash/ s3://bucket/data/*": --backup-dir "s3://bucket/data/trash/" can not be under "s3://bucket/data/*"`),
+	})
+}
@@ -0,0 +1,97 @@
+package e2e
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"gotest.tools/v3/fs"
+	"gotest.tools/v3/icmd"
+)
+
+const sseCustomerKey = "0123456789abcdef0123456789abcdef"
+
+func TestSSECustomerKeyValidation(t *testing.T) {
+	t.Parallel()
+
+	// the directory is removed after the parallel subtests are completed.
+	keys := fs.NewDir(t, "keys",
+		fs.WithFile("key", base64.StdEncoding.EncodeToString([]byte(sseCustomerKey))+"\n"),
+		fs.WithFile("short", "0123"),
+	)
+
+	key := keys.Join("key")
+	short := keys.Join("short")
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "key and key file",
+			args:     []string{"cp", "--sse-c-key=" + sseCustomerKey, "--sse-c-key-file=" + key, "file.txt", "s3://bucket/"},
+			expected: fmt.Sprintf(`ERROR "cp --sse-c-key=REDACTED --sse-c-key-file=%v file.txt s3://bucket/": --sse-c-key and --sse-c-key-file flags can not be used together`, key),
+		},
+		{
+			name:     "invalid key",
+			args:     []string{"cat", "--sse-c-key=0123", "s3://bucket/file.txt"},
+			expected: `ERROR "cat --sse-c-key=REDACTED s3://bucket/file.txt": invalid --sse-c-key: key must be 32 bytes, either raw or base64 encoded`,
+		},
+		{
+			name:     "invalid key file",
+			args:     []string{"head", "--sse-c-key-file=" + short, "s3://bucket/file.txt"},
+			expected: fmt.Sprintf(`ERROR "head --sse-c-key-file=%v s3://bucket/file.txt": invalid key file "%v": key must be 32 bytes, either raw or base64 encoded`, short, short),
+		},
+		{
+			name:     "sse and sse-c",
+			args:     []string{"cp", "--sse=aws:kms", "--sse-c-key-file=" + key, "file.txt", "s3://bucket/"},
+			expected: fmt.Sprintf(`ERROR "cp --sse=aws:kms --sse-c-key-file=%v file.txt s3://bucket/": --sse and --sse-c-key flags can not be used together`, key),
+		},
+		{
+			name:     "copy source key for download",
+			args:     []string{"cp", "--sse-c-copy-source-key-file=" + key, "s3://bucket/file.txt", "file.txt"},
+			expected: fmt.Sprintf(`ERROR "cp --sse-c-copy-source-key-file=%v s3://bucket/file.txt file.txt": --sse-c-copy-source-key flag is only supported for copies between remote storages`, key),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
+
+// keys are never sent over plain HTTP, the test server can not receive them.
+func TestSSECustomerKeyRequiresHTTPS(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	src := fmt.Sprintf("s3://%v/file.txt", bucket)
+
+	cmd := s5cmd("cat", "--sse-c-key", sseCustomerKey, src)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`cannot send SSE keys over HTTP`),
+	})
+}
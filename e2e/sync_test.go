@@ -1,6 +1,7 @@
 package e2e
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -1701,6 +1702,149 @@ func TestSyncLocalFolderToS3BucketSameObjectsSizeOnly(t *testing.T) {
 	}
 }
 
+// sync --checksum folder/ s3://bucket/
+func TestSyncLocalFolderToS3BucketSameObjectsChecksum(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	s3Content := map[string]string{
+		"test.py":                 "D: this is a python file",
+		"testfile.txt":            "D: this is an updated test file",
+		"readme.md":               "this is a readme file",
+		"a/another_test_file.txt": "yet another txt file",
+	}
+
+	for filename, content := range s3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	// local files are created after the remote objects, so they are newer.
+	folderLayout := []fs.PathOp{
+		fs.WithFile("test.py", "S: this is a python file"),    // remote has it, different content, size same
+		fs.WithFile("testfile.txt", "S: this is a test file"), // remote has it, but with different contents/size.
+		fs.WithFile("readme.md", "this is a readme file"),     // remote has it, same object.
+		fs.WithDir("a",
+			fs.WithFile("another_test_file.txt", "yet another txt file"), // remote has it, same object.
+		),
+		fs.WithDir("abc",
+			fs.WithDir("def",
+				fs.WithFile("main.py", "S: python file"), // remote does not have it
+			),
+		),
+	}
+
+	workdir := fs.NewDir(t, "somedir", folderLayout...)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("%v/", workdir.Path())
+	src = filepath.ToSlash(src)
+	dst := fmt.Sprintf("s3://%s/", bucket)
+
+	// log debug
+	cmd := s5cmd("--log", "debug", "sync", "--checksum", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`DEBUG "sync %va/another_test_file.txt %va/another_test_file.txt": object ETag matches`, src, dst),
+		1: equals(`DEBUG "sync %vreadme.md %vreadme.md": object ETag matches`, src, dst),
+		2: equals(`cp %vabc/def/main.py %vabc/def/main.py`, src, dst),
+		3: equals(`cp %vtest.py %vtest.py`, src, dst),
+		4: equals(`cp %vtestfile.txt %vtestfile.txt`, src, dst),
+	}, sortInput(true))
+
+	// expected folder structure without the timestamp.
+	expected := fs.Expected(t, folderLayout...)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	expectedS3Content := map[string]string{
+		"test.py":                 "S: this is a python file",
+		"testfile.txt":            "S: this is a test file",
+		"readme.md":               "this is a readme file",
+		"a/another_test_file.txt": "yet another txt file",
+		"abc/def/main.py":         "S: python file",
+	}
+
+	// assert s3
+	for key, content := range expectedS3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+}
+
+// sync --checksum s3://bucket/* folder/
+func TestSyncS3BucketToLocalFolderSameObjectsChecksum(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// local files are older than the remote objects.
+	folderLayout := []fs.PathOp{
+		fs.WithFile("test.py", "D: this is a python file"),
+		fs.WithFile("readme.md", "this is a readme file"),
+	}
+
+	workdir := fs.NewDir(t, "somedir", folderLayout...)
+	defer workdir.Remove()
+
+	s3Content := map[string]string{
+		"test.py":   "S: this is a python file", // different content, size same
+		"readme.md": "this is a readme file",    // same object.
+	}
+
+	for filename, content := range s3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	src := fmt.Sprintf("s3://%s/*", bucket)
+	dst := fmt.Sprintf("%v/", workdir.Path())
+	dst = filepath.ToSlash(dst)
+
+	cmd := s5cmd("--log", "debug", "sync", "--checksum", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`DEBUG "sync s3://%v/readme.md %vreadme.md": object ETag matches`, bucket, dst),
+		1: equals(`cp s3://%v/test.py %vtest.py`, bucket, dst),
+	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithFile("test.py", "S: this is a python file"),
+		fs.WithFile("readme.md", "this is a readme file"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
+
+// sync --size-only --checksum folder/ s3://bucket/
+func TestSyncSizeOnlyAndChecksumAreMutuallyExclusive(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("test.py", "content"))
+	defer workdir.Remove()
+
+	src := filepath.ToSlash(fmt.Sprintf("%v/", workdir.Path()))
+
+	cmd := s5cmd("sync", "--size-only", "--checksum", src, "s3://bucket/")
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --size-only=true --checksum=true %v s3://bucket/": --size-only and --checksum flags can not be used together`, src),
+	})
+}
+
 // sync --size-only s3://bucket/* s3://destbucket/
 func TestSyncS3BucketToS3BucketSizeOnly(t *testing.T) {
 	t.Parallel()
@@ -1948,8 +2092,8 @@ func TestSyncLocalToS3BucketWithDelete(t *testing.T) {
 	}
 }
 
-// sync --delete folder/ s3://bucket/*
-func TestSyncLocalToEmptyS3BucketWithDelete(t *testing.T) {
+// sync --delete --max-delete 2 folder/ s3://bucket/
+func TestSyncLocalToS3BucketWithDeleteExceedingMaxDelete(t *testing.T) {
 	t.Parallel()
 
 	now := time.Now()
@@ -1959,278 +2103,285 @@ func TestSyncLocalToEmptyS3BucketWithDelete(t *testing.T) {
 	createBucket(t, s3client, bucket)
 
 	folderLayout := []fs.PathOp{
-		fs.WithFile("contributing.md", "S: this is a readme file", fs.WithTimestamps(now, now)),
+		fs.WithFile("contributing.md", "S: this is a readme file", fs.WithTimestamps(now.Add(-time.Minute), now.Add(-time.Minute))),
 	}
 
 	workdir := fs.NewDir(t, "somedir", folderLayout...)
 	defer workdir.Remove()
 
+	s3Content := map[string]string{
+		"readme.md":    "D: this is a readme file",
+		"dir/main.py":  "D: this is a python file",
+		"testfile.txt": "D: this is a test file",
+	}
+
+	for filename, content := range s3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
 	src := fmt.Sprintf("%v/", workdir.Path())
 	src = filepath.ToSlash(src)
 	dst := fmt.Sprintf("s3://%v/", bucket)
 
-	cmd := s5cmd("sync", "--delete", src, dst)
+	cmd := s5cmd("sync", "--delete", "--max-delete", "2", src, dst)
 	result := icmd.RunCmd(cmd)
 
-	result.Assert(t, icmd.Success)
+	result.Assert(t, icmd.Expected{ExitCode: 1})
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
 		0: equals(`cp %vcontributing.md %vcontributing.md`, src, dst),
-	}, sortInput(true))
-
-	// assert local filesystem
-	expectedFiles := []fs.PathOp{
-		fs.WithFile("contributing.md", "S: this is a readme file"),
-	}
-	expected := fs.Expected(t, expectedFiles...)
-	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+	})
 
-	expectedS3Content := map[string]string{
-		"contributing.md": "S: this is a readme file",
-	}
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "rm %vdir/main.py": not deleted, the number of deletions exceeds the limit`, dst),
+		1: equals(`ERROR "rm %vreadme.md": not deleted, the number of deletions exceeds the limit`, dst),
+		2: equals(`ERROR "rm %vtestfile.txt": not deleted, the number of deletions exceeds the limit`, dst),
+		3: equals(`ERROR "sync --delete=true --max-delete=2 %v %v": 3 objects are to be deleted which exceeds --max-delete 2, no objects are deleted`, src, dst),
+	})
 
-	// assert s3 objects
-	for key, content := range expectedS3Content {
+	// the objects are copied but not deleted.
+	assert.Assert(t, ensureS3Object(s3client, bucket, "contributing.md", "S: this is a readme file"))
+	for key, content := range s3Content {
 		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
 	}
-}
-
-// sync --delete s3://bucket/* s3://destbucket/
-func TestSyncS3BucketToS3BucketWithDelete(t *testing.T) {
-	t.Parallel()
-
-	s3client, s5cmd := setup(t)
-
-	bucket := s3BucketFromTestName(t)
-	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
-	createBucket(t, s3client, bucket)
-	createBucket(t, s3client, dstbucket)
-
-	sourceS3Content := map[string]string{
-		"readme.md":    "S: this is a readme file",
-		"dir/main.py":  "S: this is a python file",
-		"testfile.txt": "S: this is a test file",
-	}
-
-	destS3Content := map[string]string{
-		"main.md":      "D: this is a readme file",
-		"dir/test.py":  "D: this is a python file",
-		"testfile.txt": "D: this is an updated test file", // different size from source
-		"Makefile":     "D: this is a makefile",
-	}
-
-	for filename, content := range sourceS3Content {
-		putFile(t, s3client, bucket, filename, content)
-	}
-
-	for filename, content := range destS3Content {
-		putFile(t, s3client, dstbucket, filename, content)
-	}
 
-	src := fmt.Sprintf("s3://%v/", bucket)
-	dst := fmt.Sprintf("s3://%v/", dstbucket)
-
-	cmd := s5cmd("sync", "--delete", "--size-only", src+"*", dst)
-	result := icmd.RunCmd(cmd)
+	// the deletions within the limit are performed.
+	cmd = s5cmd("sync", "--delete", "--max-delete", "3", src, dst)
+	result = icmd.RunCmd(cmd)
 
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`cp %vdir/main.py %vdir/main.py`, src, dst),
-		1: equals(`cp %vreadme.md %vreadme.md`, src, dst),
-		2: equals(`cp %vtestfile.txt %vtestfile.txt`, src, dst),
-		3: equals(`rm %vMakefile`, dst),
-		4: equals(`rm %vdir/test.py`, dst),
-		5: equals(`rm %vmain.md`, dst),
+		0: equals(`rm %vdir/main.py`, dst),
+		1: equals(`rm %vreadme.md`, dst),
+		2: equals(`rm %vtestfile.txt`, dst),
 	}, sortInput(true))
 
-	expectedDestS3Content := map[string]string{
-		"testfile.txt": "S: this is a test file", // same as source bucket.
-		"readme.md":    "S: this is a readme file",
-		"dir/main.py":  "S: this is a python file",
-	}
-
-	nonExpectedDestS3Content := map[string]string{
-		"dir/test.py": "S: this is a python file",
-		"main.md":     "D: this is a readme file",
-		"Makefile":    "S: this is a makefile",
-	}
-
-	// assert s3 objects in source.
-	for key, content := range sourceS3Content {
-		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
-	}
-
-	// assert s3 objects in destination. (should be)
-	for key, content := range expectedDestS3Content {
-		assert.Assert(t, ensureS3Object(s3client, dstbucket, key, content))
-	}
-
-	// assert s3 objects should be deleted.
-	for key, content := range nonExpectedDestS3Content {
-		err := ensureS3Object(s3client, dstbucket, key, content)
+	for key, content := range s3Content {
+		err := ensureS3Object(s3client, bucket, key, content)
 		if err == nil {
-			t.Errorf("File %v is not deleted in remote : %v\n", key, err)
+			t.Errorf("File %v is not deleted from remote : %v\n", key, err)
 		}
 	}
 }
 
-// sync s3://bucket/*.txt folder/
-func TestSyncS3toLocalWithWildcard(t *testing.T) {
+// sync --delete --max-delete-percent 50 s3://bucket/* folder/
+func TestSyncS3BucketToLocalWithDeleteExceedingMaxDeletePercent(t *testing.T) {
 	t.Parallel()
+
 	now := time.Now()
-	timeSource := newFixedTimeSource(now)
-	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+	s3client, s5cmd := setup(t)
 
 	bucket := s3BucketFromTestName(t)
 	createBucket(t, s3client, bucket)
 
-	// make local (destination) older.
-	timestamp := fs.WithTimestamps(
-		now.Add(-time.Minute), // access time
-		now.Add(-time.Minute), // mod time
-	)
+	putFile(t, s3client, bucket, "readme.md", "this is a readme file")
 
-	// even though test.py exists in the source, since '*.txt' wildcard
-	// used, test.py will not be in the source, because all of the source
-	// files will be with extension '*.txt' therefore test.py will be deleted.
+	// ensure destination is older.
+	timestamp := fs.WithTimestamps(now.Add(-time.Minute), now.Add(-time.Minute))
 	folderLayout := []fs.PathOp{
-		fs.WithFile("test.py", "D: this is a python file", timestamp),
-		fs.WithFile("test.txt", "D: this is a test file", timestamp),
-	}
-
-	s3Content := map[string]string{
-		"test.txt":          "S: this is an updated test file",
-		"readme.md":         "S: this is a readme file",
-		"main.py":           "S: py file",
-		"subfolder/sub.txt": "S: yet another txt",
-		"test.py":           "S: this is a python file",
-	}
-
-	for filename, content := range s3Content {
-		putFile(t, s3client, bucket, filename, content)
+		fs.WithFile("readme.md", "this is a readme file", timestamp),
+		fs.WithFile("main.py", "D: this is a python file", timestamp),
+		fs.WithFile("testfile.txt", "D: this is a test file", timestamp),
 	}
 
 	workdir := fs.NewDir(t, "somedir", folderLayout...)
 	defer workdir.Remove()
 
-	src := fmt.Sprintf("s3://%v/", bucket)
+	src := fmt.Sprintf("s3://%v/*", bucket)
 	dst := fmt.Sprintf("%v/", workdir.Path())
 	dst = filepath.ToSlash(dst)
 
-	cmd := s5cmd("--log", "debug", "sync", "--delete", src+"*.txt", dst)
+	cmd := s5cmd("sync", "--size-only", "--delete", "--max-delete-percent", "50", src, dst)
 	result := icmd.RunCmd(cmd)
 
-	result.Assert(t, icmd.Success)
+	result.Assert(t, icmd.Expected{ExitCode: 1})
 
-	assertLines(t, result.Stdout(), map[int]compareFunc{
-		1: equals(`cp %vtest.txt %vtest.txt`, src, dst),
-		0: equals(`cp %vsubfolder/sub.txt %vsubfolder/sub.txt`, src, dst),
-		2: equals(`rm %vtest.py`, dst),
-	}, sortInput(true))
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
 
-	expectedLayout := []fs.PathOp{
-		fs.WithFile("test.txt", "S: this is an updated test file"),
-		fs.WithDir("subfolder",
-			fs.WithFile("sub.txt", "S: yet another txt"),
-		),
-	}
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "rm %vmain.py": not deleted, the number of deletions exceeds the limit`, dst),
+		1: equals(`ERROR "rm %vtestfile.txt": not deleted, the number of deletions exceeds the limit`, dst),
+		2: equals(`ERROR "sync --delete=true --max-delete-percent=50 --size-only=true %v %v": 2 of 3 objects in destination are to be deleted which exceeds --max-delete-percent 50, no objects are deleted`, src, dst),
+	})
 
-	expected := fs.Expected(t, expectedLayout...)
+	expected := fs.Expected(t,
+		fs.WithFile("readme.md", "this is a readme file"),
+		fs.WithFile("main.py", "D: this is a python file"),
+		fs.WithFile("testfile.txt", "D: this is a test file"),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	cmd = s5cmd("sync", "--size-only", "--delete", "--max-delete-percent", "70", src, dst)
+	result = icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`rm %vmain.py`, dst),
+		1: equals(`rm %vtestfile.txt`, dst),
+	}, sortInput(true))
+
+	expected = fs.Expected(t, fs.WithFile("readme.md", "this is a readme file"))
 	assert.Assert(t, fs.Equal(workdir.Path(), expected))
 }
 
-// sync --delete s3://bucket/* .
-func TestSyncS3BucketToLocalWithDeleteFlag(t *testing.T) {
+// sync --max-delete 1 folder/ s3://bucket/
+func TestSyncMaxDeleteWithoutDelete(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("readme.md", "this is a readme file"))
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("%v/", workdir.Path())
+	src = filepath.ToSlash(src)
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--max-delete", "1", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --max-delete=1 %v %v": --max-delete flag can only be used with --delete flag`, src, dst),
+	})
+}
+
+// sync --delete --backup-dir s3://bucket/trash/ folder/ s3://bucket/static/
+func TestSyncLocalToS3BucketWithBackupDir(t *testing.T) {
 	t.Parallel()
 
+	now := time.Now()
 	s3client, s5cmd := setup(t)
 
 	bucket := s3BucketFromTestName(t)
 	createBucket(t, s3client, bucket)
 
+	folderLayout := []fs.PathOp{
+		fs.WithFile("readme.md", "S: this is an updated readme file"),
+		fs.WithFile("testfile.txt", "S: this is a test file", fs.WithTimestamps(now.Add(-time.Minute), now.Add(-time.Minute))),
+	}
+
+	workdir := fs.NewDir(t, "somedir", folderLayout...)
+	defer workdir.Remove()
+
 	s3Content := map[string]string{
-		"test.txt": "S: this is a test file",
+		"static/readme.md":       "D: this is a readme file",
+		"static/testfile.txt":    "S: this is a test file",
+		"static/dir/main.py":     "D: this is a python file",
+		"static/dir/sub/lib.py":  "D: this is a library file",
+		"trash/previous/main.py": "D: this is a previous backup",
 	}
 
 	for filename, content := range s3Content {
 		putFile(t, s3client, bucket, filename, content)
 	}
 
-	workdir := fs.NewDir(t,
-		"somedir",
-		fs.WithFile("readme.md", "D: this is a readme file"),
-		fs.WithDir(
-			"subdir",
-			fs.WithFile("main.py", "D: this is a python file")),
-	)
-	defer workdir.Remove()
-
-	src := fmt.Sprintf("s3://%v/", bucket)
-	dst := fmt.Sprintf("%v/", workdir.Path())
-	dst = filepath.ToSlash(dst)
+	src := fmt.Sprintf("%v/", workdir.Path())
+	src = filepath.ToSlash(src)
+	dst := fmt.Sprintf("s3://%v/static/", bucket)
+	trash := fmt.Sprintf("s3://%v/trash/", bucket)
 
-	cmd := s5cmd("--log", "debug", "sync", "--delete", src+"*", dst)
+	cmd := s5cmd("sync", "--delete", "--backup-dir", trash, src, dst)
 	result := icmd.RunCmd(cmd)
 
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`cp %vtest.txt %vtest.txt`, src, dst),
-		1: equals(`rm %vreadme.md`, dst),
-		2: equals(`rm %vsubdir/main.py`, dst),
+		0: equals(`backup %vdir/main.py %vdir/main.py`, dst, trash),
+		1: equals(`backup %vdir/sub/lib.py %vdir/sub/lib.py`, dst, trash),
+		2: equals(`backup %vreadme.md %vreadme.md`, dst, trash),
+		3: equals(`cp %vreadme.md %vreadme.md`, src, dst),
+		4: equals(`rm %vdir/main.py`, dst),
+		5: equals(`rm %vdir/sub/lib.py`, dst),
 	}, sortInput(true))
 
-	expectedLayout := []fs.PathOp{
-		fs.WithFile("test.txt", "S: this is a test file"),
-		fs.WithDir("subdir"),
+	expectedS3Content := map[string]string{
+		"static/readme.md":       "S: this is an updated readme file",
+		"static/testfile.txt":    "S: this is a test file",
+		"trash/readme.md":        "D: this is a readme file",
+		"trash/dir/main.py":      "D: this is a python file",
+		"trash/dir/sub/lib.py":   "D: this is a library file",
+		"trash/previous/main.py": "D: this is a previous backup",
 	}
 
-	expected := fs.Expected(t, expectedLayout...)
-	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+	for key, content := range expectedS3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+
+	for _, key := range []string{"static/dir/main.py", "static/dir/sub/lib.py"} {
+		err := ensureS3Object(s3client, bucket, key, s3Content[key])
+		assertError(t, err, errS3NoSuchKey)
+	}
 }
 
-// sync dir/ s3://bucket (symlink)
-func TestSyncLocalFilesWithSymlinksToS3Bucket(t *testing.T) {
+// sync --delete --backup-dir trash/ s3://bucket/* folder/
+func TestSyncS3BucketToLocalWithBackupDir(t *testing.T) {
 	t.Parallel()
 
+	now := time.Now()
 	s3client, s5cmd := setup(t)
 
 	bucket := s3BucketFromTestName(t)
 	createBucket(t, s3client, bucket)
 
-	fileContent := "CAFEBABE"
+	putFile(t, s3client, bucket, "readme.md", "S: this is an updated readme file")
+
+	// ensure destination is older.
+	timestamp := fs.WithTimestamps(now.Add(-time.Minute), now.Add(-time.Minute))
 	folderLayout := []fs.PathOp{
 		fs.WithDir(
-			"a",
-			fs.WithFile("file1.txt", fileContent),
-			fs.WithFile("file2.txt", fileContent),
+			"folder",
+			fs.WithFile("readme.md", "D: this is a readme file", timestamp),
+			fs.WithDir("dir", fs.WithFile("main.py", "D: this is a python file", timestamp)),
 		),
-		fs.WithDir("b"),
-		fs.WithSymlink("b/link1", "a/file1.txt"),
-		fs.WithSymlink("b/link2", "a/file2.txt"),
 	}
 
 	workdir := fs.NewDir(t, "somedir", folderLayout...)
 	defer workdir.Remove()
 
-	src := fmt.Sprintf("%v/b", workdir.Path())
-	src = filepath.ToSlash(src)
-	dst := fmt.Sprintf("s3://%v/", bucket)
+	src := fmt.Sprintf("s3://%v/*", bucket)
 
-	cmd := s5cmd("sync", src, dst)
-	result := icmd.RunCmd(cmd)
+	cmd := s5cmd("--stat", "sync", "--delete", "--backup-dir", "trash/", src, "folder/")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
 
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`cp %v/b/link1 %vb/link1`, filepath.ToSlash(workdir.Path()), dst),
-		1: equals(`cp %v/b/link2 %vb/link2`, filepath.ToSlash(workdir.Path()), dst),
+		0: equals(``),
+		1: match(`^Operation\s+Total\s+Error\s+Success\s*$`),
+		2: match(`^backup\s+2\s+0\s+2\s*$`),
+		3: equals(`backup folder/dir/main.py trash/dir/main.py`),
+		4: equals(`backup folder/readme.md trash/readme.md`),
+		5: match(`^cp\s+1\s+0\s+1\s*$`),
+		6: equals(`cp s3://%v/readme.md folder/readme.md`, bucket),
+		7: match(`^rm\s+1\s+0\s+1\s*$`),
+		8: equals(`rm folder/dir/main.py`),
+		9: match(`^sync\s+1\s+0\s+1\s*$`),
 	}, sortInput(true))
+
+	expected := fs.Expected(t,
+		fs.WithDir(
+			"folder",
+			fs.WithFile("readme.md", "S: this is an updated readme file"),
+			fs.WithDir("dir"),
+		),
+		fs.WithDir(
+			"trash",
+			fs.WithFile("readme.md", "D: this is a readme file"),
+			fs.WithDir("dir", fs.WithFile("main.py", "D: this is a python file")),
+		),
+	)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
 }
 
-// sync --no-follow-symlinks * s3://bucket/prefix/
-func TestSyncLocalFilesWithNoFollowSymlinksToS3Bucket(t *testing.T) {
+// sync --backup-dir s3://bucket/static/trash/ folder/ s3://bucket/static/
+func TestSyncWithBackupDirUnderDestination(t *testing.T) {
 	t.Parallel()
 
 	s3client, s5cmd := setup(t)
@@ -2238,514 +2389,1269 @@ func TestSyncLocalFilesWithNoFollowSymlinksToS3Bucket(t *testing.T) {
 	bucket := s3BucketFromTestName(t)
 	createBucket(t, s3client, bucket)
 
-	fileContent := "CAFEBABE"
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("readme.md", "this is a readme file"))
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("%v/", workdir.Path())
+	src = filepath.ToSlash(src)
+	dst := fmt.Sprintf("s3://%v/static/", bucket)
+	trash := fmt.Sprintf("s3://%v/static/trash/", bucket)
+
+	cmd := s5cmd("sync", "--backup-dir", trash, src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --backup-dir=%v %v %v": --backup-dir %q can not be under %q`, trash, src, dst, trash, dst),
+	})
+}
+
+// sync --delete folder/ s3://bucket/*
+func TestSyncLocalToEmptyS3BucketWithDelete(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
 	folderLayout := []fs.PathOp{
-		fs.WithDir(
-			"a",
-			fs.WithFile("file1.txt", fileContent),
-			fs.WithFile("file2.txt", fileContent),
-		),
-		fs.WithDir("b"),
-		fs.WithSymlink("b/link1", "a/file1.txt"),
-		fs.WithSymlink("b/link2", "a/file2.txt"),
+		fs.WithFile("contributing.md", "S: this is a readme file", fs.WithTimestamps(now, now)),
 	}
 
 	workdir := fs.NewDir(t, "somedir", folderLayout...)
 	defer workdir.Remove()
 
-	src := fmt.Sprintf("%v/b", workdir.Path())
+	src := fmt.Sprintf("%v/", workdir.Path())
 	src = filepath.ToSlash(src)
 	dst := fmt.Sprintf("s3://%v/", bucket)
 
-	cmd := s5cmd("sync", "--no-follow-symlinks", src, dst)
+	cmd := s5cmd("sync", "--delete", src, dst)
 	result := icmd.RunCmd(cmd)
 
 	result.Assert(t, icmd.Success)
 
-	// do not follow symlinks in directory b (empty result)
-	assertLines(t, result.Stdout(), map[int]compareFunc{})
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vcontributing.md %vcontributing.md`, src, dst),
+	}, sortInput(true))
+
+	// assert local filesystem
+	expectedFiles := []fs.PathOp{
+		fs.WithFile("contributing.md", "S: this is a readme file"),
+	}
+	expected := fs.Expected(t, expectedFiles...)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	expectedS3Content := map[string]string{
+		"contributing.md": "S: this is a readme file",
+	}
+
+	// assert s3 objects
+	for key, content := range expectedS3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
 }
 
-// sync --exclude pattern s3://bucket/* s3://anotherbucket/prefix/
-func TestSyncS3ObjectsIntoAnotherBucketWithExcludeFilters(t *testing.T) {
+// sync --delete s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketWithDelete(t *testing.T) {
 	t.Parallel()
 
-	srcbucket := s3BucketFromTestNameWithPrefix(t, "src")
-	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
-
 	s3client, s5cmd := setup(t)
 
-	createBucket(t, s3client, srcbucket)
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+	createBucket(t, s3client, bucket)
 	createBucket(t, s3client, dstbucket)
 
-	srcFiles := []string{
-		"file_already_exists_in_destination.txt",
-		"file_not_exists_in_destination.txt",
-		"main.py",
-		"main.js",
-		"readme.md",
-		"main.pdf",
-		"main/file.txt",
-	}
-
-	dstFiles := []string{
-		"prefix/file_already_exists_in_destination.txt",
-	}
-
-	expectedFiles := []string{
-		"prefix/file_not_exists_in_destination.txt",
-		"prefix/file_already_exists_in_destination.txt",
+	sourceS3Content := map[string]string{
+		"readme.md":    "S: this is a readme file",
+		"dir/main.py":  "S: this is a python file",
+		"testfile.txt": "S: this is a test file",
 	}
 
-	excludedFiles := []string{
-		"main.py",
-		"main.js",
-		"main.pdf",
-		"main/file.txt",
-		"readme.md",
+	destS3Content := map[string]string{
+		"main.md":      "D: this is a readme file",
+		"dir/test.py":  "D: this is a python file",
+		"testfile.txt": "D: this is an updated test file", // different size from source
+		"Makefile":     "D: this is a makefile",
 	}
 
-	const (
-		content         = "this is a file content"
-		excludePattern1 = "main*"
-		excludePattern2 = "*.md"
-	)
-
-	for _, filename := range srcFiles {
-		putFile(t, s3client, srcbucket, filename, content)
+	for filename, content := range sourceS3Content {
+		putFile(t, s3client, bucket, filename, content)
 	}
 
-	for _, filename := range dstFiles {
+	for filename, content := range destS3Content {
 		putFile(t, s3client, dstbucket, filename, content)
 	}
 
-	src := fmt.Sprintf("s3://%v/*", srcbucket)
-	dst := fmt.Sprintf("s3://%v/prefix/", dstbucket)
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
 
-	cmd := s5cmd("sync", "--exclude", excludePattern1, "--exclude", excludePattern2, src, dst)
+	cmd := s5cmd("sync", "--delete", "--size-only", src+"*", dst)
 	result := icmd.RunCmd(cmd)
 
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`cp s3://%s/file_not_exists_in_destination.txt s3://%s/prefix/file_not_exists_in_destination.txt`, srcbucket, dstbucket),
+		0: equals(`cp %vdir/main.py %vdir/main.py`, src, dst),
+		1: equals(`cp %vreadme.md %vreadme.md`, src, dst),
+		2: equals(`cp %vtestfile.txt %vtestfile.txt`, src, dst),
+		3: equals(`rm %vMakefile`, dst),
+		4: equals(`rm %vdir/test.py`, dst),
+		5: equals(`rm %vmain.md`, dst),
 	}, sortInput(true))
 
-	// assert s3 source objects
-	for _, filename := range srcFiles {
-		assert.Assert(t, ensureS3Object(s3client, srcbucket, filename, content))
+	expectedDestS3Content := map[string]string{
+		"testfile.txt": "S: this is a test file", // same as source bucket.
+		"readme.md":    "S: this is a readme file",
+		"dir/main.py":  "S: this is a python file",
 	}
 
-	// assert s3 destination objects
-	for _, filename := range expectedFiles {
-		assert.Assert(t, ensureS3Object(s3client, dstbucket, filename, content))
+	nonExpectedDestS3Content := map[string]string{
+		"dir/test.py": "S: this is a python file",
+		"main.md":     "D: this is a readme file",
+		"Makefile":    "S: this is a makefile",
 	}
 
-	// assert s3 destination objects which should not be in bucket.
-	for _, filename := range excludedFiles {
-		err := ensureS3Object(s3client, dstbucket, filename, content)
-		assertError(t, err, errS3NoSuchKey)
+	// assert s3 objects in source.
+	for key, content := range sourceS3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+
+	// assert s3 objects in destination. (should be)
+	for key, content := range expectedDestS3Content {
+		assert.Assert(t, ensureS3Object(s3client, dstbucket, key, content))
+	}
+
+	// assert s3 objects should be deleted.
+	for key, content := range nonExpectedDestS3Content {
+		err := ensureS3Object(s3client, dstbucket, key, content)
+		if err == nil {
+			t.Errorf("File %v is not deleted in remote : %v\n", key, err)
+		}
 	}
 }
 
-// sync --exclude "*.gz" dir s3://bucket/
-// sync --exclude "*.gz" dir/ s3://bucket/
-// sync --exclude "*.gz" dir/* s3://bucket/
-func TestSyncLocalDirectoryToS3WithExcludeFilter(t *testing.T) {
+// sync s3://bucket/*.txt folder/
+func TestSyncS3toLocalWithWildcard(t *testing.T) {
 	t.Parallel()
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
 
-	testcases := []struct {
-		name            string
-		directoryPrefix string
-	}{
-		{
-			name:            "folder without /",
-			directoryPrefix: "",
-		},
-		{
-			name:            "folder with /",
-			directoryPrefix: "/",
-		},
-		{
-			name:            "folder with / and glob *",
-			directoryPrefix: "/*",
-		},
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// make local (destination) older.
+	timestamp := fs.WithTimestamps(
+		now.Add(-time.Minute), // access time
+		now.Add(-time.Minute), // mod time
+	)
+
+	// even though test.py exists in the source, since '*.txt' wildcard
+	// used, test.py will not be in the source, because all of the source
+	// files will be with extension '*.txt' therefore test.py will be deleted.
+	folderLayout := []fs.PathOp{
+		fs.WithFile("test.py", "D: this is a python file", timestamp),
+		fs.WithFile("test.txt", "D: this is a test file", timestamp),
 	}
 
-	for _, tc := range testcases {
-		tc := tc
-		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
+	s3Content := map[string]string{
+		"test.txt":          "S: this is an updated test file",
+		"readme.md":         "S: this is a readme file",
+		"main.py":           "S: py file",
+		"subfolder/sub.txt": "S: yet another txt",
+		"test.py":           "S: this is a python file",
+	}
 
-			bucket := s3BucketFromTestName(t)
+	for filename, content := range s3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
 
-			s3client, s5cmd := setup(t)
+	workdir := fs.NewDir(t, "somedir", folderLayout...)
+	defer workdir.Remove()
 
-			createBucket(t, s3client, bucket)
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := fmt.Sprintf("%v/", workdir.Path())
+	dst = filepath.ToSlash(dst)
 
-			folderLayout := []fs.PathOp{
-				fs.WithFile("testfile1.txt", "this is a test file 1"),
-				fs.WithFile("readme.md", "this is a readme file"),
-				fs.WithDir(
-					"a",
-					fs.WithFile("another_test_file.txt", "yet another txt file. yatf."),
-				),
-				fs.WithDir(
-					"b",
-					fs.WithFile("filename-with-hypen.gz", "file has hypen in its name"),
-				),
-			}
+	cmd := s5cmd("--log", "debug", "sync", "--delete", src+"*.txt", dst)
+	result := icmd.RunCmd(cmd)
 
-			workdir := fs.NewDir(t, "somedir", folderLayout...)
-			defer workdir.Remove()
+	result.Assert(t, icmd.Success)
 
-			const excludePattern = "*.gz"
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		1: equals(`cp %vtest.txt %vtest.txt`, src, dst),
+		0: equals(`cp %vsubfolder/sub.txt %vsubfolder/sub.txt`, src, dst),
+		2: equals(`rm %vtest.py`, dst),
+	}, sortInput(true))
 
-			src := fmt.Sprintf("%v/", workdir.Path())
-			src = src + tc.directoryPrefix
-			dst := fmt.Sprintf("s3://%v/prefix/", bucket)
+	expectedLayout := []fs.PathOp{
+		fs.WithFile("test.txt", "S: this is an updated test file"),
+		fs.WithDir("subfolder",
+			fs.WithFile("sub.txt", "S: yet another txt"),
+		),
+	}
 
-			src = filepath.ToSlash(src)
-			cmd := s5cmd("sync", "--exclude", excludePattern, src, dst)
-			result := icmd.RunCmd(cmd)
+	expected := fs.Expected(t, expectedLayout...)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+}
 
-			result.Assert(t, icmd.Success)
+// sync --delete s3://bucket/* .
+func TestSyncS3BucketToLocalWithDeleteFlag(t *testing.T) {
+	t.Parallel()
 
-			// assert local filesystem
-			expected := fs.Expected(t, folderLayout...)
-			assert.Assert(t, fs.Equal(workdir.Path(), expected))
+	s3client, s5cmd := setup(t)
 
-			expectedS3Content := map[string]string{
-				"prefix/testfile1.txt":           "this is a test file 1",
-				"prefix/readme.md":               "this is a readme file",
-				"prefix/a/another_test_file.txt": "yet another txt file. yatf.",
-			}
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
 
-			nonExpectedS3Content := map[string]string{
-				"prefix/b/filename-with-hypen.gz": "file has hypen in its name",
-			}
+	s3Content := map[string]string{
+		"test.txt": "S: this is a test file",
+	}
 
-			// assert objects should be in S3
-			for key, content := range expectedS3Content {
-				assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
-			}
+	for filename, content := range s3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
 
-			// assert objects should not be in S3.
-			for key, content := range nonExpectedS3Content {
-				err := ensureS3Object(s3client, bucket, key, content)
-				assertError(t, err, errS3NoSuchKey)
-			}
-		})
+	workdir := fs.NewDir(t,
+		"somedir",
+		fs.WithFile("readme.md", "D: this is a readme file"),
+		fs.WithDir(
+			"subdir",
+			fs.WithFile("main.py", "D: this is a python file")),
+	)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := fmt.Sprintf("%v/", workdir.Path())
+	dst = filepath.ToSlash(dst)
+
+	cmd := s5cmd("--log", "debug", "sync", "--delete", src+"*", dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vtest.txt %vtest.txt`, src, dst),
+		1: equals(`rm %vreadme.md`, dst),
+		2: equals(`rm %vsubdir/main.py`, dst),
+	}, sortInput(true))
+
+	expectedLayout := []fs.PathOp{
+		fs.WithFile("test.txt", "S: this is a test file"),
+		fs.WithDir("subdir"),
 	}
+
+	expected := fs.Expected(t, expectedLayout...)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
 }
 
-// sync --delete somedir s3://bucket/ (removes 10k objects)
-func TestIssue435(t *testing.T) {
+// sync dir/ s3://bucket (symlink)
+func TestSyncLocalFilesWithSymlinksToS3Bucket(t *testing.T) {
 	t.Parallel()
 
-	// Skip this as it takes too long to complete with GCS.
-	skipTestIfGCS(t, "takes too long to complete")
+	s3client, s5cmd := setup(t)
 
 	bucket := s3BucketFromTestName(t)
-
-	s3client, s5cmd := setup(t, withS3Backend("mem"))
-
 	createBucket(t, s3client, bucket)
 
-	// empty folder
-	folderLayout := []fs.PathOp{}
+	fileContent := "CAFEBABE"
+	folderLayout := []fs.PathOp{
+		fs.WithDir(
+			"a",
+			fs.WithFile("file1.txt", fileContent),
+			fs.WithFile("file2.txt", fileContent),
+		),
+		fs.WithDir("b"),
+		fs.WithSymlink("b/link1", "a/file1.txt"),
+		fs.WithSymlink("b/link2", "a/file2.txt"),
+	}
 
 	workdir := fs.NewDir(t, "somedir", folderLayout...)
 	defer workdir.Remove()
 
-	const filecount = 10_000
+	src := fmt.Sprintf("%v/b", workdir.Path())
+	src = filepath.ToSlash(src)
+	dst := fmt.Sprintf("s3://%v/", bucket)
 
-	filenameFunc := func(i int) string { return fmt.Sprintf("file_%06d", i) }
-	contentFunc := func(i int) string { return fmt.Sprintf("file body %06d", i) }
+	cmd := s5cmd("sync", src, dst)
+	result := icmd.RunCmd(cmd)
 
-	for i := 0; i < filecount; i++ {
-		filename := filenameFunc(i)
-		content := contentFunc(i)
-		putFile(t, s3client, bucket, filename, content)
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/b/link1 %vb/link1`, filepath.ToSlash(workdir.Path()), dst),
+		1: equals(`cp %v/b/link2 %vb/link2`, filepath.ToSlash(workdir.Path()), dst),
+	}, sortInput(true))
+}
+
+// sync --no-follow-symlinks * s3://bucket/prefix/
+func TestSyncLocalFilesWithNoFollowSymlinksToS3Bucket(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	fileContent := "CAFEBABE"
+	folderLayout := []fs.PathOp{
+		fs.WithDir(
+			"a",
+			fs.WithFile("file1.txt", fileContent),
+			fs.WithFile("file2.txt", fileContent),
+		),
+		fs.WithDir("b"),
+		fs.WithSymlink("b/link1", "a/file1.txt"),
+		fs.WithSymlink("b/link2", "a/file2.txt"),
 	}
 
-	src := fmt.Sprintf("%v/", workdir.Path())
+	workdir := fs.NewDir(t, "somedir", folderLayout...)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("%v/b", workdir.Path())
 	src = filepath.ToSlash(src)
 	dst := fmt.Sprintf("s3://%v/", bucket)
 
-	cmd := s5cmd("--log", "debug", "sync", "--delete", src, dst)
+	cmd := s5cmd("sync", "--no-follow-symlinks", src, dst)
 	result := icmd.RunCmd(cmd)
 
 	result.Assert(t, icmd.Success)
 
-	assertLines(t, result.Stderr(), map[int]compareFunc{})
+	// do not follow symlinks in directory b (empty result)
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+}
+
+// sync --exclude pattern s3://bucket/* s3://anotherbucket/prefix/
+func TestSyncS3ObjectsIntoAnotherBucketWithExcludeFilters(t *testing.T) {
+	t.Parallel()
+
+	srcbucket := s3BucketFromTestNameWithPrefix(t, "src")
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	s3client, s5cmd := setup(t)
+
+	createBucket(t, s3client, srcbucket)
+	createBucket(t, s3client, dstbucket)
+
+	srcFiles := []string{
+		"file_already_exists_in_destination.txt",
+		"file_not_exists_in_destination.txt",
+		"main.py",
+		"main.js",
+		"readme.md",
+		"main.pdf",
+		"main/file.txt",
+	}
+
+	dstFiles := []string{
+		"prefix/file_already_exists_in_destination.txt",
+	}
+
+	expectedFiles := []string{
+		"prefix/file_not_exists_in_destination.txt",
+		"prefix/file_already_exists_in_destination.txt",
+	}
+
+	excludedFiles := []string{
+		"main.py",
+		"main.js",
+		"main.pdf",
+		"main/file.txt",
+		"readme.md",
+	}
+
+	const (
+		content         = "this is a file content"
+		excludePattern1 = "main*"
+		excludePattern2 = "*.md"
+	)
+
+	for _, filename := range srcFiles {
+		putFile(t, s3client, srcbucket, filename, content)
+	}
+
+	for _, filename := range dstFiles {
+		putFile(t, s3client, dstbucket, filename, content)
+	}
+
+	src := fmt.Sprintf("s3://%v/*", srcbucket)
+	dst := fmt.Sprintf("s3://%v/prefix/", dstbucket)
+
+	cmd := s5cmd("sync", "--exclude", excludePattern1, "--exclude", excludePattern2, src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%s/file_not_exists_in_destination.txt s3://%s/prefix/file_not_exists_in_destination.txt`, srcbucket, dstbucket),
+	}, sortInput(true))
+
+	// assert s3 source objects
+	for _, filename := range srcFiles {
+		assert.Assert(t, ensureS3Object(s3client, srcbucket, filename, content))
+	}
+
+	// assert s3 destination objects
+	for _, filename := range expectedFiles {
+		assert.Assert(t, ensureS3Object(s3client, dstbucket, filename, content))
+	}
+
+	// assert s3 destination objects which should not be in bucket.
+	for _, filename := range excludedFiles {
+		err := ensureS3Object(s3client, dstbucket, filename, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+// sync --exclude "*.gz" dir s3://bucket/
+// sync --exclude "*.gz" dir/ s3://bucket/
+// sync --exclude "*.gz" dir/* s3://bucket/
+func TestSyncLocalDirectoryToS3WithExcludeFilter(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name            string
+		directoryPrefix string
+	}{
+		{
+			name:            "folder without /",
+			directoryPrefix: "",
+		},
+		{
+			name:            "folder with /",
+			directoryPrefix: "/",
+		},
+		{
+			name:            "folder with / and glob *",
+			directoryPrefix: "/*",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			bucket := s3BucketFromTestName(t)
+
+			s3client, s5cmd := setup(t)
+
+			createBucket(t, s3client, bucket)
+
+			folderLayout := []fs.PathOp{
+				fs.WithFile("testfile1.txt", "this is a test file 1"),
+				fs.WithFile("readme.md", "this is a readme file"),
+				fs.WithDir(
+					"a",
+					fs.WithFile("another_test_file.txt", "yet another txt file. yatf."),
+				),
+				fs.WithDir(
+					"b",
+					fs.WithFile("filename-with-hypen.gz", "file has hypen in its name"),
+				),
+			}
+
+			workdir := fs.NewDir(t, "somedir", folderLayout...)
+			defer workdir.Remove()
+
+			const excludePattern = "*.gz"
+
+			src := fmt.Sprintf("%v/", workdir.Path())
+			src = src + tc.directoryPrefix
+			dst := fmt.Sprintf("s3://%v/prefix/", bucket)
+
+			src = filepath.ToSlash(src)
+			cmd := s5cmd("sync", "--exclude", excludePattern, src, dst)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Success)
+
+			// assert local filesystem
+			expected := fs.Expected(t, folderLayout...)
+			assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+			expectedS3Content := map[string]string{
+				"prefix/testfile1.txt":           "this is a test file 1",
+				"prefix/readme.md":               "this is a readme file",
+				"prefix/a/another_test_file.txt": "yet another txt file. yatf.",
+			}
+
+			nonExpectedS3Content := map[string]string{
+				"prefix/b/filename-with-hypen.gz": "file has hypen in its name",
+			}
+
+			// assert objects should be in S3
+			for key, content := range expectedS3Content {
+				assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+			}
+
+			// assert objects should not be in S3.
+			for key, content := range nonExpectedS3Content {
+				err := ensureS3Object(s3client, bucket, key, content)
+				assertError(t, err, errS3NoSuchKey)
+			}
+		})
+	}
+}
+
+// sync --delete somedir s3://bucket/ (removes 10k objects)
+func TestIssue435(t *testing.T) {
+	t.Parallel()
+
+	// Skip this as it takes too long to complete with GCS.
+	skipTestIfGCS(t, "takes too long to complete")
+
+	bucket := s3BucketFromTestName(t)
+
+	s3client, s5cmd := setup(t, withS3Backend("mem"))
+
+	createBucket(t, s3client, bucket)
+
+	// empty folder
+	folderLayout := []fs.PathOp{}
+
+	workdir := fs.NewDir(t, "somedir", folderLayout...)
+	defer workdir.Remove()
+
+	const filecount = 10_000
+
+	filenameFunc := func(i int) string { return fmt.Sprintf("file_%06d", i) }
+	contentFunc := func(i int) string { return fmt.Sprintf("file body %06d", i) }
+
+	for i := 0; i < filecount; i++ {
+		filename := filenameFunc(i)
+		content := contentFunc(i)
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	src := fmt.Sprintf("%v/", workdir.Path())
+	src = filepath.ToSlash(src)
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("--log", "debug", "sync", "--delete", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{})
+
+	expected := make(map[int]compareFunc)
+	for i := 0; i < filecount; i++ {
+		expected[i] = contains("rm s3://%v/file_%06d", bucket, i)
+	}
+
+	assertLines(t, result.Stdout(), expected, sortInput(true))
+
+	// assert s3 objects
+	for i := 0; i < filecount; i++ {
+		filename := filenameFunc(i)
+		content := contentFunc(i)
+
+		err := ensureS3Object(s3client, bucket, filename, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+// sync s3://bucket/* s3://bucket/ (dest bucket is empty)
+func TestSyncS3BucketToEmptyS3BucketWithExitOnErrorFlag(t *testing.T) {
+	t.Parallel()
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	const (
+		prefix = "prefix"
+	)
+	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
+
+	s3Content := map[string]string{
+		"testfile.txt":            "S: this is a test file",
+		"readme.md":               "S: this is a readme file",
+		"a/another_test_file.txt": "S: yet another txt file",
+		"abc/def/test.py":         "S: file in nested folders",
+	}
+
+	for filename, content := range s3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	bucketPath := fmt.Sprintf("s3://%v", bucket)
+	src := fmt.Sprintf("%v/*", bucketPath)
+	dst := fmt.Sprintf("s3://%v/%v/", dstbucket, prefix)
+
+	cmd := s5cmd("sync", "--exit-on-error", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v/a/another_test_file.txt %va/another_test_file.txt`, bucketPath, dst),
+		1: equals(`cp %v/abc/def/test.py %vabc/def/test.py`, bucketPath, dst),
+		2: equals(`cp %v/readme.md %vreadme.md`, bucketPath, dst),
+		3: equals(`cp %v/testfile.txt %vtestfile.txt`, bucketPath, dst),
+	}, sortInput(true))
+
+	// assert  s3 objects in source bucket.
+	for key, content := range s3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	}
+
+	// assert s3 objects in dest bucket
+	for key, content := range s3Content {
+		key = fmt.Sprintf("%s/%s", prefix, key) // add the prefix
+		assert.Assert(t, ensureS3Object(s3client, dstbucket, key, content))
+	}
+}
+
+// sync --exit-on-error s3://bucket/* s3://NotExistingBucket/ (dest bucket doesn't exist)
+func TestSyncExitOnErrorS3BucketToS3BucketThatDoesNotExist(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	destbucket := "NotExistingBucket"
+
+	createBucket(t, s3client, bucket)
+
+	s3Content := map[string]string{
+		"testfile.txt":            "S: this is a test file",
+		"readme.md":               "S: this is a readme file",
+		"a/another_test_file.txt": "S: yet another txt file",
+		"abc/def/test.py":         "S: file in nested folders",
+	}
+
+	for filename, content := range s3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+	dst := fmt.Sprintf("s3://%v/", destbucket)
+
+	cmd := s5cmd("sync", "--exit-on-error", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`status code: 404`),
+	})
+}
+
+// sync s3://bucket/* s3://NotExistingBucket/ (dest bucket doesn't exist)
+func TestSyncS3BucketToS3BucketThatDoesNotExist(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	timeSource := newFixedTimeSource(now)
+	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+
+	bucket := s3BucketFromTestName(t)
+	destbucket := "NotExistingBucket"
+
+	createBucket(t, s3client, bucket)
+
+	s3Content := map[string]string{
+		"testfile.txt":            "S: this is a test file",
+		"readme.md":               "S: this is a readme file",
+		"a/another_test_file.txt": "S: yet another txt file",
+		"abc/def/test.py":         "S: file in nested folders",
+	}
+
+	for filename, content := range s3Content {
+		putFile(t, s3client, bucket, filename, content)
+	}
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+	dst := fmt.Sprintf("s3://%v/", destbucket)
+
+	cmd := s5cmd("sync", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`status code: 404`),
+	})
+}
+
+// If source path contains a special file it should not be synced
+func TestSyncSocketDestinationEmpty(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip()
+	}
+
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	workdir := fs.NewDir(t, t.Name())
+	defer workdir.Remove()
+
+	sockaddr := workdir.Join("/s5cmd.sock")
+	ln, err := net.Listen("unix", sockaddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ln.Close()
+		os.Remove(sockaddr)
+	})
+
+	cmd := s5cmd("sync", ".", "s3://"+bucket+"/")
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	// assert error message
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: contains(`is not a regular file`),
+	})
+
+	// assert logs are empty (no sync)
+	assertLines(t, result.Stdout(), nil)
+
+	// assert exit code
+	result.Assert(t, icmd.Expected{ExitCode: 1})
+}
+
+// sync --include pattern s3://bucket/* s3://anotherbucket/prefix/
+func TestSyncS3ObjectsIntoAnotherBucketWithIncludeFilters(t *testing.T) {
+	t.Parallel()
+
+	srcbucket := s3BucketFromTestNameWithPrefix(t, "src")
+	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
+
+	s3client, s5cmd := setup(t)
+
+	createBucket(t, s3client, srcbucket)
+	createBucket(t, s3client, dstbucket)
+
+	srcFiles := []string{
+		"file_already_exists_in_destination.txt",
+		"file_not_exists_in_destination.txt",
+		"main.py",
+		"main.js",
+		"readme.md",
+		"main.pdf",
+		"main/file.txt",
+	}
+
+	dstFiles := []string{
+		"prefix/file_already_exists_in_destination.txt",
+	}
+
+	excludedFiles := []string{
+		"prefix/file_not_exists_in_destination.txt",
+	}
+
+	includedFiles := []string{
+		"main.js",
+		"main.pdf",
+		"main.py",
+		"main/file.txt",
+		"readme.md",
+	}
+
+	const (
+		content         = "this is a file content"
+		includePattern1 = "main*"
+		includePattern2 = "*.md"
+	)
+
+	for _, filename := range srcFiles {
+		putFile(t, s3client, srcbucket, filename, content)
+	}
+
+	for _, filename := range dstFiles {
+		putFile(t, s3client, dstbucket, filename, content)
+	}
+
+	src := fmt.Sprintf("s3://%v/*", srcbucket)
+	dst := fmt.Sprintf("s3://%v/prefix/", dstbucket)
+
+	cmd := s5cmd("sync", "--include", includePattern1, "--include", includePattern2, src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp s3://%s/%s s3://%s/prefix/%s`, srcbucket, includedFiles[0], dstbucket, includedFiles[0]),
+		1: equals(`cp s3://%s/%s s3://%s/prefix/%s`, srcbucket, includedFiles[1], dstbucket, includedFiles[1]),
+		2: equals(`cp s3://%s/%s s3://%s/prefix/%s`, srcbucket, includedFiles[2], dstbucket, includedFiles[2]),
+		3: equals(`cp s3://%s/%s s3://%s/prefix/%s`, srcbucket, includedFiles[3], dstbucket, includedFiles[3]),
+		4: equals(`cp s3://%s/%s s3://%s/prefix/%s`, srcbucket, includedFiles[4], dstbucket, includedFiles[4]),
+	}, sortInput(true))
+
+	// assert s3 source objects
+	for _, filename := range srcFiles {
+		assert.Assert(t, ensureS3Object(s3client, srcbucket, filename, content))
+	}
+
+	// assert s3 destination objects
+	for _, filename := range includedFiles {
+		assert.Assert(t, ensureS3Object(s3client, dstbucket, "prefix/"+filename, content))
+	}
+
+	// assert s3 destination objects which should not be in bucket.
+	for _, filename := range excludedFiles {
+		err := ensureS3Object(s3client, dstbucket, filename, content)
+		assertError(t, err, errS3NoSuchKey)
+	}
+}
+
+// sync --compress gzip dir/ s3://bucket/
+func TestSyncCompressedLocalFolderToS3Twice(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	const (
+		filename = "testfile.txt"
+		content  = "this is the content which is compressed while it is synced"
+	)
+
+	workdir := fs.NewDir(t, t.Name(), fs.WithFile(filename, content))
+	defer workdir.Remove()
+
+	dstpath := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--compress", "gzip", ".", dstpath)
+	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %v %v%v`, filename, dstpath, filename),
+	})
+
+	// the HTTP client of the SDK decompresses the objects with gzip content
+	// encoding, so the stored contents are read with cat.
+	assert.Equal(t, contentEncodingOf(t, s3client, bucket, filename), "gzip")
+
+	result = icmd.RunCmd(s5cmd("cat", dstpath+filename))
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, result.Stdout(), gzipContent(t, content))
+
+	// sizes of the compressed objects differ from the local files, but they
+	// are not uploaded again since they are not modified.
+	result = icmd.RunCmd(cmd, withWorkingDir(workdir))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+}
+
+// --list-partitions sync s3://bucket/* folder/
+func TestSyncS3BucketToFolderWithListPartitions(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	var expectedFiles []fs.PathOp
+	for i := 0; i < 16; i++ {
+		filename := fmt.Sprintf("%02x-testfile.txt", i*17)
+		content := fmt.Sprintf("S: this is the test file %d", i)
+		putFile(t, s3client, bucket, filename, content)
+		expectedFiles = append(expectedFiles, fs.WithFile(filename, content))
+	}
+
+	workdir := fs.NewDir(t, "somedir")
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/*", bucket)
+	dst := filepath.ToSlash(workdir.Path()) + "/"
+
+	cmd := s5cmd("--list-partitions", "4", "sync", src, dst)
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+	assert.Equal(t, strings.Count(result.Stdout(), "\n"), 16)
+
+	expected := fs.Expected(t, expectedFiles...)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
+
+	// the objects are listed in order, so none of them are copied again.
+	result = icmd.RunCmd(cmd)
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+}
+
+// sync --cache-ttl 1h folder/ s3://bucket/
+func TestSyncFolderToS3BucketWithListingCache(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	// local files are older than the uploaded objects, whose modification
+	// times are reported in seconds by HEAD requests.
+	now := time.Now()
+	timestamp := fs.WithTimestamps(now.Add(-time.Minute), now.Add(-time.Minute))
+
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("testfile.txt", "this is a test file", timestamp),
+		fs.WithDir("a", fs.WithFile("another_test_file.txt", "yet another txt file", timestamp), timestamp),
+	)
+	defer workdir.Remove()
+
+	cachedir := fs.NewDir(t, "cachedir")
+	defer cachedir.Remove()
+
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	syncCmd := func(ttl string) icmd.Cmd {
+		return s5cmd("sync", "--cache-dir", cachedir.Path(), "--cache-ttl", ttl, "--delete", src, dst)
+	}
+
+	result := icmd.RunCmd(syncCmd("1h"))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %va/another_test_file.txt %va/another_test_file.txt`, src, dst),
+		1: equals(`cp %vtestfile.txt %vtestfile.txt`, src, dst),
+	}, sortInput(true))
+
+	// the uploaded objects are revalidated in the cached listing, so they are
+	// not uploaded again.
+	result = icmd.RunCmd(syncCmd("1h"))
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
+
+	// the objects which are modified by other tools are not seen until the
+	// listing is expired.
+	putFile(t, s3client, bucket, "extra.txt", "this is an extra file")
+	_, err := s3client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String("testfile.txt"),
+	})
+	assert.NilError(t, err)
 
-	expected := make(map[int]compareFunc)
-	for i := 0; i < filecount; i++ {
-		expected[i] = contains("rm s3://%v/file_%06d", bucket, i)
-	}
+	result = icmd.RunCmd(syncCmd("1h"))
+	result.Assert(t, icmd.Success)
 
-	assertLines(t, result.Stdout(), expected, sortInput(true))
+	assertLines(t, result.Stdout(), map[int]compareFunc{})
 
-	// assert s3 objects
-	for i := 0; i < filecount; i++ {
-		filename := filenameFunc(i)
-		content := contentFunc(i)
+	// the cached objects of the expired listing are revalidated, the objects
+	// which are added by other tools are still not seen.
+	result = icmd.RunCmd(syncCmd("1ns"))
+	result.Assert(t, icmd.Success)
 
-		err := ensureS3Object(s3client, bucket, filename, content)
-		assertError(t, err, errS3NoSuchKey)
-	}
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vtestfile.txt %vtestfile.txt`, src, dst),
+	})
 }
 
-// sync s3://bucket/* s3://bucket/ (dest bucket is empty)
-func TestSyncS3BucketToEmptyS3BucketWithExitOnErrorFlag(t *testing.T) {
+// sync --delete folder/ s3://bucket/ (folder/.s5cmdignore)
+func TestSyncLocalToS3BucketWithDeleteAndIgnoreFile(t *testing.T) {
 	t.Parallel()
+
+	now := time.Now()
 	s3client, s5cmd := setup(t)
 
 	bucket := s3BucketFromTestName(t)
-	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
-
-	const (
-		prefix = "prefix"
-	)
 	createBucket(t, s3client, bucket)
-	createBucket(t, s3client, dstbucket)
+
+	// ensure source is older.
+	timestamp := fs.WithTimestamps(now.Add(-time.Minute), now.Add(-time.Minute))
+	folderLayout := []fs.PathOp{
+		fs.WithFile(".s5cmdignore", "*.log\n!keep.log\n", timestamp),
+		fs.WithFile("main.py", "S: this is a python file", timestamp),
+		fs.WithFile("debug.log", "S: this is a log file", timestamp),
+		fs.WithFile("keep.log", "S: this log file is kept", timestamp),
+	}
+
+	workdir := fs.NewDir(t, "somedir", folderLayout...)
+	defer workdir.Remove()
 
 	s3Content := map[string]string{
-		"testfile.txt":            "S: this is a test file",
-		"readme.md":               "S: this is a readme file",
-		"a/another_test_file.txt": "S: yet another txt file",
-		"abc/def/test.py":         "S: file in nested folders",
+		"debug.log":    "D: this is a log file",
+		"dir/app.log":  "D: this is another log file",
+		"testfile.txt": "D: this is a test file",
 	}
 
 	for filename, content := range s3Content {
 		putFile(t, s3client, bucket, filename, content)
 	}
 
-	bucketPath := fmt.Sprintf("s3://%v", bucket)
-	src := fmt.Sprintf("%v/*", bucketPath)
-	dst := fmt.Sprintf("s3://%v/%v/", dstbucket, prefix)
+	src := fmt.Sprintf("%v/", workdir.Path())
+	src = filepath.ToSlash(src)
+	dst := fmt.Sprintf("s3://%v/", bucket)
 
-	cmd := s5cmd("sync", "--exit-on-error", src, dst)
+	cmd := s5cmd("sync", "--delete", src, dst)
 	result := icmd.RunCmd(cmd)
 
 	result.Assert(t, icmd.Success)
 
 	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`cp %v/a/another_test_file.txt %va/another_test_file.txt`, bucketPath, dst),
-		1: equals(`cp %v/abc/def/test.py %vabc/def/test.py`, bucketPath, dst),
-		2: equals(`cp %v/readme.md %vreadme.md`, bucketPath, dst),
-		3: equals(`cp %v/testfile.txt %vtestfile.txt`, bucketPath, dst),
+		0: equals(`cp %v.s5cmdignore %v.s5cmdignore`, src, dst),
+		1: equals(`cp %vkeep.log %vkeep.log`, src, dst),
+		2: equals(`cp %vmain.py %vmain.py`, src, dst),
+		3: equals(`rm %vtestfile.txt`, dst),
 	}, sortInput(true))
 
-	// assert  s3 objects in source bucket.
-	for key, content := range s3Content {
-		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
+	// the objects of the ignored files are neither overwritten nor deleted.
+	expectedS3Content := map[string]string{
+		"main.py":     "S: this is a python file",
+		"keep.log":    "S: this log file is kept",
+		"debug.log":   "D: this is a log file",
+		"dir/app.log": "D: this is another log file",
 	}
 
-	// assert s3 objects in dest bucket
-	for key, content := range s3Content {
-		key = fmt.Sprintf("%s/%s", prefix, key) // add the prefix
-		assert.Assert(t, ensureS3Object(s3client, dstbucket, key, content))
+	for key, content := range expectedS3Content {
+		assert.Assert(t, ensureS3Object(s3client, bucket, key, content))
 	}
+
+	err := ensureS3Object(s3client, bucket, "testfile.txt", "D: this is a test file")
+	assertError(t, err, errS3NoSuchKey)
 }
 
-// sync --exit-on-error s3://bucket/* s3://NotExistingBucket/ (dest bucket doesn't exist)
-func TestSyncExitOnErrorS3BucketToS3BucketThatDoesNotExist(t *testing.T) {
+// sync --delete s3://bucket/* folder/ (folder/.s5cmdignore)
+func TestSyncS3BucketToLocalWithDeleteAndIgnoreFile(t *testing.T) {
 	t.Parallel()
 
-	now := time.Now()
-	timeSource := newFixedTimeSource(now)
-	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+	s3client, s5cmd := setup(t)
 
 	bucket := s3BucketFromTestName(t)
-	destbucket := "NotExistingBucket"
-
 	createBucket(t, s3client, bucket)
 
 	s3Content := map[string]string{
-		"testfile.txt":            "S: this is a test file",
-		"readme.md":               "S: this is a readme file",
-		"a/another_test_file.txt": "S: yet another txt file",
-		"abc/def/test.py":         "S: file in nested folders",
+		"contributing.md": "S: this is a readme file",
+		"config/local.py": "S: this is a config file",
 	}
 
 	for filename, content := range s3Content {
 		putFile(t, s3client, bucket, filename, content)
 	}
 
-	src := fmt.Sprintf("s3://%v/*", bucket)
-	dst := fmt.Sprintf("s3://%v/", destbucket)
+	folderLayout := []fs.PathOp{
+		fs.WithFile(".s5cmdignore", "/.s5cmdignore\nconfig/\n"),
+		fs.WithFile("testfile.txt", "D: this is a test file"),
+		fs.WithDir("config",
+			fs.WithFile("local.py", "D: this is a local config file"),
+			fs.WithFile("secret.py", "D: this is a secret file"),
+		),
+	}
 
-	cmd := s5cmd("sync", "--exit-on-error", src, dst)
+	workdir := fs.NewDir(t, "somedir", folderLayout...)
+	defer workdir.Remove()
+
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := fmt.Sprintf("%v/", workdir.Path())
+	dst = filepath.ToSlash(dst)
+
+	cmd := s5cmd("sync", "--delete", src+"*", dst)
 	result := icmd.RunCmd(cmd)
 
-	result.Assert(t, icmd.Expected{ExitCode: 1})
+	result.Assert(t, icmd.Success)
 
-	assertLines(t, result.Stderr(), map[int]compareFunc{
-		0: contains(`status code: 404`),
-	})
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vcontributing.md %vcontributing.md`, src, dst),
+		1: equals(`rm %vtestfile.txt`, dst),
+	}, sortInput(true))
+
+	// the ignored local files are neither overwritten nor deleted.
+	expectedFolderLayout := []fs.PathOp{
+		fs.WithFile(".s5cmdignore", "/.s5cmdignore\nconfig/\n"),
+		fs.WithFile("contributing.md", "S: this is a readme file"),
+		fs.WithDir("config",
+			fs.WithFile("local.py", "D: this is a local config file"),
+			fs.WithFile("secret.py", "D: this is a secret file"),
+		),
+	}
+
+	expected := fs.Expected(t, expectedFolderLayout...)
+	assert.Assert(t, fs.Equal(workdir.Path(), expected))
 }
 
-// sync s3://bucket/* s3://NotExistingBucket/ (dest bucket doesn't exist)
-func TestSyncS3BucketToS3BucketThatDoesNotExist(t *testing.T) {
+// sync --delete --exclude-from exclude.txt s3://bucket/* s3://destbucket/
+func TestSyncS3BucketToS3BucketWithDeleteAndExcludeFrom(t *testing.T) {
 	t.Parallel()
 
-	now := time.Now()
-	timeSource := newFixedTimeSource(now)
-	s3client, s5cmd := setup(t, withTimeSource(timeSource))
+	s3client, s5cmd := setup(t)
 
 	bucket := s3BucketFromTestName(t)
-	destbucket := "NotExistingBucket"
-
+	dstbucket := "copy-" + bucket
 	createBucket(t, s3client, bucket)
+	createBucket(t, s3client, dstbucket)
 
-	s3Content := map[string]string{
-		"testfile.txt":            "S: this is a test file",
-		"readme.md":               "S: this is a readme file",
-		"a/another_test_file.txt": "S: yet another txt file",
-		"abc/def/test.py":         "S: file in nested folders",
-	}
+	putFile(t, s3client, bucket, "main.py", "S: this is a python file")
+	putFile(t, s3client, bucket, "logs/app.log", "S: this is a log file")
 
-	for filename, content := range s3Content {
-		putFile(t, s3client, bucket, filename, content)
-	}
+	putFile(t, s3client, dstbucket, "logs/old.log", "D: this is an old log file")
+	putFile(t, s3client, dstbucket, "testfile.txt", "D: this is a test file")
 
-	src := fmt.Sprintf("s3://%v/*", bucket)
-	dst := fmt.Sprintf("s3://%v/", destbucket)
+	excludeFrom := fs.NewFile(t, "exclude", fs.WithContent("/logs/\n"))
+	defer excludeFrom.Remove()
 
-	cmd := s5cmd("sync", src, dst)
+	src := fmt.Sprintf("s3://%v/", bucket)
+	dst := fmt.Sprintf("s3://%v/", dstbucket)
+
+	cmd := s5cmd("sync", "--delete", "--exclude-from", excludeFrom.Path(), src+"*", dst)
 	result := icmd.RunCmd(cmd)
 
-	result.Assert(t, icmd.Expected{ExitCode: 1})
+	result.Assert(t, icmd.Success)
 
-	assertLines(t, result.Stderr(), map[int]compareFunc{
-		0: contains(`status code: 404`),
-	})
-}
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vmain.py %vmain.py`, src, dst),
+		1: equals(`rm %vtestfile.txt`, dst),
+	}, sortInput(true))
 
-// If source path contains a special file it should not be synced
-func TestSyncSocketDestinationEmpty(t *testing.T) {
-	if runtime.GOOS == "windows" {
-		t.Skip()
-	}
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "main.py", "S: this is a python file"))
+	assert.Assert(t, ensureS3Object(s3client, dstbucket, "logs/old.log", "D: this is an old log file"))
+
+	err := ensureS3Object(s3client, dstbucket, "logs/app.log", "S: this is a log file")
+	assertError(t, err, errS3NoSuchKey)
+}
 
+// sync --watch --delete folder/ s3://bucket/
+func TestSyncWatch(t *testing.T) {
 	t.Parallel()
 
 	s3client, s5cmd := setup(t)
+
 	bucket := s3BucketFromTestName(t)
 	createBucket(t, s3client, bucket)
 
-	workdir := fs.NewDir(t, t.Name())
+	workdir := fs.NewDir(t, "somedir",
+		fs.WithFile("existing.txt", "this is an existing file"),
+	)
 	defer workdir.Remove()
 
-	sockaddr := workdir.Join("/s5cmd.sock")
-	ln, err := net.Listen("unix", sockaddr)
-	if err != nil {
-		t.Fatal(err)
-	}
+	putFile(t, s3client, bucket, "stale.txt", "this is a stale object")
 
-	t.Cleanup(func() {
-		ln.Close()
-		os.Remove(sockaddr)
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
+
+	cmd := s5cmd("sync", "--watch", "--delete", "--watch-debounce", "100ms", src, dst)
+	result := icmd.StartCmd(cmd)
+	assert.NilError(t, result.Error)
+
+	// the initial sync uploads the existing files and deletes the stale
+	// objects.
+	waitUntil(t, func() error { return ensureS3Object(s3client, bucket, "existing.txt", "this is an existing file") })
+	waitUntil(t, func() error {
+		return isNoSuchKey(ensureS3Object(s3client, bucket, "stale.txt", "this is a stale object"))
 	})
 
-	cmd := s5cmd("sync", ".", "s3://"+bucket+"/")
-	result := icmd.RunCmd(cmd, withWorkingDir(workdir))
+	assert.NilError(t, os.WriteFile(workdir.Join("created.txt"), []byte("this is a created file"), 0o644))
+	waitUntil(t, func() error { return ensureS3Object(s3client, bucket, "created.txt", "this is a created file") })
 
-	// assert error message
-	assertLines(t, result.Stderr(), map[int]compareFunc{
-		0: contains(`is not a regular file`),
+	assert.NilError(t, os.WriteFile(workdir.Join("existing.txt"), []byte("this is a modified file"), 0o644))
+	waitUntil(t, func() error { return ensureS3Object(s3client, bucket, "existing.txt", "this is a modified file") })
+
+	assert.NilError(t, os.MkdirAll(workdir.Join("dir", "sub"), 0o755))
+	assert.NilError(t, os.WriteFile(workdir.Join("dir", "sub", "nested.txt"), []byte("this is a nested file"), 0o644))
+	waitUntil(t, func() error { return ensureS3Object(s3client, bucket, "dir/sub/nested.txt", "this is a nested file") })
+
+	assert.NilError(t, os.Remove(workdir.Join("created.txt")))
+	waitUntil(t, func() error {
+		return isNoSuchKey(ensureS3Object(s3client, bucket, "created.txt", "this is a created file"))
 	})
 
-	// assert logs are empty (no sync)
-	assertLines(t, result.Stdout(), nil)
+	assert.NilError(t, result.Cmd.Process.Signal(os.Interrupt))
+	result = icmd.WaitOnCmd(30*time.Second, result)
+	result.Assert(t, icmd.Success)
 
-	// assert exit code
-	result.Assert(t, icmd.Expected{ExitCode: 1})
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vcreated.txt %vcreated.txt`, src, dst),
+		1: equals(`cp %vdir/sub/nested.txt %vdir/sub/nested.txt`, src, dst),
+		2: equals(`cp %vexisting.txt %vexisting.txt`, src, dst),
+		3: equals(`cp %vexisting.txt %vexisting.txt`, src, dst),
+		4: equals(`rm %vcreated.txt`, dst),
+		5: equals(`rm %vstale.txt`, dst),
+	}, sortInput(true))
 }
 
-// sync --include pattern s3://bucket/* s3://anotherbucket/prefix/
-func TestSyncS3ObjectsIntoAnotherBucketWithIncludeFilters(t *testing.T) {
+// sync --watch --watch-debounce 1h folder/ s3://bucket/
+func TestSyncWatchFlushOnInterrupt(t *testing.T) {
 	t.Parallel()
 
-	srcbucket := s3BucketFromTestNameWithPrefix(t, "src")
-	dstbucket := s3BucketFromTestNameWithPrefix(t, "dst")
-
 	s3client, s5cmd := setup(t)
 
-	createBucket(t, s3client, srcbucket)
-	createBucket(t, s3client, dstbucket)
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
 
-	srcFiles := []string{
-		"file_already_exists_in_destination.txt",
-		"file_not_exists_in_destination.txt",
-		"main.py",
-		"main.js",
-		"readme.md",
-		"main.pdf",
-		"main/file.txt",
-	}
+	workdir := fs.NewDir(t, "somedir", fs.WithFile("existing.txt", "this is an existing file"))
+	defer workdir.Remove()
 
-	dstFiles := []string{
-		"prefix/file_already_exists_in_destination.txt",
-	}
+	src := filepath.ToSlash(workdir.Path()) + "/"
+	dst := fmt.Sprintf("s3://%v/", bucket)
 
-	excludedFiles := []string{
-		"prefix/file_not_exists_in_destination.txt",
-	}
+	cmd := s5cmd("sync", "--watch", "--watch-debounce", "1h", src, dst)
+	result := icmd.StartCmd(cmd)
+	assert.NilError(t, result.Error)
 
-	includedFiles := []string{
-		"main.js",
-		"main.pdf",
-		"main.py",
-		"main/file.txt",
-		"readme.md",
-	}
+	waitUntil(t, func() error { return ensureS3Object(s3client, bucket, "existing.txt", "this is an existing file") })
 
-	const (
-		content         = "this is a file content"
-		includePattern1 = "main*"
-		includePattern2 = "*.md"
-	)
+	assert.NilError(t, os.WriteFile(workdir.Join("created.txt"), []byte("this is a created file"), 0o644))
+	// wait for the event to be received.
+	time.Sleep(time.Second)
 
-	for _, filename := range srcFiles {
-		putFile(t, s3client, srcbucket, filename, content)
-	}
+	// the pending changes are flushed when it is interrupted.
+	assert.NilError(t, result.Cmd.Process.Signal(os.Interrupt))
+	result = icmd.WaitOnCmd(30*time.Second, result)
+	result.Assert(t, icmd.Success)
 
-	for _, filename := range dstFiles {
-		putFile(t, s3client, dstbucket, filename, content)
-	}
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`cp %vcreated.txt %vcreated.txt`, src, dst),
+		1: equals(`cp %vexisting.txt %vexisting.txt`, src, dst),
+	}, sortInput(true))
 
-	src := fmt.Sprintf("s3://%v/*", srcbucket)
-	dst := fmt.Sprintf("s3://%v/prefix/", dstbucket)
+	assert.Assert(t, ensureS3Object(s3client, bucket, "created.txt", "this is a created file"))
+}
 
-	cmd := s5cmd("sync", "--include", includePattern1, "--include", includePattern2, src, dst)
+func TestSyncWatchWithRemoteSource(t *testing.T) {
+	t.Parallel()
+
+	_, s5cmd := setup(t)
+
+	cmd := s5cmd("sync", "--watch", "s3://bucket/*", ".")
 	result := icmd.RunCmd(cmd)
 
-	result.Assert(t, icmd.Success)
+	result.Assert(t, icmd.Expected{ExitCode: 1})
 
-	assertLines(t, result.Stdout(), map[int]compareFunc{
-		0: equals(`cp s3://%s/%s s3://%s/prefix/%s`, srcbucket, includedFiles[0], dstbucket, includedFiles[0]),
-		1: equals(`cp s3://%s/%s s3://%s/prefix/%s`, srcbucket, includedFiles[1], dstbucket, includedFiles[1]),
-		2: equals(`cp s3://%s/%s s3://%s/prefix/%s`, srcbucket, includedFiles[2], dstbucket, includedFiles[2]),
-		3: equals(`cp s3://%s/%s s3://%s/prefix/%s`, srcbucket, includedFiles[3], dstbucket, includedFiles[3]),
-		4: equals(`cp s3://%s/%s s3://%s/prefix/%s`, srcbucket, includedFiles[4], dstbucket, includedFiles[4]),
-	}, sortInput(true))
+	assertLines(t, result.Stderr(), map[int]compareFunc{
+		0: equals(`ERROR "sync --watch=true s3://bucket/* .": --watch flag can only be used to sync a local directory to a bucket or a prefix`),
+	})
+}
 
-	// assert s3 source objects
-	for _, filename := range srcFiles {
-		assert.Assert(t, ensureS3Object(s3client, srcbucket, filename, content))
+func isNoSuchKey(err error) error {
+	if errors.Is(err, errS3NoSuchKey) {
+		return nil
 	}
+	return fmt.Errorf("expected %v, got %v", errS3NoSuchKey, err)
+}
 
-	// assert s3 destination objects
-	for _, filename := range includedFiles {
-		assert.Assert(t, ensureS3Object(s3client, dstbucket, "prefix/"+filename, content))
-	}
+// waitUntil waits until the given condition is satisfied. It fails the test
+// if the condition is not satisfied in a reasonable time.
+func waitUntil(t *testing.T, condition func() error) {
+	t.Helper()
 
-	// assert s3 destination objects which should not be in bucket.
-	for _, filename := range excludedFiles {
-		err := ensureS3Object(s3client, dstbucket, filename, content)
-		assertError(t, err, errS3NoSuchKey)
+	var err error
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if err = condition(); err == nil {
+			return
+		}
 	}
+	t.Fatalf("condition is not satisfied: %v", err)
 }
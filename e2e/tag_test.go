@@ -0,0 +1,107 @@
+package e2e
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/v3/icmd"
+)
+
+// --dry-run tag --set "key=value" "s3://bucket/*"
+func TestTagSetWithWildcardDryRun(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file1.txt", "content")
+	putFile(t, s3client, bucket, "file2.txt", "content")
+	putFile(t, s3client, bucket, "readme.md", "content")
+
+	cmd := s5cmd("--dry-run", "tag", "--set", "retention=short", "--exclude", "*.md", fmt.Sprintf("s3://%v/*", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`tag s3://%v/file1.txt`, bucket),
+		1: equals(`tag s3://%v/file2.txt`, bucket),
+	}, sortInput(true))
+}
+
+// --dry-run tag --delete s3://bucket/object
+func TestTagDeleteDryRun(t *testing.T) {
+	t.Parallel()
+
+	s3client, s5cmd := setup(t)
+
+	bucket := s3BucketFromTestName(t)
+	createBucket(t, s3client, bucket)
+
+	putFile(t, s3client, bucket, "file.txt", "content")
+
+	cmd := s5cmd("--dry-run", "tag", "--delete", fmt.Sprintf("s3://%v/file.txt", bucket))
+	result := icmd.RunCmd(cmd)
+
+	result.Assert(t, icmd.Success)
+
+	assertLines(t, result.Stdout(), map[int]compareFunc{
+		0: equals(`tag s3://%v/file.txt`, bucket),
+	})
+}
+
+func TestTagValidation(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "no source",
+			args:     []string{"tag"},
+			expected: `ERROR "tag": expected only 1 argument`,
+		},
+		{
+			name:     "set and delete",
+			args:     []string{"tag", "--set=key=value", "--delete", "s3://bucket/object"},
+			expected: `ERROR "tag --set=key=value --delete=true s3://bucket/object": --set and --delete flags can not be used together`,
+		},
+		{
+			name:     "invalid tag set",
+			args:     []string{"tag", "--set=key=%zz", "s3://bucket/object"},
+			expected: `ERROR "tag --set=key=%%zz s3://bucket/object": invalid tagging "key=%%zz": invalid URL escape "%%zz"`,
+		},
+		{
+			name:     "local source",
+			args:     []string{"tag", "file.txt"},
+			expected: `ERROR "tag file.txt": source must be a remote object`,
+		},
+		{
+			name:     "prefix without wildcard",
+			args:     []string{"tag", "s3://bucket/prefix/"},
+			expected: `ERROR "tag s3://bucket/prefix/": source argument must contain wildcard character`,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, s5cmd := setup(t)
+
+			cmd := s5cmd(tc.args...)
+			result := icmd.RunCmd(cmd)
+
+			result.Assert(t, icmd.Expected{ExitCode: 1})
+
+			assertLines(t, result.Stderr(), map[int]compareFunc{
+				0: equals(tc.expected),
+			})
+		})
+	}
+}
@@ -4,6 +4,7 @@ package e2e
 
 import (
 	"bytes"
+	"compress/gzip"
 	jsonpkg "encoding/json"
 	"errors"
 	"flag"
@@ -578,6 +579,7 @@ type ensureOpts struct {
 	contentEncoding    *string
 	encryptionMethod   *string
 	encryptionKeyID    *string
+	tagging            *string
 	metadata           map[string]*string
 }
 
@@ -629,6 +631,14 @@ func ensureEncryptionKeyID(encryptionKeyID string) ensureOption {
 		opts.encryptionKeyID = &encryptionKeyID
 	}
 }
+
+// ensureTagging checks the URL encoded tag set of the object.
+func ensureTagging(tagging string) ensureOption {
+	return func(opts *ensureOpts) {
+		opts.tagging = &tagging
+	}
+}
+
 func ensureArbitraryMetadata(metadata map[string]*string) ensureOption {
 	return func(opts *ensureOpts) {
 		opts.metadata = metadata
@@ -647,10 +657,11 @@ func ensureS3Object(
 		fn(opts)
 	}
 
-	output, err := client.GetObject(&s3.GetObjectInput{
+	req, output := client.GetObjectRequest(&s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	})
+	err := req.Send()
 
 	awsErr, ok := err.(awserr.Error)
 	if ok {
@@ -727,6 +738,31 @@ func ensureS3Object(
 		}
 	}
 
+	if opts.tagging != nil {
+		// the fake S3 server doesn't support tagging API, but it keeps the
+		// tagging header of the object as it is.
+		tagging := req.HTTPResponse.Header.Get("X-Amz-Tagging")
+		if tagging == "" {
+			output, err := client.GetObjectTagging(&s3.GetObjectTaggingInput{
+				Bucket: aws.String(bucket),
+				Key:    aws.String(key),
+			})
+			if err != nil {
+				return err
+			}
+
+			values := urlpkg.Values{}
+			for _, tag := range output.TagSet {
+				values.Set(aws.StringValue(tag.Key), aws.StringValue(tag.Value))
+			}
+			tagging = values.Encode()
+		}
+
+		if diff := cmp.Diff(*opts.tagging, tagging); diff != "" {
+			return fmt.Errorf("tagging of %v/%v: (-want +got):\n%v", bucket, key, diff)
+		}
+	}
+
 	if opts.metadata != nil {
 		for mkey := range opts.metadata {
 			if opts.metadata[mkey] == nil || output.Metadata[mkey] == nil {
@@ -1207,3 +1243,60 @@ func indexSlice(slice []string, target string, fn func(str, target string) bool)
 	}
 	return -1
 }
+
+// gzipContent returns the gzip compressed content.
+func gzipContent(t *testing.T, content string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// contentEncodingOf returns the content encoding of the object.
+func contentEncodingOf(t *testing.T, client *s3.S3, bucket, key string) string {
+	t.Helper()
+
+	output, err := client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aws.StringValue(output.ContentEncoding)
+}
+
+// putInventory puts a CSV inventory report of the keys of the source bucket to
+// the destination bucket. It returns the URL of the manifest of the report.
+func putInventory(t *testing.T, client *s3.S3, destination, source string, keys ...string) string {
+	t.Helper()
+
+	var records strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&records, "%q,%q,\"1\",\"2024-01-02T03:04:05.000Z\",\"etag\",\"STANDARD\"\n", source, urlpkg.QueryEscape(key))
+	}
+
+	file := fmt.Sprintf("%v/config/data/inventory.csv.gz", source)
+	putFile(t, client, destination, file, gzipContent(t, records.String()))
+
+	manifest := fmt.Sprintf(`{
+		"sourceBucket": %q,
+		"destinationBucket": "arn:aws:s3:::%v",
+		"version": "2016-11-30",
+		"fileFormat": "CSV",
+		"fileSchema": "Bucket, Key, Size, LastModifiedDate, ETag, StorageClass",
+		"files": [{"key": %q}]
+	}`, source, destination, file)
+
+	key := fmt.Sprintf("%v/config/2024-01-02T03-00Z/manifest.json", source)
+	putFile(t, client, destination, key, manifest)
+
+	return fmt.Sprintf("s3://%v/%v", destination, key)
+}
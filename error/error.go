@@ -80,6 +80,9 @@ var (
 	// ErrObjectIsNewerAndSizesMatch indicates the specified object is newer or same age and sizes of objects match.
 	ErrObjectIsNewerAndSizesMatch = fmt.Errorf("%v and %v", ErrObjectIsNewer, ErrObjectSizesMatch)
 
+	// ErrObjectEtagsMatch indicates the ETags of objects match.
+	ErrObjectEtagsMatch = fmt.Errorf("object ETag matches")
+
 	// ErrObjectIsGlacier indicates the object is in Glacier storage class.
 	ErrorObjectIsGlacier = fmt.Errorf("object is in Glacier storage class")
 )
@@ -88,7 +91,7 @@ var (
 // ErrObjectIsNewer or ErrObjectSizesMatch.
 func IsWarning(err error) bool {
 	switch err {
-	case ErrObjectExists, ErrObjectIsNewer, ErrObjectSizesMatch, ErrObjectIsNewerAndSizesMatch, ErrorObjectIsGlacier:
+	case ErrObjectExists, ErrObjectIsNewer, ErrObjectSizesMatch, ErrObjectIsNewerAndSizesMatch, ErrObjectEtagsMatch, ErrorObjectIsGlacier:
 		return true
 	}
 
@@ -0,0 +1,347 @@
+// Package ignore implements matching of paths against the patterns of
+// gitignore syntax, both from single pattern files and from the ignore files
+// of a directory tree.
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// pattern is a single compiled line of a pattern file.
+type pattern struct {
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher matches slash separated paths against the patterns of a pattern
+// file. The paths are relative to the directory of the pattern file. A nil
+// Matcher matches nothing.
+type Matcher struct {
+	patterns []pattern
+}
+
+// New compiles the given lines of gitignore syntax.
+func New(lines []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, line := range lines {
+		p, ok, err := parsePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return m, nil
+}
+
+// Parse reads the patterns from the given reader.
+func Parse(r io.Reader) (*Matcher, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return New(lines)
+}
+
+// ReadFile reads the patterns from the given file.
+func ReadFile(filename string) (*Matcher, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// Match reports whether the given path is matched by the patterns. A path is
+// matched if one of its parent directories is matched, since the contents of
+// an excluded directory can not be included again, as in git. Otherwise the
+// last pattern which matches the path decides.
+func (m *Matcher) Match(path string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+
+	return matchPath(path, isDir, func(path string, isDir bool) bool {
+		_, matched := m.match(path, isDir)
+		return matched
+	})
+}
+
+// match returns whether any of the patterns matches the path itself, without
+// its parents, and whether the path is matched by the last one of them.
+func (m *Matcher) match(path string, isDir bool) (found, matched bool) {
+	if m == nil {
+		return false, false
+	}
+
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.regex.MatchString(path) {
+			found, matched = true, !p.negate
+		}
+	}
+	return found, matched
+}
+
+// matchPath checks the parent directories of the path from top to bottom,
+// then the path itself.
+func matchPath(path string, isDir bool, match func(string, bool) bool) bool {
+	path = strings.Trim(path, "/")
+	if path == "" || path == "." {
+		return false
+	}
+
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && match(path[:i], true) {
+			return true
+		}
+	}
+	return match(path, isDir)
+}
+
+// Tree matches the paths of a local directory tree against the patterns of
+// the ignore files in the directory and its subdirectories. The patterns of
+// an ignore file are relative to the directory the file is in, and the
+// patterns of the ignore files in deeper directories take precedence. The
+// ignore files are read as they are needed. It is safe for concurrent use.
+type Tree struct {
+	root string
+	name string
+
+	mu       sync.Mutex
+	matchers map[string]*Matcher
+	errs     map[string]error
+}
+
+// NewTree returns the Tree of the ignore files with the given name in the
+// root directory.
+func NewTree(root, name string) *Tree {
+	return &Tree{
+		root:     root,
+		name:     name,
+		matchers: map[string]*Matcher{},
+		errs:     map[string]error{},
+	}
+}
+
+// Root returns the root directory of the tree.
+func (t *Tree) Root() string {
+	return t.root
+}
+
+// Ignored reports whether the given slash separated path, which is relative
+// to the root of the tree, is ignored. A nil Tree ignores nothing. It returns
+// an error if an ignore file which applies to the path can not be read.
+func (t *Tree) Ignored(relpath string, isDir bool) (bool, error) {
+	if t == nil {
+		return false, nil
+	}
+
+	var err error
+	ignored := matchPath(relpath, isDir, func(relpath string, isDir bool) bool {
+		if err != nil {
+			return false
+		}
+
+		var matched bool
+		// the ignore files of the parent directories of the path, from the
+		// root to the deepest one.
+		dir := ""
+		for {
+			var m *Matcher
+			m, err = t.matcher(dir)
+			if err != nil {
+				return false
+			}
+
+			rel := relpath
+			if dir != "" {
+				rel = strings.TrimPrefix(relpath, dir+"/")
+			}
+			if found, ok := m.match(rel, isDir); found {
+				matched = ok
+			}
+
+			i := strings.IndexByte(rel, '/')
+			if i < 0 {
+				return matched
+			}
+			dir = path.Join(dir, rel[:i])
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+	return ignored, nil
+}
+
+// matcher returns the Matcher of the ignore file in the given directory. It
+// returns nil if there is no ignore file in the directory.
+func (t *Tree) matcher(dir string) (*Matcher, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if m, ok := t.matchers[dir]; ok {
+		return m, nil
+	}
+	if err, ok := t.errs[dir]; ok {
+		return nil, err
+	}
+
+	m, err := ReadFile(filepath.Join(t.root, filepath.FromSlash(dir), t.name))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.errs[dir] = err
+			return nil, err
+		}
+		m = nil
+	}
+	t.matchers[dir] = m
+	return m, nil
+}
+
+// parsePattern compiles a line of a pattern file. It reports false if the
+// line is blank or a comment.
+func parsePattern(line string) (pattern, bool, error) {
+	line = strings.TrimSuffix(line, "\r")
+
+	// trailing spaces are ignored unless they are escaped.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false, nil
+	}
+
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	// a pattern with a slash at the beginning or in the middle is relative
+	// to the directory of the pattern file, otherwise it matches at any
+	// level.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return pattern{}, false, nil
+	}
+
+	expr := "^"
+	if !anchored {
+		expr += "(?:.*/)?"
+	}
+	expr += translate(line) + "$"
+
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return pattern{}, false, err
+	}
+	p.regex = regex
+	return p, true, nil
+}
+
+// translate converts a glob of gitignore syntax to a regular expression.
+func translate(glob string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		atSegmentStart := i == 0 || glob[i-1] == '/'
+
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/") && atSegmentStart:
+			// zero or more directories.
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && atSegmentStart:
+			// everything inside.
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			class, n := translateClass(glob[i:])
+			if n == 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			sb.WriteString(class)
+			i += n - 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return sb.String()
+}
+
+// translateClass converts the bracket expression at the beginning of the
+// glob. It returns the length of the expression in the glob, or zero if the
+// bracket is not closed.
+func translateClass(glob string) (string, int) {
+	var sb strings.Builder
+	sb.WriteString("[")
+
+	i := 1
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		// a negated class never matches the separator.
+		sb.WriteString("^/")
+		i++
+	}
+
+	for start := i; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == ']' && i > start:
+			sb.WriteString("]")
+			return sb.String(), i + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(quoteClassChar(glob[i]))
+		case c == '\\' || c == '[' || c == ']' || c == '^':
+			sb.WriteString(`\` + glob[i:i+1])
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", 0
+}
+
+// quoteClassChar escapes the given character to be used literally in a
+// character class.
+func quoteClassChar(c byte) string {
+	if c == '-' || strings.ContainsRune(`\[]^`, rune(c)) {
+		return `\` + string(c)
+	}
+	return string(c)
+}
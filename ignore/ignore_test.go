@@ -0,0 +1,129 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMatcherMatch(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{name: "comment", patterns: []string{"#a"}, path: "#a", expected: false},
+		{name: "escaped comment", patterns: []string{`\#a`}, path: "#a", expected: true},
+		{name: "blank line", patterns: []string{"", "   "}, path: "a", expected: false},
+		{name: "trailing spaces", patterns: []string{"a  "}, path: "a", expected: true},
+		{name: "escaped trailing space", patterns: []string{`a\ `}, path: "a ", expected: true},
+		{name: "name at any level", patterns: []string{"a.txt"}, path: "x/y/a.txt", expected: true},
+		{name: "star", patterns: []string{"*.log"}, path: "x/debug.log", expected: true},
+		{name: "star does not match separator", patterns: []string{"x*.log"}, path: "x/debug.log", expected: false},
+		{name: "question mark", patterns: []string{"a?c"}, path: "abc", expected: true},
+		{name: "question mark does not match separator", patterns: []string{"a?c"}, path: "a/c", expected: false},
+		{name: "class", patterns: []string{"[a-c].txt"}, path: "b.txt", expected: true},
+		{name: "negated class", patterns: []string{"[!a-c].txt"}, path: "b.txt", expected: false},
+		{name: "negated class other", patterns: []string{"[!a-c].txt"}, path: "d.txt", expected: true},
+		{name: "unclosed class", patterns: []string{"[a"}, path: "[a", expected: true},
+		{name: "anchored", patterns: []string{"/a.txt"}, path: "a.txt", expected: true},
+		{name: "anchored in subdirectory", patterns: []string{"/a.txt"}, path: "x/a.txt", expected: false},
+		{name: "middle slash is anchored", patterns: []string{"x/a.txt"}, path: "y/x/a.txt", expected: false},
+		{name: "middle slash", patterns: []string{"x/a.txt"}, path: "x/a.txt", expected: true},
+		{name: "directory only matches directory", patterns: []string{"build/"}, path: "build", isDir: true, expected: true},
+		{name: "directory only does not match file", patterns: []string{"build/"}, path: "build", expected: false},
+		{name: "directory only matches contents", patterns: []string{"build/"}, path: "x/build/a/b.o", expected: true},
+		{name: "leading double star", patterns: []string{"**/logs"}, path: "a/b/logs", isDir: true, expected: true},
+		{name: "leading double star at top", patterns: []string{"**/logs"}, path: "logs", isDir: true, expected: true},
+		{name: "trailing double star", patterns: []string{"logs/**"}, path: "logs/a/b.log", expected: true},
+		{name: "trailing double star is anchored", patterns: []string{"logs/**"}, path: "x/logs/a.log", expected: false},
+		{name: "middle double star", patterns: []string{"a/**/b"}, path: "a/x/y/b", expected: true},
+		{name: "middle double star zero directories", patterns: []string{"a/**/b"}, path: "a/b", expected: true},
+		{name: "negation", patterns: []string{"*.log", "!keep.log"}, path: "keep.log", expected: false},
+		{name: "last match wins", patterns: []string{"!keep.log", "*.log"}, path: "keep.log", expected: true},
+		{name: "escaped negation", patterns: []string{`\!a`}, path: "!a", expected: true},
+		{name: "excluded directory can not be included", patterns: []string{"build/", "!build/keep.txt"}, path: "build/keep.txt", expected: true},
+		{name: "directory contents included", patterns: []string{"build/*", "!build/keep.txt"}, path: "build/keep.txt", expected: false},
+		{name: "directory contents excluded", patterns: []string{"build/*", "!build/keep.txt"}, path: "build/other.txt", expected: true},
+		{name: "literal dot", patterns: []string{"a.txt"}, path: "abtxt", expected: false},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := New(tc.patterns)
+			assert.NilError(t, err)
+			assert.Equal(t, m.Match(tc.path, tc.isDir), tc.expected)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	m, err := Parse(strings.NewReader("# comment\r\n*.tmp\r\n!important.tmp\r\n"))
+	assert.NilError(t, err)
+	assert.Equal(t, m.Match("a/b.tmp", false), true)
+	assert.Equal(t, m.Match("important.tmp", false), false)
+
+	var nilMatcher *Matcher
+	assert.Equal(t, nilMatcher.Match("a", false), false)
+}
+
+func TestTreeIgnored(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.NilError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NilError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	writeFile(".ignore", "*.log\n/top.txt\ncache/\n")
+	writeFile("a/.ignore", "!keep.log\ntop.txt\n")
+	writeFile("a/b/.ignore", "/local.txt\n")
+
+	testcases := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{path: "debug.log", expected: true},
+		{path: "top.txt", expected: true},
+		{path: "x/top.txt", expected: false},
+		{path: "cache", isDir: true, expected: true},
+		{path: "x/cache/file", expected: true},
+		{path: "a/debug.log", expected: true},
+		{path: "a/keep.log", expected: false},
+		{path: "a/c/keep.log", expected: false},
+		{path: "a/top.txt", expected: true},
+		{path: "a/b/top.txt", expected: true},
+		{path: "a/b/local.txt", expected: true},
+		{path: "a/local.txt", expected: false},
+		{path: "a/b/c/local.txt", expected: false},
+		{path: "other.txt", expected: false},
+	}
+
+	tree := NewTree(root, ".ignore")
+	for _, tc := range testcases {
+		ignored, err := tree.Ignored(tc.path, tc.isDir)
+		assert.NilError(t, err)
+		assert.Equal(t, ignored, tc.expected, tc.path)
+	}
+
+	var nilTree *Tree
+	ignored, err := nilTree.Ignored("debug.log", false)
+	assert.NilError(t, err)
+	assert.Equal(t, ignored, false)
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import "io"
+
+// Direction is the direction of a transfer.
+type Direction int
+
+const (
+	// Upload is the direction of the transfers to remote storage.
+	Upload Direction = iota
+	// Download is the direction of the transfers from remote storage.
+	Download
+)
+
+var (
+	total    *Limiter
+	upload   *Limiter
+	download *Limiter
+)
+
+// Init creates the global limiters. totalRate is shared by all transfers
+// while uploadRate and downloadRate are applied only to the transfers in the
+// corresponding direction. Non-positive rates are unlimited.
+func Init(totalRate, uploadRate, downloadRate int64) {
+	total = New(totalRate)
+	upload = New(uploadRate)
+	download = New(downloadRate)
+}
+
+// Wait blocks until n bytes are allowed to be transferred in the given
+// direction.
+func Wait(dir Direction, n int) {
+	if dir == Upload {
+		wait(n, total, upload)
+		return
+	}
+	wait(n, total, download)
+}
+
+type reader struct {
+	r   io.Reader
+	dir Direction
+}
+
+// NewReader returns an io.Reader which limits the reads from r by the global
+// limiters of the given direction.
+func NewReader(r io.Reader, dir Direction) io.Reader {
+	return &reader{r: r, dir: dir}
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	Wait(r.dir, n)
+	return n, err
+}
+
+type writerAt struct {
+	w   io.WriterAt
+	dir Direction
+}
+
+// NewWriterAt returns an io.WriterAt which limits the writes to w by the
+// global limiters of the given direction.
+func NewWriterAt(w io.WriterAt, dir Direction) io.WriterAt {
+	return &writerAt{w: w, dir: dir}
+}
+
+func (w *writerAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := w.w.WriteAt(p, off)
+	Wait(w.dir, n)
+	return n, err
+}
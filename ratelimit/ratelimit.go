@@ -0,0 +1,125 @@
+// Package ratelimit implements token bucket rate limiters for limiting the
+// bandwidth of transfers.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket which is refilled at a constant rate, in bytes per
+// second. The bucket can hold at most one second worth of tokens. A nil
+// Limiter doesn't limit anything.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// New creates a new Limiter which allows rate bytes per second. It returns
+// nil if rate is not positive.
+func New(rate int64) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &Limiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// WaitN blocks until n bytes are allowed by the limiter.
+func (l *Limiter) WaitN(n int) {
+	wait(n, l)
+}
+
+// reserve takes n tokens from the bucket and returns the duration the caller
+// must wait for the tokens to be available. The bucket may go into debt so
+// that requests larger than the bucket size can still be served.
+func (l *Limiter) reserve(now time.Time, n int) time.Duration {
+	if l == nil || n <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = math.Min(l.rate, l.tokens+elapsed.Seconds()*l.rate)
+		l.last = now
+	}
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// wait reserves n tokens from all the given limiters and blocks until all of
+// them allow n bytes.
+func wait(n int, limiters ...*Limiter) {
+	var (
+		now   = time.Now()
+		delay time.Duration
+	)
+	for _, l := range limiters {
+		if d := l.reserve(now, n); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+var rateUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1e9,
+	"gib": 1 << 30,
+}
+
+// ParseRate parses a transfer rate such as "50MiB/s", "500KB/s" or "1.5M" into
+// bytes per second. The "/s" suffix is optional. Single letter units and IEC
+// units (KiB, MiB, GiB) are powers of 1024 while SI units (KB, MB, GB) are
+// powers of 1000.
+func ParseRate(s string) (int64, error) {
+	str := strings.TrimSuffix(strings.TrimSpace(s), "/s")
+
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(str)
+	}
+
+	value, err := strconv.ParseFloat(str[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+
+	unit, ok := rateUnits[strings.ToLower(str[i:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid rate %q: unknown unit %q", s, str[i:])
+	}
+
+	rate := int64(value * unit)
+	if rate <= 0 {
+		return 0, fmt.Errorf("invalid rate %q: rate must be positive", s)
+	}
+	return rate, nil
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseRate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		input    string
+		expected int64
+		err      string
+	}{
+		{input: "1024", expected: 1024},
+		{input: "100B/s", expected: 100},
+		{input: "500K", expected: 500 << 10},
+		{input: "500KB/s", expected: 500_000},
+		{input: "50MiB/s", expected: 50 << 20},
+		{input: "50mb", expected: 50_000_000},
+		{input: "1.5M", expected: 3 << 19},
+		{input: "2GiB/s", expected: 2 << 30},
+		{input: "", err: `invalid rate ""`},
+		{input: "MiB/s", err: `invalid rate "MiB/s"`},
+		{input: "10TB/s", err: `invalid rate "10TB/s": unknown unit "TB"`},
+		{input: "0", err: `invalid rate "0": rate must be positive`},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+
+			rate, err := ParseRate(tc.input)
+			if tc.err != "" {
+				assert.Error(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, rate, tc.expected)
+		})
+	}
+}
+
+func TestLimiterReserve(t *testing.T) {
+	t.Parallel()
+
+	l := New(1000)
+	now := l.last
+
+	// the bucket is full initially.
+	assert.Equal(t, l.reserve(now, 1000), time.Duration(0))
+
+	// the bucket goes into debt for the requests larger than its capacity.
+	assert.Equal(t, l.reserve(now, 500), 500*time.Millisecond)
+	assert.Equal(t, l.reserve(now, 500), time.Second)
+
+	// the debt is paid off over time.
+	now = now.Add(time.Second)
+	assert.Equal(t, l.reserve(now, 0), time.Duration(0))
+	assert.Equal(t, l.reserve(now, 100), 100*time.Millisecond)
+
+	// the bucket can not hold more than one second worth of tokens.
+	now = now.Add(time.Minute)
+	assert.Equal(t, l.reserve(now, 1500), 500*time.Millisecond)
+}
+
+func TestNilLimiter(t *testing.T) {
+	t.Parallel()
+
+	var l *Limiter
+	assert.Assert(t, New(0) == nil)
+	assert.Equal(t, l.reserve(time.Now(), 1<<30), time.Duration(0))
+}
@@ -0,0 +1,1175 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	urlpkg "net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	// azureAPIVersion is the version of the Blob service REST API used by the
+	// requests.
+	azureAPIVersion = "2020-10-02"
+
+	// azureSASVersion is the version of the shared access signatures
+	// generated by Presign.
+	azureSASVersion = "2019-12-12"
+
+	// azureDefaultBlockSize is the size of the blocks of the uploads when the
+	// part size is not given.
+	azureDefaultBlockSize = 50 * 1024 * 1024
+
+	// azureMaxBlocks is the maximum number of blocks of a block blob.
+	azureMaxBlocks = 50000
+
+	// azureDeleteConcurrency is the number of blobs deleted in parallel by
+	// MultiDelete, since the Blob service deletes one blob per request.
+	azureDeleteConcurrency = 16
+
+	// azureDevelopmentAccount and azureDevelopmentKey are the well-known
+	// credentials of the Azurite emulator.
+	azureDevelopmentAccount  = "devstoreaccount1"
+	azureDevelopmentKey      = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	azureDevelopmentEndpoint = "http://127.0.0.1:10000/" + azureDevelopmentAccount
+)
+
+func init() {
+	Register("az", url.SchemeOptions{}, func(_ context.Context, _ *url.URL, opts Options) (Storage, error) {
+		return NewAzureClient(opts)
+	})
+}
+
+// azureClients caches the clients by their options, so that the connections
+// are reused by the operations.
+var azureClients sync.Map
+
+// Azure is the Storage implementation of Azure Blob Storage. Containers are
+// addressed as buckets and blobs as objects, e.g. az://container/path/blob.
+//
+// The storage account is configured with AZURE_STORAGE_CONNECTION_STRING or
+// with AZURE_STORAGE_ACCOUNT and either AZURE_STORAGE_KEY or
+// AZURE_STORAGE_SAS_TOKEN environment variables. AZURE_STORAGE_BLOB_ENDPOINT
+// overrides the endpoint of the account, e.g. to use Azurite.
+type Azure struct {
+	client     *http.Client
+	endpoint   *urlpkg.URL
+	account    string
+	key        []byte
+	sas        urlpkg.Values
+	maxRetries int
+	dryRun     bool
+}
+
+// NewAzureClient returns a client of the Azure Blob Storage account which is
+// configured with the environment variables.
+func NewAzureClient(opts Options) (*Azure, error) {
+	// only the options used by the client are kept in the cache key.
+	key := Options{
+		MaxRetries:    opts.MaxRetries,
+		NoVerifySSL:   opts.NoVerifySSL,
+		DryRun:        opts.DryRun,
+		NoSignRequest: opts.NoSignRequest,
+	}
+	if client, ok := azureClients.Load(key); ok {
+		return client.(*Azure), nil
+	}
+
+	client, err := newAzureClient(key, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := azureClients.LoadOrStore(key, client)
+	return actual.(*Azure), nil
+}
+
+func newAzureClient(opts Options, getenv func(string) string) (*Azure, error) {
+	creds, err := loadAzureCredentials(getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableCompression = true
+	if opts.NoVerifySSL {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	a := &Azure{
+		client:     &http.Client{Transport: transport},
+		endpoint:   creds.endpoint,
+		account:    creds.account,
+		maxRetries: opts.MaxRetries,
+		dryRun:     opts.DryRun,
+	}
+
+	if !opts.NoSignRequest {
+		a.key = creds.key
+		a.sas = creds.sas
+	}
+	return a, nil
+}
+
+type azureCredentials struct {
+	account  string
+	key      []byte
+	sas      urlpkg.Values
+	endpoint *urlpkg.URL
+}
+
+// loadAzureCredentials reads the account, the credentials and the endpoint
+// from the environment variables. The connection string takes precedence
+// over the other variables.
+func loadAzureCredentials(getenv func(string) string) (*azureCredentials, error) {
+	var (
+		account  = getenv("AZURE_STORAGE_ACCOUNT")
+		key      = getenv("AZURE_STORAGE_KEY")
+		sas      = getenv("AZURE_STORAGE_SAS_TOKEN")
+		endpoint = getenv("AZURE_STORAGE_BLOB_ENDPOINT")
+		protocol = "https"
+		suffix   = "core.windows.net"
+	)
+
+	if connStr := getenv("AZURE_STORAGE_CONNECTION_STRING"); connStr != "" {
+		for _, field := range strings.Split(connStr, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+			if !ok {
+				continue
+			}
+			switch strings.ToLower(name) {
+			case "accountname":
+				account = value
+			case "accountkey":
+				key = value
+			case "sharedaccesssignature":
+				sas = value
+			case "blobendpoint":
+				endpoint = value
+			case "defaultendpointsprotocol":
+				protocol = value
+			case "endpointsuffix":
+				suffix = value
+			case "usedevelopmentstorage":
+				if strings.EqualFold(value, "true") {
+					account = azureDevelopmentAccount
+					key = azureDevelopmentKey
+					endpoint = azureDevelopmentEndpoint
+				}
+			}
+		}
+	}
+
+	if account == "" && endpoint == "" {
+		return nil, fmt.Errorf("azure storage account is not set, set AZURE_STORAGE_ACCOUNT or AZURE_STORAGE_CONNECTION_STRING environment variable")
+	}
+
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("%v://%v.blob.%v", protocol, account, suffix)
+	}
+
+	u, err := urlpkg.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid azure blob endpoint %q", endpoint)
+	}
+
+	if account == "" {
+		// the account is the first label of the host name of the default
+		// endpoints, e.g. account.blob.core.windows.net.
+		account, _, _ = strings.Cut(u.Hostname(), ".")
+	}
+
+	creds := &azureCredentials{
+		account:  account,
+		endpoint: u,
+	}
+
+	if key != "" {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid azure storage account key: %w", err)
+		}
+		creds.key = decoded
+	}
+
+	if sas != "" {
+		values, err := urlpkg.ParseQuery(strings.TrimPrefix(sas, "?"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid azure shared access signature: %w", err)
+		}
+		creds.sas = values
+	}
+
+	return creds, nil
+}
+
+// ErrAzure is the error returned by the Blob service.
+type ErrAzure struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *ErrAzure) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%v status code: %v", e.Code, e.StatusCode)
+	}
+	return fmt.Sprintf("%v: %v status code: %v", e.Code, e.Message, e.StatusCode)
+}
+
+func isAzureNotFound(err error) bool {
+	azErr, ok := err.(*ErrAzure)
+	return ok && azErr.StatusCode == http.StatusNotFound
+}
+
+// azureRequest describes a request of the Blob service. Container and blob
+// are empty for the requests of the account.
+type azureRequest struct {
+	method    string
+	container string
+	blob      string
+	query     urlpkg.Values
+	header    http.Header
+	body      []byte
+}
+
+// resourceURL returns the URL of the container or the blob, without the
+// query.
+func (a *Azure) resourceURL(container, blob string) *urlpkg.URL {
+	u := *a.endpoint
+	if container != "" {
+		u.Path += "/" + container
+	}
+	if blob != "" {
+		u.Path += "/" + blob
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawPath = ""
+	return &u
+}
+
+// do sends the request and returns the response if it is successful. The
+// failed requests are retried as long as they are transient.
+func (a *Azure) do(ctx context.Context, r azureRequest) (*http.Response, error) {
+	u := a.resourceURL(r.container, r.blob)
+
+	query := urlpkg.Values{}
+	for k, v := range r.query {
+		query[k] = v
+	}
+	for k, v := range a.sas {
+		query[k] = v
+	}
+	u.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, r.method, u.String(), bytes.NewReader(r.body))
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = int64(len(r.body))
+		if r.body == nil {
+			req.Body = http.NoBody
+		}
+
+		for k, v := range r.header {
+			req.Header[k] = v
+		}
+		req.Header.Set("x-ms-version", azureAPIVersion)
+		req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+
+		if a.key != nil {
+			a.sign(req)
+		}
+
+		resp, err := a.client.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		if err == nil {
+			err = readAzureError(resp)
+		}
+
+		if !isAzureRetryable(err) || attempt >= a.maxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		msg := log.DebugMessage{Err: fmt.Sprintf("retrying %v %v: %v", r.method, u.Path, err)}
+		log.Debug(msg)
+
+		select {
+		case <-time.After(retryDelay(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func readAzureError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	azErr := &ErrAzure{
+		StatusCode: resp.StatusCode,
+		Code:       resp.Header.Get("x-ms-error-code"),
+	}
+
+	var body struct {
+		Code    string
+		Message string
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if xml.Unmarshal(data, &body) == nil {
+		if body.Code != "" {
+			azErr.Code = body.Code
+		}
+		// only the first line is the message, the rest is the request
+		// identifiers.
+		azErr.Message, _, _ = strings.Cut(body.Message, "\n")
+	}
+
+	if azErr.Code == "" {
+		azErr.Code = http.StatusText(resp.StatusCode)
+	}
+	return azErr
+}
+
+func isAzureRetryable(err error) bool {
+	azErr, ok := err.(*ErrAzure)
+	if !ok {
+		// network errors
+		return true
+	}
+	return azErr.StatusCode == http.StatusTooManyRequests ||
+		azErr.StatusCode == http.StatusRequestTimeout ||
+		azErr.StatusCode >= 500
+}
+
+// retryDelay returns the exponential backoff delay of the retries of the
+// backends which are not based on the AWS SDK.
+func retryDelay(attempt int) time.Duration {
+	delay := time.Duration(math.Pow(2, float64(attempt))) * 100 * time.Millisecond
+	if delay > 10*time.Second {
+		delay = 10 * time.Second
+	}
+	return delay
+}
+
+// sign authorizes the request with the account key using the Shared Key
+// scheme.
+func (a *Azure) sign(req *http.Request) {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, x-ms-date is used instead.
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}, "\n") + "\n" + canonicalizedAzureHeaders(req.Header) + a.canonicalizedResource(req.URL)
+
+	req.Header.Set("Authorization", fmt.Sprintf("SharedKey %v:%v", a.account, a.signature(stringToSign)))
+}
+
+func (a *Azure) signature(stringToSign string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func canonicalizedAzureHeaders(header http.Header) string {
+	var names []string
+	for name := range header {
+		if strings.HasPrefix(strings.ToLower(name), "x-ms-") {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(strings.ToLower(name))
+		b.WriteString(":")
+		b.WriteString(strings.TrimSpace(strings.Join(header[name], ",")))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (a *Azure) canonicalizedResource(u *urlpkg.URL) string {
+	var b strings.Builder
+	b.WriteString("/" + a.account + u.EscapedPath())
+
+	query := u.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := query[name]
+		sort.Strings(values)
+		b.WriteString("\n" + strings.ToLower(name) + ":" + strings.Join(values, ","))
+	}
+	return b.String()
+}
+
+// objectFromAzureHeader returns the object described by the headers of a blob.
+func objectFromAzureHeader(u *url.URL, header http.Header) *Object {
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	obj := &Object{
+		URL:          u,
+		Etag:         strings.Trim(header.Get("ETag"), `"`),
+		Size:         size,
+		StorageClass: StorageClass(header.Get("x-ms-access-tier")),
+	}
+	if mod, err := http.ParseTime(header.Get("Last-Modified")); err == nil {
+		mod = mod.UTC()
+		obj.ModTime = &mod
+	}
+	return obj
+}
+
+// Stat retrieves the properties of the blob.
+func (a *Azure) Stat(ctx context.Context, u *url.URL) (*Object, error) {
+	obj, _, err := a.HeadObject(ctx, u)
+	return obj, err
+}
+
+// HeadObject retrieves the properties and the metadata of the blob.
+func (a *Azure) HeadObject(ctx context.Context, u *url.URL) (*Object, *Metadata, error) {
+	resp, err := a.do(ctx, azureRequest{
+		method:    http.MethodHead,
+		container: u.Bucket,
+		blob:      u.Path,
+	})
+	if err != nil {
+		if isAzureNotFound(err) {
+			return nil, nil, &ErrGivenObjectNotFound{ObjectAbsPath: u.Absolute()}
+		}
+		return nil, nil, err
+	}
+	resp.Body.Close()
+
+	metadata := &Metadata{
+		ContentType:        resp.Header.Get("Content-Type"),
+		ContentEncoding:    resp.Header.Get("Content-Encoding"),
+		ContentDisposition: resp.Header.Get("Content-Disposition"),
+		ContentLanguage:    resp.Header.Get("Content-Language"),
+		CacheControl:       resp.Header.Get("Cache-Control"),
+		StorageClass:       resp.Header.Get("x-ms-access-tier"),
+	}
+	for name, values := range resp.Header {
+		lower := strings.ToLower(name)
+		if !strings.HasPrefix(lower, "x-ms-meta-") {
+			continue
+		}
+		if metadata.UserDefined == nil {
+			metadata.UserDefined = map[string]string{}
+		}
+		metadata.UserDefined[strings.TrimPrefix(lower, "x-ms-meta-")] = strings.Join(values, ",")
+	}
+	if count, err := strconv.ParseInt(resp.Header.Get("x-ms-tag-count"), 10, 64); err == nil {
+		metadata.TagCount = count
+	}
+
+	return objectFromAzureHeader(u, resp.Header), metadata, nil
+}
+
+type azureBlobList struct {
+	Blobs struct {
+		Blob []struct {
+			Name       string
+			Properties struct {
+				LastModified  string `xml:"Last-Modified"`
+				Etag          string
+				ContentLength int64 `xml:"Content-Length"`
+				AccessTier    string
+			}
+		}
+		BlobPrefix []struct {
+			Name string
+		}
+	}
+	NextMarker string
+}
+
+// List lists the blobs and the prefixes of the container in the same manner
+// as S3.
+func (a *Azure) List(ctx context.Context, src *url.URL, _ bool) <-chan *Object {
+	objCh := make(chan *Object)
+
+	go func() {
+		defer close(objCh)
+
+		query := urlpkg.Values{
+			"restype":    {"container"},
+			"comp":       {"list"},
+			"maxresults": {"5000"},
+		}
+		if src.Prefix != "" {
+			query.Set("prefix", src.Prefix)
+		}
+		if src.Delimiter != "" {
+			query.Set("delimiter", src.Delimiter)
+		}
+
+		var (
+			objectFound bool
+			now         = time.Now().UTC()
+		)
+		for {
+			var list azureBlobList
+			if err := a.doXML(ctx, azureRequest{method: http.MethodGet, container: src.Bucket, query: query}, &list); err != nil {
+				sendError(ctx, err, objCh)
+				return
+			}
+
+			for _, p := range list.Blobs.BlobPrefix {
+				if !src.Match(p.Name) {
+					continue
+				}
+
+				newurl := src.Clone()
+				newurl.Path = p.Name
+				sendObject(ctx, &Object{URL: newurl, Type: ObjectType{os.ModeDir}}, objCh)
+				objectFound = true
+			}
+
+			for _, b := range list.Blobs.Blob {
+				if !src.Match(b.Name) {
+					continue
+				}
+
+				mod, _ := http.ParseTime(b.Properties.LastModified)
+				mod = mod.UTC()
+				// skip the blobs created after the listing is started.
+				if mod.After(now) {
+					objectFound = true
+					continue
+				}
+
+				var objtype os.FileMode
+				if strings.HasSuffix(b.Name, "/") {
+					objtype = os.ModeDir
+				}
+
+				newurl := src.Clone()
+				newurl.Path = b.Name
+				sendObject(ctx, &Object{
+					URL:          newurl,
+					Etag:         strings.Trim(b.Properties.Etag, `"`),
+					ModTime:      &mod,
+					Type:         ObjectType{objtype},
+					Size:         b.Properties.ContentLength,
+					StorageClass: StorageClass(b.Properties.AccessTier),
+				}, objCh)
+				objectFound = true
+			}
+
+			if list.NextMarker == "" {
+				break
+			}
+			query.Set("marker", list.NextMarker)
+		}
+
+		if !objectFound && !src.IsBucket() {
+			sendError(ctx, ErrNoObjectFound, objCh)
+		}
+	}()
+
+	return objCh
+}
+
+func (a *Azure) doXML(ctx context.Context, r azureRequest, v interface{}) error {
+	resp, err := a.do(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+// Delete deletes the blob. Deleting a missing blob is not an error, as in S3.
+func (a *Azure) Delete(ctx context.Context, u *url.URL) error {
+	if a.dryRun {
+		return nil
+	}
+
+	resp, err := a.do(ctx, azureRequest{
+		method:    http.MethodDelete,
+		container: u.Bucket,
+		blob:      u.Path,
+		header:    http.Header{"x-ms-delete-snapshots": {"include"}},
+	})
+	if err != nil {
+		if isAzureNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// MultiDelete deletes the blobs read from the channel. The Blob service
+// deletes one blob per request, so the blobs are deleted in parallel.
+func (a *Azure) MultiDelete(ctx context.Context, urlch <-chan *url.URL) <-chan *Object {
+	resultch := make(chan *Object)
+
+	go func() {
+		defer close(resultch)
+
+		var wg sync.WaitGroup
+		for i := 0; i < azureDeleteConcurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for u := range urlch {
+					sendObject(ctx, &Object{URL: u, Err: a.Delete(ctx, u)}, resultch)
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+
+	return resultch
+}
+
+// Copy copies the blob on the server side. The properties and the metadata
+// of the source blob are kept unless the metadata is replaced.
+func (a *Azure) Copy(ctx context.Context, from, to *url.URL, metadata Metadata) error {
+	if a.dryRun {
+		return nil
+	}
+
+	source := a.resourceURL(from.Bucket, from.Path)
+	if len(a.sas) > 0 {
+		source.RawQuery = a.sas.Encode()
+	}
+
+	header := http.Header{"x-ms-copy-source": {source.String()}}
+	if metadata.StorageClass != "" {
+		header.Set("x-ms-access-tier", metadata.StorageClass)
+	}
+	if len(metadata.Tags) > 0 {
+		header.Set("x-ms-tags", encodeTags(metadata.Tags))
+	}
+
+	replace := metadata.Directive == "REPLACE"
+	if replace || len(metadata.UserDefined) > 0 {
+		setAzureUserMetadata(header, metadata.UserDefined)
+	}
+
+	resp, err := a.do(ctx, azureRequest{
+		method:    http.MethodPut,
+		container: to.Bucket,
+		blob:      to.Path,
+		header:    header,
+	})
+	if err != nil {
+		if isAzureNotFound(err) {
+			return &ErrGivenObjectNotFound{ObjectAbsPath: from.Absolute()}
+		}
+		return err
+	}
+	resp.Body.Close()
+
+	if err := a.waitCopy(ctx, to, resp.Header.Get("x-ms-copy-status")); err != nil {
+		return err
+	}
+
+	if !replace && metadata.ContentType == "" {
+		return nil
+	}
+
+	// properties are always copied from the source blob, they are set once
+	// the copy is completed.
+	properties := http.Header{}
+	setAzureProperties(properties, metadata)
+	resp, err = a.do(ctx, azureRequest{
+		method:    http.MethodPut,
+		container: to.Bucket,
+		blob:      to.Path,
+		query:     urlpkg.Values{"comp": {"properties"}},
+		header:    properties,
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// waitCopy waits for the completion of the asynchronous copy operations.
+// Copies within the same storage account are mostly completed synchronously.
+func (a *Azure) waitCopy(ctx context.Context, u *url.URL, status string) error {
+	for delay := 100 * time.Millisecond; ; delay *= 2 {
+		switch status {
+		case "success":
+			return nil
+		case "aborted", "failed":
+			return fmt.Errorf("copy of %v is %v", u, status)
+		}
+
+		if delay > 5*time.Second {
+			delay = 5 * time.Second
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		resp, err := a.do(ctx, azureRequest{
+			method:    http.MethodHead,
+			container: u.Bucket,
+			blob:      u.Path,
+		})
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		status = resp.Header.Get("x-ms-copy-status")
+	}
+}
+
+// Read returns the contents of the blob as a stream.
+func (a *Azure) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
+	resp, err := a.do(ctx, azureRequest{
+		method:    http.MethodGet,
+		container: src.Bucket,
+		blob:      src.Path,
+	})
+	if err != nil {
+		if isAzureNotFound(err) {
+			return nil, &ErrGivenObjectNotFound{ObjectAbsPath: src.Absolute()}
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Get downloads the blob to the destination. Blobs larger than the part
+// size are downloaded in ranges in parallel.
+func (a *Azure) Get(ctx context.Context, from *url.URL, to io.WriterAt, concurrency int, partSize int64) (int64, error) {
+	if a.dryRun {
+		return 0, nil
+	}
+
+	obj, err := a.Stat(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if partSize <= 0 {
+		partSize = obj.Size
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		merr     error
+		sem      = make(chan struct{}, concurrency)
+		numParts = int64(1)
+	)
+	if obj.Size > 0 {
+		numParts = (obj.Size + partSize - 1) / partSize
+	}
+
+	for partNumber := int64(1); partNumber <= numParts; partNumber++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(partNumber int64) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			offset := (partNumber - 1) * partSize
+			length := partLength(obj.Size, partSize, partNumber)
+
+			err := a.getRange(ctx, from, to, obj.Etag, offset, length)
+			if err != nil {
+				mu.Lock()
+				if merr == nil {
+					merr = err
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}(partNumber)
+	}
+
+	wg.Wait()
+
+	if merr != nil {
+		return 0, merr
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return obj.Size, nil
+}
+
+func (a *Azure) getRange(ctx context.Context, from *url.URL, to io.WriterAt, etag string, offset, length int64) error {
+	header := http.Header{}
+	if length > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	}
+	if etag != "" {
+		header.Set("If-Match", strconv.Quote(etag))
+	}
+
+	resp, err := a.do(ctx, azureRequest{
+		method:    http.MethodGet,
+		container: from.Bucket,
+		blob:      from.Path,
+		header:    header,
+	})
+	if err != nil {
+		if azErr, ok := err.(*ErrAzure); ok && azErr.StatusCode == http.StatusPreconditionFailed {
+			return ErrObjectModified
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.NewOffsetWriter(to, offset), resp.Body)
+	return err
+}
+
+// Put uploads the contents of the reader as a block blob. Contents larger
+// than the part size are uploaded as blocks in parallel, which are committed
+// once all of them are uploaded.
+func (a *Azure) Put(
+	ctx context.Context,
+	reader io.Reader,
+	to *url.URL,
+	metadata Metadata,
+	concurrency int,
+	partSize int64,
+) error {
+	if a.dryRun {
+		return nil
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if partSize <= 0 {
+		partSize = azureDefaultBlockSize
+	}
+
+	header := http.Header{}
+	setAzureProperties(header, metadata)
+	setAzureUserMetadata(header, metadata.UserDefined)
+	if metadata.StorageClass != "" {
+		header.Set("x-ms-access-tier", metadata.StorageClass)
+	}
+	if len(metadata.Tags) > 0 {
+		header.Set("x-ms-tags", encodeTags(metadata.Tags))
+	}
+
+	first, err := readPart(reader, partSize)
+	if err != nil {
+		return err
+	}
+
+	// contents which fit into a single part are uploaded with a single
+	// request.
+	if int64(len(first)) < partSize {
+		header.Set("x-ms-blob-type", "BlockBlob")
+		resp, err := a.do(ctx, azureRequest{
+			method:    http.MethodPut,
+			container: to.Bucket,
+			blob:      to.Path,
+			header:    header,
+			body:      first,
+		})
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	blockIDs, err := a.putBlocks(ctx, io.MultiReader(bytes.NewReader(first), reader), to, concurrency, partSize)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	body.WriteString(xml.Header + "<BlockList>")
+	for _, id := range blockIDs {
+		body.WriteString("<Latest>" + id + "</Latest>")
+	}
+	body.WriteString("</BlockList>")
+
+	resp, err := a.do(ctx, azureRequest{
+		method:    http.MethodPut,
+		container: to.Bucket,
+		blob:      to.Path,
+		query:     urlpkg.Values{"comp": {"blocklist"}},
+		header:    header,
+		body:      body.Bytes(),
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// putBlocks stages the contents of the reader as uncommitted blocks and
+// returns the IDs of the blocks in order.
+func (a *Azure) putBlocks(ctx context.Context, reader io.Reader, to *url.URL, concurrency int, partSize int64) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		merr     error
+		sem      = make(chan struct{}, concurrency)
+		blockIDs []string
+	)
+
+	for ctx.Err() == nil {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		data, err := readPart(reader, partSize)
+		if err != nil || len(data) == 0 {
+			<-sem
+			if err != nil {
+				mu.Lock()
+				if merr == nil {
+					merr = err
+				}
+				mu.Unlock()
+			}
+			break
+		}
+
+		if len(blockIDs) == azureMaxBlocks {
+			<-sem
+			mu.Lock()
+			if merr == nil {
+				merr = fmt.Errorf("object is too large to upload with %v byte parts, increase the part size", partSize)
+			}
+			mu.Unlock()
+			break
+		}
+
+		// block IDs of a blob must be of the same length.
+		id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("s5cmd-%08d", len(blockIDs))))
+		blockIDs = append(blockIDs, id)
+
+		wg.Add(1)
+		go func(id string, data []byte) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			resp, err := a.do(ctx, azureRequest{
+				method:    http.MethodPut,
+				container: to.Bucket,
+				blob:      to.Path,
+				query:     urlpkg.Values{"comp": {"block"}, "blockid": {id}},
+				body:      data,
+			})
+			if err == nil {
+				resp.Body.Close()
+				return
+			}
+
+			mu.Lock()
+			if merr == nil {
+				merr = err
+			}
+			mu.Unlock()
+			cancel()
+		}(id, data)
+	}
+
+	wg.Wait()
+
+	if merr != nil {
+		return nil, merr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return blockIDs, nil
+}
+
+// readPart reads up to size bytes from the reader.
+func readPart(reader io.Reader, size int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, size))
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func setAzureProperties(header http.Header, metadata Metadata) {
+	contentType := metadata.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("x-ms-blob-content-type", contentType)
+
+	for name, value := range map[string]string{
+		"x-ms-blob-cache-control":       metadata.CacheControl,
+		"x-ms-blob-content-encoding":    metadata.ContentEncoding,
+		"x-ms-blob-content-disposition": metadata.ContentDisposition,
+		"x-ms-blob-content-language":    metadata.ContentLanguage,
+	} {
+		if value != "" {
+			header.Set(name, value)
+		}
+	}
+}
+
+func setAzureUserMetadata(header http.Header, userDefined map[string]string) {
+	for k, v := range userDefined {
+		header.Set("x-ms-meta-"+k, v)
+	}
+}
+
+// Presign generates a URL with a shared access signature, which grants read
+// access to the blob until the expiration.
+func (a *Azure) Presign(_ context.Context, from *url.URL, expire time.Duration) (string, error) {
+	if a.key == nil {
+		return "", fmt.Errorf("presigned urls can only be generated with an azure storage account key")
+	}
+
+	var (
+		permissions = "r"
+		expiry      = time.Now().UTC().Add(expire).Format(time.RFC3339)
+		resource    = "/blob/" + a.account + "/" + from.Bucket + "/" + from.Path
+	)
+
+	stringToSign := strings.Join([]string{
+		permissions,
+		"", // start
+		expiry,
+		resource,
+		"", // identifier
+		"", // ip
+		"https,http",
+		azureSASVersion,
+		"b", // resource type
+		"",  // snapshot
+		"",  // cache-control
+		"",  // content-disposition
+		"",  // content-encoding
+		"",  // content-language
+		"",  // content-type
+	}, "\n")
+
+	query := urlpkg.Values{
+		"sp":  {permissions},
+		"se":  {expiry},
+		"spr": {"https,http"},
+		"sv":  {azureSASVersion},
+		"sr":  {"b"},
+		"sig": {a.signature(stringToSign)},
+	}
+
+	u := a.resourceURL(from.Bucket, from.Path)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+type azureContainerList struct {
+	Containers struct {
+		Container []struct {
+			Name       string
+			Properties struct {
+				LastModified string `xml:"Last-Modified"`
+			}
+		}
+	}
+	NextMarker string
+}
+
+// ListBuckets returns the containers of the account which match with the
+// given prefix.
+func (a *Azure) ListBuckets(ctx context.Context, prefix string) ([]Bucket, error) {
+	query := urlpkg.Values{"comp": {"list"}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+
+	var buckets []Bucket
+	for {
+		var list azureContainerList
+		if err := a.doXML(ctx, azureRequest{method: http.MethodGet, query: query}, &list); err != nil {
+			return nil, err
+		}
+
+		for _, c := range list.Containers.Container {
+			created, _ := http.ParseTime(c.Properties.LastModified)
+			buckets = append(buckets, Bucket{
+				CreationDate: created.UTC(),
+				Name:         c.Name,
+				Scheme:       "az",
+			})
+		}
+
+		if list.NextMarker == "" {
+			return buckets, nil
+		}
+		query.Set("marker", list.NextMarker)
+	}
+}
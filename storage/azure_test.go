@@ -0,0 +1,737 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	urlpkg "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestAzureImplementsCapabilities(t *testing.T) {
+	var i interface{} = new(Azure)
+	if _, ok := i.(Storage); !ok {
+		t.Errorf("expected %t to implement Storage interface", i)
+	}
+	if _, ok := i.(Getter); !ok {
+		t.Errorf("expected %t to implement Getter interface", i)
+	}
+	if _, ok := i.(Putter); !ok {
+		t.Errorf("expected %t to implement Putter interface", i)
+	}
+	if _, ok := i.(Reader); !ok {
+		t.Errorf("expected %t to implement Reader interface", i)
+	}
+	if _, ok := i.(MetadataReader); !ok {
+		t.Errorf("expected %t to implement MetadataReader interface", i)
+	}
+	if _, ok := i.(Presigner); !ok {
+		t.Errorf("expected %t to implement Presigner interface", i)
+	}
+	if _, ok := i.(BucketLister); !ok {
+		t.Errorf("expected %t to implement BucketLister interface", i)
+	}
+}
+
+// fakeAzure is an in-process fake of the Blob service. The endpoint of the
+// account is path-style as in Azurite, e.g. http://host/account.
+type fakeAzure struct {
+	t   *testing.T
+	key []byte
+
+	mu         sync.Mutex
+	containers map[string]map[string]*fakeBlob
+	blocks     map[string]map[string][]byte
+	pageSize   int
+	failures   int32
+	requests   []string
+}
+
+type fakeBlob struct {
+	data    []byte
+	header  http.Header
+	modTime time.Time
+	etag    string
+}
+
+func newFakeAzure(t *testing.T, containers ...string) (*fakeAzure, *Azure) {
+	t.Helper()
+
+	fake := &fakeAzure{
+		t:          t,
+		key:        []byte("s5cmd-test-account-key"),
+		containers: map[string]map[string]*fakeBlob{},
+		blocks:     map[string]map[string][]byte{},
+		pageSize:   1000,
+	}
+	for _, c := range containers {
+		fake.containers[c] = map[string]*fakeBlob{}
+	}
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	env := map[string]string{
+		"AZURE_STORAGE_CONNECTION_STRING": fmt.Sprintf(
+			"AccountName=account;AccountKey=%v;BlobEndpoint=%v/account",
+			base64.StdEncoding.EncodeToString(fake.key), server.URL,
+		),
+	}
+
+	client, err := newAzureClient(Options{MaxRetries: 2}, func(k string) string { return env[k] })
+	assert.NilError(t, err)
+	return fake, client
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f.verifySignature(r); err != nil {
+		f.t.Errorf("%v %v: %v", r.Method, r.URL, err)
+		f.writeError(w, http.StatusForbidden, "AuthenticationFailed")
+		return
+	}
+
+	if atomic.AddInt32(&f.failures, -1) >= 0 {
+		f.writeError(w, http.StatusServiceUnavailable, "ServerBusy")
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, r.Method+" "+r.URL.Query().Get("comp"))
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/account"), "/", 3)
+	var container, blob string
+	if len(parts) > 1 {
+		container = parts[1]
+	}
+	if len(parts) > 2 {
+		blob = parts[2]
+	}
+
+	query := r.URL.Query()
+	switch {
+	case container == "":
+		f.listContainers(w, query)
+	case blob == "":
+		f.listBlobs(w, container, query)
+	case r.Method == http.MethodPut:
+		f.put(w, r, container, blob, body)
+	case r.Method == http.MethodDelete:
+		if _, ok := f.containers[container][blob]; !ok {
+			f.writeError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(f.containers[container], blob)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		f.get(w, r, container, blob)
+	}
+}
+
+func (f *fakeAzure) verifySignature(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	prefix := "SharedKey account:"
+	if !strings.HasPrefix(auth, prefix) {
+		return fmt.Errorf("unexpected authorization %q", auth)
+	}
+
+	var headers []string
+	for name := range r.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			headers = append(headers, lower+":"+r.Header.Get(name)+"\n")
+		}
+	}
+	sort.Strings(headers)
+
+	resource := "/account" + r.URL.EscapedPath()
+	query := r.URL.Query()
+	var names []string
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		resource += "\n" + name + ":" + strings.Join(query[name], ",")
+	}
+
+	contentLength := ""
+	if r.ContentLength > 0 {
+		contentLength = strconv.FormatInt(r.ContentLength, 10)
+	}
+
+	stringToSign := r.Method + "\n" +
+		r.Header.Get("Content-Encoding") + "\n" +
+		r.Header.Get("Content-Language") + "\n" +
+		contentLength + "\n" +
+		r.Header.Get("Content-MD5") + "\n" +
+		r.Header.Get("Content-Type") + "\n" +
+		"\n" +
+		r.Header.Get("If-Modified-Since") + "\n" +
+		r.Header.Get("If-Match") + "\n" +
+		r.Header.Get("If-None-Match") + "\n" +
+		r.Header.Get("If-Unmodified-Since") + "\n" +
+		r.Header.Get("Range") + "\n" +
+		strings.Join(headers, "") +
+		resource
+
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write([]byte(stringToSign))
+	if expected := base64.StdEncoding.EncodeToString(mac.Sum(nil)); auth != prefix+expected {
+		return fmt.Errorf("signature mismatch for %q", stringToSign)
+	}
+	return nil
+}
+
+func (f *fakeAzure) writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"utf-8\"?><Error><Code>%v</Code><Message>%v\nRequestId:1</Message></Error>", code, code)
+}
+
+func (f *fakeAzure) store(container, blob string, data []byte, header http.Header) {
+	sum := md5.Sum(data)
+	f.containers[container][blob] = &fakeBlob{
+		data:    data,
+		header:  header,
+		modTime: time.Now().UTC().Add(-time.Second),
+		etag:    `"` + hex.EncodeToString(sum[:]) + `"`,
+	}
+}
+
+func blobHeader(r *http.Request) http.Header {
+	header := http.Header{}
+	for name, values := range r.Header {
+		lower := strings.ToLower(name)
+		switch {
+		case strings.HasPrefix(lower, "x-ms-meta-"), lower == "x-ms-access-tier", lower == "x-ms-tags":
+			header[name] = values
+		case strings.HasPrefix(lower, "x-ms-blob-content-"), lower == "x-ms-blob-cache-control":
+			header.Set(strings.Replace(strings.TrimPrefix(lower, "x-ms-blob-"), "content-", "Content-", 1), values[0])
+		}
+	}
+	return header
+}
+
+func (f *fakeAzure) put(w http.ResponseWriter, r *http.Request, container, blob string, body []byte) {
+	if _, ok := f.containers[container]; !ok {
+		f.writeError(w, http.StatusNotFound, "ContainerNotFound")
+		return
+	}
+
+	query := r.URL.Query()
+	key := container + "/" + blob
+	switch {
+	case query.Get("comp") == "block":
+		if f.blocks[key] == nil {
+			f.blocks[key] = map[string][]byte{}
+		}
+		f.blocks[key][query.Get("blockid")] = body
+	case query.Get("comp") == "blocklist":
+		var list struct {
+			Latest []string
+		}
+		assert.NilError(f.t, xml.Unmarshal(body, &list))
+
+		var data []byte
+		for _, id := range list.Latest {
+			block, ok := f.blocks[key][id]
+			if !ok {
+				f.writeError(w, http.StatusBadRequest, "InvalidBlockList")
+				return
+			}
+			data = append(data, block...)
+		}
+		delete(f.blocks, key)
+		f.store(container, blob, data, blobHeader(r))
+	case query.Get("comp") == "properties":
+		b, ok := f.containers[container][blob]
+		if !ok {
+			f.writeError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		for name := range b.header {
+			if strings.HasPrefix(name, "Content-") || name == "Cache-Control" {
+				delete(b.header, name)
+			}
+		}
+		for name, values := range blobHeader(r) {
+			b.header[name] = values
+		}
+	case r.Header.Get("x-ms-copy-source") != "":
+		source, err := urlpkg.Parse(r.Header.Get("x-ms-copy-source"))
+		assert.NilError(f.t, err)
+		parts := strings.SplitN(strings.TrimPrefix(source.Path, "/account/"), "/", 2)
+		src, ok := f.containers[parts[0]][parts[1]]
+		if !ok {
+			f.writeError(w, http.StatusNotFound, "CannotVerifyCopySource")
+			return
+		}
+
+		header := http.Header{}
+		for name, values := range src.header {
+			header[name] = values
+		}
+		if metadata := blobHeader(r); len(metadata) > 0 {
+			for name := range header {
+				if strings.HasPrefix(strings.ToLower(name), "x-ms-meta-") {
+					delete(header, name)
+				}
+			}
+			for name, values := range metadata {
+				header[name] = values
+			}
+		}
+		f.store(container, blob, src.data, header)
+		w.Header().Set("x-ms-copy-status", "success")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	default:
+		assert.Equal(f.t, r.Header.Get("x-ms-blob-type"), "BlockBlob")
+		f.store(container, blob, body, blobHeader(r))
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *fakeAzure) get(w http.ResponseWriter, r *http.Request, container, blob string) {
+	b, ok := f.containers[container][blob]
+	if !ok {
+		f.writeError(w, http.StatusNotFound, "BlobNotFound")
+		return
+	}
+
+	if match := r.Header.Get("If-Match"); match != "" && match != b.etag {
+		f.writeError(w, http.StatusPreconditionFailed, "ConditionNotMet")
+		return
+	}
+
+	for name, values := range b.header {
+		if name != "X-Ms-Tags" {
+			w.Header()[name] = values
+		}
+	}
+	w.Header().Set("ETag", b.etag)
+	w.Header().Set("Last-Modified", b.modTime.Format(http.TimeFormat))
+
+	data := b.data
+	status := http.StatusOK
+	if rng := r.Header.Get("Range"); rng != "" {
+		var start, end int
+		_, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+		assert.NilError(f.t, err)
+		data = data[start : end+1]
+		status = http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+func (f *fakeAzure) listBlobs(w http.ResponseWriter, container string, query urlpkg.Values) {
+	blobs, ok := f.containers[container]
+	if !ok {
+		f.writeError(w, http.StatusNotFound, "ContainerNotFound")
+		return
+	}
+
+	prefix, delimiter, marker := query.Get("prefix"), query.Get("delimiter"), query.Get("marker")
+
+	var names []string
+	for name := range blobs {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var (
+		b          strings.Builder
+		seen       = map[string]bool{}
+		count      int
+		nextMarker string
+	)
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>`)
+	for _, name := range names {
+		if name <= marker && marker != "" {
+			continue
+		}
+		if count == f.pageSize {
+			nextMarker = names[indexOf(names, name)-1]
+			break
+		}
+
+		if delimiter != "" {
+			if i := strings.Index(name[len(prefix):], delimiter); i >= 0 {
+				p := name[:len(prefix)+i+len(delimiter)]
+				if !seen[p] {
+					seen[p] = true
+					fmt.Fprintf(&b, "<BlobPrefix><Name>%v</Name></BlobPrefix>", p)
+					count++
+				}
+				continue
+			}
+		}
+
+		blob := blobs[name]
+		fmt.Fprintf(&b, "<Blob><Name>%v</Name><Properties><Last-Modified>%v</Last-Modified><Etag>%v</Etag><Content-Length>%v</Content-Length><AccessTier>%v</AccessTier></Properties></Blob>",
+			name, blob.modTime.Format(http.TimeFormat), blob.etag, len(blob.data), blob.header.Get("x-ms-access-tier"))
+		count++
+	}
+	fmt.Fprintf(&b, "</Blobs><NextMarker>%v</NextMarker></EnumerationResults>", nextMarker)
+
+	fmt.Fprint(w, b.String())
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *fakeAzure) listContainers(w http.ResponseWriter, query urlpkg.Values) {
+	var names []string
+	for name := range f.containers {
+		if strings.HasPrefix(name, query.Get("prefix")) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Containers>`)
+	for _, name := range names {
+		fmt.Fprintf(&b, "<Container><Name>%v</Name><Properties><Last-Modified>Mon, 02 Jan 2023 15:04:05 GMT</Last-Modified></Properties></Container>", name)
+	}
+	b.WriteString("</Containers><NextMarker /></EnumerationResults>")
+	fmt.Fprint(w, b.String())
+}
+
+func TestAzurePutAndGet(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		size     int
+		partSize int64
+		requests []string
+	}{
+		{name: "single request", size: 1000, partSize: 1024, requests: []string{"PUT "}},
+		{name: "empty", size: 0, partSize: 1024, requests: []string{"PUT "}},
+		{name: "blocks", size: 5000, partSize: 1024, requests: []string{"PUT block", "PUT block", "PUT block", "PUT block", "PUT block", "PUT blocklist"}},
+		{name: "exact part size", size: 2048, partSize: 1024, requests: []string{"PUT block", "PUT block", "PUT blocklist"}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake, client := newFakeAzure(t, "container")
+			ctx := context.Background()
+
+			data := make([]byte, tc.size)
+			rand.Read(data)
+
+			u := mustNewURL(t, "az://container/dir/file.bin")
+			metadata := Metadata{
+				ContentType:  "application/x-test",
+				CacheControl: "no-cache",
+				StorageClass: "Cool",
+				UserDefined:  map[string]string{"key": "value"},
+			}
+			err := client.Put(ctx, bytes.NewReader(data), u, metadata, 3, tc.partSize)
+			assert.NilError(t, err)
+
+			sort.Strings(fake.requests)
+			assert.DeepEqual(t, fake.requests, tc.requests)
+
+			obj, got, err := client.HeadObject(ctx, u)
+			assert.NilError(t, err)
+			assert.Equal(t, obj.Size, int64(tc.size))
+			assert.Equal(t, obj.StorageClass, StorageClass("Cool"))
+			assert.Equal(t, got.ContentType, "application/x-test")
+			assert.Equal(t, got.CacheControl, "no-cache")
+			assert.DeepEqual(t, got.UserDefined, map[string]string{"key": "value"})
+
+			buf := aws.NewWriteAtBuffer(nil)
+			n, err := client.Get(ctx, u, buf, 3, 700)
+			assert.NilError(t, err)
+			assert.Equal(t, n, int64(tc.size))
+			assert.Assert(t, bytes.Equal(buf.Bytes(), data))
+		})
+	}
+}
+
+func TestAzureGetModifiedObject(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeAzure(t, "container")
+	ctx := context.Background()
+
+	u := mustNewURL(t, "az://container/file")
+	assert.NilError(t, client.Put(ctx, strings.NewReader("content"), u, Metadata{}, 1, 1024))
+
+	// the blob is modified after its properties are retrieved.
+	obj, err := client.Stat(ctx, u)
+	assert.NilError(t, err)
+	fake.mu.Lock()
+	fake.containers["container"]["file"].etag = `"modified"`
+	fake.mu.Unlock()
+
+	err = client.getRange(ctx, u, aws.NewWriteAtBuffer(nil), obj.Etag, 0, obj.Size)
+	assert.Equal(t, err, ErrObjectModified)
+}
+
+func TestAzureList(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeAzure(t, "container")
+	fake.pageSize = 2
+	ctx := context.Background()
+
+	for _, key := range []string{"a.txt", "b.csv", "dir/c.txt", "dir/sub/d.txt", "e.txt"} {
+		err := client.Put(ctx, strings.NewReader(key), mustNewURL(t, "az://container/"+key), Metadata{}, 1, 1024)
+		assert.NilError(t, err)
+	}
+
+	testcases := []struct {
+		src      string
+		expected []string
+	}{
+		{src: "az://container/", expected: []string{"dir/", "a.txt", "b.csv", "e.txt"}},
+		{src: "az://container/*", expected: []string{"a.txt", "b.csv", "dir/c.txt", "dir/sub/d.txt", "e.txt"}},
+		{src: "az://container/*.txt", expected: []string{"a.txt", "dir/c.txt", "dir/sub/d.txt", "e.txt"}},
+		{src: "az://container/dir/", expected: []string{"dir/sub/", "dir/c.txt"}},
+	}
+
+	for _, tc := range testcases {
+		var keys []string
+		for obj := range client.List(ctx, mustNewURL(t, tc.src), false) {
+			assert.NilError(t, obj.Err, tc.src)
+			keys = append(keys, obj.URL.Path)
+		}
+		sort.SliceStable(keys, func(i, j int) bool {
+			return strings.HasSuffix(keys[i], "/") && !strings.HasSuffix(keys[j], "/")
+		})
+		assert.DeepEqual(t, keys, tc.expected)
+	}
+
+	for obj := range client.List(ctx, mustNewURL(t, "az://container/missing/"), false) {
+		assert.Equal(t, obj.Err, ErrNoObjectFound)
+	}
+
+	for obj := range client.List(ctx, mustNewURL(t, "az://missing/"), false) {
+		assert.ErrorContains(t, obj.Err, "ContainerNotFound")
+	}
+}
+
+func TestAzureCopyAndDelete(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeAzure(t, "container", "other")
+	ctx := context.Background()
+
+	src := mustNewURL(t, "az://container/src.txt")
+	metadata := Metadata{ContentType: "text/plain", UserDefined: map[string]string{"key": "value"}}
+	assert.NilError(t, client.Put(ctx, strings.NewReader("content"), src, metadata, 1, 1024))
+
+	// properties and metadata of the source are kept.
+	dst := mustNewURL(t, "az://other/dst.txt")
+	assert.NilError(t, client.Copy(ctx, src, dst, Metadata{}))
+
+	_, got, err := client.HeadObject(ctx, dst)
+	assert.NilError(t, err)
+	assert.Equal(t, got.ContentType, "text/plain")
+	assert.DeepEqual(t, got.UserDefined, map[string]string{"key": "value"})
+
+	// metadata is replaced.
+	replaced := mustNewURL(t, "az://other/replaced.txt")
+	err = client.Copy(ctx, src, replaced, Metadata{
+		Directive:   "REPLACE",
+		ContentType: "application/json",
+		UserDefined: map[string]string{"other": "value"},
+	})
+	assert.NilError(t, err)
+
+	_, got, err = client.HeadObject(ctx, replaced)
+	assert.NilError(t, err)
+	assert.Equal(t, got.ContentType, "application/json")
+	assert.DeepEqual(t, got.UserDefined, map[string]string{"other": "value"})
+
+	err = client.Copy(ctx, mustNewURL(t, "az://container/missing"), dst, Metadata{})
+	assert.Error(t, err, "given object az://container/missing not found")
+
+	urlch := make(chan *url.URL, 3)
+	urlch <- src
+	urlch <- dst
+	urlch <- mustNewURL(t, "az://other/missing")
+	close(urlch)
+
+	for obj := range client.MultiDelete(ctx, urlch) {
+		assert.NilError(t, obj.Err)
+	}
+
+	assert.Equal(t, len(fake.containers["container"]), 0)
+	assert.Equal(t, len(fake.containers["other"]), 1)
+
+	_, err = client.Stat(ctx, src)
+	assert.Error(t, err, "given object az://container/src.txt not found")
+
+	_, err = client.Read(ctx, src)
+	assert.Error(t, err, "given object az://container/src.txt not found")
+}
+
+func TestAzureRetry(t *testing.T) {
+	log.Init("error", false)
+
+	fake, client := newFakeAzure(t, "container")
+	ctx := context.Background()
+
+	u := mustNewURL(t, "az://container/file")
+
+	fake.failures = 2
+	assert.NilError(t, client.Put(ctx, strings.NewReader("content"), u, Metadata{}, 1, 1024))
+
+	fake.failures = 3
+	_, err := client.Stat(ctx, u)
+	assert.Error(t, err, "ServerBusy status code: 503")
+}
+
+func TestAzureListBuckets(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeAzure(t, "logs", "logs-archive", "data")
+
+	buckets, err := client.ListBuckets(context.Background(), "logs")
+	assert.NilError(t, err)
+	assert.Equal(t, len(buckets), 2)
+	assert.Equal(t, buckets[0].Name, "logs")
+	assert.Equal(t, buckets[1].Name, "logs-archive")
+	assert.Equal(t, buckets[0].String(), "2023/01/02 15:04:05  az://logs")
+}
+
+func TestAzurePresign(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeAzure(t, "container")
+
+	presigned, err := client.Presign(context.Background(), mustNewURL(t, "az://container/dir/file.txt"), time.Hour)
+	assert.NilError(t, err)
+
+	u, err := urlpkg.Parse(presigned)
+	assert.NilError(t, err)
+	assert.Equal(t, u.Path, "/account/container/dir/file.txt")
+
+	query := u.Query()
+	assert.Equal(t, query.Get("sp"), "r")
+	assert.Equal(t, query.Get("sr"), "b")
+	assert.Equal(t, query.Get("sv"), azureSASVersion)
+
+	expiry, err := time.Parse(time.RFC3339, query.Get("se"))
+	assert.NilError(t, err)
+	assert.Assert(t, time.Until(expiry) > 59*time.Minute)
+
+	stringToSign := strings.Join([]string{
+		"r", "", query.Get("se"), "/blob/account/container/dir/file.txt", "", "", "https,http", azureSASVersion, "b", "", "", "", "", "", "",
+	}, "\n")
+	assert.Equal(t, query.Get("sig"), client.signature(stringToSign))
+}
+
+func TestLoadAzureCredentials(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		env      map[string]string
+		account  string
+		endpoint string
+		sas      string
+		err      string
+	}{
+		{
+			name:     "account and key",
+			env:      map[string]string{"AZURE_STORAGE_ACCOUNT": "account", "AZURE_STORAGE_KEY": "a2V5"},
+			account:  "account",
+			endpoint: "https://account.blob.core.windows.net",
+		},
+		{
+			name:     "sas token",
+			env:      map[string]string{"AZURE_STORAGE_ACCOUNT": "account", "AZURE_STORAGE_SAS_TOKEN": "?sv=2020-10-02&sig=abc"},
+			account:  "account",
+			endpoint: "https://account.blob.core.windows.net",
+			sas:      "sig=abc&sv=2020-10-02",
+		},
+		{
+			name: "connection string",
+			env: map[string]string{
+				"AZURE_STORAGE_ACCOUNT":           "ignored",
+				"AZURE_STORAGE_CONNECTION_STRING": "DefaultEndpointsProtocol=http;AccountName=account;AccountKey=a2V5;EndpointSuffix=core.chinacloudapi.cn",
+			},
+			account:  "account",
+			endpoint: "http://account.blob.core.chinacloudapi.cn",
+		},
+		{
+			name:     "development storage",
+			env:      map[string]string{"AZURE_STORAGE_CONNECTION_STRING": "UseDevelopmentStorage=true"},
+			account:  azureDevelopmentAccount,
+			endpoint: azureDevelopmentEndpoint,
+		},
+		{
+			name:     "endpoint",
+			env:      map[string]string{"AZURE_STORAGE_BLOB_ENDPOINT": "https://account.blob.core.windows.net/"},
+			account:  "account",
+			endpoint: "https://account.blob.core.windows.net",
+		},
+		{
+			name: "missing account",
+			env:  map[string]string{},
+			err:  "azure storage account is not set, set AZURE_STORAGE_ACCOUNT or AZURE_STORAGE_CONNECTION_STRING environment variable",
+		},
+		{
+			name: "invalid key",
+			env:  map[string]string{"AZURE_STORAGE_ACCOUNT": "account", "AZURE_STORAGE_KEY": "not base64"},
+			err:  "invalid azure storage account key: illegal base64 data at input byte 3",
+		},
+	}
+
+	for _, tc := range testcases {
+		creds, err := loadAzureCredentials(func(k string) string { return tc.env[k] })
+		if tc.err != "" {
+			assert.Error(t, err, tc.err, tc.name)
+			continue
+		}
+		assert.NilError(t, err, tc.name)
+		assert.Equal(t, creds.account, tc.account, tc.name)
+		assert.Equal(t, creds.endpoint.String(), tc.endpoint, tc.name)
+		assert.Equal(t, creds.sas.Encode(), tc.sas, tc.name)
+	}
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// Backend creates the clients of a remote storage for the URLs of the scheme
+// it is registered with.
+type Backend func(ctx context.Context, url *url.URL, opts Options) (Storage, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{}
+)
+
+// Register makes the backend available for the URLs of the given scheme.
+// Register panics if a backend is already registered for the scheme.
+func Register(scheme string, opts url.SchemeOptions, backend Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if _, ok := backends[scheme]; ok {
+		panic(fmt.Sprintf("storage: backend of %q scheme is already registered", scheme))
+	}
+	backends[scheme] = backend
+	url.RegisterScheme(scheme, opts)
+}
+
+func lookupBackend(scheme string) (Backend, bool) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	backend, ok := backends[scheme]
+	return backend, ok
+}
+
+// ErrNotSupported indicates that the storage of the URL does not support the
+// requested operation.
+type ErrNotSupported struct {
+	Operation string
+	Scheme    string
+}
+
+func (e *ErrNotSupported) Error() string {
+	return fmt.Sprintf("%v is not supported by %v:// storage", e.Operation, e.Scheme)
+}
+
+// NotSupported returns an ErrNotSupported for the operation on the storage of
+// the URL.
+func NotSupported(url *url.URL, operation string) error {
+	return &ErrNotSupported{Operation: operation, Scheme: url.Scheme}
+}
+
+// The following interfaces are the capabilities that remote storages may
+// implement in addition to Storage. Commands check the capabilities they
+// use, so that a storage is usable by any command it has the capabilities
+// of.
+
+// Getter downloads objects into any destination that implements io.WriterAt.
+type Getter interface {
+	Get(ctx context.Context, from *url.URL, to io.WriterAt, concurrency int, partSize int64) (int64, error)
+}
+
+// Putter uploads the contents of a reader as an object.
+type Putter interface {
+	Put(ctx context.Context, reader io.Reader, to *url.URL, metadata Metadata, concurrency int, partSize int64) error
+}
+
+// Reader returns the contents of an object as a stream.
+type Reader interface {
+	Read(ctx context.Context, src *url.URL) (io.ReadCloser, error)
+}
+
+// MetadataReader retrieves the metadata of an object.
+type MetadataReader interface {
+	HeadObject(ctx context.Context, url *url.URL) (*Object, *Metadata, error)
+}
+
+// Presigner generates URLs to download objects without credentials.
+type Presigner interface {
+	Presign(ctx context.Context, from *url.URL, expire time.Duration) (string, error)
+}
+
+// BucketLister lists the buckets of a storage.
+type BucketLister interface {
+	ListBuckets(ctx context.Context, prefix string) ([]Bucket, error)
+}
+
+// ResumableGetter downloads objects by recording the downloaded ranges, so
+// that interrupted downloads can be continued.
+type ResumableGetter interface {
+	Getter
+	GetResumable(
+		ctx context.Context,
+		from *url.URL,
+		to io.WriterAt,
+		concurrency int,
+		partSize int64,
+		state *DownloadState,
+		resumed func(completed int64),
+	) (int64, error)
+}
+
+// ResumablePutter uploads objects by recording the uploaded parts, so that
+// interrupted uploads can be continued.
+type ResumablePutter interface {
+	Putter
+	PutResumable(
+		ctx context.Context,
+		reader io.ReaderAt,
+		to *url.URL,
+		metadata Metadata,
+		concurrency int,
+		partSize int64,
+		state *UploadState,
+		resumed func(completed int64),
+	) error
+}
+
+// BucketHeader checks whether a bucket exists and is accessible.
+type BucketHeader interface {
+	HeadBucket(ctx context.Context, url *url.URL) error
+}
+
+// Tagger reads and modifies the tags of objects.
+type Tagger interface {
+	GetObjectTagging(ctx context.Context, url *url.URL) (map[string]string, error)
+	PutObjectTagging(ctx context.Context, url *url.URL, tags map[string]string) error
+	DeleteObjectTagging(ctx context.Context, url *url.URL) error
+}
+
+// ACLAccessor reads and modifies the access control policies of objects.
+type ACLAccessor interface {
+	GetObjectACL(ctx context.Context, url *url.URL) (*ObjectACL, error)
+	PutObjectACL(ctx context.Context, url *url.URL, acl *ObjectACL) error
+}
+
+// Restorer restores the archived objects for a number of days.
+type Restorer interface {
+	RestoreObject(ctx context.Context, url *url.URL, days int64, tier string) error
+}
+
+// Selector runs queries against the contents of objects on the server side.
+type Selector interface {
+	Select(ctx context.Context, url *url.URL, query *SelectQuery, resultCh chan<- json.RawMessage) error
+}
+
+// MultipartCopier copies objects on the server side part by part, which
+// allows copying the objects that are too large to be copied at once.
+type MultipartCopier interface {
+	MultipartCopy(ctx context.Context, from *Object, to *url.URL, metadata Metadata, concurrency int, partSize int64) error
+}
@@ -0,0 +1,601 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	// listingCacheFile is the name of the database file of the listing cache
+	// in the cache directory.
+	listingCacheFile = "listings.db"
+
+	// listingCacheLockTimeout is the duration to wait for the other processes
+	// which use the listing cache.
+	listingCacheLockTimeout = time.Second
+
+	// listingCacheBatchSize is the number of the objects which are read from
+	// or written to the cache in a single transaction.
+	listingCacheBatchSize = 1000
+
+	// maxRevalidatedObjects is the maximum number of the modified objects of
+	// a cached listing, or the objects of an expired listing, which are
+	// revalidated with HEAD requests. The listing is refreshed if there are
+	// more objects.
+	maxRevalidatedObjects = 1000
+
+	// revalidationConcurrency is the maximum number of concurrent HEAD
+	// requests to revalidate the objects.
+	revalidationConcurrency = 16
+)
+
+// ErrListingCacheLocked indicates that the listing cache is used by another
+// process.
+var ErrListingCacheLocked = fmt.Errorf("listing cache is used by another process")
+
+// errCachedListingRemoved indicates that a cached listing is removed while it
+// is used, by another listing of the same objects which is failed.
+var errCachedListingRemoved = fmt.Errorf("cached listing is removed")
+
+var (
+	listingCacheObjects  = []byte("objects")
+	listingCacheModified = []byte("modified")
+	listingCacheListedAt = []byte("listed-at")
+	listingCachePrefix   = []byte("prefix")
+)
+
+// ListingCache is a persistent cache of the listings of remote storages. Each
+// listing is cached with the time it is listed or revalidated. The objects
+// which are modified by the clients which use the cache are revalidated with
+// HEAD requests before the listing is served again. Once the TTL expires, all
+// objects of the listing are revalidated the same way, so the objects which
+// are added by the other clients are not seen until the listing is refreshed.
+type ListingCache struct {
+	db  *bolt.DB
+	ttl time.Duration
+}
+
+// cachedObject is an object of a cached listing.
+type cachedObject struct {
+	Etag         string     `json:"etag,omitempty"`
+	ModTime      *time.Time `json:"last_modified,omitempty"`
+	Size         int64      `json:"size"`
+	StorageClass string     `json:"storage_class,omitempty"`
+}
+
+// OpenListingCache opens the listing cache in the given directory. The user
+// cache directory is used if dir is empty. The listings which are older than
+// ttl are revalidated. ErrListingCacheLocked is returned if the cache is used
+// by another process.
+func OpenListingCache(dir string, ttl time.Duration) (*ListingCache, error) {
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find the cache directory: %w", err)
+		}
+		dir = filepath.Join(cacheDir, "s5cmd")
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, listingCacheFile)
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: listingCacheLockTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("unable to open listing cache %q: %w", path, ErrListingCacheLocked)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open listing cache %q: %w", path, err)
+	}
+
+	return &ListingCache{db: db, ttl: ttl}, nil
+}
+
+// Close closes the listing cache.
+func (c *ListingCache) Close() error {
+	return c.db.Close()
+}
+
+// List lists the objects of src from the cache, or with the given list
+// function if the listing is not cached or it has too many objects to
+// revalidate. The modified objects of the cached listing, or all of them if
+// its TTL is expired, are revalidated with the given stat function. The
+// listings are distinguished by the namespace and src.
+func (c *ListingCache) List(
+	ctx context.Context,
+	namespace string,
+	src *url.URL,
+	list func(context.Context, *url.URL) <-chan *Object,
+	stat func(context.Context, *url.URL) (*Object, error),
+) <-chan *Object {
+	objCh := make(chan *Object)
+
+	go func() {
+		defer close(objCh)
+
+		name := listingCacheName(namespace, src)
+
+		cached, err := c.renew(name)
+		if err == nil && cached {
+			cached, err = c.revalidate(ctx, name, src, stat)
+		}
+		if err != nil {
+			objCh <- &Object{Err: err}
+			return
+		}
+
+		if cached {
+			c.listCached(ctx, name, src, objCh)
+			return
+		}
+		c.refresh(ctx, name, src, list, objCh)
+	}()
+
+	return objCh
+}
+
+// Invalidate marks the given keys of the bucket as modified in the cached
+// listings which they belong to.
+func (c *ListingCache) Invalidate(namespace, bucket string, keys ...string) error {
+	prefix := listingCacheNamePrefix(namespace, bucket)
+
+	return c.db.Batch(func(tx *bolt.Tx) error {
+		var names [][]byte
+		cursor := tx.Cursor()
+		for name, _ := cursor.Seek(prefix); name != nil && bytes.HasPrefix(name, prefix); name, _ = cursor.Next() {
+			names = append(names, name)
+		}
+
+		for _, name := range names {
+			listing := tx.Bucket(name)
+			listingPrefix := string(listing.Get(listingCachePrefix))
+			modified := listing.Bucket(listingCacheModified)
+
+			for _, key := range keys {
+				if !strings.HasPrefix(key, listingPrefix) {
+					continue
+				}
+				// the sequence tells the revalidated objects apart from the
+				// objects which are modified again meanwhile.
+				seq, err := modified.NextSequence()
+				if err != nil {
+					return err
+				}
+				if err := modified.Put([]byte(key), itob(seq)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// renew reports whether the listing is cached. The objects of the listing are
+// marked as modified once its TTL expires, so they are revalidated instead of
+// listing them again. It reports false if there are too many objects to
+// revalidate, so the listing must be refreshed.
+func (c *ListingCache) renew(name []byte) (bool, error) {
+	var cached bool
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		listing := tx.Bucket(name)
+		if listing == nil {
+			return nil
+		}
+
+		listedAt := listing.Get(listingCacheListedAt)
+		if listedAt == nil {
+			return nil
+		}
+
+		var t time.Time
+		if err := t.UnmarshalBinary(listedAt); err != nil {
+			return err
+		}
+		if time.Since(t) < c.ttl {
+			cached = true
+			return nil
+		}
+
+		cachedObjects := listing.Bucket(listingCacheObjects)
+		modified := listing.Bucket(listingCacheModified)
+
+		var keys [][]byte
+		cursor := cachedObjects.Cursor()
+		for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+			if len(keys) == maxRevalidatedObjects {
+				return nil
+			}
+			// the keys are copied, since they are modified below.
+			keys = append(keys, append([]byte(nil), k...))
+		}
+
+		for _, key := range keys {
+			seq, err := modified.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := modified.Put(key, itob(seq)); err != nil {
+				return err
+			}
+		}
+
+		now, err := time.Now().UTC().MarshalBinary()
+		if err != nil {
+			return err
+		}
+		cached = true
+		return listing.Put(listingCacheListedAt, now)
+	})
+	return cached, err
+}
+
+// revalidate updates the modified objects of the cached listing with HEAD
+// requests. It reports false if there are too many modified objects, so the
+// listing must be refreshed.
+func (c *ListingCache) revalidate(
+	ctx context.Context,
+	name []byte,
+	src *url.URL,
+	stat func(context.Context, *url.URL) (*Object, error),
+) (bool, error) {
+	var (
+		keys, seqs [][]byte
+		tooMany    bool
+	)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		listing := tx.Bucket(name)
+		if listing == nil {
+			// the listing is removed by a concurrent listing meanwhile.
+			tooMany = true
+			return nil
+		}
+
+		cursor := listing.Bucket(listingCacheModified).Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			if len(keys) == maxRevalidatedObjects {
+				tooMany = true
+				return nil
+			}
+			// the slices are valid only during the transaction.
+			keys = append(keys, append([]byte(nil), k...))
+			seqs = append(seqs, append([]byte(nil), v...))
+		}
+		return nil
+	})
+	if err != nil || tooMany {
+		return false, err
+	}
+	if len(keys) == 0 {
+		return true, nil
+	}
+
+	objects := make([]*Object, len(keys))
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		merr error
+		sem  = make(chan struct{}, revalidationConcurrency)
+	)
+	for i, key := range keys {
+		i, key := i, key
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			objurl := src.Clone()
+			objurl.Path = string(key)
+
+			obj, err := stat(ctx, objurl)
+			var notFoundErr *ErrGivenObjectNotFound
+			if errors.As(err, &notFoundErr) {
+				return
+			}
+			if err != nil {
+				mu.Lock()
+				merr = err
+				mu.Unlock()
+				return
+			}
+			objects[i] = obj
+		}()
+	}
+	wg.Wait()
+
+	if merr != nil {
+		return false, merr
+	}
+
+	return true, c.db.Update(func(tx *bolt.Tx) error {
+		listing := tx.Bucket(name)
+		if listing == nil {
+			return errCachedListingRemoved
+		}
+		modified := listing.Bucket(listingCacheModified)
+		cachedObjects := listing.Bucket(listingCacheObjects)
+
+		for i, key := range keys {
+			// the object is modified again after it is revalidated.
+			if !bytes.Equal(modified.Get(key), seqs[i]) {
+				continue
+			}
+			if err := modified.Delete(key); err != nil {
+				return err
+			}
+
+			obj := objects[i]
+			if obj == nil || !src.Match(string(key)) {
+				if err := cachedObjects.Delete(key); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// HEAD requests don't report the storage class of the objects
+			// in the standard storage class.
+			var previous cachedObject
+			if v := cachedObjects.Get(key); v != nil {
+				_ = json.Unmarshal(v, &previous)
+			}
+			obj.StorageClass = StorageClass(previous.StorageClass)
+
+			if err := putCachedObject(cachedObjects, key, obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// listCached sends the objects of the cached listing in batches, so the
+// transactions are not kept open while the objects are consumed.
+func (c *ListingCache) listCached(ctx context.Context, name []byte, src *url.URL, objCh chan<- *Object) {
+	var (
+		after       []byte
+		objectFound bool
+	)
+	for {
+		var keys, values [][]byte
+		err := c.db.View(func(tx *bolt.Tx) error {
+			listing := tx.Bucket(name)
+			if listing == nil {
+				return errCachedListingRemoved
+			}
+
+			cursor := listing.Bucket(listingCacheObjects).Cursor()
+
+			k, v := cursor.First()
+			if after != nil {
+				k, v = cursor.Seek(after)
+				if bytes.Equal(k, after) {
+					k, v = cursor.Next()
+				}
+			}
+			for ; k != nil && len(keys) < listingCacheBatchSize; k, v = cursor.Next() {
+				// the slices are valid only during the transaction.
+				keys = append(keys, append([]byte(nil), k...))
+				values = append(values, append([]byte(nil), v...))
+			}
+			return nil
+		})
+		if err != nil {
+			objCh <- &Object{Err: err}
+			return
+		}
+		if len(keys) == 0 {
+			break
+		}
+		after = keys[len(keys)-1]
+
+		for i, key := range keys {
+			var cached cachedObject
+			if err := json.Unmarshal(values[i], &cached); err != nil {
+				objCh <- &Object{Err: fmt.Errorf("unable to read cached object %q: %w", key, err)}
+				return
+			}
+
+			if !src.Match(string(key)) {
+				continue
+			}
+			objectFound = true
+
+			select {
+			case objCh <- cached.object(src, string(key)):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	if !objectFound && !src.IsBucket() {
+		objCh <- &Object{Err: ErrNoObjectFound}
+	}
+}
+
+// refresh lists the objects with the given list function and caches them as
+// they are sent. The listing is cached only if it is completed.
+func (c *ListingCache) refresh(
+	ctx context.Context,
+	name []byte,
+	src *url.URL,
+	list func(context.Context, *url.URL) <-chan *Object,
+	objCh chan<- *Object,
+) {
+	listedAt := time.Now().UTC()
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(name) != nil {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+
+		listing, err := tx.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+		if _, err := listing.CreateBucket(listingCacheObjects); err != nil {
+			return err
+		}
+		if _, err := listing.CreateBucket(listingCacheModified); err != nil {
+			return err
+		}
+		return listing.Put(listingCachePrefix, []byte(src.Prefix))
+	})
+	if err != nil {
+		objCh <- &Object{Err: err}
+		return
+	}
+
+	var batch []*Object
+	// flush writes the batch of the objects to the cache. The listing time
+	// is written once the listing is completed.
+	flush := func(completed bool) error {
+		err := c.db.Update(func(tx *bolt.Tx) error {
+			listing := tx.Bucket(name)
+			if listing == nil {
+				return errCachedListingRemoved
+			}
+			cachedObjects := listing.Bucket(listingCacheObjects)
+			for _, obj := range batch {
+				if err := putCachedObject(cachedObjects, []byte(obj.URL.Path), obj); err != nil {
+					return err
+				}
+			}
+			if !completed {
+				return nil
+			}
+
+			t, err := listedAt.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			return listing.Put(listingCacheListedAt, t)
+		})
+		batch = batch[:0]
+		return err
+	}
+
+	objects := list(ctx, src)
+	defer func() {
+		// the listing is stopped once the context is canceled.
+		for range objects {
+		}
+	}()
+
+	completed := true
+	for obj := range objects {
+		switch {
+		case errors.Is(obj.Err, ErrNoObjectFound):
+		case obj.Err != nil:
+			completed = false
+		default:
+			batch = append(batch, obj)
+			if len(batch) == listingCacheBatchSize {
+				if err := flush(false); err != nil {
+					obj = &Object{Err: err}
+					completed = false
+				}
+			}
+		}
+
+		select {
+		case objCh <- obj:
+		case <-ctx.Done():
+			completed = false
+		}
+		if !completed {
+			break
+		}
+	}
+
+	if completed && ctx.Err() == nil {
+		err = flush(true)
+	} else {
+		// the incomplete listings are not cached.
+		err = c.db.Update(func(tx *bolt.Tx) error {
+			if tx.Bucket(name) == nil {
+				return nil
+			}
+			return tx.DeleteBucket(name)
+		})
+	}
+	if err != nil && ctx.Err() == nil {
+		objCh <- &Object{Err: err}
+	}
+}
+
+// object returns the object of the listing with the given key. The key must
+// be matched with src beforehand.
+func (o cachedObject) object(src *url.URL, key string) *Object {
+	var objtype os.FileMode
+	if strings.HasSuffix(key, "/") {
+		objtype = os.ModeDir
+	}
+
+	newurl := src.Clone()
+	newurl.Path = key
+
+	return &Object{
+		URL:          newurl,
+		Etag:         o.Etag,
+		ModTime:      o.ModTime,
+		Type:         ObjectType{objtype},
+		Size:         o.Size,
+		StorageClass: StorageClass(o.StorageClass),
+	}
+}
+
+func putCachedObject(bucket *bolt.Bucket, key []byte, obj *Object) error {
+	cached := cachedObject{
+		Etag:         obj.Etag,
+		Size:         obj.Size,
+		StorageClass: string(obj.StorageClass),
+	}
+	if obj.ModTime != nil {
+		mod := obj.ModTime.UTC()
+		cached.ModTime = &mod
+	}
+
+	value, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, value)
+}
+
+// listingCacheName returns the name of the cache bucket of the listing of
+// src. The names of the listings of the same storage bucket share a prefix.
+func listingCacheName(namespace string, src *url.URL) []byte {
+	raw := "0"
+	if src.IsRaw() {
+		raw = "1"
+	}
+	return []byte(string(listingCacheNamePrefix(namespace, src.Bucket)) + src.Path + "\x00" + raw)
+}
+
+func listingCacheNamePrefix(namespace, bucket string) []byte {
+	return []byte(namespace + "\x00" + bucket + "\x00")
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// fakeListing is a bucket which counts the requests of the listing cache.
+type fakeListing struct {
+	mu      sync.Mutex
+	objects map[string]int64
+	lists   int
+	stats   int
+}
+
+func (f *fakeListing) list(ctx context.Context, src *url.URL) <-chan *Object {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lists++
+
+	var keys []string
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	objCh := make(chan *Object, len(keys)+1)
+	defer close(objCh)
+
+	mod := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, key := range keys {
+		if !src.Match(key) {
+			continue
+		}
+		newurl := src.Clone()
+		newurl.Path = key
+		objCh <- &Object{URL: newurl, Size: f.objects[key], ModTime: &mod, StorageClass: "GLACIER"}
+	}
+	if len(objCh) == 0 {
+		objCh <- &Object{Err: ErrNoObjectFound}
+	}
+	return objCh
+}
+
+func (f *fakeListing) stat(ctx context.Context, src *url.URL) (*Object, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stats++
+
+	size, ok := f.objects[src.Path]
+	if !ok {
+		return nil, &ErrGivenObjectNotFound{ObjectAbsPath: src.Absolute()}
+	}
+	mod := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	return &Object{URL: src, Size: size, ModTime: &mod}, nil
+}
+
+func listCached(t *testing.T, cache *ListingCache, fake *fakeListing, src string) []string {
+	t.Helper()
+
+	var got []string
+	for obj := range cache.List(context.Background(), "endpoint", mustNewURL(t, src), fake.list, fake.stat) {
+		if obj.Err != nil {
+			got = append(got, obj.Err.Error())
+			continue
+		}
+		got = append(got, fmt.Sprintf("%v %v %v", obj.URL.Relative(), obj.Size, obj.StorageClass))
+	}
+	return got
+}
+
+func TestListingCacheList(t *testing.T) {
+	t.Parallel()
+
+	cache, err := OpenListingCache(t.TempDir(), time.Hour)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	fake := &fakeListing{objects: map[string]int64{
+		"dir/a.txt":     1,
+		"dir/b.txt":     2,
+		"dir/sub/c.txt": 3,
+		"other/d.txt":   4,
+	}}
+
+	expected := []string{"a.txt 1 GLACIER", "b.txt 2 GLACIER", "sub/c.txt 3 GLACIER"}
+
+	assert.DeepEqual(t, listCached(t, cache, fake, "s3://bucket/dir/*"), expected)
+	assert.Equal(t, fake.lists, 1)
+
+	// the listing is served from the cache.
+	assert.DeepEqual(t, listCached(t, cache, fake, "s3://bucket/dir/*"), expected)
+	assert.Equal(t, fake.lists, 1)
+	assert.Equal(t, fake.stats, 0)
+
+	// the listings of the other urls are cached separately.
+	assert.DeepEqual(t, listCached(t, cache, fake, "s3://bucket/*.txt"), []string{
+		"dir/a.txt 1 GLACIER", "dir/b.txt 2 GLACIER", "dir/sub/c.txt 3 GLACIER", "other/d.txt 4 GLACIER",
+	})
+	assert.Equal(t, fake.lists, 2)
+}
+
+func TestListingCacheInvalidate(t *testing.T) {
+	t.Parallel()
+
+	cache, err := OpenListingCache(t.TempDir(), time.Hour)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	fake := &fakeListing{objects: map[string]int64{
+		"dir/a.txt": 1,
+		"dir/b.txt": 2,
+	}}
+
+	listCached(t, cache, fake, "s3://bucket/dir/*")
+
+	fake.objects["dir/a.txt"] = 10
+	fake.objects["dir/c.txt"] = 3
+	fake.objects["other/d.txt"] = 4
+	delete(fake.objects, "dir/b.txt")
+
+	assert.NilError(t, cache.Invalidate("endpoint", "bucket", "dir/a.txt", "dir/b.txt", "dir/c.txt", "other/d.txt"))
+	assert.NilError(t, cache.Invalidate("another-endpoint", "bucket", "dir/e.txt"))
+
+	// the objects out of the prefix of the listing are not revalidated. the
+	// storage class of the revalidated objects is kept.
+	assert.DeepEqual(t, listCached(t, cache, fake, "s3://bucket/dir/*"), []string{
+		"a.txt 10 GLACIER", "c.txt 3 ",
+	})
+	assert.Equal(t, fake.lists, 1)
+	assert.Equal(t, fake.stats, 3)
+
+	// the revalidated objects are not requested again.
+	listCached(t, cache, fake, "s3://bucket/dir/*")
+	assert.Equal(t, fake.stats, 3)
+
+	delete(fake.objects, "dir/a.txt")
+	delete(fake.objects, "dir/c.txt")
+	assert.NilError(t, cache.Invalidate("endpoint", "bucket", "dir/a.txt", "dir/c.txt"))
+
+	assert.DeepEqual(t, listCached(t, cache, fake, "s3://bucket/dir/*"), []string{ErrNoObjectFound.Error()})
+	assert.Equal(t, fake.lists, 1)
+}
+
+func TestListingCacheExpired(t *testing.T) {
+	t.Parallel()
+
+	cache, err := OpenListingCache(t.TempDir(), time.Nanosecond)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	fake := &fakeListing{objects: map[string]int64{"a.txt": 1, "b.txt": 2}}
+
+	listCached(t, cache, fake, "s3://bucket/*")
+	fake.objects["a.txt"] = 10
+	fake.objects["c.txt"] = 3
+	delete(fake.objects, "b.txt")
+
+	// the objects of the expired listing are revalidated, the objects which
+	// are added meanwhile are not seen.
+	assert.DeepEqual(t, listCached(t, cache, fake, "s3://bucket/*"), []string{"a.txt 10 GLACIER"})
+	assert.Equal(t, fake.lists, 1)
+	assert.Equal(t, fake.stats, 2)
+}
+
+func TestListingCacheLocked(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	cache, err := OpenListingCache(dir, time.Hour)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	_, err = OpenListingCache(dir, time.Hour)
+	assert.Assert(t, errors.Is(err, ErrListingCacheLocked))
+}
+
+func TestListingCacheIncompleteListing(t *testing.T) {
+	t.Parallel()
+
+	cache, err := OpenListingCache(t.TempDir(), time.Hour)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	lists := 0
+	list := func(ctx context.Context, src *url.URL) <-chan *Object {
+		lists++
+		objCh := make(chan *Object, 2)
+		objCh <- &Object{URL: mustNewURL(t, "s3://bucket/a.txt")}
+		objCh <- &Object{Err: fmt.Errorf("connection reset")}
+		close(objCh)
+		return objCh
+	}
+
+	for i := 0; i < 2; i++ {
+		var errs []error
+		for obj := range cache.List(context.Background(), "endpoint", mustNewURL(t, "s3://bucket/*"), list, nil) {
+			if obj.Err != nil {
+				errs = append(errs, obj.Err)
+			}
+		}
+		assert.Equal(t, len(errs), 1)
+	}
+	assert.Equal(t, lists, 2)
+}
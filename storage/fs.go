@@ -7,16 +7,36 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/karrick/godirwalk"
 	"github.com/termie/go-shutil"
 
+	"github.com/peak/s5cmd/v2/ignore"
 	"github.com/peak/s5cmd/v2/storage/url"
 )
 
 // Filesystem is the Storage implementation of a local filesystem.
 type Filesystem struct {
-	dryRun bool
+	dryRun     bool
+	ignoreFile string
+}
+
+// IgnoreTree returns the tree of the ignore files of the directory which the
+// relative paths of the objects listed by src are based on. It returns nil if
+// the ignore files are disabled.
+func (f *Filesystem) IgnoreTree(src *url.URL) *ignore.Tree {
+	if f.ignoreFile == "" || src.IsRemote() {
+		return nil
+	}
+
+	basePath := src.Absolute()
+	if src.IsWildcard() {
+		if loc := strings.IndexAny(basePath, "?*"); loc >= 0 {
+			basePath = basePath[:loc]
+		}
+	}
+	return ignore.NewTree(filepath.Dir(basePath), f.ignoreFile)
 }
 
 // Stat returns the Object structure describing object.
@@ -74,6 +94,8 @@ func (f *Filesystem) expandGlob(ctx context.Context, src *url.URL, followSymlink
 	go func() {
 		defer close(ch)
 
+		tree := f.IgnoreTree(src)
+
 		matchedFiles, err := filepath.Glob(src.Absolute())
 		if err != nil {
 			sendError(ctx, err, ch)
@@ -102,12 +124,21 @@ func (f *Filesystem) expandGlob(ctx context.Context, src *url.URL, followSymlink
 				return
 			}
 
+			ignored, err := isIgnored(tree, filename, obj.Type.IsDir())
+			if err != nil {
+				sendError(ctx, err, ch)
+				return
+			}
+			if ignored {
+				continue
+			}
+
 			if !obj.Type.IsDir() {
 				sendObject(ctx, obj, ch)
 				continue
 			}
 
-			walkDir(ctx, f, fileurl, followSymlinks, func(obj *Object) {
+			walkDir(ctx, f, fileurl, tree, followSymlinks, func(obj *Object) {
 				sendObject(ctx, obj, ch)
 			})
 		}
@@ -115,13 +146,33 @@ func (f *Filesystem) expandGlob(ctx context.Context, src *url.URL, followSymlink
 	return ch
 }
 
-func walkDir(ctx context.Context, fs *Filesystem, src *url.URL, followSymlinks bool, fn func(o *Object)) {
+// walkDir walks the given directory and calls fn with the files in it. The
+// files and directories ignored by the given tree are skipped.
+func walkDir(ctx context.Context, fs *Filesystem, src *url.URL, tree *ignore.Tree, followSymlinks bool, fn func(o *Object)) {
 	//skip if symlink is pointing to a dir and --no-follow-symlink
 	if !ShouldProcessURL(src, followSymlinks) {
 		return
 	}
-	err := godirwalk.Walk(src.Absolute(), &godirwalk.Options{
+	root := src.Absolute()
+	err := godirwalk.Walk(root, &godirwalk.Options{
 		Callback: func(pathname string, dirent *godirwalk.Dirent) error {
+			if tree != nil && filepath.Clean(pathname) != filepath.Clean(root) {
+				isDir := dirent.IsDir()
+				if dirent.IsSymlink() && followSymlinks {
+					isDir, _ = dirent.IsDirOrSymlinkToDir()
+				}
+				ignored, err := isIgnored(tree, pathname, isDir)
+				if err != nil {
+					return err
+				}
+				if ignored && isDir {
+					return filepath.SkipDir
+				}
+				if ignored {
+					return nil
+				}
+			}
+
 			// we're interested in files
 			if dirent.IsDir() {
 				return nil
@@ -160,13 +211,26 @@ func (f *Filesystem) walkDir(ctx context.Context, src *url.URL, followSymlinks b
 	go func() {
 		defer close(ch)
 
-		walkDir(ctx, f, src, followSymlinks, func(obj *Object) {
+		walkDir(ctx, f, src, f.IgnoreTree(src), followSymlinks, func(obj *Object) {
 			sendObject(ctx, obj, ch)
 		})
 	}()
 	return ch
 }
 
+// isIgnored reports whether the given local path is ignored by the tree.
+func isIgnored(tree *ignore.Tree, pathname string, isDir bool) (bool, error) {
+	if tree == nil {
+		return false, nil
+	}
+
+	relpath, err := filepath.Rel(tree.Root(), pathname)
+	if err != nil {
+		return false, err
+	}
+	return tree.Ignored(filepath.ToSlash(relpath), isDir)
+}
+
 // Copy copies given source to destination.
 func (f *Filesystem) Copy(ctx context.Context, src, dst *url.URL, _ Metadata) error {
 	if f.dryRun {
@@ -234,6 +298,15 @@ func (f *Filesystem) Open(path string) (*os.File, error) {
 	return file, nil
 }
 
+// OpenFile opens the given path with the specified flag and permissions.
+func (f *Filesystem) OpenFile(path string, flag int, perm os.FileMode) (*os.File, error) {
+	if f.dryRun {
+		return &os.File{}, nil
+	}
+
+	return os.OpenFile(path, flag, perm)
+}
+
 // CreateTemp creates a new temporary file
 func (f *Filesystem) CreateTemp(dir, pattern string) (*os.File, error) {
 	if f.dryRun {
@@ -1,6 +1,16 @@
 package storage
 
-import "testing"
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
 
 func TestFilesystemImplementsStorageInterface(t *testing.T) {
 	var i interface{} = new(Filesystem)
@@ -8,3 +18,63 @@ func TestFilesystemImplementsStorageInterface(t *testing.T) {
 		t.Errorf("expected %t to implement Storage interface", i)
 	}
 }
+
+func TestFilesystemListIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".s5cmdignore":           "*.log\nbuild/\n",
+		"a.txt":                  "",
+		"a.log":                  "",
+		"build/out.bin":          "",
+		"src/main.go":            "",
+		"src/.s5cmdignore":       "!debug.log\n/generated.go\n",
+		"src/debug.log":          "",
+		"src/generated.go":       "",
+		"src/pkg/generated.go":   "",
+		"src/pkg/build/cache.go": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{
+		".s5cmdignore",
+		"a.txt",
+		"src/.s5cmdignore",
+		"src/debug.log",
+		"src/main.go",
+		"src/pkg/generated.go",
+	}
+
+	for _, src := range []string{dir, filepath.Join(dir, "*")} {
+		srcurl, err := url.New(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fs := NewLocalClient(Options{IgnoreFile: ".s5cmdignore"})
+
+		var got []string
+		for object := range fs.List(context.Background(), srcurl, true) {
+			if object.Err != nil {
+				t.Fatal(object.Err)
+			}
+			relpath, err := filepath.Rel(dir, object.URL.Absolute())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, filepath.ToSlash(relpath))
+		}
+		sort.Strings(got)
+
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Errorf("%v: (-want +got):\n%v", src, diff)
+		}
+	}
+}
@@ -0,0 +1,1334 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	urlpkg "net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	gcsDefaultEndpoint = "https://storage.googleapis.com"
+
+	// gcsDefaultPartSize is the size of the parts of the composite uploads
+	// when the part size is not given.
+	gcsDefaultPartSize = 50 * 1024 * 1024
+
+	// gcsMaxComposeSources is the maximum number of the source objects of a
+	// compose request.
+	gcsMaxComposeSources = 32
+
+	// gcsMaxComponents is the maximum number of the components of a
+	// composite object.
+	gcsMaxComponents = 1024
+
+	// gcsMaxBatchSize is the maximum number of the requests of a batch
+	// request.
+	gcsMaxBatchSize = 100
+
+	// gcsCompositePrefix is the prefix of the temporary objects of the
+	// composite uploads. They are deleted once the upload is completed.
+	gcsCompositePrefix = ".s5cmd/composite/"
+
+	// gcsMaxPresignExpire is the maximum expiration of the V4 signed URLs.
+	gcsMaxPresignExpire = 7 * 24 * time.Hour
+)
+
+// gcsCRC32CTable is the table of the CRC32C checksums of the objects.
+var gcsCRC32CTable = crc32.MakeTable(crc32.Castagnoli)
+
+func init() {
+	Register("gs", url.SchemeOptions{}, func(_ context.Context, _ *url.URL, opts Options) (Storage, error) {
+		return NewGCSClient(opts)
+	})
+}
+
+// gcsClients caches the clients by their options, so that the connections
+// and the access tokens are reused by the operations.
+var gcsClients sync.Map
+
+// GCS is the Storage implementation of Google Cloud Storage, which uses the
+// JSON API, e.g. gs://bucket/path/object. Unlike the XML API used with the
+// s3:// URLs, it supports batch deletes, object generations as versions and
+// CRC32C checksums.
+//
+// The credentials are found in the same way as the Google Cloud client
+// libraries. STORAGE_EMULATOR_HOST points the client to an emulator, without
+// credentials.
+type GCS struct {
+	client     *http.Client
+	endpoint   *urlpkg.URL
+	creds      *gcsCredentials
+	maxRetries int
+	dryRun     bool
+}
+
+// NewGCSClient returns a client of Google Cloud Storage which is configured
+// with the environment variables.
+func NewGCSClient(opts Options) (*GCS, error) {
+	// only the options used by the client are kept in the cache key.
+	key := Options{
+		MaxRetries:    opts.MaxRetries,
+		NoVerifySSL:   opts.NoVerifySSL,
+		DryRun:        opts.DryRun,
+		NoSignRequest: opts.NoSignRequest,
+	}
+	if client, ok := gcsClients.Load(key); ok {
+		return client.(*GCS), nil
+	}
+
+	client, err := newGCSClient(key, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := gcsClients.LoadOrStore(key, client)
+	return actual.(*GCS), nil
+}
+
+func newGCSClient(opts Options, getenv func(string) string) (*GCS, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// objects stored with gzip encoding are transferred as is.
+	transport.DisableCompression = true
+	if opts.NoVerifySSL {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	g := &GCS{
+		client:     &http.Client{Transport: transport},
+		maxRetries: opts.MaxRetries,
+		dryRun:     opts.DryRun,
+	}
+
+	endpoint := gcsDefaultEndpoint
+	emulator := getenv("STORAGE_EMULATOR_HOST")
+	if emulator != "" {
+		endpoint = emulator
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+	}
+
+	u, err := urlpkg.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid google cloud storage endpoint %q", endpoint)
+	}
+	g.endpoint = u
+
+	if emulator != "" || opts.NoSignRequest {
+		g.creds = &gcsCredentials{projectID: getenv("GOOGLE_CLOUD_PROJECT")}
+		return g, nil
+	}
+
+	creds, err := loadGCSCredentials(g.client, getenv)
+	if err != nil {
+		return nil, err
+	}
+	g.creds = creds
+	return g, nil
+}
+
+// ErrGCS is the error returned by the JSON API of Google Cloud Storage.
+type ErrGCS struct {
+	StatusCode int
+	Reason     string
+	Message    string
+}
+
+func (e *ErrGCS) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%v status code: %v", e.Reason, e.StatusCode)
+	}
+	return fmt.Sprintf("%v: %v status code: %v", e.Reason, e.Message, e.StatusCode)
+}
+
+func isGCSStatus(err error, statusCode int) bool {
+	gcsErr, ok := err.(*ErrGCS)
+	return ok && gcsErr.StatusCode == statusCode
+}
+
+func isGCSRetryable(err error) bool {
+	gcsErr, ok := err.(*ErrGCS)
+	if !ok {
+		// network errors
+		return true
+	}
+	return gcsErr.StatusCode == http.StatusTooManyRequests ||
+		gcsErr.StatusCode == http.StatusRequestTimeout ||
+		gcsErr.StatusCode >= 500
+}
+
+func readGCSError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	gcsErr := &ErrGCS{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Error struct {
+			Message string
+			Errors  []struct {
+				Reason string
+			}
+		}
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if json.Unmarshal(data, &body) == nil {
+		gcsErr.Message = body.Error.Message
+		if len(body.Error.Errors) > 0 {
+			gcsErr.Reason = body.Error.Errors[0].Reason
+		}
+	}
+
+	if gcsErr.Reason == "" {
+		gcsErr.Reason = http.StatusText(resp.StatusCode)
+	}
+	return gcsErr
+}
+
+// gcsRequest describes a request of the JSON API. Path is the escaped path of
+// the resource.
+type gcsRequest struct {
+	method string
+	path   string
+	query  urlpkg.Values
+	header http.Header
+	body   []byte
+}
+
+// gcsObjectPath returns the escaped path of the object resource. Slashes are
+// escaped as well, since object names are a single path segment.
+func gcsObjectPath(bucket, object string) string {
+	return "/storage/v1/b/" + urlpkg.PathEscape(bucket) + "/o/" + urlpkg.PathEscape(object)
+}
+
+// do sends the request and returns the response if it is successful. The
+// failed requests are retried as long as they are transient.
+func (g *GCS) do(ctx context.Context, r gcsRequest) (*http.Response, error) {
+	endpoint := g.endpoint.String() + r.path
+	if len(r.query) > 0 {
+		endpoint += "?" + r.query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, r.method, endpoint, bytes.NewReader(r.body))
+		if err != nil {
+			return nil, err
+		}
+		req.ContentLength = int64(len(r.body))
+		if r.body == nil {
+			req.Body = http.NoBody
+		}
+
+		for k, v := range r.header {
+			req.Header[k] = v
+		}
+
+		if g.creds.tokenSource != nil {
+			token, err := g.creds.tokenSource.Token(ctx)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := g.client.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		if err == nil {
+			err = readGCSError(resp)
+		}
+
+		if !isGCSRetryable(err) || attempt >= g.maxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		msg := log.DebugMessage{Err: fmt.Sprintf("retrying %v %v: %v", r.method, r.path, err)}
+		log.Debug(msg)
+
+		select {
+		case <-time.After(retryDelay(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (g *GCS) doJSON(ctx context.Context, r gcsRequest, v interface{}) error {
+	resp, err := g.do(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// gcsObject is the object resource of the JSON API.
+type gcsObject struct {
+	Name               string            `json:"name,omitempty"`
+	Bucket             string            `json:"bucket,omitempty"`
+	Generation         string            `json:"generation,omitempty"`
+	Size               string            `json:"size,omitempty"`
+	Updated            string            `json:"updated,omitempty"`
+	Md5Hash            string            `json:"md5Hash,omitempty"`
+	Crc32c             string            `json:"crc32c,omitempty"`
+	StorageClass       string            `json:"storageClass,omitempty"`
+	ContentType        string            `json:"contentType,omitempty"`
+	ContentEncoding    string            `json:"contentEncoding,omitempty"`
+	ContentDisposition string            `json:"contentDisposition,omitempty"`
+	ContentLanguage    string            `json:"contentLanguage,omitempty"`
+	CacheControl       string            `json:"cacheControl,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+}
+
+// object returns the Object of the resource with the given URL.
+func (o *gcsObject) object(u *url.URL) *Object {
+	size, _ := strconv.ParseInt(o.Size, 10, 64)
+
+	var objtype os.FileMode
+	if strings.HasSuffix(o.Name, "/") {
+		objtype = os.ModeDir
+	}
+
+	obj := &Object{
+		URL:          u,
+		Etag:         o.etag(),
+		CRC32C:       o.Crc32c,
+		Size:         size,
+		Type:         ObjectType{objtype},
+		StorageClass: StorageClass(o.StorageClass),
+	}
+	if mod, err := time.Parse(time.RFC3339Nano, o.Updated); err == nil {
+		mod = mod.UTC()
+		obj.ModTime = &mod
+	}
+	return obj
+}
+
+// etag returns the MD5 checksum of the object in hex, as the ETags of S3.
+// Composite objects do not have MD5 checksums, so their ETags are empty.
+func (o *gcsObject) etag() string {
+	sum, err := base64.StdEncoding.DecodeString(o.Md5Hash)
+	if err != nil || len(sum) == 0 {
+		return ""
+	}
+	return hex.EncodeToString(sum)
+}
+
+func (o *gcsObject) metadata() *Metadata {
+	return &Metadata{
+		ContentType:        o.ContentType,
+		ContentEncoding:    o.ContentEncoding,
+		ContentDisposition: o.ContentDisposition,
+		ContentLanguage:    o.ContentLanguage,
+		CacheControl:       o.CacheControl,
+		StorageClass:       o.StorageClass,
+		UserDefined:        o.Metadata,
+	}
+}
+
+// setMetadata sets the properties of the resource which are given in the
+// metadata.
+func (o *gcsObject) setMetadata(metadata Metadata) {
+	for dst, value := range map[*string]string{
+		&o.ContentType:        metadata.ContentType,
+		&o.ContentEncoding:    metadata.ContentEncoding,
+		&o.ContentDisposition: metadata.ContentDisposition,
+		&o.ContentLanguage:    metadata.ContentLanguage,
+		&o.CacheControl:       metadata.CacheControl,
+		&o.StorageClass:       metadata.StorageClass,
+	} {
+		if value != "" {
+			*dst = value
+		}
+	}
+	if len(metadata.UserDefined) > 0 {
+		o.Metadata = metadata.UserDefined
+	}
+}
+
+// gcsPredefinedACL returns the predefined ACL of Google Cloud Storage which
+// corresponds to the canned ACL of S3.
+func gcsPredefinedACL(acl string) (string, error) {
+	switch acl {
+	case "private":
+		return "private", nil
+	case "public-read":
+		return "publicRead", nil
+	case "public-read-write":
+		return "publicReadWrite", nil
+	case "authenticated-read":
+		return "authenticatedRead", nil
+	case "bucket-owner-read":
+		return "bucketOwnerRead", nil
+	case "bucket-owner-full-control":
+		return "bucketOwnerFullControl", nil
+	default:
+		return "", fmt.Errorf("acl %q is not supported by google cloud storage", acl)
+	}
+}
+
+func gcsGenerationQuery(u *url.URL) urlpkg.Values {
+	query := urlpkg.Values{}
+	if u.VersionID != "" {
+		query.Set("generation", u.VersionID)
+	}
+	return query
+}
+
+func (g *GCS) stat(ctx context.Context, u *url.URL) (*gcsObject, error) {
+	var obj gcsObject
+	err := g.doJSON(ctx, gcsRequest{
+		method: http.MethodGet,
+		path:   gcsObjectPath(u.Bucket, u.Path),
+		query:  gcsGenerationQuery(u),
+	}, &obj)
+	if err != nil {
+		if isGCSStatus(err, http.StatusNotFound) {
+			return nil, &ErrGivenObjectNotFound{ObjectAbsPath: u.Absolute()}
+		}
+		return nil, err
+	}
+	return &obj, nil
+}
+
+// Stat retrieves the metadata of the object. The generation of the URL is
+// used as the version of the object.
+func (g *GCS) Stat(ctx context.Context, u *url.URL) (*Object, error) {
+	obj, err := g.stat(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return obj.object(u), nil
+}
+
+// HeadObject retrieves the metadata of the object.
+func (g *GCS) HeadObject(ctx context.Context, u *url.URL) (*Object, *Metadata, error) {
+	obj, err := g.stat(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	return obj.object(u), obj.metadata(), nil
+}
+
+type gcsObjectList struct {
+	Items         []gcsObject
+	Prefixes      []string
+	NextPageToken string
+}
+
+// List lists the objects and the prefixes of the bucket in the same manner as
+// S3. All generations of the objects are listed for the versioned URLs.
+func (g *GCS) List(ctx context.Context, src *url.URL, _ bool) <-chan *Object {
+	objCh := make(chan *Object)
+
+	go func() {
+		defer close(objCh)
+
+		query := urlpkg.Values{"maxResults": {"1000"}}
+		if src.Prefix != "" {
+			query.Set("prefix", src.Prefix)
+		}
+		if src.Delimiter != "" {
+			query.Set("delimiter", src.Delimiter)
+		}
+		if src.IsVersioned() {
+			query.Set("versions", "true")
+		}
+
+		var (
+			objectFound bool
+			now         = time.Now().UTC()
+		)
+		for {
+			var list gcsObjectList
+			err := g.doJSON(ctx, gcsRequest{
+				method: http.MethodGet,
+				path:   "/storage/v1/b/" + urlpkg.PathEscape(src.Bucket) + "/o",
+				query:  query,
+			}, &list)
+			if err != nil {
+				sendError(ctx, err, objCh)
+				return
+			}
+
+			for _, prefix := range list.Prefixes {
+				if !src.Match(prefix) {
+					continue
+				}
+
+				newurl := src.Clone()
+				newurl.Path = prefix
+				sendObject(ctx, &Object{URL: newurl, Type: ObjectType{os.ModeDir}}, objCh)
+				objectFound = true
+			}
+
+			for i := range list.Items {
+				item := &list.Items[i]
+				if !src.Match(item.Name) {
+					continue
+				}
+				if src.VersionID != "" && src.VersionID != item.Generation {
+					continue
+				}
+
+				newurl := src.Clone()
+				newurl.Path = item.Name
+				if src.IsVersioned() {
+					newurl.VersionID = item.Generation
+				}
+
+				obj := item.object(newurl)
+				// skip the objects created after the listing is started.
+				if obj.ModTime != nil && obj.ModTime.After(now) {
+					objectFound = true
+					continue
+				}
+
+				sendObject(ctx, obj, objCh)
+				objectFound = true
+			}
+
+			if list.NextPageToken == "" {
+				break
+			}
+			query.Set("pageToken", list.NextPageToken)
+		}
+
+		if !objectFound && !src.IsBucket() {
+			sendError(ctx, ErrNoObjectFound, objCh)
+		}
+	}()
+
+	return objCh
+}
+
+// Delete deletes the object, or the generation of the object given with the
+// version of the URL. Deleting a missing object is not an error, as in S3.
+func (g *GCS) Delete(ctx context.Context, u *url.URL) error {
+	if g.dryRun {
+		return nil
+	}
+
+	err := g.doJSON(ctx, gcsRequest{
+		method: http.MethodDelete,
+		path:   gcsObjectPath(u.Bucket, u.Path),
+		query:  gcsGenerationQuery(u),
+	}, nil)
+	if err != nil && !isGCSStatus(err, http.StatusNotFound) {
+		return err
+	}
+	return nil
+}
+
+// MultiDelete deletes the objects read from the channel with batch requests.
+func (g *GCS) MultiDelete(ctx context.Context, urlch <-chan *url.URL) <-chan *Object {
+	resultch := make(chan *Object)
+
+	go func() {
+		defer close(resultch)
+
+		var (
+			wg  sync.WaitGroup
+			sem = make(chan struct{}, 10)
+		)
+		deleteBatch := func(urls []*url.URL) {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				for _, obj := range g.deleteBatch(ctx, urls) {
+					sendObject(ctx, obj, resultch)
+				}
+			}()
+		}
+
+		var urls []*url.URL
+		for u := range urlch {
+			urls = append(urls, u)
+			if len(urls) == gcsMaxBatchSize {
+				deleteBatch(urls)
+				urls = nil
+			}
+		}
+		if len(urls) > 0 {
+			deleteBatch(urls)
+		}
+
+		wg.Wait()
+	}()
+
+	return resultch
+}
+
+// deleteBatch deletes the objects with a single batch request, and returns
+// the result of each of them.
+func (g *GCS) deleteBatch(ctx context.Context, urls []*url.URL) []*Object {
+	results := make([]*Object, len(urls))
+	for i, u := range urls {
+		results[i] = &Object{URL: u}
+	}
+
+	if g.dryRun {
+		return results
+	}
+
+	if len(urls) == 1 {
+		results[0].Err = g.Delete(ctx, urls[0])
+		return results
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for i, u := range urls {
+		path := g.endpoint.Path + gcsObjectPath(u.Bucket, u.Path)
+		if query := gcsGenerationQuery(u); len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+
+		part, _ := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"application/http"},
+			"Content-Id":   {fmt.Sprintf("<%d>", i+1)},
+		})
+		fmt.Fprintf(part, "DELETE %v HTTP/1.1\r\n\r\n", path)
+	}
+	w.Close()
+
+	resp, err := g.do(ctx, gcsRequest{
+		method: http.MethodPost,
+		path:   "/batch/storage/v1",
+		header: http.Header{"Content-Type": {"multipart/mixed; boundary=" + w.Boundary()}},
+		body:   body.Bytes(),
+	})
+	if err != nil {
+		for _, result := range results {
+			result.Err = err
+		}
+		return results
+	}
+	defer resp.Body.Close()
+
+	responded := make([]bool, len(urls))
+	err = readGCSBatchResponse(resp, func(i int, partResp *http.Response) {
+		if i < 0 || i >= len(urls) {
+			return
+		}
+		responded[i] = true
+
+		if partResp.StatusCode < 300 || partResp.StatusCode == http.StatusNotFound {
+			partResp.Body.Close()
+			return
+		}
+
+		err := readGCSError(partResp)
+		// transient failures of a batch are retried one by one.
+		if isGCSRetryable(err) {
+			err = g.Delete(ctx, urls[i])
+		}
+		results[i].Err = err
+	})
+
+	for i, ok := range responded {
+		if ok {
+			continue
+		}
+		if err == nil {
+			err = fmt.Errorf("no response for the deletion of %v in the batch", urls[i])
+		}
+		results[i].Err = err
+	}
+	return results
+}
+
+// readGCSBatchResponse parses the responses of the requests in a batch. The
+// index of the request is read from the Content-ID of the response.
+func readGCSBatchResponse(resp *http.Response, fn func(i int, resp *http.Response)) error {
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("invalid batch response: %w", err)
+	}
+
+	reader := multipart.NewReader(resp.Body, params["boundary"])
+	for i := 0; ; i++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid batch response: %w", err)
+		}
+
+		index := i
+		contentID := strings.Trim(part.Header.Get("Content-Id"), "<>")
+		if n, err := strconv.Atoi(strings.TrimPrefix(contentID, "response-")); err == nil {
+			index = n - 1
+		}
+
+		partResp, err := http.ReadResponse(bufio.NewReader(part), nil)
+		if err != nil {
+			return fmt.Errorf("invalid batch response: %w", err)
+		}
+		fn(index, partResp)
+	}
+}
+
+// Copy copies the object on the server side with rewrite requests, so that
+// the objects can be copied across locations and storage classes. The
+// metadata of the source object is kept unless the metadata is replaced.
+func (g *GCS) Copy(ctx context.Context, from, to *url.URL, metadata Metadata) error {
+	if g.dryRun {
+		return nil
+	}
+	if len(metadata.Tags) > 0 {
+		return NotSupported(to, "object tagging")
+	}
+
+	query := urlpkg.Values{}
+	if from.VersionID != "" {
+		query.Set("sourceGeneration", from.VersionID)
+	}
+	if metadata.ACL != "" {
+		acl, err := gcsPredefinedACL(metadata.ACL)
+		if err != nil {
+			return err
+		}
+		query.Set("destinationPredefinedAcl", acl)
+	}
+
+	var resource *gcsObject
+	switch {
+	case metadata.Directive == "REPLACE":
+		resource = &gcsObject{}
+		resource.setMetadata(metadata)
+	case metadata.ContentType != "" || metadata.StorageClass != "" || len(metadata.UserDefined) > 0:
+		// the properties given in the request body replace all of the
+		// properties of the source object, so the properties which are not
+		// given are kept from the source object.
+		source, err := g.stat(ctx, from)
+		if err != nil {
+			return err
+		}
+		resource = &gcsObject{
+			ContentType:        source.ContentType,
+			ContentEncoding:    source.ContentEncoding,
+			ContentDisposition: source.ContentDisposition,
+			ContentLanguage:    source.ContentLanguage,
+			CacheControl:       source.CacheControl,
+			StorageClass:       source.StorageClass,
+			Metadata:           source.Metadata,
+		}
+		resource.setMetadata(metadata)
+	}
+
+	var body []byte
+	if resource != nil {
+		body, _ = json.Marshal(resource)
+	}
+
+	path := gcsObjectPath(from.Bucket, from.Path) + "/rewriteTo" +
+		strings.TrimPrefix(gcsObjectPath(to.Bucket, to.Path), "/storage/v1")
+
+	// large objects are rewritten in several requests, each of them returns
+	// the token to continue with.
+	for {
+		var result struct {
+			Done         bool
+			RewriteToken string
+		}
+		err := g.doJSON(ctx, gcsRequest{
+			method: http.MethodPost,
+			path:   path,
+			query:  query,
+			header: http.Header{"Content-Type": {"application/json"}},
+			body:   body,
+		}, &result)
+		if err != nil {
+			if isGCSStatus(err, http.StatusNotFound) {
+				return &ErrGivenObjectNotFound{ObjectAbsPath: from.Absolute()}
+			}
+			return err
+		}
+
+		if result.Done {
+			return nil
+		}
+		query.Set("rewriteToken", result.RewriteToken)
+	}
+}
+
+// Read returns the contents of the object as a stream.
+func (g *GCS) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
+	query := gcsGenerationQuery(src)
+	query.Set("alt", "media")
+
+	resp, err := g.do(ctx, gcsRequest{
+		method: http.MethodGet,
+		path:   gcsObjectPath(src.Bucket, src.Path),
+		query:  query,
+		header: http.Header{"Accept-Encoding": {"gzip"}},
+	})
+	if err != nil {
+		if isGCSStatus(err, http.StatusNotFound) {
+			return nil, &ErrGivenObjectNotFound{ObjectAbsPath: src.Absolute()}
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Get downloads the object to the destination. Objects larger than the part
+// size are downloaded in ranges in parallel. All ranges are read from the
+// same generation of the object.
+func (g *GCS) Get(ctx context.Context, from *url.URL, to io.WriterAt, concurrency int, partSize int64) (int64, error) {
+	if g.dryRun {
+		return 0, nil
+	}
+
+	resource, err := g.stat(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+	size, _ := strconv.ParseInt(resource.Size, 10, 64)
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if partSize <= 0 {
+		partSize = size
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		merr     error
+		sem      = make(chan struct{}, concurrency)
+		numParts = int64(1)
+	)
+	if size > 0 {
+		numParts = (size + partSize - 1) / partSize
+	}
+
+	for partNumber := int64(1); partNumber <= numParts; partNumber++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(partNumber int64) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			offset := (partNumber - 1) * partSize
+			length := partLength(size, partSize, partNumber)
+
+			err := g.getRange(ctx, from, to, resource.Generation, offset, length)
+			if err != nil {
+				mu.Lock()
+				if merr == nil {
+					merr = err
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}(partNumber)
+	}
+
+	wg.Wait()
+
+	if merr != nil {
+		return 0, merr
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+func (g *GCS) getRange(ctx context.Context, from *url.URL, to io.WriterAt, generation string, offset, length int64) error {
+	// the stored bytes are requested, the objects stored with gzip encoding
+	// are decompressed otherwise and the ranges do not match.
+	header := http.Header{"Accept-Encoding": {"gzip"}}
+	if length > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	}
+
+	query := urlpkg.Values{"alt": {"media"}}
+	if generation != "" {
+		query.Set("ifGenerationMatch", generation)
+	}
+
+	resp, err := g.do(ctx, gcsRequest{
+		method: http.MethodGet,
+		path:   gcsObjectPath(from.Bucket, from.Path),
+		query:  query,
+		header: header,
+	})
+	if err != nil {
+		if isGCSStatus(err, http.StatusPreconditionFailed) {
+			return ErrObjectModified
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.NewOffsetWriter(to, offset), resp.Body)
+	return err
+}
+
+// Put uploads the contents of the reader. Contents larger than the part size
+// are uploaded as temporary objects in parallel, which are composed into the
+// object once all of them are uploaded.
+func (g *GCS) Put(
+	ctx context.Context,
+	reader io.Reader,
+	to *url.URL,
+	metadata Metadata,
+	concurrency int,
+	partSize int64,
+) error {
+	if g.dryRun {
+		return nil
+	}
+	if len(metadata.Tags) > 0 {
+		return NotSupported(to, "object tagging")
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if partSize <= 0 {
+		partSize = gcsDefaultPartSize
+	}
+
+	query := urlpkg.Values{}
+	if metadata.ACL != "" {
+		acl, err := gcsPredefinedACL(metadata.ACL)
+		if err != nil {
+			return err
+		}
+		query.Set("predefinedAcl", acl)
+	}
+
+	resource := &gcsObject{Name: to.Path}
+	resource.setMetadata(metadata)
+
+	first, err := readPart(reader, partSize)
+	if err != nil {
+		return err
+	}
+
+	// contents which fit into a single part are uploaded with a single
+	// request.
+	if int64(len(first)) < partSize {
+		return g.upload(ctx, to.Bucket, resource, query, first)
+	}
+
+	checksum := crc32.New(gcsCRC32CTable)
+	reader = io.TeeReader(io.MultiReader(bytes.NewReader(first), reader), checksum)
+
+	tempPrefix := gcsCompositePrefix + randomHex(16) + "/"
+	parts, err := g.putParts(ctx, reader, to.Bucket, tempPrefix, concurrency, partSize)
+
+	// the temporary objects are deleted even if the upload is cancelled.
+	defer g.deleteParts(context.Background(), to, tempPrefix)
+
+	if err != nil {
+		return err
+	}
+
+	composed, err := g.compose(ctx, to.Bucket, parts, resource, query)
+	if err != nil {
+		return err
+	}
+
+	if expected := gcsCRC32C(checksum); composed.Crc32c != expected {
+		g.Delete(ctx, to)
+		return fmt.Errorf("crc32c checksum of %v does not match: expected %v, got %v", to, expected, composed.Crc32c)
+	}
+	return nil
+}
+
+// upload uploads the data and the resource with a single multipart request.
+// The checksums of the data are sent along to be validated by the server.
+func (g *GCS) upload(ctx context.Context, bucket string, resource *gcsObject, query urlpkg.Values, data []byte) error {
+	md5sum := md5.Sum(data)
+	checksum := crc32.New(gcsCRC32CTable)
+	checksum.Write(data)
+
+	withChecksums := *resource
+	withChecksums.Md5Hash = base64.StdEncoding.EncodeToString(md5sum[:])
+	withChecksums.Crc32c = gcsCRC32C(checksum)
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	part, _ := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	json.NewEncoder(part).Encode(&withChecksums)
+
+	contentType := resource.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	part, _ = w.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+	part.Write(data)
+	w.Close()
+
+	uploadQuery := urlpkg.Values{"uploadType": {"multipart"}}
+	for k, v := range query {
+		uploadQuery[k] = v
+	}
+
+	return g.doJSON(ctx, gcsRequest{
+		method: http.MethodPost,
+		path:   "/upload/storage/v1/b/" + urlpkg.PathEscape(bucket) + "/o",
+		query:  uploadQuery,
+		header: http.Header{"Content-Type": {"multipart/related; boundary=" + w.Boundary()}},
+		body:   body.Bytes(),
+	}, nil)
+}
+
+// putParts uploads the contents of the reader as temporary objects under the
+// prefix and returns their names in order.
+func (g *GCS) putParts(ctx context.Context, reader io.Reader, bucket, prefix string, concurrency int, partSize int64) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		merr  error
+		sem   = make(chan struct{}, concurrency)
+		parts []string
+	)
+
+	for ctx.Err() == nil {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		data, err := readPart(reader, partSize)
+		if err != nil || len(data) == 0 {
+			<-sem
+			if err != nil {
+				mu.Lock()
+				if merr == nil {
+					merr = err
+				}
+				mu.Unlock()
+			}
+			break
+		}
+
+		if len(parts) == gcsMaxComponents {
+			<-sem
+			mu.Lock()
+			if merr == nil {
+				merr = fmt.Errorf("object is too large to upload with %v byte parts, increase the part size", partSize)
+			}
+			mu.Unlock()
+			break
+		}
+
+		name := fmt.Sprintf("%v%05d", prefix, len(parts))
+		parts = append(parts, name)
+
+		wg.Add(1)
+		go func(name string, data []byte) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := g.upload(ctx, bucket, &gcsObject{Name: name}, nil, data)
+			if err == nil {
+				return
+			}
+
+			mu.Lock()
+			if merr == nil {
+				merr = err
+			}
+			mu.Unlock()
+			cancel()
+		}(name, data)
+	}
+
+	wg.Wait()
+
+	if merr != nil {
+		return nil, merr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// compose composes the parts into the object of the resource. A compose
+// request accepts a limited number of sources, so the parts are composed
+// into intermediate objects until they fit into a single request.
+func (g *GCS) compose(ctx context.Context, bucket string, parts []string, resource *gcsObject, query urlpkg.Values) (*gcsObject, error) {
+	for round := 0; len(parts) > gcsMaxComposeSources; round++ {
+		var composed []string
+		for i := 0; i < len(parts); i += gcsMaxComposeSources {
+			end := i + gcsMaxComposeSources
+			if end > len(parts) {
+				end = len(parts)
+			}
+
+			// intermediate objects are kept under the prefix of the parts
+			// to be deleted along with them.
+			name := fmt.Sprintf("%v.%d-%05d", parts[0], round, i/gcsMaxComposeSources)
+			if _, err := g.composeRequest(ctx, bucket, name, parts[i:end], &gcsObject{}, nil); err != nil {
+				return nil, err
+			}
+			composed = append(composed, name)
+		}
+		parts = composed
+	}
+
+	return g.composeRequest(ctx, bucket, resource.Name, parts, resource, query)
+}
+
+func (g *GCS) composeRequest(
+	ctx context.Context,
+	bucket, name string,
+	sources []string,
+	destination *gcsObject,
+	query urlpkg.Values,
+) (*gcsObject, error) {
+	type sourceObject struct {
+		Name string `json:"name"`
+	}
+	request := struct {
+		SourceObjects []sourceObject `json:"sourceObjects"`
+		Destination   *gcsObject     `json:"destination"`
+	}{Destination: destination}
+	for _, source := range sources {
+		request.SourceObjects = append(request.SourceObjects, sourceObject{Name: source})
+	}
+	body, _ := json.Marshal(request)
+
+	composeQuery := urlpkg.Values{}
+	if acl := query.Get("predefinedAcl"); acl != "" {
+		composeQuery.Set("destinationPredefinedAcl", acl)
+	}
+
+	var composed gcsObject
+	err := g.doJSON(ctx, gcsRequest{
+		method: http.MethodPost,
+		path:   gcsObjectPath(bucket, name) + "/compose",
+		query:  composeQuery,
+		header: http.Header{"Content-Type": {"application/json"}},
+		body:   body,
+	}, &composed)
+	if err != nil {
+		return nil, err
+	}
+	return &composed, nil
+}
+
+// deleteParts deletes the temporary objects of a composite upload.
+func (g *GCS) deleteParts(ctx context.Context, to *url.URL, prefix string) {
+	// all generations are listed, so that the parts are deleted permanently
+	// in the buckets with object versioning.
+	src, err := url.New(fmt.Sprintf("gs://%v/%v*", to.Bucket, prefix), url.WithAllVersions(true))
+	if err != nil {
+		return
+	}
+
+	urlch := make(chan *url.URL)
+	go func() {
+		defer close(urlch)
+		for obj := range g.List(ctx, src, false) {
+			if obj.Err == nil {
+				urlch <- obj.URL
+			}
+		}
+	}()
+
+	for obj := range g.MultiDelete(ctx, urlch) {
+		if obj.Err != nil {
+			msg := log.DebugMessage{Err: fmt.Sprintf("unable to delete temporary object %v: %v", obj.URL, obj.Err)}
+			log.Debug(msg)
+		}
+	}
+}
+
+// gcsCRC32C returns the CRC32C checksum in the format of Google Cloud
+// Storage, which is the base64 encoding of the checksum in big-endian order.
+func gcsCRC32C(h hash.Hash32) string {
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], h.Sum32())
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n/2)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Presign generates a V4 signed URL, which grants read access to the object
+// until the expiration. It requires the key of a service account.
+func (g *GCS) Presign(_ context.Context, from *url.URL, expire time.Duration) (string, error) {
+	if g.creds.signer == nil {
+		return "", fmt.Errorf("presigned urls can only be generated with a google service account key")
+	}
+	if expire > gcsMaxPresignExpire {
+		return "", fmt.Errorf("presigned urls of google cloud storage expire in at most %v", gcsMaxPresignExpire)
+	}
+
+	var (
+		now      = time.Now().UTC()
+		datetime = now.Format("20060102T150405Z")
+		scope    = now.Format("20060102") + "/auto/storage/goog4_request"
+		host     = g.endpoint.Host
+	)
+
+	segments := strings.Split(from.Bucket+"/"+from.Path, "/")
+	for i, segment := range segments {
+		segments[i] = gcsEscape(segment)
+	}
+	path := g.endpoint.Path + "/" + strings.Join(segments, "/")
+
+	query := map[string]string{
+		"X-Goog-Algorithm":     "GOOG4-RSA-SHA256",
+		"X-Goog-Credential":    g.creds.signer.email + "/" + scope,
+		"X-Goog-Date":          datetime,
+		"X-Goog-Expires":       strconv.FormatInt(int64(expire.Seconds()), 10),
+		"X-Goog-SignedHeaders": "host",
+	}
+	if from.VersionID != "" {
+		query["generation"] = from.VersionID
+	}
+
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, gcsEscape(name)+"="+gcsEscape(query[name]))
+	}
+	canonicalQuery := strings.Join(pairs, "&")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		path,
+		canonicalQuery,
+		"host:" + host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		"GOOG4-RSA-SHA256",
+		datetime,
+		scope,
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+
+	signature, err := g.creds.signer.sign([]byte(stringToSign))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%v://%v%v?%v&X-Goog-Signature=%v",
+		g.endpoint.Scheme, host, path, canonicalQuery, hex.EncodeToString(signature)), nil
+}
+
+// gcsEscape escapes the string as in RFC 3986, which is required by the
+// canonical requests of the signed URLs.
+func gcsEscape(s string) string {
+	return strings.ReplaceAll(urlpkg.QueryEscape(s), "+", "%20")
+}
+
+type gcsBucketList struct {
+	Items []struct {
+		Name        string
+		TimeCreated string
+	}
+	NextPageToken string
+}
+
+// ListBuckets returns the buckets of the project which match with the given
+// prefix. The project is read from the credentials, or from
+// GOOGLE_CLOUD_PROJECT environment variable.
+func (g *GCS) ListBuckets(ctx context.Context, prefix string) ([]Bucket, error) {
+	if g.creds.projectID == "" {
+		return nil, fmt.Errorf("google cloud project is not set, set GOOGLE_CLOUD_PROJECT environment variable")
+	}
+
+	query := urlpkg.Values{"project": {g.creds.projectID}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+
+	var buckets []Bucket
+	for {
+		var list gcsBucketList
+		err := g.doJSON(ctx, gcsRequest{method: http.MethodGet, path: "/storage/v1/b", query: query}, &list)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, b := range list.Items {
+			created, _ := time.Parse(time.RFC3339Nano, b.TimeCreated)
+			buckets = append(buckets, Bucket{
+				CreationDate: created.UTC(),
+				Name:         b.Name,
+				Scheme:       "gs",
+			})
+		}
+
+		if list.NextPageToken == "" {
+			return buckets, nil
+		}
+		query.Set("pageToken", list.NextPageToken)
+	}
+}
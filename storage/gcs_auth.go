@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	urlpkg "net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gcsScope           = "https://www.googleapis.com/auth/devstorage.full_control"
+	gcsDefaultTokenURI = "https://oauth2.googleapis.com/token"
+	gcsMetadataHost    = "metadata.google.internal"
+)
+
+// gcsTokenSource returns the OAuth2 access tokens of the requests.
+type gcsTokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// gcsCredentials are the credentials of Google Cloud Storage.
+type gcsCredentials struct {
+	tokenSource gcsTokenSource
+	projectID   string
+
+	// signer signs the presigned URLs, it is only available for the service
+	// account keys.
+	signer *gcsSigner
+}
+
+// gcsCredentialsFile is the JSON file of the application default
+// credentials, either a service account key or the credentials of an
+// authorized user.
+type gcsCredentialsFile struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+
+	QuotaProjectID string `json:"quota_project_id"`
+}
+
+// loadGCSCredentials finds the credentials in the same order as the Google
+// Cloud client libraries: an access token given with GOOGLE_OAUTH_ACCESS_TOKEN,
+// the file given with GOOGLE_APPLICATION_CREDENTIALS, the file created by
+// "gcloud auth application-default login" and the metadata server of the
+// Google Cloud environments.
+func loadGCSCredentials(client *http.Client, getenv func(string) string) (*gcsCredentials, error) {
+	projectID := getenv("GOOGLE_CLOUD_PROJECT")
+	if projectID == "" {
+		projectID = getenv("CLOUDSDK_CORE_PROJECT")
+	}
+
+	if token := getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return &gcsCredentials{
+			tokenSource: gcsStaticToken(token),
+			projectID:   projectID,
+		}, nil
+	}
+
+	path := getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		path = gcloudCredentialsPath(getenv)
+		if _, err := os.Stat(path); err != nil {
+			return &gcsCredentials{
+				tokenSource: &gcsCachedToken{fetch: gcsMetadataToken(client, getenv)},
+				projectID:   projectID,
+			}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read google credentials: %w", err)
+	}
+
+	creds, err := parseGCSCredentials(client, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid google credentials file %q: %w", path, err)
+	}
+	if projectID != "" {
+		creds.projectID = projectID
+	}
+	return creds, nil
+}
+
+func gcloudCredentialsPath(getenv func(string) string) string {
+	if dir := getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return filepath.Join(dir, "application_default_credentials.json")
+	}
+	if dir := getenv("APPDATA"); dir != "" {
+		return filepath.Join(dir, "gcloud", "application_default_credentials.json")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+func parseGCSCredentials(client *http.Client, data []byte) (*gcsCredentials, error) {
+	var file gcsCredentialsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	tokenURI := file.TokenURI
+	if tokenURI == "" {
+		tokenURI = gcsDefaultTokenURI
+	}
+
+	switch file.Type {
+	case "service_account":
+		signer, err := newGCSSigner(file.ClientEmail, file.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		return &gcsCredentials{
+			tokenSource: &gcsCachedToken{fetch: signer.tokenFetcher(client, tokenURI)},
+			projectID:   file.ProjectID,
+			signer:      signer,
+		}, nil
+	case "authorized_user":
+		form := urlpkg.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {file.ClientID},
+			"client_secret": {file.ClientSecret},
+			"refresh_token": {file.RefreshToken},
+		}
+		return &gcsCredentials{
+			tokenSource: &gcsCachedToken{fetch: func(ctx context.Context) (*gcsToken, error) {
+				return exchangeGCSToken(ctx, client, tokenURI, form)
+			}},
+			projectID: file.QuotaProjectID,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported credentials type %q", file.Type)
+	}
+}
+
+// gcsStaticToken is an access token given by the user.
+type gcsStaticToken string
+
+func (t gcsStaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+type gcsToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	expiry      time.Time
+}
+
+// gcsCachedToken caches the fetched token until it is about to expire.
+type gcsCachedToken struct {
+	fetch func(ctx context.Context) (*gcsToken, error)
+
+	mu    sync.Mutex
+	token *gcsToken
+}
+
+func (c *gcsCachedToken) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != nil && time.Now().Before(c.token.expiry) {
+		return c.token.AccessToken, nil
+	}
+
+	token, err := c.fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("unable to get google access token: %w", err)
+	}
+
+	// tokens are refreshed a minute before they expire.
+	token.expiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	c.token = token
+	return token.AccessToken, nil
+}
+
+func exchangeGCSToken(ctx context.Context, client *http.Client, tokenURI string, form urlpkg.Values) (*gcsToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doGCSTokenRequest(client, req)
+}
+
+func doGCSTokenRequest(client *http.Client, req *http.Request) (*gcsToken, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v: %v", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var token gcsToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// gcsMetadataToken fetches the tokens of the service account attached to the
+// Google Cloud environment from the metadata server.
+func gcsMetadataToken(client *http.Client, getenv func(string) string) func(ctx context.Context) (*gcsToken, error) {
+	host := getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = gcsMetadataHost
+	}
+
+	return func(ctx context.Context) (*gcsToken, error) {
+		endpoint := fmt.Sprintf("http://%v/computeMetadata/v1/instance/service-accounts/default/token", host)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+
+		token, err := doGCSTokenRequest(client, req)
+		if err != nil {
+			return nil, fmt.Errorf("no google credentials are found, set GOOGLE_APPLICATION_CREDENTIALS environment variable: %w", err)
+		}
+		return token, nil
+	}
+}
+
+// gcsSigner signs the tokens and the URLs with the private key of a service
+// account.
+type gcsSigner struct {
+	email string
+	key   *rsa.PrivateKey
+}
+
+func newGCSSigner(email, privateKey string) (*gcsSigner, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return &gcsSigner{email: email, key: key}, nil
+}
+
+func (s *gcsSigner) sign(data []byte) ([]byte, error) {
+	sum := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+}
+
+// tokenFetcher returns a function which exchanges a signed JWT for an access
+// token.
+func (s *gcsSigner) tokenFetcher(client *http.Client, tokenURI string) func(ctx context.Context) (*gcsToken, error) {
+	return func(ctx context.Context) (*gcsToken, error) {
+		now := time.Now()
+
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+		claims, _ := json.Marshal(map[string]interface{}{
+			"iss":   s.email,
+			"scope": gcsScope,
+			"aud":   tokenURI,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		})
+
+		unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+		signature, err := s.sign([]byte(unsigned))
+		if err != nil {
+			return nil, err
+		}
+
+		return exchangeGCSToken(ctx, client, tokenURI, urlpkg.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+		})
+	}
+}
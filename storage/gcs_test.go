@@ -0,0 +1,933 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	urlpkg "net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestGCSImplementsCapabilities(t *testing.T) {
+	var i interface{} = new(GCS)
+	if _, ok := i.(Storage); !ok {
+		t.Errorf("expected %t to implement Storage interface", i)
+	}
+	if _, ok := i.(Getter); !ok {
+		t.Errorf("expected %t to implement Getter interface", i)
+	}
+	if _, ok := i.(Putter); !ok {
+		t.Errorf("expected %t to implement Putter interface", i)
+	}
+	if _, ok := i.(Reader); !ok {
+		t.Errorf("expected %t to implement Reader interface", i)
+	}
+	if _, ok := i.(MetadataReader); !ok {
+		t.Errorf("expected %t to implement MetadataReader interface", i)
+	}
+	if _, ok := i.(Presigner); !ok {
+		t.Errorf("expected %t to implement Presigner interface", i)
+	}
+	if _, ok := i.(BucketLister); !ok {
+		t.Errorf("expected %t to implement BucketLister interface", i)
+	}
+}
+
+// fakeGCS is an in-process fake of the JSON API of Google Cloud Storage.
+// Object versioning is enabled on all of its buckets.
+type fakeGCS struct {
+	t *testing.T
+
+	mu         sync.Mutex
+	buckets    map[string][]*fakeGCSObject
+	generation int64
+	pageSize   int
+	failures   int32
+	requests   []string
+}
+
+type fakeGCSObject struct {
+	resource gcsObject
+	data     []byte
+	live     bool
+}
+
+func newFakeGCS(t *testing.T, buckets ...string) (*fakeGCS, *GCS) {
+	t.Helper()
+
+	fake := &fakeGCS{
+		t:          t,
+		buckets:    map[string][]*fakeGCSObject{},
+		generation: 1000,
+		pageSize:   1000,
+	}
+	for _, b := range buckets {
+		fake.buckets[b] = nil
+	}
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	env := map[string]string{
+		"STORAGE_EMULATOR_HOST": strings.TrimPrefix(server.URL, "http://"),
+		"GOOGLE_CLOUD_PROJECT":  "project",
+	}
+
+	client, err := newGCSClient(Options{MaxRetries: 2}, func(k string) string { return env[k] })
+	assert.NilError(t, err)
+	return fake, client
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.AddInt32(&f.failures, -1) >= 0 {
+		f.writeError(w, http.StatusServiceUnavailable, "backendError")
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+
+	if r.URL.Path == "/batch/storage/v1" {
+		f.batch(w, r, body)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.serve(w, r, body)
+}
+
+func (f *fakeGCS) serve(w http.ResponseWriter, r *http.Request, body []byte) {
+	var segments []string
+	for _, segment := range strings.Split(r.URL.EscapedPath(), "/")[1:] {
+		unescaped, _ := urlpkg.PathUnescape(segment)
+		segments = append(segments, unescaped)
+	}
+
+	query := r.URL.Query()
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/upload/storage/v1/b/"):
+		f.requests = append(f.requests, "upload")
+		f.upload(w, r, segments[4], body)
+	case len(segments) == 3:
+		f.listBuckets(w, query)
+	case len(segments) == 5:
+		f.list(w, segments[3], query)
+	case len(segments) == 6 && r.Method == http.MethodDelete:
+		f.requests = append(f.requests, "delete")
+		f.delete(w, segments[3], segments[5], query.Get("generation"))
+	case len(segments) == 6:
+		f.get(w, r, segments[3], segments[5], query)
+	case len(segments) == 7 && segments[6] == "compose":
+		f.requests = append(f.requests, "compose")
+		f.compose(w, segments[3], segments[5], body)
+	case len(segments) == 11 && segments[6] == "rewriteTo":
+		f.requests = append(f.requests, "rewrite")
+		f.rewrite(w, segments[3], segments[5], segments[8], segments[10], query, body)
+	default:
+		f.writeError(w, http.StatusBadRequest, "invalid")
+	}
+}
+
+func (f *fakeGCS) writeError(w http.ResponseWriter, status int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error":{"code":%d,"message":"%v","errors":[{"reason":"%v"}]}}`, status, http.StatusText(status), reason)
+}
+
+func (f *fakeGCS) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// find returns the live object, or the given generation of the object.
+func (f *fakeGCS) find(bucket, name, generation string) *fakeGCSObject {
+	for _, obj := range f.buckets[bucket] {
+		if obj.resource.Name != name {
+			continue
+		}
+		if generation == "" && obj.live || generation != "" && obj.resource.Generation == generation {
+			return obj
+		}
+	}
+	return nil
+}
+
+func (f *fakeGCS) store(bucket string, resource gcsObject, data []byte, composite bool) *fakeGCSObject {
+	if old := f.find(bucket, resource.Name, ""); old != nil {
+		old.live = false
+	}
+
+	f.generation++
+	checksum := crc32.New(gcsCRC32CTable)
+	checksum.Write(data)
+
+	resource.Bucket = bucket
+	resource.Generation = strconv.FormatInt(f.generation, 10)
+	resource.Size = strconv.Itoa(len(data))
+	resource.Updated = time.Now().UTC().Format(time.RFC3339Nano)
+	resource.Crc32c = gcsCRC32C(checksum)
+	resource.Md5Hash = ""
+	if !composite {
+		sum := md5.Sum(data)
+		resource.Md5Hash = base64.StdEncoding.EncodeToString(sum[:])
+	}
+	if resource.StorageClass == "" {
+		resource.StorageClass = "STANDARD"
+	}
+
+	obj := &fakeGCSObject{resource: resource, data: data, live: true}
+	f.buckets[bucket] = append(f.buckets[bucket], obj)
+	return obj
+}
+
+func (f *fakeGCS) upload(w http.ResponseWriter, r *http.Request, bucket string, body []byte) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	assert.NilError(f.t, err)
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	part, err := reader.NextPart()
+	assert.NilError(f.t, err)
+
+	var resource gcsObject
+	assert.NilError(f.t, json.NewDecoder(part).Decode(&resource))
+
+	part, err = reader.NextPart()
+	assert.NilError(f.t, err)
+	data, _ := io.ReadAll(part)
+
+	obj := f.store(bucket, resource, data, false)
+	if obj.resource.Md5Hash != resource.Md5Hash || obj.resource.Crc32c != resource.Crc32c {
+		f.t.Errorf("checksums of %v do not match", resource.Name)
+	}
+	f.writeJSON(w, obj.resource)
+}
+
+func (f *fakeGCS) get(w http.ResponseWriter, r *http.Request, bucket, name string, query urlpkg.Values) {
+	obj := f.find(bucket, name, query.Get("generation"))
+	if obj == nil {
+		f.writeError(w, http.StatusNotFound, "notFound")
+		return
+	}
+
+	if query.Get("alt") != "media" {
+		f.writeJSON(w, obj.resource)
+		return
+	}
+
+	if generation := query.Get("ifGenerationMatch"); generation != "" && generation != obj.resource.Generation {
+		f.writeError(w, http.StatusPreconditionFailed, "conditionNotMet")
+		return
+	}
+
+	data := obj.data
+	if rng := r.Header.Get("Range"); rng != "" {
+		var start, end int
+		fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+		data = data[start : end+1]
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	w.Write(data)
+}
+
+func (f *fakeGCS) delete(w http.ResponseWriter, bucket, name, generation string) {
+	obj := f.find(bucket, name, generation)
+	if obj == nil {
+		f.writeError(w, http.StatusNotFound, "notFound")
+		return
+	}
+
+	if generation == "" {
+		obj.live = false
+	} else {
+		objects := f.buckets[bucket]
+		for i := range objects {
+			if objects[i] == obj {
+				f.buckets[bucket] = append(objects[:i], objects[i+1:]...)
+				break
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeGCS) list(w http.ResponseWriter, bucket string, query urlpkg.Values) {
+	objects, ok := f.buckets[bucket]
+	if !ok {
+		f.writeError(w, http.StatusNotFound, "notFound")
+		return
+	}
+
+	var (
+		prefix    = query.Get("prefix")
+		delimiter = query.Get("delimiter")
+		versions  = query.Get("versions") == "true"
+		token     = query.Get("pageToken")
+	)
+
+	// entries are the names of the objects and the prefixes in order.
+	var entries []interface{}
+	seen := map[string]bool{}
+	for _, obj := range objects {
+		name := obj.resource.Name
+		if !strings.HasPrefix(name, prefix) || !obj.live && !versions {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(name[len(prefix):], delimiter); i >= 0 {
+				p := name[:len(prefix)+i+1]
+				if !seen[p] {
+					seen[p] = true
+					entries = append(entries, p)
+				}
+				continue
+			}
+		}
+		entries = append(entries, obj)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return fakeGCSEntryKey(entries[i]) < fakeGCSEntryKey(entries[j])
+	})
+
+	// the page token is the key of the last entry of the previous page, so
+	// that the pages are consistent while the objects are deleted.
+	start := sort.Search(len(entries), func(i int) bool {
+		return token == "" || fakeGCSEntryKey(entries[i]) > token
+	})
+
+	list := gcsObjectList{}
+	end := start + f.pageSize
+	if end < len(entries) {
+		list.NextPageToken = fakeGCSEntryKey(entries[end-1])
+	} else {
+		end = len(entries)
+	}
+	for _, entry := range entries[start:end] {
+		switch entry := entry.(type) {
+		case string:
+			list.Prefixes = append(list.Prefixes, entry)
+		case *fakeGCSObject:
+			list.Items = append(list.Items, entry.resource)
+		}
+	}
+	f.writeJSON(w, list)
+}
+
+func fakeGCSEntryKey(entry interface{}) string {
+	if obj, ok := entry.(*fakeGCSObject); ok {
+		return obj.resource.Name + "\x00" + obj.resource.Generation
+	}
+	return entry.(string)
+}
+
+func (f *fakeGCS) compose(w http.ResponseWriter, bucket, name string, body []byte) {
+	var request struct {
+		SourceObjects []struct{ Name string }
+		Destination   gcsObject
+	}
+	assert.NilError(f.t, json.Unmarshal(body, &request))
+
+	if len(request.SourceObjects) > gcsMaxComposeSources {
+		f.writeError(w, http.StatusBadRequest, "tooManyComponents")
+		return
+	}
+
+	var data []byte
+	for _, source := range request.SourceObjects {
+		obj := f.find(bucket, source.Name, "")
+		if obj == nil {
+			f.writeError(w, http.StatusNotFound, "notFound")
+			return
+		}
+		data = append(data, obj.data...)
+	}
+
+	request.Destination.Name = name
+	f.writeJSON(w, f.store(bucket, request.Destination, data, true).resource)
+}
+
+func (f *fakeGCS) rewrite(w http.ResponseWriter, srcBucket, srcName, dstBucket, dstName string, query urlpkg.Values, body []byte) {
+	src := f.find(srcBucket, srcName, query.Get("sourceGeneration"))
+	if src == nil {
+		f.writeError(w, http.StatusNotFound, "notFound")
+		return
+	}
+
+	// the objects are rewritten in two requests.
+	if query.Get("rewriteToken") == "" {
+		f.writeJSON(w, map[string]interface{}{"done": false, "rewriteToken": "token"})
+		return
+	}
+
+	resource := src.resource
+	if len(body) > 0 {
+		resource = gcsObject{}
+		assert.NilError(f.t, json.Unmarshal(body, &resource))
+	}
+	resource.Name = dstName
+
+	f.store(dstBucket, resource, src.data, false)
+	f.writeJSON(w, map[string]interface{}{"done": true})
+}
+
+func (f *fakeGCS) listBuckets(w http.ResponseWriter, query urlpkg.Values) {
+	assert.Equal(f.t, query.Get("project"), "project")
+
+	var names []string
+	for name := range f.buckets {
+		if strings.HasPrefix(name, query.Get("prefix")) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	list := map[string]interface{}{}
+	var items []map[string]string
+	for _, name := range names {
+		items = append(items, map[string]string{"name": name, "timeCreated": "2023-01-02T15:04:05.000Z"})
+	}
+	list["items"] = items
+	f.writeJSON(w, list)
+}
+
+// batch serves the requests in the batch one by one, and writes their
+// responses in a multipart response.
+func (f *fakeGCS) batch(w http.ResponseWriter, r *http.Request, body []byte) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	assert.NilError(f.t, err)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, "batch")
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		assert.NilError(f.t, err)
+
+		req, err := http.ReadRequest(bufio.NewReader(part))
+		assert.NilError(f.t, err)
+
+		rec := httptest.NewRecorder()
+		// the batch requests are not recorded.
+		requests := f.requests
+		f.serve(rec, req, nil)
+		f.requests = requests
+
+		contentID := strings.Trim(part.Header.Get("Content-Id"), "<>")
+		respPart, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"application/http"},
+			"Content-Id":   {"<response-" + contentID + ">"},
+		})
+		rec.Result().Write(respPart)
+	}
+	mw.Close()
+
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	w.Write(buf.Bytes())
+}
+
+func TestGCSPutAndGet(t *testing.T) {
+	t.Parallel()
+	log.Init("error", false)
+
+	testcases := []struct {
+		name      string
+		size      int
+		partSize  int64
+		composite bool
+		requests  []string
+	}{
+		{name: "single request", size: 1000, partSize: 1024, requests: []string{"upload"}},
+		{name: "empty", size: 0, partSize: 1024, requests: []string{"upload"}},
+		{name: "composite", size: 5000, partSize: 1024, composite: true, requests: []string{"batch", "compose", "upload", "upload", "upload", "upload", "upload"}},
+		{
+			name:      "composite of composites",
+			size:      4000,
+			partSize:  100,
+			composite: true,
+			requests: append(
+				[]string{"batch", "compose", "compose", "compose"},
+				strings.Split(strings.Repeat("upload ", 40), " ")[:40]...,
+			),
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake, client := newFakeGCS(t, "bucket")
+			ctx := context.Background()
+
+			data := make([]byte, tc.size)
+			rand.Read(data)
+
+			u := mustNewURL(t, "gs://bucket/dir/file.bin")
+			metadata := Metadata{
+				ContentType:  "application/x-test",
+				CacheControl: "no-cache",
+				StorageClass: "NEARLINE",
+				UserDefined:  map[string]string{"key": "value"},
+			}
+			err := client.Put(ctx, bytes.NewReader(data), u, metadata, 3, tc.partSize)
+			assert.NilError(t, err)
+
+			sort.Strings(fake.requests)
+			assert.DeepEqual(t, fake.requests, tc.requests)
+
+			// temporary objects of the composite uploads are deleted.
+			assert.Equal(t, len(fake.buckets["bucket"]), 1)
+
+			checksum := crc32.New(gcsCRC32CTable)
+			checksum.Write(data)
+
+			obj, got, err := client.HeadObject(ctx, u)
+			assert.NilError(t, err)
+			assert.Equal(t, obj.Size, int64(tc.size))
+			assert.Equal(t, obj.StorageClass, StorageClass("NEARLINE"))
+			assert.Equal(t, obj.CRC32C, gcsCRC32C(checksum))
+			assert.Equal(t, got.ContentType, "application/x-test")
+			assert.Equal(t, got.CacheControl, "no-cache")
+			assert.DeepEqual(t, got.UserDefined, map[string]string{"key": "value"})
+
+			if tc.composite {
+				assert.Equal(t, obj.Etag, "")
+			} else {
+				sum := md5.Sum(data)
+				assert.Equal(t, obj.Etag, hex.EncodeToString(sum[:]))
+			}
+
+			buf := aws.NewWriteAtBuffer(nil)
+			n, err := client.Get(ctx, u, buf, 3, 700)
+			assert.NilError(t, err)
+			assert.Equal(t, n, int64(tc.size))
+			assert.Assert(t, bytes.Equal(buf.Bytes(), data))
+		})
+	}
+}
+
+func TestGCSPutTooManyParts(t *testing.T) {
+	t.Parallel()
+	log.Init("error", false)
+
+	fake, client := newFakeGCS(t, "bucket")
+
+	data := make([]byte, gcsMaxComponents+1)
+	err := client.Put(context.Background(), bytes.NewReader(data), mustNewURL(t, "gs://bucket/file"), Metadata{}, 4, 1)
+	assert.Error(t, err, "object is too large to upload with 1 byte parts, increase the part size")
+
+	// uploaded parts are deleted.
+	assert.Equal(t, len(fake.buckets["bucket"]), 0)
+}
+
+func TestGCSGetModifiedObject(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeGCS(t, "bucket")
+	ctx := context.Background()
+
+	u := mustNewURL(t, "gs://bucket/file")
+	assert.NilError(t, client.Put(ctx, strings.NewReader("content"), u, Metadata{}, 1, 1024))
+
+	// the object is overwritten after its metadata is retrieved.
+	resource, err := client.stat(ctx, u)
+	assert.NilError(t, err)
+	fake.mu.Lock()
+	fake.store("bucket", gcsObject{Name: "file"}, []byte("modified"), false)
+	fake.mu.Unlock()
+
+	err = client.getRange(ctx, u, aws.NewWriteAtBuffer(nil), resource.Generation, 0, 7)
+	assert.Equal(t, err, ErrObjectModified)
+}
+
+func TestGCSList(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeGCS(t, "bucket")
+	fake.pageSize = 2
+	ctx := context.Background()
+
+	for _, key := range []string{"a.txt", "b.csv", "dir/c.txt", "dir/sub/d.txt", "e.txt"} {
+		err := client.Put(ctx, strings.NewReader(key), mustNewURL(t, "gs://bucket/"+key), Metadata{}, 1, 1024)
+		assert.NilError(t, err)
+	}
+
+	testcases := []struct {
+		src      string
+		expected []string
+	}{
+		{src: "gs://bucket/", expected: []string{"dir/", "a.txt", "b.csv", "e.txt"}},
+		{src: "gs://bucket/*", expected: []string{"a.txt", "b.csv", "dir/c.txt", "dir/sub/d.txt", "e.txt"}},
+		{src: "gs://bucket/*.txt", expected: []string{"a.txt", "dir/c.txt", "dir/sub/d.txt", "e.txt"}},
+		{src: "gs://bucket/dir/", expected: []string{"dir/sub/", "dir/c.txt"}},
+	}
+
+	for _, tc := range testcases {
+		var keys []string
+		for obj := range client.List(ctx, mustNewURL(t, tc.src), false) {
+			assert.NilError(t, obj.Err, tc.src)
+			keys = append(keys, obj.URL.Path)
+		}
+		sort.SliceStable(keys, func(i, j int) bool {
+			return strings.HasSuffix(keys[i], "/") && !strings.HasSuffix(keys[j], "/")
+		})
+		assert.DeepEqual(t, keys, tc.expected)
+	}
+
+	for obj := range client.List(ctx, mustNewURL(t, "gs://bucket/missing/"), false) {
+		assert.Equal(t, obj.Err, ErrNoObjectFound)
+	}
+
+	for obj := range client.List(ctx, mustNewURL(t, "gs://missing/"), false) {
+		assert.ErrorContains(t, obj.Err, "notFound")
+	}
+}
+
+func TestGCSVersions(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeGCS(t, "bucket")
+	ctx := context.Background()
+
+	u := mustNewURL(t, "gs://bucket/file.txt")
+	assert.NilError(t, client.Put(ctx, strings.NewReader("first"), u, Metadata{}, 1, 1024))
+	assert.NilError(t, client.Put(ctx, strings.NewReader("second"), u, Metadata{}, 1, 1024))
+
+	var versions []string
+	for obj := range client.List(ctx, mustNewURL(t, "gs://bucket/file.txt", url.WithAllVersions(true)), false) {
+		assert.NilError(t, obj.Err)
+		versions = append(versions, obj.URL.VersionID)
+	}
+	assert.DeepEqual(t, versions, []string{"1001", "1002"})
+
+	for obj := range client.List(ctx, mustNewURL(t, "gs://bucket/file.txt", url.WithVersion("1001")), false) {
+		assert.NilError(t, obj.Err)
+		assert.Equal(t, obj.URL.VersionID, "1001")
+		assert.Equal(t, obj.Size, int64(len("first")))
+	}
+
+	first := mustNewURL(t, "gs://bucket/file.txt", url.WithVersion("1001"))
+	reader, err := client.Read(ctx, first)
+	assert.NilError(t, err)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, string(data), "first")
+
+	// deleting the live object keeps its noncurrent versions.
+	assert.NilError(t, client.Delete(ctx, u))
+	_, err = client.Stat(ctx, u)
+	assert.Error(t, err, "given object gs://bucket/file.txt not found")
+
+	obj, err := client.Stat(ctx, first)
+	assert.NilError(t, err)
+	assert.Equal(t, obj.Size, int64(len("first")))
+
+	assert.NilError(t, client.Delete(ctx, first))
+	_, err = client.Stat(ctx, first)
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestGCSCopyAndDelete(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeGCS(t, "bucket", "other")
+	ctx := context.Background()
+
+	src := mustNewURL(t, "gs://bucket/src.txt")
+	metadata := Metadata{ContentType: "text/plain", UserDefined: map[string]string{"key": "value"}}
+	assert.NilError(t, client.Put(ctx, strings.NewReader("content"), src, metadata, 1, 1024))
+
+	// metadata of the source is kept.
+	dst := mustNewURL(t, "gs://other/dst.txt")
+	assert.NilError(t, client.Copy(ctx, src, dst, Metadata{}))
+
+	_, got, err := client.HeadObject(ctx, dst)
+	assert.NilError(t, err)
+	assert.Equal(t, got.ContentType, "text/plain")
+	assert.DeepEqual(t, got.UserDefined, map[string]string{"key": "value"})
+
+	// metadata of the source is kept along with the given storage class.
+	archived := mustNewURL(t, "gs://other/archived.txt")
+	assert.NilError(t, client.Copy(ctx, src, archived, Metadata{StorageClass: "ARCHIVE"}))
+
+	_, got, err = client.HeadObject(ctx, archived)
+	assert.NilError(t, err)
+	assert.Equal(t, got.StorageClass, "ARCHIVE")
+	assert.Equal(t, got.ContentType, "text/plain")
+	assert.DeepEqual(t, got.UserDefined, map[string]string{"key": "value"})
+
+	// metadata is replaced.
+	replaced := mustNewURL(t, "gs://other/replaced.txt")
+	err = client.Copy(ctx, src, replaced, Metadata{
+		Directive:   "REPLACE",
+		ContentType: "application/json",
+		UserDefined: map[string]string{"other": "value"},
+	})
+	assert.NilError(t, err)
+
+	_, got, err = client.HeadObject(ctx, replaced)
+	assert.NilError(t, err)
+	assert.Equal(t, got.ContentType, "application/json")
+	assert.DeepEqual(t, got.UserDefined, map[string]string{"other": "value"})
+
+	err = client.Copy(ctx, mustNewURL(t, "gs://bucket/missing"), dst, Metadata{})
+	assert.Error(t, err, "given object gs://bucket/missing not found")
+
+	err = client.Copy(ctx, src, dst, Metadata{ACL: "unknown"})
+	assert.Error(t, err, `acl "unknown" is not supported by google cloud storage`)
+
+	fake.requests = nil
+
+	urlch := make(chan *url.URL, 4)
+	urlch <- src
+	urlch <- dst
+	urlch <- archived
+	urlch <- mustNewURL(t, "gs://other/missing")
+	close(urlch)
+
+	for obj := range client.MultiDelete(ctx, urlch) {
+		assert.NilError(t, obj.Err)
+	}
+	assert.DeepEqual(t, fake.requests, []string{"batch"})
+
+	_, err = client.Stat(ctx, src)
+	assert.Error(t, err, "given object gs://bucket/src.txt not found")
+
+	_, err = client.Read(ctx, dst)
+	assert.Error(t, err, "given object gs://other/dst.txt not found")
+
+	_, err = client.Stat(ctx, replaced)
+	assert.NilError(t, err)
+}
+
+func TestGCSRetry(t *testing.T) {
+	log.Init("error", false)
+
+	fake, client := newFakeGCS(t, "bucket")
+	ctx := context.Background()
+
+	u := mustNewURL(t, "gs://bucket/file")
+
+	fake.failures = 2
+	assert.NilError(t, client.Put(ctx, strings.NewReader("content"), u, Metadata{}, 1, 1024))
+
+	fake.failures = 3
+	_, err := client.Stat(ctx, u)
+	assert.Error(t, err, "backendError: Service Unavailable status code: 503")
+}
+
+func TestGCSListBuckets(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeGCS(t, "logs", "logs-archive", "data")
+
+	buckets, err := client.ListBuckets(context.Background(), "logs")
+	assert.NilError(t, err)
+	assert.Equal(t, len(buckets), 2)
+	assert.Equal(t, buckets[0].Name, "logs")
+	assert.Equal(t, buckets[1].Name, "logs-archive")
+	assert.Equal(t, buckets[0].String(), "2023/01/02 15:04:05  gs://logs")
+
+	client.creds.projectID = ""
+	_, err = client.ListBuckets(context.Background(), "")
+	assert.Error(t, err, "google cloud project is not set, set GOOGLE_CLOUD_PROJECT environment variable")
+}
+
+func newTestServiceAccount(t *testing.T, tokenURI string) ([]byte, *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.New(rand.NewSource(1)), 2048)
+	assert.NilError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NilError(t, err)
+
+	data, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "project",
+		"client_email": "s5cmd@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURI,
+	})
+	assert.NilError(t, err)
+	return data, key
+}
+
+func TestGCSPresign(t *testing.T) {
+	t.Parallel()
+
+	_, client := newFakeGCS(t, "bucket")
+
+	data, key := newTestServiceAccount(t, "")
+	creds, err := parseGCSCredentials(http.DefaultClient, data)
+	assert.NilError(t, err)
+	client.creds = creds
+
+	presigned, err := client.Presign(context.Background(), mustNewURL(t, "gs://bucket/dir/file name.txt"), time.Hour)
+	assert.NilError(t, err)
+
+	u, err := urlpkg.Parse(presigned)
+	assert.NilError(t, err)
+	assert.Equal(t, u.EscapedPath(), "/bucket/dir/file%20name.txt")
+
+	query := u.Query()
+	assert.Equal(t, query.Get("X-Goog-Algorithm"), "GOOG4-RSA-SHA256")
+	assert.Equal(t, query.Get("X-Goog-Expires"), "3600")
+	assert.Equal(t, query.Get("X-Goog-SignedHeaders"), "host")
+	assert.Assert(t, strings.HasPrefix(query.Get("X-Goog-Credential"), "s5cmd@project.iam.gserviceaccount.com/"))
+
+	// the signature is verified with the canonical request built from the
+	// presigned url.
+	canonicalQuery, _, _ := strings.Cut(u.RawQuery, "&X-Goog-Signature=")
+	canonicalRequest := strings.Join([]string{
+		"GET", u.EscapedPath(), canonicalQuery, "host:" + u.Host + "\n", "host", "UNSIGNED-PAYLOAD",
+	}, "\n")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+
+	_, scope, _ := strings.Cut(query.Get("X-Goog-Credential"), "/")
+	stringToSign := strings.Join([]string{
+		"GOOG4-RSA-SHA256", query.Get("X-Goog-Date"), scope, hex.EncodeToString(hashed[:]),
+	}, "\n")
+	digest := sha256.Sum256([]byte(stringToSign))
+
+	signature, err := hex.DecodeString(query.Get("X-Goog-Signature"))
+	assert.NilError(t, err)
+	assert.NilError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+	_, err = client.Presign(context.Background(), mustNewURL(t, "gs://bucket/file"), 8*24*time.Hour)
+	assert.ErrorContains(t, err, "expire in at most")
+}
+
+func TestLoadGCSCredentials(t *testing.T) {
+	t.Parallel()
+
+	var (
+		key      *rsa.PrivateKey
+		exchange int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&exchange, 1)
+		r.ParseForm()
+
+		switch r.Form.Get("grant_type") {
+		case "urn:ietf:params:oauth:grant-type:jwt-bearer":
+			parts := strings.Split(r.Form.Get("assertion"), ".")
+			assert.Equal(t, len(parts), 3)
+
+			signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"service-account-token","expires_in":3600}`)
+		case "refresh_token":
+			assert.Equal(t, r.Form.Get("refresh_token"), "refresh")
+			fmt.Fprint(w, `{"access_token":"user-token","expires_in":3600}`)
+		default:
+			http.Error(w, "unexpected grant type", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+
+	serviceAccount, privateKey := newTestServiceAccount(t, server.URL)
+	key = privateKey
+	serviceAccountPath := filepath.Join(dir, "service-account.json")
+	assert.NilError(t, os.WriteFile(serviceAccountPath, serviceAccount, 0600))
+
+	authorizedUser := fmt.Sprintf(
+		`{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"refresh","token_uri":%q}`,
+		server.URL,
+	)
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "application_default_credentials.json"), []byte(authorizedUser), 0600))
+
+	testcases := []struct {
+		name    string
+		env     map[string]string
+		token   string
+		project string
+	}{
+		{
+			name:  "access token",
+			env:   map[string]string{"GOOGLE_OAUTH_ACCESS_TOKEN": "static-token", "GOOGLE_CLOUD_PROJECT": "project"},
+			token: "static-token", project: "project",
+		},
+		{
+			name:  "service account",
+			env:   map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": serviceAccountPath},
+			token: "service-account-token", project: "project",
+		},
+		{
+			name:  "gcloud credentials",
+			env:   map[string]string{"CLOUDSDK_CONFIG": dir, "CLOUDSDK_CORE_PROJECT": "other"},
+			token: "user-token", project: "other",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			creds, err := loadGCSCredentials(http.DefaultClient, func(k string) string { return tc.env[k] })
+			assert.NilError(t, err)
+			assert.Equal(t, creds.projectID, tc.project)
+
+			before := atomic.LoadInt32(&exchange)
+			for i := 0; i < 2; i++ {
+				token, err := creds.tokenSource.Token(context.Background())
+				assert.NilError(t, err)
+				assert.Equal(t, token, tc.token)
+			}
+
+			// tokens are cached until they expire.
+			assert.Assert(t, atomic.LoadInt32(&exchange)-before <= 1)
+		})
+	}
+
+	_, err := loadGCSCredentials(http.DefaultClient, func(k string) string {
+		return map[string]string{"GOOGLE_APPLICATION_CREDENTIALS": filepath.Join(dir, "missing.json")}[k]
+	})
+	assert.ErrorContains(t, err, "unable to read google credentials")
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func init() {
+	for _, scheme := range []string{"http", "https"} {
+		Register(scheme, url.SchemeOptions{HostAuthority: true, NoWildcard: true}, func(_ context.Context, _ *url.URL, opts Options) (Storage, error) {
+			return NewHTTPClient(opts), nil
+		})
+	}
+}
+
+// HTTP is the read-only Storage implementation of the objects served over
+// HTTP(S). Objects can be downloaded but they can not be listed, modified or
+// deleted.
+type HTTP struct {
+	client *http.Client
+	dryRun bool
+}
+
+// NewHTTPClient returns a client of the objects served over HTTP(S).
+func NewHTTPClient(opts Options) *HTTP {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// objects are downloaded as they are served, gzip encoded objects must
+	// not be decompressed on the fly.
+	transport.DisableCompression = true
+	if opts.NoVerifySSL {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &HTTP{
+		client: &http.Client{Transport: transport},
+		dryRun: opts.DryRun,
+	}
+}
+
+func (h *HTTP) do(ctx context.Context, method string, url *url.URL) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url.Absolute(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, &ErrGivenObjectNotFound{ObjectAbsPath: url.Absolute()}
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%v %v: %v", method, url, resp.Status)
+	}
+	return resp, nil
+}
+
+// Stat retrieves the properties of the object with a HEAD request.
+func (h *HTTP) Stat(ctx context.Context, url *url.URL) (*Object, error) {
+	resp, err := h.do(ctx, http.MethodHead, url)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	obj := &Object{
+		URL:  url,
+		Etag: strings.Trim(resp.Header.Get("ETag"), `"`),
+		Size: resp.ContentLength,
+	}
+	if mod, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.ModTime = &mod
+	}
+	return obj, nil
+}
+
+// List returns the object itself since objects can not be listed over HTTP.
+func (h *HTTP) List(ctx context.Context, src *url.URL, _ bool) <-chan *Object {
+	ch := make(chan *Object, 1)
+	defer close(ch)
+
+	if src.IsBucket() || src.IsPrefix() {
+		ch <- &Object{Err: NotSupported(src, "listing")}
+		return ch
+	}
+
+	obj, err := h.Stat(ctx, src)
+	if err != nil {
+		obj = &Object{Err: err}
+	}
+	ch <- obj
+	return ch
+}
+
+// Delete is not supported.
+func (h *HTTP) Delete(_ context.Context, url *url.URL) error {
+	return NotSupported(url, "delete")
+}
+
+// MultiDelete is not supported.
+func (h *HTTP) MultiDelete(ctx context.Context, urlch <-chan *url.URL) <-chan *Object {
+	resultch := make(chan *Object)
+
+	go func() {
+		defer close(resultch)
+
+		for url := range urlch {
+			sendObject(ctx, &Object{URL: url, Err: NotSupported(url, "delete")}, resultch)
+		}
+	}()
+
+	return resultch
+}
+
+// Copy is not supported.
+func (h *HTTP) Copy(_ context.Context, _, to *url.URL, _ Metadata) error {
+	return NotSupported(to, "copy")
+}
+
+// Read returns the body of the object.
+func (h *HTTP) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
+	resp, err := h.do(ctx, http.MethodGet, src)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Get downloads the object to the destination. The object is downloaded with
+// a single request regardless of the concurrency.
+func (h *HTTP) Get(ctx context.Context, from *url.URL, to io.WriterAt, _ int, _ int64) (int64, error) {
+	if h.dryRun {
+		return 0, nil
+	}
+
+	body, err := h.Read(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	return io.Copy(io.NewOffsetWriter(to, 0), body)
+}
+
+// HeadObject returns the object and the metadata in the response headers.
+func (h *HTTP) HeadObject(ctx context.Context, url *url.URL) (*Object, *Metadata, error) {
+	resp, err := h.do(ctx, http.MethodHead, url)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp.Body.Close()
+
+	obj := &Object{
+		URL:  url,
+		Etag: strings.Trim(resp.Header.Get("ETag"), `"`),
+		Size: resp.ContentLength,
+	}
+	if mod, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.ModTime = &mod
+	}
+
+	metadata := &Metadata{
+		ContentType:        resp.Header.Get("Content-Type"),
+		ContentEncoding:    resp.Header.Get("Content-Encoding"),
+		ContentDisposition: resp.Header.Get("Content-Disposition"),
+		CacheControl:       resp.Header.Get("Cache-Control"),
+		Expires:            resp.Header.Get("Expires"),
+	}
+	return obj, metadata, nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"gotest.tools/v3/assert"
+)
+
+func TestHTTPImplementsCapabilities(t *testing.T) {
+	var i interface{} = new(HTTP)
+	if _, ok := i.(Storage); !ok {
+		t.Errorf("expected %t to implement Storage interface", i)
+	}
+	if _, ok := i.(Getter); !ok {
+		t.Errorf("expected %t to implement Getter interface", i)
+	}
+	if _, ok := i.(Putter); ok {
+		t.Errorf("expected %t not to implement Putter interface", i)
+	}
+}
+
+func TestHTTPStatAndGet(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dir/file.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Last-Modified", modTime.Format(http.TimeFormat))
+		fmt.Fprint(w, "content")
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	base := server.URL
+
+	src := mustNewURL(t, base+"/dir/file.txt")
+	assert.Equal(t, src.Absolute(), base+"/dir/file.txt")
+
+	client, err := NewClient(ctx, src, Options{})
+	assert.NilError(t, err)
+	h := client.(*HTTP)
+
+	obj, err := h.Stat(ctx, src)
+	assert.NilError(t, err)
+	assert.Equal(t, obj.Size, int64(len("content")))
+	assert.Equal(t, obj.Etag, "etag")
+	assert.Equal(t, *obj.ModTime, modTime)
+
+	_, metadata, err := h.HeadObject(ctx, src)
+	assert.NilError(t, err)
+	assert.Equal(t, metadata.ContentType, "text/plain")
+
+	buf := aws.NewWriteAtBuffer(nil)
+	n, err := h.Get(ctx, src, buf, 5, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, n, int64(len("content")))
+	assert.Equal(t, string(buf.Bytes()), "content")
+
+	reader, err := h.Read(ctx, src)
+	assert.NilError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NilError(t, err)
+	reader.Close()
+	assert.Equal(t, string(data), "content")
+
+	missing := mustNewURL(t, base+"/missing.txt")
+	_, err = h.Stat(ctx, missing)
+	assert.Error(t, err, fmt.Sprintf("given object %v/missing.txt not found", base))
+
+	// wildcards are part of the path.
+	glob := mustNewURL(t, base+"/dir/*.txt")
+	assert.Assert(t, !glob.IsWildcard())
+
+	err = h.Delete(ctx, src)
+	assert.Error(t, err, "delete is not supported by http:// storage")
+
+	for obj := range h.List(ctx, mustNewURL(t, base+"/dir/"), false) {
+		assert.Error(t, obj.Err, "listing is not supported by http:// storage")
+	}
+}
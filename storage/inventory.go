@@ -0,0 +1,489 @@
+package storage
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	urlpkg "net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/scritchley/orc"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// file formats of the S3 Inventory reports.
+const (
+	inventoryCSV     = "CSV"
+	inventoryORC     = "ORC"
+	inventoryParquet = "Parquet"
+)
+
+// names of the inventory fields, as they are named in the schema of the CSV
+// reports. The columns of the ORC and Parquet reports are named in snake
+// case.
+const (
+	inventoryKey            = "Key"
+	inventoryVersionID      = "VersionId"
+	inventoryIsLatest       = "IsLatest"
+	inventoryIsDeleteMarker = "IsDeleteMarker"
+	inventorySize           = "Size"
+	inventoryLastModified   = "LastModifiedDate"
+	inventoryETag           = "ETag"
+	inventoryStorageClass   = "StorageClass"
+)
+
+var inventoryColumns = map[string]string{
+	"key":                inventoryKey,
+	"version_id":         inventoryVersionID,
+	"is_latest":          inventoryIsLatest,
+	"is_delete_marker":   inventoryIsDeleteMarker,
+	"size":               inventorySize,
+	"last_modified_date": inventoryLastModified,
+	"e_tag":              inventoryETag,
+	"storage_class":      inventoryStorageClass,
+}
+
+// inventoryManifest is the manifest.json file of an S3 Inventory report.
+type inventoryManifest struct {
+	SourceBucket      string `json:"sourceBucket"`
+	DestinationBucket string `json:"destinationBucket"`
+	FileFormat        string `json:"fileFormat"`
+	FileSchema        string `json:"fileSchema"`
+	Files             []struct {
+		Key string `json:"key"`
+	} `json:"files"`
+}
+
+// inventoryRecord is an object in the files of an inventory report.
+type inventoryRecord struct {
+	key            string
+	versionID      string
+	isLatest       bool
+	isDeleteMarker bool
+	size           int64
+	modTime        time.Time
+	etag           string
+	storageClass   string
+}
+
+// set sets the field of the record from the value of the named inventory
+// field.
+func (r *inventoryRecord) set(field string, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		value = string(v)
+	}
+
+	var ok bool
+	switch field {
+	case inventoryKey:
+		r.key, ok = value.(string)
+	case inventoryVersionID:
+		r.versionID, ok = value.(string)
+	case inventoryETag:
+		r.etag, ok = value.(string)
+	case inventoryStorageClass:
+		r.storageClass, ok = value.(string)
+	case inventoryIsLatest:
+		r.isLatest, ok = value.(bool)
+	case inventoryIsDeleteMarker:
+		r.isDeleteMarker, ok = value.(bool)
+	case inventorySize:
+		r.size, ok = value.(int64)
+	case inventoryLastModified:
+		r.modTime, ok = value.(time.Time)
+	default:
+		ok = true
+	}
+
+	if !ok {
+		return fmt.Errorf("unexpected value of inventory field %v: %v", field, value)
+	}
+	return nil
+}
+
+// Inventory is the Storage which lists the objects from the S3 Inventory
+// report of the given manifest instead of listing the storage, which takes
+// hours for buckets with hundreds of millions of objects. The other
+// operations are performed by the underlying storage.
+type Inventory struct {
+	Storage
+
+	manifest *url.URL
+	reader   Reader
+}
+
+// NewInventoryClient creates a client which lists the objects from the
+// inventory report of the given manifest.json file.
+func NewInventoryClient(ctx context.Context, client Storage, manifest *url.URL, opts Options) (*Inventory, error) {
+	manifestClient, err := NewClient(ctx, manifest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, ok := manifestClient.(Reader)
+	if !ok {
+		return nil, NotSupported(manifest, "reading inventory reports")
+	}
+
+	return &Inventory{
+		Storage:  client,
+		manifest: manifest,
+		reader:   reader,
+	}, nil
+}
+
+// List lists the objects of the inventory report which match the given
+// source. Only the latest versions of the objects are listed, unless all
+// versions are requested and the report includes them.
+func (i *Inventory) List(ctx context.Context, src *url.URL, _ bool) <-chan *Object {
+	objCh := make(chan *Object)
+
+	go func() {
+		defer close(objCh)
+
+		manifest, err := i.readManifest(ctx)
+		if err != nil {
+			objCh <- &Object{Err: err}
+			return
+		}
+
+		if manifest.SourceBucket != src.Bucket {
+			objCh <- &Object{Err: fmt.Errorf(
+				"inventory %q is the report of bucket %q, not %q", i.manifest, manifest.SourceBucket, src.Bucket,
+			)}
+			return
+		}
+
+		var (
+			objectFound bool
+			dirs        = map[string]struct{}{}
+		)
+		send := func(record *inventoryRecord) error {
+			object := i.object(src, record, dirs)
+			if object == nil {
+				return nil
+			}
+
+			select {
+			case objCh <- object:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			objectFound = true
+			return nil
+		}
+
+		for _, file := range manifest.Files {
+			err := i.readFile(ctx, manifest, file.Key, send)
+			if err != nil {
+				objCh <- &Object{Err: err}
+				return
+			}
+		}
+
+		if !objectFound && !src.IsBucket() {
+			objCh <- &Object{Err: ErrNoObjectFound}
+		}
+	}()
+
+	return objCh
+}
+
+// object returns the object of the inventory record if it matches with the
+// source. Objects in the sub-directories of a source with a delimiter are
+// listed as their directories, once per directory.
+func (i *Inventory) object(src *url.URL, record *inventoryRecord, dirs map[string]struct{}) *Object {
+	if record.isDeleteMarker || (!record.isLatest && !src.AllVersions) {
+		return nil
+	}
+
+	if !strings.HasPrefix(record.key, src.Prefix) || !src.Match(record.key) {
+		return nil
+	}
+
+	if src.Delimiter != "" {
+		rel := strings.TrimPrefix(record.key, src.Prefix)
+		if loc := strings.Index(rel, src.Delimiter); loc >= 0 {
+			dir := src.Prefix + rel[:loc+1]
+			if _, ok := dirs[dir]; ok || !src.Match(dir) {
+				return nil
+			}
+			dirs[dir] = struct{}{}
+
+			newurl := src.Clone()
+			newurl.Path = dir
+			return &Object{
+				URL:  newurl,
+				Type: ObjectType{os.ModeDir},
+			}
+		}
+	}
+
+	var objtype os.FileMode
+	if strings.HasSuffix(record.key, "/") {
+		objtype = os.ModeDir
+	}
+
+	newurl := src.Clone()
+	newurl.Path = record.key
+
+	modTime := record.modTime.UTC()
+	object := &Object{
+		URL:          newurl,
+		Etag:         strings.Trim(record.etag, `"`),
+		ModTime:      &modTime,
+		Type:         ObjectType{objtype},
+		Size:         record.size,
+		StorageClass: StorageClass(record.storageClass),
+	}
+	if src.AllVersions {
+		newurl.VersionID = record.versionID
+		object.VersionID = record.versionID
+	}
+	return object
+}
+
+func (i *Inventory) readManifest(ctx context.Context) (*inventoryManifest, error) {
+	rc, err := i.reader.Read(ctx, i.manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var manifest inventoryManifest
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("unable to parse inventory manifest %q: %w", i.manifest, err)
+	}
+	return &manifest, nil
+}
+
+// fileURL returns the URL of an inventory file. The files are stored in the
+// destination bucket of the report, which is given as an ARN.
+func (i *Inventory) fileURL(manifest *inventoryManifest, key string) *url.URL {
+	bucket := manifest.DestinationBucket
+	if loc := strings.LastIndex(bucket, ":"); loc >= 0 {
+		bucket = bucket[loc+1:]
+	}
+	if bucket == "" {
+		bucket = i.manifest.Bucket
+	}
+
+	return &url.URL{
+		Type:   i.manifest.Type,
+		Scheme: i.manifest.Scheme,
+		Bucket: bucket,
+		Path:   key,
+	}
+}
+
+func (i *Inventory) readFile(
+	ctx context.Context,
+	manifest *inventoryManifest,
+	key string,
+	fn func(*inventoryRecord) error,
+) error {
+	fileurl := i.fileURL(manifest, key)
+
+	rc, err := i.reader.Read(ctx, fileurl)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	switch manifest.FileFormat {
+	case inventoryCSV:
+		var r io.Reader = rc
+		if strings.HasSuffix(key, ".gz") {
+			gr, err := gzip.NewReader(rc)
+			if err != nil {
+				return fmt.Errorf("unable to read inventory file %q: %w", fileurl, err)
+			}
+			defer gr.Close()
+			r = gr
+		}
+		err = readInventoryCSV(r, manifest.FileSchema, fn)
+	case inventoryORC, inventoryParquet:
+		// the columnar formats are read from the end of the files, so they
+		// are downloaded first.
+		var file *os.File
+		file, err = downloadInventoryFile(rc)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+
+		if manifest.FileFormat == inventoryORC {
+			err = readInventoryORC(file, fn)
+		} else {
+			err = readInventoryParquet(file, fn)
+		}
+	default:
+		return fmt.Errorf("unsupported inventory file format %q", manifest.FileFormat)
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to read inventory file %q: %w", fileurl, err)
+	}
+	return nil
+}
+
+func downloadInventoryFile(r io.Reader) (*os.File, error) {
+	file, err := os.CreateTemp("", "s5cmd-inventory-")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+// readInventoryCSV reads the records of a CSV inventory file. The fields of
+// the records are given by the schema of the report, and the keys are URL
+// encoded.
+func readInventoryCSV(r io.Reader, schema string, fn func(*inventoryRecord) error) error {
+	var fields []string
+	for _, field := range strings.Split(schema, ",") {
+		fields = append(fields, strings.TrimSpace(field))
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(fields)
+	reader.ReuseRecord = true
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		record := inventoryRecord{isLatest: true}
+		for i, field := range fields {
+			value, err := parseInventoryCSVField(field, row[i])
+			if err != nil {
+				return err
+			}
+			if err := record.set(field, value); err != nil {
+				return err
+			}
+		}
+
+		if err := fn(&record); err != nil {
+			return err
+		}
+	}
+}
+
+func parseInventoryCSVField(field, value string) (interface{}, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	switch field {
+	case inventoryKey:
+		return urlpkg.QueryUnescape(value)
+	case inventoryIsLatest, inventoryIsDeleteMarker:
+		return strconv.ParseBool(value)
+	case inventorySize:
+		return strconv.ParseInt(value, 10, 64)
+	case inventoryLastModified:
+		return time.Parse(time.RFC3339, value)
+	default:
+		return value, nil
+	}
+}
+
+// readInventoryParquet reads the records of a Parquet inventory file.
+func readInventoryParquet(file *os.File, fn func(*inventoryRecord) error) error {
+	reader, err := goparquet.NewFileReader(file)
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := reader.NextRow()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		record := inventoryRecord{isLatest: true}
+		for column, value := range row {
+			field, ok := inventoryColumns[column]
+			if !ok {
+				continue
+			}
+			// timestamps are stored in milliseconds.
+			if millis, ok := value.(int64); ok && field == inventoryLastModified {
+				value = time.UnixMilli(millis).UTC()
+			}
+			if err := record.set(field, value); err != nil {
+				return err
+			}
+		}
+
+		if err := fn(&record); err != nil {
+			return err
+		}
+	}
+}
+
+// readInventoryORC reads the records of an ORC inventory file.
+func readInventoryORC(file *os.File, fn func(*inventoryRecord) error) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	reader, err := orc.NewReader(io.NewSectionReader(file, 0, info.Size()))
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var columns, fields []string
+	for _, column := range reader.Schema().Columns() {
+		if field, ok := inventoryColumns[column]; ok {
+			columns = append(columns, column)
+			fields = append(fields, field)
+		}
+	}
+
+	cursor := reader.Select(columns...)
+	for cursor.Stripes() {
+		for cursor.Next() {
+			record := inventoryRecord{isLatest: true}
+			for i, value := range cursor.Row() {
+				if err := record.set(fields[i], value); err != nil {
+					return err
+				}
+			}
+
+			if err := fn(&record); err != nil {
+				return err
+			}
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/parquetschema"
+	"github.com/scritchley/orc"
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+)
+
+var inventoryTestModTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// inventoryTestObjects are the objects of the inventory reports of the tests,
+// including a non-current version and a delete marker.
+var inventoryTestObjects = []inventoryRecord{
+	{key: "a.txt", versionID: "v1", isLatest: true, size: 1, etag: "etag-a", storageClass: "STANDARD"},
+	{key: "dir/b.txt", versionID: "v2", isLatest: true, size: 2, etag: "etag-b", storageClass: "GLACIER"},
+	{key: "dir/b.txt", versionID: "v1", isLatest: false, size: 20, etag: "etag-b1", storageClass: "STANDARD"},
+	{key: "dir/sub dir/c.log", versionID: "v3", isLatest: true, size: 3, etag: "etag-c", storageClass: "STANDARD"},
+	{key: "deleted.txt", versionID: "v4", isLatest: true, isDeleteMarker: true},
+}
+
+// putInventory puts the manifest and the files of an inventory report of the
+// test objects to the memory storage. The objects are split into two files.
+func putInventory(t *testing.T, destination, source, format string) string {
+	t.Helper()
+
+	client := NewMemoryClient(Options{})
+	ctx := context.Background()
+
+	prefix := fmt.Sprintf("%v/config/2024-01-02T03-00Z", source)
+	manifest := map[string]interface{}{
+		"sourceBucket":      source,
+		"destinationBucket": "arn:aws:s3:::" + destination,
+		"version":           "2016-11-30",
+		"fileFormat":        format,
+	}
+
+	var files []map[string]interface{}
+	for i, records := range [][]inventoryRecord{inventoryTestObjects[:2], inventoryTestObjects[2:]} {
+		var (
+			data   []byte
+			schema string
+			ext    string
+		)
+		switch format {
+		case inventoryCSV:
+			data, schema = inventoryCSVFile(t, source, records), "Bucket, Key, VersionId, IsLatest, IsDeleteMarker, Size, LastModifiedDate, ETag, StorageClass"
+			ext = ".csv.gz"
+		case inventoryParquet:
+			data, schema = inventoryParquetFile(t, source, records), "message s3.inventory { ... }"
+			ext = ".parquet"
+		case inventoryORC:
+			data, schema = inventoryORCFile(t, source, records), "struct<...>"
+			ext = ".orc"
+		}
+
+		key := fmt.Sprintf("%v/data/file-%d%v", source, i, ext)
+		err := client.Put(ctx, bytes.NewReader(data), mustNewURL(t, fmt.Sprintf("memory://%v/%v", destination, key)), Metadata{}, 1, 0)
+		assert.NilError(t, err)
+
+		manifest["fileSchema"] = schema
+		files = append(files, map[string]interface{}{"key": key, "size": len(data)})
+	}
+	manifest["files"] = files
+
+	data, err := json.Marshal(manifest)
+	assert.NilError(t, err)
+
+	manifestURL := fmt.Sprintf("memory://%v/%v/manifest.json", destination, prefix)
+	err = client.Put(ctx, bytes.NewReader(data), mustNewURL(t, manifestURL), Metadata{}, 1, 0)
+	assert.NilError(t, err)
+
+	return manifestURL
+}
+
+func inventoryCSVFile(t *testing.T, bucket string, records []inventoryRecord) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	for _, r := range records {
+		// keys are URL encoded in the CSV reports.
+		key := strings.ReplaceAll(r.key, " ", "+")
+		fmt.Fprintf(w, "%q,%q,%q,%q,%q,%q,%q,%q,%q\n",
+			bucket, key, r.versionID, fmt.Sprint(r.isLatest), fmt.Sprint(r.isDeleteMarker),
+			fmt.Sprint(r.size), inventoryTestModTime.Format("2006-01-02T15:04:05.000Z"), r.etag, r.storageClass,
+		)
+	}
+	assert.NilError(t, w.Close())
+	return buf.Bytes()
+}
+
+func inventoryParquetFile(t *testing.T, bucket string, records []inventoryRecord) []byte {
+	schema, err := parquetschema.ParseSchemaDefinition(`message inventory {
+		required binary bucket (STRING);
+		required binary key (STRING);
+		optional binary version_id (STRING);
+		optional boolean is_latest;
+		optional boolean is_delete_marker;
+		optional int64 size;
+		optional int64 last_modified_date (TIMESTAMP(MILLIS, true));
+		optional binary e_tag (STRING);
+		optional binary storage_class (STRING);
+	}`)
+	assert.NilError(t, err)
+
+	var buf bytes.Buffer
+	w := goparquet.NewFileWriter(&buf, goparquet.WithSchemaDefinition(schema))
+	for _, r := range records {
+		row := map[string]interface{}{
+			"bucket":             []byte(bucket),
+			"key":                []byte(r.key),
+			"is_latest":          r.isLatest,
+			"is_delete_marker":   r.isDeleteMarker,
+			"last_modified_date": inventoryTestModTime.UnixMilli(),
+		}
+		// the fields of the delete markers are null.
+		if !r.isDeleteMarker {
+			row["version_id"] = []byte(r.versionID)
+			row["size"] = r.size
+			row["e_tag"] = []byte(r.etag)
+			row["storage_class"] = []byte(r.storageClass)
+		}
+		assert.NilError(t, w.AddData(row))
+	}
+	assert.NilError(t, w.Close())
+	return buf.Bytes()
+}
+
+func inventoryORCFile(t *testing.T, bucket string, records []inventoryRecord) []byte {
+	schema, err := orc.ParseSchema("struct<bucket:string,key:string,version_id:string,is_latest:boolean,is_delete_marker:boolean,size:bigint,last_modified_date:timestamp,e_tag:string,storage_class:string>")
+	assert.NilError(t, err)
+
+	var buf bytes.Buffer
+	w, err := orc.NewWriter(&buf, orc.SetSchema(schema))
+	assert.NilError(t, err)
+	for _, r := range records {
+		err := w.Write(bucket, r.key, r.versionID, r.isLatest, r.isDeleteMarker, r.size, inventoryTestModTime, r.etag, r.storageClass)
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestInventoryList(t *testing.T) {
+	t.Parallel()
+	log.Init("error", false)
+
+	for _, format := range []string{inventoryCSV, inventoryParquet, inventoryORC} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			destination := "inventory-" + strings.ToLower(format)
+			source := "source-" + strings.ToLower(format)
+			manifest := putInventory(t, destination, source, format)
+
+			ctx := context.Background()
+			client, err := NewInventoryClient(ctx, NewMemoryClient(Options{}), mustNewURL(t, manifest), Options{})
+			assert.NilError(t, err)
+
+			testcases := []struct {
+				src         string
+				allVersions bool
+				expected    []string
+			}{
+				{src: "*", expected: []string{"a.txt", "dir/b.txt", "dir/sub dir/c.log"}},
+				{src: "dir/*.txt", expected: []string{"dir/b.txt"}},
+				{src: "", expected: []string{"a.txt", "dir/"}},
+				{src: "dir/", expected: []string{"dir/b.txt", "dir/sub dir/"}},
+				{src: "dir/*", allVersions: true, expected: []string{"dir/b.txt@v1", "dir/b.txt@v2", "dir/sub dir/c.log@v3"}},
+			}
+
+			for _, tc := range testcases {
+				src := mustNewURL(t, fmt.Sprintf("memory://%v/%v", source, tc.src))
+				src.AllVersions = tc.allVersions
+
+				var keys []string
+				for obj := range client.List(ctx, src, false) {
+					assert.NilError(t, obj.Err, tc.src)
+
+					key := obj.URL.Path
+					if obj.URL.VersionID != "" {
+						key += "@" + obj.URL.VersionID
+					}
+					keys = append(keys, key)
+
+					if obj.URL.Path == "dir/b.txt" && !tc.allVersions {
+						assert.Equal(t, obj.Size, int64(2))
+						assert.Equal(t, obj.Etag, "etag-b")
+						assert.Equal(t, obj.StorageClass, StorageClass("GLACIER"))
+						assert.Assert(t, obj.ModTime.Equal(inventoryTestModTime))
+					}
+				}
+				sort.Strings(keys)
+				assert.DeepEqual(t, keys, tc.expected)
+			}
+
+			for obj := range client.List(ctx, mustNewURL(t, fmt.Sprintf("memory://%v/missing/*", source)), false) {
+				assert.Equal(t, obj.Err, ErrNoObjectFound)
+			}
+
+			for obj := range client.List(ctx, mustNewURL(t, "memory://other-bucket/*"), false) {
+				assert.ErrorContains(t, obj.Err, fmt.Sprintf(`is the report of bucket %q, not "other-bucket"`, source))
+			}
+		})
+	}
+}
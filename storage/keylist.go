@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// maxKeyListLineSize is the maximum size of a line of a key list. The lines
+// of the JSON key lists are longer than the keys themselves.
+const maxKeyListLineSize = 1024 * 1024
+
+// keyListObject is an object in the JSON output of ls command.
+type keyListObject struct {
+	Key          string     `json:"key"`
+	Etag         string     `json:"etag"`
+	ModTime      *time.Time `json:"last_modified"`
+	Type         string     `json:"type"`
+	Size         int64      `json:"size"`
+	StorageClass string     `json:"storage_class"`
+	VersionID    string     `json:"version_id"`
+}
+
+// KeyList is the Storage which lists the objects from a list of keys instead
+// of listing the storage. The other operations are performed by the
+// underlying storage.
+//
+// Each line of the list is either a key, a URL of an object or an object in
+// the JSON output of ls command. The keys are filtered with the wildcards of
+// the listed source.
+type KeyList struct {
+	Storage
+
+	keys io.ReadCloser
+}
+
+// NewKeyListClient creates a client which lists the objects from the keys
+// read from the given reader. The keys can be listed only once, the reader is
+// closed once they are listed.
+func NewKeyListClient(client Storage, keys io.ReadCloser) *KeyList {
+	return &KeyList{
+		Storage: client,
+		keys:    keys,
+	}
+}
+
+// List lists the objects of the key list which match with the source.
+func (k *KeyList) List(ctx context.Context, src *url.URL, _ bool) <-chan *Object {
+	objCh := make(chan *Object)
+
+	go func() {
+		defer close(objCh)
+		defer k.keys.Close()
+
+		scanner := bufio.NewScanner(k.keys)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxKeyListLineSize)
+
+		var objectFound bool
+		for scanner.Scan() {
+			line := strings.TrimSuffix(scanner.Text(), "\r")
+			if line == "" {
+				continue
+			}
+
+			object, err := keyListObjectOf(src, line)
+			if err != nil {
+				object = &Object{Err: err}
+			}
+			if object == nil {
+				continue
+			}
+
+			select {
+			case objCh <- object:
+			case <-ctx.Done():
+				return
+			}
+			objectFound = true
+		}
+
+		if err := scanner.Err(); err != nil {
+			objCh <- &Object{Err: fmt.Errorf("unable to read key list: %w", err)}
+			return
+		}
+
+		if !objectFound {
+			objCh <- &Object{Err: ErrNoObjectFound}
+		}
+	}()
+
+	return objCh
+}
+
+// keyListObjectOf returns the object of the line of a key list if it matches
+// with the source.
+func keyListObjectOf(src *url.URL, line string) (*Object, error) {
+	var (
+		obj         keyListObject
+		sizeUnknown bool
+	)
+	if strings.HasPrefix(line, "{") {
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			return nil, fmt.Errorf("unable to parse key list line %q: %w", line, err)
+		}
+		if obj.Type == "directory" {
+			return nil, nil
+		}
+		// the sizes of the empty objects are omitted in the output of ls
+		// command, a missing size is known to be zero only if the line is
+		// such an output.
+		sizeUnknown = obj.Size == 0 && obj.ModTime == nil
+	} else {
+		obj.Key = line
+		sizeUnknown = true
+	}
+
+	key := obj.Key
+	if strings.Contains(key, "://") {
+		keyurl, err := url.New(key, url.WithRaw(true))
+		if err != nil {
+			return nil, err
+		}
+		if keyurl.Scheme != src.Scheme || keyurl.Bucket != src.Bucket {
+			return nil, fmt.Errorf("object %q is not in bucket %q", key, src.Bucket)
+		}
+		key = keyurl.Path
+	}
+
+	if !strings.HasPrefix(key, src.Prefix) || !src.Match(key) {
+		return nil, nil
+	}
+
+	var objtype os.FileMode
+	if strings.HasSuffix(key, "/") {
+		objtype = os.ModeDir
+	}
+
+	newurl := src.Clone()
+	newurl.Path = key
+	newurl.VersionID = obj.VersionID
+
+	return &Object{
+		URL:          newurl,
+		Etag:         obj.Etag,
+		ModTime:      obj.ModTime,
+		Type:         ObjectType{objtype},
+		Size:         obj.Size,
+		StorageClass: StorageClass(obj.StorageClass),
+		VersionID:    obj.VersionID,
+		sizeUnknown:  sizeUnknown,
+	}, nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestKeyListList(t *testing.T) {
+	t.Parallel()
+
+	keys := strings.Join([]string{
+		"a.txt",
+		"",
+		"dir/b.txt\r",
+		"s3://bucket/dir/c.log",
+		`{"key":"s3://bucket/dir/d.txt","type":"file","size":4,"storage_class":"GLACIER","version_id":"v1"}`,
+		`{"key":"s3://bucket/dir/sub/","type":"directory"}`,
+		"dir/file with *.txt",
+	}, "\n")
+
+	testcases := []struct {
+		name     string
+		src      string
+		expected []string
+	}{
+		{name: "all keys", src: "s3://bucket/*", expected: []string{"a.txt", "dir/b.txt", "dir/c.log", "dir/d.txt", "dir/file with *.txt"}},
+		{name: "wildcard", src: "s3://bucket/dir/*.txt", expected: []string{"dir/b.txt", "dir/d.txt", "dir/file with *.txt"}},
+		{name: "prefix", src: "s3://bucket/dir/c*", expected: []string{"dir/c.log"}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := NewKeyListClient(NewMemoryClient(Options{}), io.NopCloser(strings.NewReader(keys)))
+
+			var got []string
+			for obj := range client.List(context.Background(), mustNewURL(t, tc.src), false) {
+				assert.NilError(t, obj.Err)
+				got = append(got, obj.URL.Path)
+
+				// only the keys of the JSON lines have their sizes.
+				assert.Equal(t, obj.SizeUnknown(), obj.URL.Path != "dir/d.txt")
+
+				if obj.URL.Path == "dir/d.txt" {
+					assert.Equal(t, obj.Size, int64(4))
+					assert.Equal(t, obj.StorageClass, StorageClass("GLACIER"))
+					assert.Equal(t, obj.URL.VersionID, "v1")
+				}
+			}
+			assert.DeepEqual(t, got, tc.expected)
+		})
+	}
+}
+
+func TestKeyListListErrors(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		keys     string
+		expected string
+	}{
+		{name: "no matching key", keys: "a.txt\nb.txt\n", expected: ErrNoObjectFound.Error()},
+		{name: "another bucket", keys: "s3://other-bucket/a.txt\n", expected: `object "s3://other-bucket/a.txt" is not in bucket "bucket"`},
+		{name: "invalid json", keys: "{\"key\":\n", expected: "unable to parse key list line"},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := NewKeyListClient(NewMemoryClient(Options{}), io.NopCloser(strings.NewReader(tc.keys)))
+
+			var errs []error
+			for obj := range client.List(context.Background(), mustNewURL(t, "s3://bucket/dir/*"), false) {
+				errs = append(errs, obj.Err)
+			}
+			assert.Assert(t, len(errs) > 0)
+			assert.ErrorContains(t, errs[0], tc.expected)
+		})
+	}
+}
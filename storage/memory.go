@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func init() {
+	Register("memory", url.SchemeOptions{}, func(_ context.Context, _ *url.URL, opts Options) (Storage, error) {
+		return NewMemoryClient(opts), nil
+	})
+}
+
+// Memory is the Storage implementation which keeps the objects in memory. It
+// is meant for testing, the objects are shared by all the clients in the same
+// process. Buckets exist as long as they have objects.
+type Memory struct {
+	store  *memoryStore
+	dryRun bool
+}
+
+type memoryObject struct {
+	data     []byte
+	metadata Metadata
+	modTime  time.Time
+	etag     string
+}
+
+type memoryStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string]*memoryObject
+}
+
+var globalMemoryStore = &memoryStore{
+	buckets: map[string]map[string]*memoryObject{},
+}
+
+// NewMemoryClient returns a client of the in-memory storage.
+func NewMemoryClient(opts Options) *Memory {
+	return &Memory{store: globalMemoryStore, dryRun: opts.DryRun}
+}
+
+func (m *Memory) lookup(url *url.URL) (*memoryObject, bool) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	obj, ok := m.store.buckets[url.Bucket][url.Path]
+	return obj, ok
+}
+
+func (m *Memory) object(url *url.URL, obj *memoryObject) *Object {
+	mod := obj.modTime
+	return &Object{
+		URL:     url,
+		Etag:    obj.etag,
+		ModTime: &mod,
+		Size:    int64(len(obj.data)),
+	}
+}
+
+// Stat returns the Object structure describing the object.
+func (m *Memory) Stat(_ context.Context, url *url.URL) (*Object, error) {
+	obj, ok := m.lookup(url)
+	if !ok {
+		return nil, &ErrGivenObjectNotFound{ObjectAbsPath: url.Absolute()}
+	}
+	return m.object(url, obj), nil
+}
+
+// List lists the objects and the prefixes in the same manner as S3.
+func (m *Memory) List(ctx context.Context, src *url.URL, _ bool) <-chan *Object {
+	ch := make(chan *Object)
+
+	go func() {
+		defer close(ch)
+
+		m.store.mu.RLock()
+		bucket := m.store.buckets[src.Bucket]
+		keys := make([]string, 0, len(bucket))
+		for key := range bucket {
+			if strings.HasPrefix(key, src.Prefix) {
+				keys = append(keys, key)
+			}
+		}
+		m.store.mu.RUnlock()
+
+		sort.Strings(keys)
+
+		var (
+			objectFound bool
+			lastPrefix  string
+		)
+		for _, key := range keys {
+			if src.Delimiter != "" {
+				rest := strings.TrimPrefix(key, src.Prefix)
+				if i := strings.Index(rest, src.Delimiter); i >= 0 {
+					prefix := src.Prefix + rest[:i+len(src.Delimiter)]
+					if prefix == lastPrefix || !src.Match(prefix) {
+						continue
+					}
+					lastPrefix = prefix
+
+					newurl := src.Clone()
+					newurl.Path = prefix
+					sendObject(ctx, &Object{URL: newurl, Type: ObjectType{os.ModeDir}}, ch)
+					objectFound = true
+					continue
+				}
+			}
+
+			if !src.Match(key) {
+				continue
+			}
+
+			obj, ok := m.lookup(&url.URL{Bucket: src.Bucket, Path: key})
+			if !ok {
+				continue
+			}
+
+			newurl := src.Clone()
+			newurl.Path = key
+			sendObject(ctx, m.object(newurl, obj), ch)
+			objectFound = true
+		}
+
+		if !objectFound && !src.IsBucket() {
+			sendError(ctx, ErrNoObjectFound, ch)
+		}
+	}()
+
+	return ch
+}
+
+// Delete deletes the object. Deleting a missing object is not an error.
+func (m *Memory) Delete(_ context.Context, url *url.URL) error {
+	if m.dryRun {
+		return nil
+	}
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	bucket := m.store.buckets[url.Bucket]
+	delete(bucket, url.Path)
+	if len(bucket) == 0 {
+		delete(m.store.buckets, url.Bucket)
+	}
+	return nil
+}
+
+// MultiDelete deletes all the objects read from the channel.
+func (m *Memory) MultiDelete(ctx context.Context, urlch <-chan *url.URL) <-chan *Object {
+	resultch := make(chan *Object)
+
+	go func() {
+		defer close(resultch)
+
+		for url := range urlch {
+			sendObject(ctx, &Object{URL: url, Err: m.Delete(ctx, url)}, resultch)
+		}
+	}()
+
+	return resultch
+}
+
+// Copy copies the object within the storage. The metadata of the source
+// object is kept unless it is replaced.
+func (m *Memory) Copy(_ context.Context, from, to *url.URL, metadata Metadata) error {
+	if m.dryRun {
+		return nil
+	}
+
+	obj, ok := m.lookup(from)
+	if !ok {
+		return &ErrGivenObjectNotFound{ObjectAbsPath: from.Absolute()}
+	}
+
+	if metadata.Directive != "REPLACE" {
+		metadata = obj.metadata
+	}
+	m.put(to, obj.data, metadata)
+	return nil
+}
+
+// Get writes the contents of the object to the destination.
+func (m *Memory) Get(_ context.Context, from *url.URL, to io.WriterAt, _ int, _ int64) (int64, error) {
+	if m.dryRun {
+		return 0, nil
+	}
+
+	obj, ok := m.lookup(from)
+	if !ok {
+		return 0, &ErrGivenObjectNotFound{ObjectAbsPath: from.Absolute()}
+	}
+
+	n, err := to.WriteAt(obj.data, 0)
+	return int64(n), err
+}
+
+// Read returns the contents of the object.
+func (m *Memory) Read(_ context.Context, src *url.URL) (io.ReadCloser, error) {
+	obj, ok := m.lookup(src)
+	if !ok {
+		return nil, &ErrGivenObjectNotFound{ObjectAbsPath: src.Absolute()}
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
+
+// Put stores the contents of the reader as an object.
+func (m *Memory) Put(_ context.Context, reader io.Reader, to *url.URL, metadata Metadata, _ int, _ int64) error {
+	if m.dryRun {
+		return nil
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.put(to, data, metadata)
+	return nil
+}
+
+func (m *Memory) put(to *url.URL, data []byte, metadata Metadata) {
+	sum := md5.Sum(data)
+
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+
+	bucket, ok := m.store.buckets[to.Bucket]
+	if !ok {
+		bucket = map[string]*memoryObject{}
+		m.store.buckets[to.Bucket] = bucket
+	}
+	bucket[to.Path] = &memoryObject{
+		data:     data,
+		metadata: metadata,
+		modTime:  time.Now().UTC(),
+		etag:     hex.EncodeToString(sum[:]),
+	}
+}
+
+// HeadObject returns the object and its metadata.
+func (m *Memory) HeadObject(_ context.Context, url *url.URL) (*Object, *Metadata, error) {
+	obj, ok := m.lookup(url)
+	if !ok {
+		return nil, nil, &ErrGivenObjectNotFound{ObjectAbsPath: url.Absolute()}
+	}
+
+	metadata := obj.metadata
+	if metadata.UserDefined != nil {
+		metadata.UserDefined = make(map[string]string, len(obj.metadata.UserDefined))
+		for k, v := range obj.metadata.UserDefined {
+			metadata.UserDefined[k] = v
+		}
+	}
+	return m.object(url, obj), &metadata, nil
+}
+
+// ListBuckets returns the buckets which match with the given prefix.
+func (m *Memory) ListBuckets(_ context.Context, prefix string) ([]Bucket, error) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+
+	var buckets []Bucket
+	for name := range m.store.buckets {
+		if strings.HasPrefix(name, prefix) {
+			buckets = append(buckets, Bucket{Name: name, Scheme: "memory"})
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Name < buckets[j].Name
+	})
+	return buckets, nil
+}
@@ -0,0 +1,149 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+func TestMemoryImplementsCapabilities(t *testing.T) {
+	var i interface{} = new(Memory)
+	if _, ok := i.(Storage); !ok {
+		t.Errorf("expected %t to implement Storage interface", i)
+	}
+	if _, ok := i.(Getter); !ok {
+		t.Errorf("expected %t to implement Getter interface", i)
+	}
+	if _, ok := i.(Putter); !ok {
+		t.Errorf("expected %t to implement Putter interface", i)
+	}
+	if _, ok := i.(BucketLister); !ok {
+		t.Errorf("expected %t to implement BucketLister interface", i)
+	}
+	if _, ok := i.(Presigner); ok {
+		t.Errorf("expected %t not to implement Presigner interface", i)
+	}
+}
+
+func mustNewURL(t *testing.T, s string, opts ...url.Option) *url.URL {
+	t.Helper()
+
+	u, err := url.New(s, opts...)
+	assert.NilError(t, err)
+	return u
+}
+
+func TestMemoryPutGetCopyDelete(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	client, err := NewClient(ctx, mustNewURL(t, "memory://put-get/"), Options{})
+	assert.NilError(t, err)
+	memory := client.(*Memory)
+
+	src := mustNewURL(t, "memory://put-get/dir/file.txt")
+	metadata := Metadata{ContentType: "text/plain", UserDefined: map[string]string{"key": "value"}}
+	err = memory.Put(ctx, strings.NewReader("content"), src, metadata, 1, 0)
+	assert.NilError(t, err)
+
+	obj, err := memory.Stat(ctx, src)
+	assert.NilError(t, err)
+	assert.Equal(t, obj.Size, int64(len("content")))
+	assert.Equal(t, obj.Etag, "9a0364b9e99bb480dd25e1f0284c8555")
+
+	buf := aws.NewWriteAtBuffer(nil)
+	n, err := memory.Get(ctx, src, buf, 1, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, n, int64(len("content")))
+	assert.Equal(t, string(buf.Bytes()), "content")
+
+	dst := mustNewURL(t, "memory://put-get/copy.txt")
+	err = memory.Copy(ctx, src, dst, Metadata{ContentType: "application/json"})
+	assert.NilError(t, err)
+
+	_, copied, err := memory.HeadObject(ctx, dst)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *copied, metadata)
+
+	err = memory.Delete(ctx, src)
+	assert.NilError(t, err)
+
+	_, err = memory.Stat(ctx, src)
+	assert.Error(t, err, "given object memory://put-get/dir/file.txt not found")
+
+	reader, err := memory.Read(ctx, dst)
+	assert.NilError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "content")
+}
+
+func TestMemoryList(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	memory := NewMemoryClient(Options{})
+
+	for _, key := range []string{"a.txt", "b.csv", "dir/c.txt", "dir/sub/d.txt"} {
+		err := memory.Put(ctx, strings.NewReader(key), mustNewURL(t, "memory://list/"+key), Metadata{}, 1, 0)
+		assert.NilError(t, err)
+	}
+
+	testcases := []struct {
+		src      string
+		expected []string
+	}{
+		{src: "memory://list/", expected: []string{"a.txt", "b.csv", "dir/"}},
+		{src: "memory://list/*", expected: []string{"a.txt", "b.csv", "dir/c.txt", "dir/sub/d.txt"}},
+		{src: "memory://list/*.txt", expected: []string{"a.txt", "dir/c.txt", "dir/sub/d.txt"}},
+		{src: "memory://list/dir/", expected: []string{"dir/c.txt", "dir/sub/"}},
+	}
+
+	for _, tc := range testcases {
+		var keys []string
+		for obj := range memory.List(ctx, mustNewURL(t, tc.src), false) {
+			assert.NilError(t, obj.Err, tc.src)
+			keys = append(keys, obj.URL.Path)
+		}
+		assert.DeepEqual(t, keys, tc.expected)
+	}
+
+	for obj := range memory.List(ctx, mustNewURL(t, "memory://list/missing/"), false) {
+		assert.Equal(t, obj.Err, ErrNoObjectFound)
+	}
+
+	buckets, err := memory.ListBuckets(ctx, "lis")
+	assert.NilError(t, err)
+	assert.Equal(t, len(buckets), 1)
+	assert.Equal(t, buckets[0].Name, "list")
+	assert.Assert(t, strings.HasSuffix(buckets[0].String(), "memory://list"))
+}
+
+func TestMemoryDryRun(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	memory := NewMemoryClient(Options{DryRun: true})
+
+	dst := mustNewURL(t, "memory://dry-run/file.txt")
+	err := memory.Put(ctx, strings.NewReader("content"), dst, Metadata{}, 1, 0)
+	assert.NilError(t, err)
+
+	_, err = memory.Stat(ctx, dst)
+	assert.Error(t, err, "given object memory://dry-run/file.txt not found")
+}
+
+func TestNewClientWithUnsupportedScheme(t *testing.T) {
+	_, err := url.New("unknown://bucket/key")
+	assert.Error(t, err, `unsupported url scheme "unknown"`)
+
+	_, err = NewRemoteClient(context.Background(), mustNewURL(t, "memory://bucket/key"), Options{})
+	assert.Error(t, err, "the operation is only supported for s3 urls: memory://bucket/key")
+}
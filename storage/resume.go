@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// UploadState is the persisted state of a resumable multipart upload. It is
+// used to continue an interrupted upload from the last completed part.
+type UploadState struct {
+	// Source is the absolute path of the uploaded file. Size and ModTime are
+	// recorded to detect changes on the source file between runs.
+	Source  string    `json:"source"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+
+	Bucket   string         `json:"bucket"`
+	Key      string         `json:"key"`
+	UploadID string         `json:"upload_id,omitempty"`
+	RetryID  string         `json:"retry_id,omitempty"`
+	PartSize int64          `json:"part_size,omitempty"`
+	Parts    []UploadedPart `json:"parts,omitempty"`
+
+	// stale reports whether the recorded upload belongs to a previous
+	// version of the source file.
+	stale bool
+	path  string
+	mu    sync.Mutex
+}
+
+// UploadedPart is a part of a multipart upload which is uploaded
+// successfully.
+type UploadedPart struct {
+	PartNumber int64  `json:"part_number"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+}
+
+// LoadUploadState reads the upload state of the given source file from path.
+// A fresh state is returned if there is no state file. If the source file has
+// been changed since the state is recorded, the returned state is marked as
+// stale and the upload will be started from scratch.
+func LoadUploadState(path string, source *Object) (*UploadState, error) {
+	fresh := &UploadState{
+		Source:  source.URL.Absolute(),
+		Size:    source.Size,
+		ModTime: source.ModTime.UTC(),
+		path:    path,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fresh, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state UploadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		// a corrupted state file can not be resumed.
+		return fresh, nil
+	}
+	state.path = path
+
+	if state.Source != fresh.Source || state.Size != fresh.Size || !state.ModTime.Equal(fresh.ModTime) {
+		fresh.Bucket = state.Bucket
+		fresh.Key = state.Key
+		fresh.UploadID = state.UploadID
+		fresh.stale = true
+		return fresh, nil
+	}
+
+	return &state, nil
+}
+
+// Remove deletes the state file.
+func (st *UploadState) Remove() error {
+	return removeState(st.path)
+}
+
+// CompletedBytes returns the total size of the uploaded parts.
+func (st *UploadState) CompletedBytes() int64 {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var total int64
+	for _, part := range st.Parts {
+		total += part.Size
+	}
+	return total
+}
+
+func (st *UploadState) save() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.saveLocked()
+}
+
+func (st *UploadState) saveLocked() error {
+	return writeState(st.path, st)
+}
+
+func (st *UploadState) addPart(part UploadedPart) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.Parts = append(st.Parts, part)
+	return st.saveLocked()
+}
+
+func (st *UploadState) reset() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.UploadID = ""
+	st.RetryID = ""
+	st.PartSize = 0
+	st.Parts = nil
+	st.stale = false
+}
+
+func (st *UploadState) sortedParts() []UploadedPart {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	parts := make([]UploadedPart, len(st.Parts))
+	copy(parts, st.Parts)
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	return parts
+}
+
+// DownloadState is the persisted state of a resumable download. It describes
+// the byte ranges of the source object which are already written to the
+// partial file.
+type DownloadState struct {
+	// Source is the URL of the downloaded object. ETag and Size are recorded
+	// to detect changes on the source object between runs.
+	Source string      `json:"source"`
+	ETag   string      `json:"etag"`
+	Size   int64       `json:"size"`
+	Ranges []ByteRange `json:"ranges,omitempty"`
+
+	// fresh reports whether the partial file is not usable and must be
+	// truncated before downloading.
+	fresh bool
+	path  string
+	mu    sync.Mutex
+}
+
+// ByteRange is a half-open range of bytes, [Start, End).
+type ByteRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// LoadDownloadState reads the state of the partial file at given path for
+// the given source object. The state is kept in a sidecar file next to the
+// partial file. A fresh state is returned if there is no usable state or the
+// source object has been changed since the state is recorded.
+func LoadDownloadState(partPath string, source *Object) (*DownloadState, error) {
+	fresh := &DownloadState{
+		Source: source.URL.Absolute(),
+		ETag:   source.Etag,
+		Size:   source.Size,
+		fresh:  true,
+		path:   partPath + ".json",
+	}
+
+	// without an ETag, changes on the source object can't be detected.
+	if source.Etag == "" {
+		return fresh, nil
+	}
+
+	if _, err := os.Stat(partPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fresh, nil
+		}
+		return nil, err
+	}
+
+	data, err := os.ReadFile(fresh.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fresh, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state DownloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		// a corrupted state file can not be resumed.
+		return fresh, nil
+	}
+	state.path = fresh.path
+
+	if state.Source != fresh.Source || state.ETag != fresh.ETag || state.Size != fresh.Size {
+		return fresh, nil
+	}
+
+	return &state, nil
+}
+
+// IsFresh reports whether the download starts from scratch, which means any
+// existing content of the partial file must be discarded.
+func (st *DownloadState) IsFresh() bool {
+	return st.fresh
+}
+
+// Remove deletes the state file.
+func (st *DownloadState) Remove() error {
+	return removeState(st.path)
+}
+
+// CompletedBytes returns the total size of the downloaded ranges.
+func (st *DownloadState) CompletedBytes() int64 {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var total int64
+	for _, r := range st.Ranges {
+		total += r.End - r.Start
+	}
+	return total
+}
+
+func (st *DownloadState) addRange(r ByteRange) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.Ranges = append(st.Ranges, r)
+	sort.Slice(st.Ranges, func(i, j int) bool {
+		return st.Ranges[i].Start < st.Ranges[j].Start
+	})
+
+	// merge adjacent ranges to keep the state file small.
+	merged := st.Ranges[:1]
+	for _, r := range st.Ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	st.Ranges = merged
+
+	return writeState(st.path, st)
+}
+
+// missingRanges returns the ranges which are not downloaded yet, split into
+// chunks of at most partSize bytes.
+func (st *DownloadState) missingRanges(partSize int64) []ByteRange {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var (
+		missing []ByteRange
+		offset  int64
+	)
+	split := func(start, end int64) {
+		for ; start < end; start += partSize {
+			chunkEnd := start + partSize
+			if chunkEnd > end {
+				chunkEnd = end
+			}
+			missing = append(missing, ByteRange{Start: start, End: chunkEnd})
+		}
+	}
+
+	for _, r := range st.Ranges {
+		split(offset, r.Start)
+		offset = r.End
+	}
+	split(offset, st.Size)
+
+	return missing
+}
+
+// writeState writes the given state to the path as JSON. The state is written
+// to a temporary file first so that an interruption during write doesn't
+// leave a corrupted state behind.
+func writeState(path string, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func removeState(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
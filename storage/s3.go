@@ -14,6 +14,7 @@ import (
 	"net/http"
 	urlpkg "net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -52,6 +53,14 @@ const (
 	metadataKeyRetryID = "s5cmd-upload-retry-id"
 )
 
+// MaxCopyObjectSize is the size limit of the source objects which can be
+// copied with a single CopyObject request.
+const MaxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+// SSECustomerAlgorithm is the only algorithm supported for the server-side
+// encryption with customer-provided keys.
+const SSECustomerAlgorithm = "AES256"
+
 // Re-used AWS sessions dramatically improve performance.
 var globalSessionCache = &SessionCache{
 	sessions: map[Options]*session.Session{},
@@ -66,8 +75,17 @@ type S3 struct {
 	endpointURL            urlpkg.URL
 	dryRun                 bool
 	useListObjectsV1       bool
+	listRestoreStatus      bool
+	listPartitions         int
 	noSuchUploadRetryCount int
 	requestPayer           string
+	listingCache           *ListingCache
+	cacheListings          bool
+
+	// customer-provided keys of the objects encrypted with SSE-C. The copy
+	// source key is used for the source objects of the remote copies.
+	sseCustomerKey           string
+	copySourceSSECustomerKey string
 }
 
 func (s *S3) RequestPayer() *string {
@@ -77,6 +95,26 @@ func (s *S3) RequestPayer() *string {
 	return &s.requestPayer
 }
 
+// SSECustomerKey returns the algorithm and the key of the objects encrypted
+// with a customer-provided key. The SDK computes the MD5 digest of the key.
+func (s *S3) SSECustomerKey() (algorithm, key *string) {
+	return sseCustomerKey(s.sseCustomerKey)
+}
+
+// CopySourceSSECustomerKey returns the algorithm and the key of the source
+// objects of the remote copies which are encrypted with a customer-provided
+// key.
+func (s *S3) CopySourceSSECustomerKey() (algorithm, key *string) {
+	return sseCustomerKey(s.copySourceSSECustomerKey)
+}
+
+func sseCustomerKey(key string) (*string, *string) {
+	if key == "" {
+		return nil, nil
+	}
+	return aws.String(SSECustomerAlgorithm), aws.String(key)
+}
+
 func parseEndpoint(endpoint string) (urlpkg.URL, error) {
 	if endpoint == "" {
 		return sentinelURL, nil
@@ -109,8 +147,15 @@ func newS3Storage(ctx context.Context, opts Options) (*S3, error) {
 		endpointURL:            endpointURL,
 		dryRun:                 opts.DryRun,
 		useListObjectsV1:       opts.UseListObjectsV1,
+		listRestoreStatus:      opts.ListRestoreStatus,
+		listPartitions:         opts.ListPartitions,
 		requestPayer:           opts.RequestPayer,
 		noSuchUploadRetryCount: opts.NoSuchUploadRetryCount,
+		listingCache:           opts.ListingCache,
+		cacheListings:          opts.CacheListings,
+
+		sseCustomerKey:           opts.SSECustomerKey,
+		copySourceSSECustomerKey: opts.CopySourceSSECustomerKey,
 	}, nil
 }
 
@@ -121,6 +166,7 @@ func (s *S3) Stat(ctx context.Context, url *url.URL) (*Object, error) {
 		Key:          aws.String(url.Path),
 		RequestPayer: s.RequestPayer(),
 	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
 	if url.VersionID != "" {
 		input.SetVersionId(url.VersionID)
 	}
@@ -159,9 +205,24 @@ func (s *S3) List(ctx context.Context, url *url.URL, _ bool) <-chan *Object {
 	if url.VersionID != "" || url.AllVersions {
 		return s.listObjectVersions(ctx, url)
 	}
+	// the listings with a delimiter are not cached, since their directories
+	// can't be revalidated.
+	if s.cacheListings && s.listingCache != nil && url.Delimiter == "" && !s.listRestoreStatus {
+		return s.listingCache.List(ctx, s.endpointURL.String(), url, s.list, s.Stat)
+	}
+
+	return s.list(ctx, url)
+}
+
+// list lists the objects of the url with the ListObjects API in use.
+func (s *S3) list(ctx context.Context, url *url.URL) <-chan *Object {
 	if s.useListObjectsV1 {
 		return s.listObjects(ctx, url)
 	}
+	// the listings with a delimiter are paginated by the directories.
+	if s.listPartitions > 1 && url.Delimiter == "" {
+		return s.listObjectsV2Partitioned(ctx, url)
+	}
 
 	return s.listObjectsV2(ctx, url)
 }
@@ -176,6 +237,10 @@ func (s *S3) listObjectVersions(ctx context.Context, url *url.URL) <-chan *Objec
 		listInput.SetDelimiter(url.Delimiter)
 	}
 
+	if s.listRestoreStatus {
+		listInput.SetOptionalObjectAttributes(aws.StringSlice([]string{s3.OptionalObjectAttributesRestoreStatus}))
+	}
+
 	objCh := make(chan *Object)
 
 	go func() {
@@ -240,6 +305,7 @@ func (s *S3) listObjectVersions(ctx context.Context, url *url.URL) <-chan *Objec
 						Type:         ObjectType{objtype},
 						Size:         aws.Int64Value(v.Size),
 						StorageClass: StorageClass(aws.StringValue(v.StorageClass)),
+						Restore:      restoreStatus(v.RestoreStatus),
 					}
 
 					objectFound = true
@@ -306,6 +372,10 @@ func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 		listInput.SetDelimiter(url.Delimiter)
 	}
 
+	if s.listRestoreStatus {
+		listInput.SetOptionalObjectAttributes(aws.StringSlice([]string{s3.OptionalObjectAttributesRestoreStatus}))
+	}
+
 	objCh := make(chan *Object)
 
 	go func() {
@@ -342,30 +412,12 @@ func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 					continue
 				}
 
-				mod := aws.TimeValue(c.LastModified).UTC()
-				if mod.After(now) {
+				if aws.TimeValue(c.LastModified).After(now) {
 					objectFound = true
 					continue
 				}
 
-				var objtype os.FileMode
-				if strings.HasSuffix(key, "/") {
-					objtype = os.ModeDir
-				}
-
-				newurl := url.Clone()
-				newurl.Path = aws.StringValue(c.Key)
-				etag := aws.StringValue(c.ETag)
-
-				objCh <- &Object{
-					URL:          newurl,
-					Etag:         strings.Trim(etag, `"`),
-					ModTime:      &mod,
-					Type:         ObjectType{objtype},
-					Size:         aws.Int64Value(c.Size),
-					StorageClass: StorageClass(aws.StringValue(c.StorageClass)),
-				}
-
+				objCh <- listedObject(url, c)
 				objectFound = true
 			}
 
@@ -384,6 +436,45 @@ func (s *S3) listObjectsV2(ctx context.Context, url *url.URL) <-chan *Object {
 	return objCh
 }
 
+// invalidateListings marks the given keys of the bucket as modified in the
+// cached listings, since they are written or deleted by the client.
+func (s *S3) invalidateListings(bucket string, keys ...string) {
+	if s.listingCache == nil {
+		return
+	}
+
+	if err := s.listingCache.Invalidate(s.endpointURL.String(), bucket, keys...); err != nil {
+		msg := log.DebugMessage{Err: fmt.Sprintf("Failed to invalidate cached listings of %v: %q", bucket, err.Error())}
+		log.Debug(msg)
+	}
+}
+
+// listedObject returns the object of an item of the ListObjectsV2 results.
+// The item must be matched with the url beforehand.
+func listedObject(url *url.URL, c *s3.Object) *Object {
+	key := aws.StringValue(c.Key)
+
+	var objtype os.FileMode
+	if strings.HasSuffix(key, "/") {
+		objtype = os.ModeDir
+	}
+
+	newurl := url.Clone()
+	newurl.Path = key
+	etag := aws.StringValue(c.ETag)
+	mod := aws.TimeValue(c.LastModified).UTC()
+
+	return &Object{
+		URL:          newurl,
+		Etag:         strings.Trim(etag, `"`),
+		ModTime:      &mod,
+		Type:         ObjectType{objtype},
+		Size:         aws.Int64Value(c.Size),
+		StorageClass: StorageClass(aws.StringValue(c.StorageClass)),
+		Restore:      restoreStatus(c.RestoreStatus),
+	}
+}
+
 // listObjects is used for cloud services that does not support S3
 // ListObjectsV2 API. I'm looking at you GCS.
 func (s *S3) listObjects(ctx context.Context, url *url.URL) <-chan *Object {
@@ -397,6 +488,10 @@ func (s *S3) listObjects(ctx context.Context, url *url.URL) <-chan *Object {
 		listInput.SetDelimiter(url.Delimiter)
 	}
 
+	if s.listRestoreStatus {
+		listInput.SetOptionalObjectAttributes(aws.StringSlice([]string{s3.OptionalObjectAttributesRestoreStatus}))
+	}
+
 	objCh := make(chan *Object)
 
 	go func() {
@@ -455,6 +550,7 @@ func (s *S3) listObjects(ctx context.Context, url *url.URL) <-chan *Object {
 					Type:         ObjectType{objtype},
 					Size:         aws.Int64Value(c.Size),
 					StorageClass: StorageClass(aws.StringValue(c.StorageClass)),
+					Restore:      restoreStatus(c.RestoreStatus),
 				}
 
 				objectFound = true
@@ -481,6 +577,12 @@ func (s *S3) Copy(ctx context.Context, from, to *url.URL, metadata Metadata) err
 	if s.dryRun {
 		return nil
 	}
+	defer s.invalidateListings(to.Bucket, to.Path)
+
+	in, err := newObjectInput(metadata)
+	if err != nil {
+		return err
+	}
 
 	// SDK expects CopySource like "bucket[/key]"
 	copySource := from.EscapedPath()
@@ -491,6 +593,8 @@ func (s *S3) Copy(ctx context.Context, from, to *url.URL, metadata Metadata) err
 		CopySource:   aws.String(copySource),
 		RequestPayer: s.RequestPayer(),
 	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
+	input.CopySourceSSECustomerAlgorithm, input.CopySourceSSECustomerKey = s.CopySourceSSECustomerKey()
 	if from.VersionID != "" {
 		// Unlike many other *Input and *Output types version ID is not a field,
 		// but rather something that must be appended to CopySource string.
@@ -500,72 +604,194 @@ func (s *S3) Copy(ctx context.Context, from, to *url.URL, metadata Metadata) err
 		input.CopySource = aws.String(copySource + "?versionId=" + from.VersionID)
 	}
 
-	storageClass := metadata.StorageClass
-	if storageClass != "" {
-		input.StorageClass = aws.String(storageClass)
+	input.ACL = in.ACL
+	input.CacheControl = in.CacheControl
+	input.ContentDisposition = in.ContentDisposition
+	input.ContentEncoding = in.ContentEncoding
+	input.ContentLanguage = in.ContentLanguage
+	input.ContentType = in.ContentType
+	input.Expires = in.Expires
+	input.Metadata = in.Metadata
+	input.SSEKMSKeyId = in.SSEKMSKeyId
+	input.ServerSideEncryption = in.ServerSideEncryption
+	input.StorageClass = in.StorageClass
+	input.Tagging = in.Tagging
+
+	if input.Tagging != nil {
+		input.TaggingDirective = aws.String(s3.TaggingDirectiveReplace)
 	}
 
-	acl := metadata.ACL
-	if acl != "" {
-		input.ACL = aws.String(acl)
+	// add retry ID to the object metadata
+	if s.noSuchUploadRetryCount > 0 {
+		input.Metadata[metadataKeyRetryID] = generateRetryID()
 	}
 
-	cacheControl := metadata.CacheControl
-	if cacheControl != "" {
-		input.CacheControl = aws.String(cacheControl)
+	if metadata.Directive != "" {
+		input.MetadataDirective = aws.String(metadata.Directive)
 	}
 
-	expires := metadata.Expires
-	if expires != "" {
-		t, err := time.Parse(time.RFC3339, expires)
-		if err != nil {
-			return err
-		}
-		input.Expires = aws.Time(t)
+	_, err = s.api.CopyObject(input)
+	return err
+}
+
+// MultipartCopy copies the source object to the destination on the server
+// side with UploadPartCopy requests. CopyObject API rejects source objects
+// larger than MaxCopyObjectSize, such objects must be copied part by part.
+// Unlike CopyObject, the properties of the source object are not carried
+// over, they must be given in metadata. The ETag of the source is used to
+// ensure that the object is not modified during the copy.
+func (s *S3) MultipartCopy(
+	ctx context.Context,
+	from *Object,
+	to *url.URL,
+	metadata Metadata,
+	concurrency int,
+	partSize int64,
+) error {
+	if s.dryRun {
+		return nil
 	}
+	defer s.invalidateListings(to.Bucket, to.Path)
 
-	sseEncryption := metadata.EncryptionMethod
-	if sseEncryption != "" {
-		input.ServerSideEncryption = aws.String(sseEncryption)
-		sseKmsKeyID := metadata.EncryptionKeyID
-		if sseKmsKeyID != "" {
-			input.SSEKMSKeyId = aws.String(sseKmsKeyID)
-		}
+	size := from.Size
+	if partSize < s3manager.MinUploadPartSize {
+		partSize = s3manager.MinUploadPartSize
+	}
+	if size/partSize >= s3manager.MaxUploadParts {
+		partSize = size/s3manager.MaxUploadParts + 1
 	}
 
-	contentEncoding := metadata.ContentEncoding
-	if contentEncoding != "" {
-		input.ContentEncoding = aws.String(contentEncoding)
+	input, err := s.createMultipartUploadInput(to, metadata)
+	if err != nil {
+		return err
 	}
 
-	contentDisposition := metadata.ContentDisposition
-	if contentDisposition != "" {
-		input.ContentDisposition = aws.String(contentDisposition)
+	output, err := s.api.CreateMultipartUploadWithContext(ctx, input)
+	if err != nil {
+		return err
 	}
+	uploadID := aws.StringValue(output.UploadId)
 
-	// add retry ID to the object metadata
-	if s.noSuchUploadRetryCount > 0 {
-		input.Metadata[metadataKeyRetryID] = generateRetryID()
+	parts, err := s.copyParts(ctx, from, to, uploadID, concurrency, partSize)
+	if err == nil {
+		_, err = s.api.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          aws.String(to.Bucket),
+			Key:             aws.String(to.Path),
+			UploadId:        aws.String(uploadID),
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+			RequestPayer:    s.RequestPayer(),
+		})
+	}
+	if err == nil {
+		return nil
 	}
 
-	if metadata.Directive != "" {
-		input.MetadataDirective = aws.String(metadata.Directive)
+	// the parts which are copied so far are billed until the upload is
+	// aborted. the context might have been canceled already.
+	_, abortErr := s.api.AbortMultipartUploadWithContext(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:       aws.String(to.Bucket),
+		Key:          aws.String(to.Path),
+		UploadId:     aws.String(uploadID),
+		RequestPayer: s.RequestPayer(),
+	})
+	if abortErr != nil {
+		msg := log.DebugMessage{Err: fmt.Sprintf("Failed to abort multipart copy of %v: %q", to, abortErr.Error())}
+		log.Debug(msg)
 	}
+	return err
+}
 
-	if metadata.ContentType != "" {
-		input.ContentType = aws.String(metadata.ContentType)
+// copyParts copies the source object in ranges of partSize bytes to the
+// multipart upload with the given ID.
+func (s *S3) copyParts(
+	ctx context.Context,
+	from *Object,
+	to *url.URL,
+	uploadID string,
+	concurrency int,
+	partSize int64,
+) ([]*s3.CompletedPart, error) {
+	// SDK expects CopySource like "bucket[/key]"
+	copySource := from.URL.EscapedPath()
+	if from.URL.VersionID != "" {
+		copySource += "?versionId=" + from.URL.VersionID
 	}
 
-	if len(metadata.UserDefined) != 0 {
-		m := make(map[string]*string)
-		for k, v := range metadata.UserDefined {
-			m[k] = aws.String(v)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		merr     error
+		sem      = make(chan struct{}, concurrency)
+		numParts = (from.Size + partSize - 1) / partSize
+		parts    = make([]*s3.CompletedPart, numParts)
+	)
+
+	for partNumber := int64(1); partNumber <= numParts; partNumber++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
 		}
-		input.Metadata = m
+
+		wg.Add(1)
+		go func(partNumber int64) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			offset := (partNumber - 1) * partSize
+			length := partLength(from.Size, partSize, partNumber)
+
+			input := &s3.UploadPartCopyInput{
+				Bucket:          aws.String(to.Bucket),
+				Key:             aws.String(to.Path),
+				UploadId:        aws.String(uploadID),
+				PartNumber:      aws.Int64(partNumber),
+				CopySource:      aws.String(copySource),
+				CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+				RequestPayer:    s.RequestPayer(),
+			}
+			input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
+			input.CopySourceSSECustomerAlgorithm, input.CopySourceSSECustomerKey = s.CopySourceSSECustomerKey()
+			if from.Etag != "" {
+				input.CopySourceIfMatch = aws.String(strconv.Quote(from.Etag))
+			}
+
+			output, err := s.api.UploadPartCopyWithContext(ctx, input)
+			if err == nil {
+				parts[partNumber-1] = &s3.CompletedPart{
+					ETag:       output.CopyPartResult.ETag,
+					PartNumber: aws.Int64(partNumber),
+				}
+			}
+			if errHasCode(err, "PreconditionFailed") {
+				err = ErrObjectModified
+			}
+
+			if err != nil {
+				mu.Lock()
+				if merr == nil {
+					merr = err
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}(partNumber)
 	}
 
-	_, err := s.api.CopyObject(input)
-	return err
+	wg.Wait()
+
+	if merr != nil {
+		return nil, merr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return parts, nil
 }
 
 // Read fetches the remote object and returns its contents as an io.ReadCloser.
@@ -575,6 +801,7 @@ func (s *S3) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
 		Key:          aws.String(src.Path),
 		RequestPayer: s.RequestPayer(),
 	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
 	if src.VersionID != "" {
 		input.SetVersionId(src.VersionID)
 	}
@@ -586,12 +813,16 @@ func (s *S3) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
 	return resp.Body, nil
 }
 
+// Presign returns a presigned URL to download the object. If the object is
+// encrypted with a customer-provided key, the SSE-C headers are signed too and
+// they must be sent along with the requests to the URL.
 func (s *S3) Presign(ctx context.Context, from *url.URL, expire time.Duration) (string, error) {
 	input := &s3.GetObjectInput{
 		Bucket:       aws.String(from.Bucket),
 		Key:          aws.String(from.Path),
 		RequestPayer: s.RequestPayer(),
 	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
 
 	req, _ := s.api.GetObjectRequest(input)
 
@@ -617,6 +848,7 @@ func (s *S3) Get(
 		Key:          aws.String(from.Path),
 		RequestPayer: s.RequestPayer(),
 	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
 	if from.VersionID != "" {
 		input.VersionId = aws.String(from.VersionID)
 	}
@@ -627,6 +859,112 @@ func (s *S3) Get(
 	})
 }
 
+// GetResumable is a download operation which records the downloaded byte
+// ranges to the given state, so that an interrupted download can be
+// continued by fetching only the missing ranges. Ranges are requested with
+// If-Match condition, and ErrObjectModified is returned if the object has
+// been changed since the state is recorded.
+func (s *S3) GetResumable(
+	ctx context.Context,
+	from *url.URL,
+	to io.WriterAt,
+	concurrency int,
+	partSize int64,
+	state *DownloadState,
+	resumed func(completed int64),
+) (int64, error) {
+	if s.dryRun {
+		return 0, nil
+	}
+
+	if resumed != nil {
+		resumed(state.CompletedBytes())
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		merr error
+		sem  = make(chan struct{}, concurrency)
+	)
+
+	for _, r := range state.missingRanges(partSize) {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(r ByteRange) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := s.getRange(ctx, from, to, state.ETag, r)
+			if err == nil {
+				err = state.addRange(r)
+			}
+
+			if err != nil {
+				mu.Lock()
+				if merr == nil {
+					merr = err
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}(r)
+	}
+
+	wg.Wait()
+
+	if merr != nil {
+		return 0, merr
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return state.Size, state.Remove()
+}
+
+func (s *S3) getRange(ctx context.Context, from *url.URL, to io.WriterAt, etag string, r ByteRange) error {
+	input := &s3.GetObjectInput{
+		Bucket:       aws.String(from.Bucket),
+		Key:          aws.String(from.Path),
+		Range:        aws.String(fmt.Sprintf("bytes=%d-%d", r.Start, r.End-1)),
+		IfMatch:      aws.String(strconv.Quote(etag)),
+		RequestPayer: s.RequestPayer(),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
+	if from.VersionID != "" {
+		input.VersionId = aws.String(from.VersionID)
+	}
+
+	output, err := s.api.GetObjectWithContext(ctx, input)
+	if err != nil {
+		if errHasCode(err, "PreconditionFailed") {
+			return ErrObjectModified
+		}
+		return err
+	}
+	defer output.Body.Close()
+
+	n, err := io.Copy(io.NewOffsetWriter(to, r.Start), output.Body)
+	if err != nil {
+		return err
+	}
+	if n != r.End-r.Start {
+		return fmt.Errorf("unexpected size of range %d-%d: %d bytes", r.Start, r.End-1, n)
+	}
+	return nil
+}
+
 type SelectQuery struct {
 	InputFormat           string
 	InputContentStructure string
@@ -747,6 +1085,7 @@ func (s *S3) Select(ctx context.Context, url *url.URL, query *SelectQuery, resul
 		InputSerialization:  inputFormat,
 		OutputSerialization: outputFormat,
 	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
 
 	resp, err := s.api.SelectObjectContentWithContext(ctx, input)
 	if err != nil {
@@ -802,120 +1141,483 @@ func (s *S3) Put(
 	if s.dryRun {
 		return nil
 	}
+	defer s.invalidateListings(to.Bucket, to.Path)
 
-	contentType := metadata.ContentType
-	if contentType == "" {
-		contentType = "application/octet-stream"
+	in, err := newObjectInput(metadata)
+	if err != nil {
+		return err
+	}
+	if in.ContentType == nil {
+		in.ContentType = aws.String("application/octet-stream")
 	}
 
 	input := &s3manager.UploadInput{
-		Bucket:       aws.String(to.Bucket),
-		Key:          aws.String(to.Path),
-		Body:         reader,
-		ContentType:  aws.String(contentType),
-		Metadata:     make(map[string]*string),
-		RequestPayer: s.RequestPayer(),
+		Bucket:               aws.String(to.Bucket),
+		Key:                  aws.String(to.Path),
+		Body:                 reader,
+		ACL:                  in.ACL,
+		CacheControl:         in.CacheControl,
+		ContentDisposition:   in.ContentDisposition,
+		ContentEncoding:      in.ContentEncoding,
+		ContentLanguage:      in.ContentLanguage,
+		ContentType:          in.ContentType,
+		Expires:              in.Expires,
+		Metadata:             in.Metadata,
+		SSEKMSKeyId:          in.SSEKMSKeyId,
+		ServerSideEncryption: in.ServerSideEncryption,
+		StorageClass:         in.StorageClass,
+		Tagging:              in.Tagging,
+		RequestPayer:         s.RequestPayer(),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
+
+	// add retry ID to the object metadata
+	if s.noSuchUploadRetryCount > 0 {
+		input.Metadata[metadataKeyRetryID] = generateRetryID()
 	}
 
-	storageClass := metadata.StorageClass
-	if storageClass != "" {
-		input.StorageClass = aws.String(storageClass)
+	uploaderOptsFn := func(u *s3manager.Uploader) {
+		u.PartSize = partSize
+		u.Concurrency = concurrency
+	}
+	upload := func() error {
+		_, err := s.uploader.UploadWithContext(ctx, input, uploaderOptsFn)
+		return err
 	}
+	err = upload()
 
-	acl := metadata.ACL
-	if acl != "" {
-		input.ACL = aws.String(acl)
+	if errHasCode(err, s3.ErrCodeNoSuchUpload) && s.noSuchUploadRetryCount > 0 {
+		retryID := func() string { return aws.StringValue(input.Metadata[metadataKeyRetryID]) }
+		return s.retryOnNoSuchUpload(ctx, to, retryID, err, upload)
 	}
 
-	cacheControl := metadata.CacheControl
-	if cacheControl != "" {
-		input.CacheControl = aws.String(cacheControl)
+	return err
+}
+
+// retryOnNoSuchUpload calls upload again as long as it fails with
+// NoSuchUpload error, up to the configured number of times. retryID returns
+// the retry ID of the last attempt, which is used to detect the attempts that
+// succeeded despite the received error.
+func (s *S3) retryOnNoSuchUpload(ctx aws.Context, to *url.URL, retryID func() string,
+	err error, upload func() error,
+) error {
+	attempts := 0
+	for ; errHasCode(err, s3.ErrCodeNoSuchUpload) && attempts < s.noSuchUploadRetryCount; attempts++ {
+		// check if object exists and has the retry ID we provided, if it does
+		// then it means that one of previous uploads was succesfull despite the received error.
+		obj, sErr := s.Stat(ctx, to)
+		if expectedRetryID := retryID(); sErr == nil && expectedRetryID != "" && obj.retryID == expectedRetryID {
+			err = nil
+			break
+		}
+
+		msg := log.DebugMessage{Err: fmt.Sprintf("Retrying to upload %v upon error: %q", to, err.Error())}
+		log.Debug(msg)
+
+		err = upload()
 	}
 
-	expires := metadata.Expires
-	if expires != "" {
-		t, err := time.Parse(time.RFC3339, expires)
-		if err != nil {
+	if errHasCode(err, s3.ErrCodeNoSuchUpload) && s.noSuchUploadRetryCount > 0 {
+		err = awserr.New(s3.ErrCodeNoSuchUpload, fmt.Sprintf(
+			"RetryOnNoSuchUpload: %v attempts to retry resulted in %v", attempts,
+			s3.ErrCodeNoSuchUpload), err)
+	}
+	return err
+}
+
+// PutResumable is a multipart upload operation which records its progress to
+// the given state, so that an interrupted upload can be continued from the
+// last uploaded part. Parts which are recorded in the state are verified
+// against the parts that S3 has before resuming. If the upload can't be
+// resumed, a new one is started.
+func (s *S3) PutResumable(
+	ctx context.Context,
+	reader io.ReaderAt,
+	to *url.URL,
+	metadata Metadata,
+	concurrency int,
+	partSize int64,
+	state *UploadState,
+	resumed func(completed int64),
+) error {
+	if s.dryRun {
+		return nil
+	}
+	defer s.invalidateListings(to.Bucket, to.Path)
+
+	size := state.Size
+
+	// the uploader would increase the part size if the object can't fit into
+	// the maximum number of parts allowed.
+	if size/partSize >= s3manager.MaxUploadParts {
+		partSize = size/s3manager.MaxUploadParts + 1
+	}
+
+	// objects which fit into a single part are not uploaded with multipart
+	// uploads, so there is nothing to resume.
+	if size <= partSize {
+		if err := s.Put(ctx, io.NewSectionReader(reader, 0, size), to, metadata, concurrency, partSize); err != nil {
 			return err
 		}
-		input.Expires = aws.Time(t)
+		return state.Remove()
 	}
 
-	sseEncryption := metadata.EncryptionMethod
-	if sseEncryption != "" {
-		input.ServerSideEncryption = aws.String(sseEncryption)
-		sseKmsKeyID := metadata.EncryptionKeyID
-		if sseKmsKeyID != "" {
-			input.SSEKMSKeyId = aws.String(sseKmsKeyID)
-		}
+	resume := func() error {
+		return s.resumeMultipartUpload(ctx, reader, to, metadata, concurrency, partSize, state, resumed)
 	}
+	err := resume()
 
-	contentEncoding := metadata.ContentEncoding
-	if contentEncoding != "" {
-		input.ContentEncoding = aws.String(contentEncoding)
+	if errHasCode(err, s3.ErrCodeNoSuchUpload) && s.noSuchUploadRetryCount > 0 {
+		retryID := func() string { return state.RetryID }
+		err = s.retryOnNoSuchUpload(ctx, to, retryID, err, func() error {
+			state.reset()
+			return resume()
+		})
 	}
 
-	contentDisposition := metadata.ContentDisposition
-	if contentDisposition != "" {
-		input.ContentDisposition = aws.String(contentDisposition)
+	if err != nil {
+		return err
 	}
 
-	// add retry ID to the object metadata
-	if s.noSuchUploadRetryCount > 0 {
-		input.Metadata[metadataKeyRetryID] = generateRetryID()
+	return state.Remove()
+}
+
+func (s *S3) resumeMultipartUpload(
+	ctx context.Context,
+	reader io.ReaderAt,
+	to *url.URL,
+	metadata Metadata,
+	concurrency int,
+	partSize int64,
+	state *UploadState,
+	resumed func(completed int64),
+) error {
+	if state.UploadID != "" {
+		var reason string
+		switch {
+		case state.stale:
+			reason = "source file has been changed"
+		case state.Bucket != to.Bucket || state.Key != to.Path:
+			reason = "destination has been changed"
+		case state.PartSize != partSize:
+			reason = "part size has been changed"
+		}
+
+		if reason != "" {
+			msg := log.DebugMessage{Err: fmt.Sprintf("Not resuming upload of %v: %v", to, reason)}
+			log.Debug(msg)
+
+			// abort the previous upload not to be charged for its parts. It
+			// is not fatal since the parts are cleaned up by the bucket's
+			// lifecycle rules, if any.
+			_, _ = s.api.AbortMultipartUploadWithContext(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:       aws.String(state.Bucket),
+				Key:          aws.String(state.Key),
+				UploadId:     aws.String(state.UploadID),
+				RequestPayer: s.RequestPayer(),
+			})
+			state.reset()
+		}
 	}
 
-	if len(metadata.UserDefined) != 0 {
-		m := make(map[string]*string)
-		for k, v := range metadata.UserDefined {
-			m[k] = aws.String(v)
+	if state.UploadID != "" {
+		if err := s.verifyUploadedParts(ctx, to, partSize, state); err != nil {
+			if !errHasCode(err, s3.ErrCodeNoSuchUpload) {
+				return err
+			}
+
+			msg := log.DebugMessage{Err: fmt.Sprintf("Not resuming upload of %v: upload does not exist", to)}
+			log.Debug(msg)
+			state.reset()
 		}
-		input.Metadata = m
 	}
 
-	uploaderOptsFn := func(u *s3manager.Uploader) {
-		u.PartSize = partSize
-		u.Concurrency = concurrency
+	if state.UploadID == "" {
+		input, err := s.createMultipartUploadInput(to, metadata)
+		if err != nil {
+			return err
+		}
+		if s.noSuchUploadRetryCount > 0 {
+			retryID := generateRetryID()
+			state.RetryID = *retryID
+			input.Metadata[metadataKeyRetryID] = retryID
+		}
+
+		output, err := s.api.CreateMultipartUploadWithContext(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		state.Bucket = to.Bucket
+		state.Key = to.Path
+		state.UploadID = aws.StringValue(output.UploadId)
+		state.PartSize = partSize
+		if err := state.save(); err != nil {
+			return err
+		}
 	}
-	_, err := s.uploader.UploadWithContext(ctx, input, uploaderOptsFn)
 
-	if errHasCode(err, s3.ErrCodeNoSuchUpload) && s.noSuchUploadRetryCount > 0 {
-		return s.retryOnNoSuchUpload(ctx, to, input, err, uploaderOptsFn)
+	if resumed != nil {
+		resumed(state.CompletedBytes())
 	}
 
+	if err := s.uploadMissingParts(ctx, reader, to, concurrency, partSize, state); err != nil {
+		return err
+	}
+
+	parts := state.sortedParts()
+	completedParts := make([]*s3.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completedParts = append(completedParts, &s3.CompletedPart{
+			ETag:       aws.String(part.ETag),
+			PartNumber: aws.Int64(part.PartNumber),
+		})
+	}
+
+	_, err := s.api.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(to.Bucket),
+		Key:             aws.String(to.Path),
+		UploadId:        aws.String(state.UploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completedParts},
+		RequestPayer:    s.RequestPayer(),
+	})
 	return err
 }
 
-func (s *S3) retryOnNoSuchUpload(ctx aws.Context, to *url.URL, input *s3manager.UploadInput,
-	err error, uploaderOpts ...func(*s3manager.Uploader),
+// verifyUploadedParts lists the parts of the recorded upload and keeps only
+// the ones that S3 has. Parts which are uploaded but not recorded due to an
+// interruption are adopted.
+func (s *S3) verifyUploadedParts(ctx context.Context, to *url.URL, partSize int64, state *UploadState) error {
+	recorded := map[int64]UploadedPart{}
+	for _, part := range state.Parts {
+		recorded[part.PartNumber] = part
+	}
+
+	var parts []UploadedPart
+	input := &s3.ListPartsInput{
+		Bucket:       aws.String(to.Bucket),
+		Key:          aws.String(to.Path),
+		UploadId:     aws.String(state.UploadID),
+		RequestPayer: s.RequestPayer(),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
+	err := s.api.ListPartsPagesWithContext(ctx, input, func(p *s3.ListPartsOutput, lastPage bool) bool {
+		for _, p := range p.Parts {
+			part := UploadedPart{
+				PartNumber: aws.Int64Value(p.PartNumber),
+				ETag:       aws.StringValue(p.ETag),
+				Size:       aws.Int64Value(p.Size),
+			}
+
+			if r, ok := recorded[part.PartNumber]; ok && r.ETag != part.ETag {
+				continue
+			}
+			if part.Size != partLength(state.Size, partSize, part.PartNumber) {
+				continue
+			}
+			parts = append(parts, part)
+		}
+		return !lastPage
+	})
+	if err != nil {
+		return err
+	}
+
+	state.mu.Lock()
+	state.Parts = parts
+	state.mu.Unlock()
+
+	return state.save()
+}
+
+// uploadMissingParts uploads the parts which are not in the state
+// concurrently, recording each uploaded part to the state.
+func (s *S3) uploadMissingParts(
+	ctx context.Context,
+	reader io.ReaderAt,
+	to *url.URL,
+	concurrency int,
+	partSize int64,
+	state *UploadState,
 ) error {
-	var expectedRetryID string
-	if ID, ok := input.Metadata[metadataKeyRetryID]; ok {
-		expectedRetryID = *ID
+	uploaded := map[int64]struct{}{}
+	for _, part := range state.sortedParts() {
+		uploaded[part.PartNumber] = struct{}{}
 	}
 
-	attempts := 0
-	for ; errHasCode(err, s3.ErrCodeNoSuchUpload) && attempts < s.noSuchUploadRetryCount; attempts++ {
-		// check if object exists and has the retry ID we provided, if it does
-		// then it means that one of previous uploads was succesfull despite the received error.
-		obj, sErr := s.Stat(ctx, to)
-		if sErr == nil && obj.retryID == expectedRetryID {
-			err = nil
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		merr     error
+		sem      = make(chan struct{}, concurrency)
+		numParts = (state.Size + partSize - 1) / partSize
+	)
+
+	for partNumber := int64(1); partNumber <= numParts; partNumber++ {
+		if _, ok := uploaded[partNumber]; ok {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
 			break
 		}
 
-		msg := log.DebugMessage{Err: fmt.Sprintf("Retrying to upload %v upon error: %q", to, err.Error())}
-		log.Debug(msg)
+		wg.Add(1)
+		go func(partNumber int64) {
+			defer wg.Done()
+			defer func() { <-sem }()
 
-		_, err = s.uploader.UploadWithContext(ctx, input, uploaderOpts...)
+			offset := (partNumber - 1) * partSize
+			length := partLength(state.Size, partSize, partNumber)
+
+			input := &s3.UploadPartInput{
+				Bucket:       aws.String(to.Bucket),
+				Key:          aws.String(to.Path),
+				UploadId:     aws.String(state.UploadID),
+				PartNumber:   aws.Int64(partNumber),
+				Body:         io.NewSectionReader(reader, offset, length),
+				RequestPayer: s.RequestPayer(),
+			}
+			input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
+
+			output, err := s.api.UploadPartWithContext(ctx, input)
+			if err == nil {
+				err = state.addPart(UploadedPart{
+					PartNumber: partNumber,
+					ETag:       aws.StringValue(output.ETag),
+					Size:       length,
+				})
+			}
+
+			if err != nil {
+				mu.Lock()
+				if merr == nil {
+					merr = err
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}(partNumber)
 	}
 
-	if errHasCode(err, s3.ErrCodeNoSuchUpload) && s.noSuchUploadRetryCount > 0 {
-		err = awserr.New(s3.ErrCodeNoSuchUpload, fmt.Sprintf(
-			"RetryOnNoSuchUpload: %v attempts to retry resulted in %v", attempts,
-			s3.ErrCodeNoSuchUpload), err)
+	wg.Wait()
+
+	if merr != nil {
+		return merr
 	}
-	return err
+	return ctx.Err()
+}
+
+// partLength returns the length of the part with the given number.
+func partLength(size, partSize, partNumber int64) int64 {
+	offset := (partNumber - 1) * partSize
+	if size-offset < partSize {
+		return size - offset
+	}
+	return partSize
+}
+
+func (s *S3) createMultipartUploadInput(to *url.URL, metadata Metadata) (*s3.CreateMultipartUploadInput, error) {
+	in, err := newObjectInput(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if in.ContentType == nil {
+		in.ContentType = aws.String("application/octet-stream")
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(to.Bucket),
+		Key:                  aws.String(to.Path),
+		ACL:                  in.ACL,
+		CacheControl:         in.CacheControl,
+		ContentDisposition:   in.ContentDisposition,
+		ContentEncoding:      in.ContentEncoding,
+		ContentLanguage:      in.ContentLanguage,
+		ContentType:          in.ContentType,
+		Expires:              in.Expires,
+		Metadata:             in.Metadata,
+		SSEKMSKeyId:          in.SSEKMSKeyId,
+		ServerSideEncryption: in.ServerSideEncryption,
+		StorageClass:         in.StorageClass,
+		Tagging:              in.Tagging,
+		RequestPayer:         s.RequestPayer(),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
+
+	return input, nil
+}
+
+// objectInput holds the properties of an object which are common to the
+// inputs of the upload and copy requests, so that the given metadata is
+// mapped to all of them in the same way.
+type objectInput struct {
+	ACL                  *string
+	CacheControl         *string
+	ContentDisposition   *string
+	ContentEncoding      *string
+	ContentLanguage      *string
+	ContentType          *string
+	Expires              *time.Time
+	Metadata             map[string]*string
+	SSEKMSKeyId          *string
+	ServerSideEncryption *string
+	StorageClass         *string
+	Tagging              *string
+}
+
+// newObjectInput maps the given metadata to the object properties. The
+// properties which are not given are left nil.
+func newObjectInput(metadata Metadata) (*objectInput, error) {
+	in := &objectInput{
+		Metadata: make(map[string]*string),
+	}
+
+	for dst, value := range map[**string]string{
+		&in.ACL:                metadata.ACL,
+		&in.CacheControl:       metadata.CacheControl,
+		&in.ContentDisposition: metadata.ContentDisposition,
+		&in.ContentEncoding:    metadata.ContentEncoding,
+		&in.ContentLanguage:    metadata.ContentLanguage,
+		&in.ContentType:        metadata.ContentType,
+		&in.StorageClass:       metadata.StorageClass,
+	} {
+		if value != "" {
+			*dst = aws.String(value)
+		}
+	}
+
+	if metadata.Expires != "" {
+		t, err := time.Parse(time.RFC3339, metadata.Expires)
+		if err != nil {
+			return nil, err
+		}
+		in.Expires = aws.Time(t)
+	}
+
+	if metadata.EncryptionMethod != "" {
+		in.ServerSideEncryption = aws.String(metadata.EncryptionMethod)
+		if metadata.EncryptionKeyID != "" {
+			in.SSEKMSKeyId = aws.String(metadata.EncryptionKeyID)
+		}
+	}
+
+	if len(metadata.Tags) != 0 {
+		in.Tagging = aws.String(encodeTags(metadata.Tags))
+	}
+
+	for k, v := range metadata.UserDefined {
+		in.Metadata[k] = aws.String(v)
+	}
+
+	return in, nil
 }
 
 // chunk is an object identifier container which is used on MultiDelete
@@ -1005,6 +1707,14 @@ func (s *S3) doDelete(ctx context.Context, chunk chunk, resultch chan *Object) {
 		return
 	}
 
+	defer func() {
+		keys := make([]string, 0, len(chunk.Keys))
+		for _, k := range chunk.Keys {
+			keys = append(keys, aws.StringValue(k.Key))
+		}
+		s.invalidateListings(chunk.Bucket, keys...)
+	}()
+
 	// GCS does not support multi delete.
 	if IsGoogleEndpoint(s.endpointURL) {
 		for _, k := range chunk.Keys {
@@ -1174,12 +1884,17 @@ func (s *S3) HeadObject(ctx context.Context, url *url.URL) (*Object, *Metadata,
 		Key:          aws.String(url.Path),
 		RequestPayer: s.RequestPayer(),
 	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey = s.SSECustomerKey()
 
 	if url.VersionID != "" {
 		input.SetVersionId(url.VersionID)
 	}
 
-	output, err := s.api.HeadObjectWithContext(ctx, input)
+	// the number of tags is not exposed by the SDK, it is read from the
+	// response headers.
+	req, output := s.api.HeadObjectRequest(input)
+	req.SetContext(ctx)
+	err := req.Send()
 	if err != nil {
 		if errHasCode(err, "NotFound") {
 			return nil, nil, &ErrGivenObjectNotFound{ObjectAbsPath: url.Absolute()}
@@ -1200,17 +1915,227 @@ func (s *S3) HeadObject(ctx context.Context, url *url.URL) (*Object, *Metadata,
 		Etag:         strings.Trim(aws.StringValue(output.ETag), `"`),
 		Size:         aws.Int64Value(output.ContentLength),
 		StorageClass: StorageClass(storageClassStr),
+		Restore:      parseRestoreHeader(aws.StringValue(output.Restore)),
 	}
 
 	metadata := &Metadata{
-		ContentType:      aws.StringValue(output.ContentType),
-		EncryptionMethod: aws.StringValue(output.ServerSideEncryption),
-		UserDefined:      aws.StringValueMap(output.Metadata),
+		CacheControl:       aws.StringValue(output.CacheControl),
+		StorageClass:       storageClassStr,
+		ContentType:        aws.StringValue(output.ContentType),
+		ContentEncoding:    aws.StringValue(output.ContentEncoding),
+		ContentDisposition: aws.StringValue(output.ContentDisposition),
+		ContentLanguage:    aws.StringValue(output.ContentLanguage),
+		EncryptionMethod:   aws.StringValue(output.ServerSideEncryption),
+		EncryptionKeyID:    aws.StringValue(output.SSEKMSKeyId),
+		UserDefined:        aws.StringValueMap(output.Metadata),
+	}
+
+	if req.HTTPResponse != nil {
+		tagCount := req.HTTPResponse.Header.Get("X-Amz-Tagging-Count")
+		metadata.TagCount, _ = strconv.ParseInt(tagCount, 10, 64)
+	}
+
+	// Expires header is returned in HTTP date format, but it is represented
+	// in RFC3339 format throughout s5cmd.
+	if expires := aws.StringValue(output.Expires); expires != "" {
+		if t, err := http.ParseTime(expires); err == nil {
+			metadata.Expires = t.UTC().Format(time.RFC3339)
+		}
 	}
 
 	return obj, metadata, nil
 }
 
+// RestoreObject initiates a restore request for the given archived object.
+// The restored copy is available for the given number of days. Restoring an
+// object which is being restored already is not an error.
+func (s *S3) RestoreObject(ctx context.Context, url *url.URL, days int64, tier string) error {
+	if s.dryRun {
+		return nil
+	}
+
+	input := &s3.RestoreObjectInput{
+		Bucket: aws.String(url.Bucket),
+		Key:    aws.String(url.Path),
+		RestoreRequest: &s3.RestoreRequest{
+			Days: aws.Int64(days),
+			GlacierJobParameters: &s3.GlacierJobParameters{
+				Tier: aws.String(tier),
+			},
+		},
+		RequestPayer: s.RequestPayer(),
+	}
+	if url.VersionID != "" {
+		input.SetVersionId(url.VersionID)
+	}
+
+	_, err := s.api.RestoreObjectWithContext(ctx, input)
+	if errHasCode(err, "RestoreAlreadyInProgress") {
+		return nil
+	}
+	return err
+}
+
+// restoreStatus converts the restore status returned by the list APIs.
+func restoreStatus(status *s3.RestoreStatus) *RestoreStatus {
+	if status == nil {
+		return nil
+	}
+	return &RestoreStatus{
+		InProgress: aws.BoolValue(status.IsRestoreInProgress),
+		ExpiryDate: status.RestoreExpiryDate,
+	}
+}
+
+// parseRestoreHeader parses the x-amz-restore header, which looks like
+// 'ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"'.
+// nil is returned if the object is not restored.
+func parseRestoreHeader(header string) *RestoreStatus {
+	if header == "" {
+		return nil
+	}
+
+	status := &RestoreStatus{
+		InProgress: strings.Contains(header, `ongoing-request="true"`),
+	}
+
+	const expiryDate = `expiry-date="`
+	if i := strings.Index(header, expiryDate); i >= 0 {
+		value := header[i+len(expiryDate):]
+		if j := strings.Index(value, `"`); j >= 0 {
+			if t, err := http.ParseTime(value[:j]); err == nil {
+				t = t.UTC()
+				status.ExpiryDate = &t
+			}
+		}
+	}
+	return status
+}
+
+// GetObjectTagging returns the tag set of the given object.
+func (s *S3) GetObjectTagging(ctx context.Context, url *url.URL) (map[string]string, error) {
+	input := &s3.GetObjectTaggingInput{
+		Bucket:       aws.String(url.Bucket),
+		Key:          aws.String(url.Path),
+		RequestPayer: s.RequestPayer(),
+	}
+	if url.VersionID != "" {
+		input.SetVersionId(url.VersionID)
+	}
+
+	output, err := s.api.GetObjectTaggingWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(output.TagSet))
+	for _, tag := range output.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+// PutObjectTagging replaces the tag set of the given object.
+func (s *S3) PutObjectTagging(ctx context.Context, url *url.URL, tags map[string]string) error {
+	if s.dryRun {
+		return nil
+	}
+
+	tagSet := make([]*s3.Tag, 0, len(tags))
+	for k, v := range tags {
+		tagSet = append(tagSet, &s3.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	sort.Slice(tagSet, func(i, j int) bool {
+		return aws.StringValue(tagSet[i].Key) < aws.StringValue(tagSet[j].Key)
+	})
+
+	input := &s3.PutObjectTaggingInput{
+		Bucket:       aws.String(url.Bucket),
+		Key:          aws.String(url.Path),
+		Tagging:      &s3.Tagging{TagSet: tagSet},
+		RequestPayer: s.RequestPayer(),
+	}
+	if url.VersionID != "" {
+		input.SetVersionId(url.VersionID)
+	}
+
+	_, err := s.api.PutObjectTaggingWithContext(ctx, input)
+	return err
+}
+
+// DeleteObjectTagging removes all the tags of the given object.
+func (s *S3) DeleteObjectTagging(ctx context.Context, url *url.URL) error {
+	if s.dryRun {
+		return nil
+	}
+
+	input := &s3.DeleteObjectTaggingInput{
+		Bucket: aws.String(url.Bucket),
+		Key:    aws.String(url.Path),
+	}
+	if url.VersionID != "" {
+		input.SetVersionId(url.VersionID)
+	}
+
+	_, err := s.api.DeleteObjectTaggingWithContext(ctx, input)
+	return err
+}
+
+// ObjectACL is the access control policy of an object, which consists of the
+// owner of the object and the grants.
+type ObjectACL struct {
+	policy *s3.AccessControlPolicy
+}
+
+// GetObjectACL returns the access control policy of the given object.
+func (s *S3) GetObjectACL(ctx context.Context, url *url.URL) (*ObjectACL, error) {
+	input := &s3.GetObjectAclInput{
+		Bucket:       aws.String(url.Bucket),
+		Key:          aws.String(url.Path),
+		RequestPayer: s.RequestPayer(),
+	}
+	if url.VersionID != "" {
+		input.SetVersionId(url.VersionID)
+	}
+
+	output, err := s.api.GetObjectAclWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectACL{
+		policy: &s3.AccessControlPolicy{
+			Owner:  output.Owner,
+			Grants: output.Grants,
+		},
+	}, nil
+}
+
+// PutObjectACL replaces the access control policy of the given object.
+func (s *S3) PutObjectACL(ctx context.Context, url *url.URL, acl *ObjectACL) error {
+	if s.dryRun {
+		return nil
+	}
+
+	_, err := s.api.PutObjectAclWithContext(ctx, &s3.PutObjectAclInput{
+		Bucket:              aws.String(url.Bucket),
+		Key:                 aws.String(url.Path),
+		AccessControlPolicy: acl.policy,
+		RequestPayer:        s.RequestPayer(),
+	})
+	return err
+}
+
+// encodeTags encodes the given tags as URL query parameters, which is the
+// format expected by the x-amz-tagging header.
+func encodeTags(tags map[string]string) string {
+	values := urlpkg.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
 type sdkLogger struct{}
 
 func (l sdkLogger) Log(args ...interface{}) {
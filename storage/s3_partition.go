@@ -0,0 +1,354 @@
+package storage
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	// partitionAlphabet is the characters which the key space is sampled
+	// with. The keys which start with other characters still belong to the
+	// partitions of the closest characters.
+	partitionAlphabet = "!\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+
+	// maxPartitionDepth is the maximum length of the common prefix of the
+	// keys which is skipped to sample the key space.
+	maxPartitionDepth = 16
+
+	// partitionProbeConcurrency is the maximum number of concurrent requests
+	// to sample the key space.
+	partitionProbeConcurrency = 16
+)
+
+// listPartition is a range of the keys of a partitioned listing, which starts
+// with the given object and ends before the given key. A nil object means
+// that the range starts with the first key and an empty key means that it is
+// not bounded at the end.
+type listPartition struct {
+	first *s3.Object
+	end   string
+}
+
+// listObjectsV2Partitioned lists the objects in partitions of the key space
+// concurrently. The partitions are discovered by sampling the key space with
+// StartAfter, and each of them starts with one of the sampled keys. The
+// objects of the first partition are sent as they are listed, the objects of
+// the others are spooled to temporary files and sent in the order of the
+// partitions, so the objects are listed in the same order as listObjectsV2.
+func (s *S3) listObjectsV2Partitioned(ctx context.Context, url *url.URL) <-chan *Object {
+	objCh := make(chan *Object)
+
+	go func() {
+		defer close(objCh)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		boundaries, err := s.partitionBoundaries(ctx, url)
+		if err != nil {
+			objCh <- &Object{Err: err}
+			return
+		}
+
+		partitions := make([]listPartition, len(boundaries)+1)
+		for i, boundary := range boundaries {
+			partitions[i].end = aws.StringValue(boundary.Key)
+			partitions[i+1].first = boundary
+		}
+
+		// bypass the objects created after the listing began.
+		now := time.Now().UTC()
+		objectFound := false
+		send := func(c *s3.Object) bool {
+			if !url.Match(aws.StringValue(c.Key)) {
+				return true
+			}
+			objectFound = true
+			if aws.TimeValue(c.LastModified).After(now) {
+				return true
+			}
+
+			select {
+			case objCh <- listedObject(url, c):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		spools := make([]*partitionSpool, len(partitions))
+		for i := 1; i < len(partitions); i++ {
+			spools[i] = s.spoolPartition(ctx, url, partitions[i])
+		}
+		defer func() {
+			// stop the partitions which are still listed.
+			cancel()
+			for _, spool := range spools[1:] {
+				spool.remove()
+			}
+		}()
+
+		err = s.listPartition(ctx, url, partitions[0], send)
+		for _, spool := range spools[1:] {
+			if err != nil {
+				break
+			}
+			err = spool.read(send)
+		}
+
+		if err != nil {
+			objCh <- &Object{Err: err}
+			return
+		}
+
+		if !objectFound && !url.IsBucket() {
+			objCh <- &Object{Err: ErrNoObjectFound}
+		}
+	}()
+
+	return objCh
+}
+
+// listPartition lists the objects of the partition until fn returns false.
+func (s *S3) listPartition(
+	ctx context.Context,
+	url *url.URL,
+	partition listPartition,
+	fn func(*s3.Object) bool,
+) error {
+	listInput := s3.ListObjectsV2Input{
+		Bucket:       aws.String(url.Bucket),
+		Prefix:       aws.String(url.Prefix),
+		RequestPayer: s.RequestPayer(),
+	}
+	if partition.first != nil {
+		if !fn(partition.first) {
+			return nil
+		}
+		listInput.SetStartAfter(aws.StringValue(partition.first.Key))
+	}
+	if s.listRestoreStatus {
+		listInput.SetOptionalObjectAttributes(aws.StringSlice([]string{s3.OptionalObjectAttributesRestoreStatus}))
+	}
+
+	return s.api.ListObjectsV2PagesWithContext(ctx, &listInput, func(p *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, c := range p.Contents {
+			if partition.end != "" && aws.StringValue(c.Key) >= partition.end {
+				return false
+			}
+			if !fn(c) {
+				return false
+			}
+		}
+		return !lastPage
+	})
+}
+
+// partitionSpool is a temporary file which the objects of a partition are
+// written to while the previous partitions are sent.
+type partitionSpool struct {
+	file *os.File
+	done chan struct{}
+	err  error
+}
+
+// spoolPartition lists the objects of the partition into a temporary file in
+// the background.
+func (s *S3) spoolPartition(ctx context.Context, url *url.URL, partition listPartition) *partitionSpool {
+	spool := &partitionSpool{done: make(chan struct{})}
+
+	go func() {
+		defer close(spool.done)
+
+		spool.file, spool.err = os.CreateTemp("", "s5cmd-list-")
+		if spool.err != nil {
+			return
+		}
+
+		var encErr error
+		enc := gob.NewEncoder(spool.file)
+		spool.err = s.listPartition(ctx, url, partition, func(c *s3.Object) bool {
+			encErr = enc.Encode(c)
+			return encErr == nil
+		})
+		if spool.err == nil {
+			spool.err = encErr
+		}
+		if spool.err == nil {
+			_, spool.err = spool.file.Seek(0, io.SeekStart)
+		}
+	}()
+
+	return spool
+}
+
+// read waits for the partition to be listed and calls fn for its objects
+// until fn returns false.
+func (p *partitionSpool) read(fn func(*s3.Object) bool) error {
+	<-p.done
+	if p.err != nil {
+		return p.err
+	}
+
+	dec := gob.NewDecoder(p.file)
+	for {
+		var c s3.Object
+		err := dec.Decode(&c)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !fn(&c) {
+			return nil
+		}
+	}
+}
+
+// remove removes the temporary file once the partition is listed.
+func (p *partitionSpool) remove() {
+	<-p.done
+	if p.file != nil {
+		p.file.Close()
+		os.Remove(p.file.Name())
+	}
+}
+
+// partitionBoundaries returns the first objects of the partitions of the
+// url except the first one. The key space is sampled with StartAfter requests
+// for each character of the alphabet after the common prefix of the keys, and
+// the distinct sampled keys are grouped into partitions. No boundaries are
+// returned if the keys can't be split.
+func (s *S3) partitionBoundaries(ctx context.Context, url *url.URL) ([]*s3.Object, error) {
+	first, err := s.firstObjectAfter(ctx, url, "")
+	if err != nil || first == nil {
+		return nil, err
+	}
+	firstKey := aws.StringValue(first.Key)
+
+	prefix := url.Prefix
+	for depth := 0; depth < maxPartitionDepth && len(prefix) < len(firstKey); depth++ {
+		samples := make([]string, len(partitionAlphabet))
+		for i := range partitionAlphabet {
+			samples[i] = prefix + partitionAlphabet[i:i+1]
+		}
+
+		sampled, err := s.sampleObjects(ctx, url, samples)
+		if err != nil {
+			return nil, err
+		}
+
+		starts := []*s3.Object{first}
+		seen := map[string]struct{}{firstKey: {}}
+		for _, obj := range sampled {
+			if obj == nil {
+				continue
+			}
+			key := aws.StringValue(obj.Key)
+			if _, ok := seen[key]; ok || key < firstKey {
+				continue
+			}
+			seen[key] = struct{}{}
+			starts = append(starts, obj)
+		}
+
+		if len(starts) > 1 {
+			sort.Slice(starts, func(i, j int) bool {
+				return aws.StringValue(starts[i].Key) < aws.StringValue(starts[j].Key)
+			})
+			return groupPartitionStarts(starts, s.listPartitions), nil
+		}
+
+		// all keys share the next character of their common prefix, they are
+		// sampled again after it.
+		prefix = firstKey[:len(prefix)+1]
+	}
+	return nil, nil
+}
+
+// groupPartitionStarts groups the consecutive ranges which start with the
+// given objects into the given number of partitions, and returns the first
+// objects of the partitions except the first one.
+func groupPartitionStarts(starts []*s3.Object, n int) []*s3.Object {
+	if n > len(starts) {
+		n = len(starts)
+	}
+
+	var boundaries []*s3.Object
+	for i := 1; i < n; i++ {
+		boundaries = append(boundaries, starts[i*len(starts)/n])
+	}
+	return boundaries
+}
+
+// sampleObjects returns the first objects after each of the given samples
+// concurrently.
+func (s *S3) sampleObjects(ctx context.Context, url *url.URL, samples []string) ([]*s3.Object, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		merr    error
+		objects = make([]*s3.Object, len(samples))
+		sem     = make(chan struct{}, partitionProbeConcurrency)
+		probe   = func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			obj, err := s.firstObjectAfter(ctx, url, samples[i])
+			if err != nil {
+				mu.Lock()
+				merr = err
+				mu.Unlock()
+				return
+			}
+			objects[i] = obj
+		}
+	)
+
+	for i := range samples {
+		wg.Add(1)
+		sem <- struct{}{}
+		go probe(i)
+	}
+	wg.Wait()
+
+	return objects, merr
+}
+
+// firstObjectAfter returns the first object of the url after the given key.
+// It returns nil if there is no such object.
+func (s *S3) firstObjectAfter(ctx context.Context, url *url.URL, startAfter string) (*s3.Object, error) {
+	listInput := s3.ListObjectsV2Input{
+		Bucket:       aws.String(url.Bucket),
+		Prefix:       aws.String(url.Prefix),
+		MaxKeys:      aws.Int64(1),
+		RequestPayer: s.RequestPayer(),
+	}
+	if startAfter != "" {
+		listInput.SetStartAfter(startAfter)
+	}
+	if s.listRestoreStatus {
+		listInput.SetOptionalObjectAttributes(aws.StringSlice([]string{s3.OptionalObjectAttributesRestoreStatus}))
+	}
+
+	output, err := s.api.ListObjectsV2WithContext(ctx, &listInput)
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Contents) == 0 {
+		return nil, nil
+	}
+	return output.Contents[0], nil
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/awstesting/unit"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// newFakeListS3 returns a client whose ListObjectsV2 requests are served from
+// the given keys. It counts the requests which list the objects after a key.
+func newFakeListS3(keys []string, partitions int, startAfterCount *int64) *S3 {
+	sort.Strings(keys)
+
+	mockAPI := s3.New(unit.Session)
+	mockAPI.Handlers.Send.Clear()
+	mockAPI.Handlers.Unmarshal.Clear()
+	mockAPI.Handlers.UnmarshalMeta.Clear()
+	mockAPI.Handlers.ValidateResponse.Clear()
+	mockAPI.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		input := r.Params.(*s3.ListObjectsV2Input)
+
+		startAfter := aws.StringValue(input.StartAfter)
+		if token := aws.StringValue(input.ContinuationToken); token != "" {
+			startAfter = token
+		} else if startAfter != "" {
+			atomic.AddInt64(startAfterCount, 1)
+		}
+
+		maxKeys := int(aws.Int64Value(input.MaxKeys))
+		if maxKeys == 0 {
+			maxKeys = 3
+		}
+
+		output := &s3.ListObjectsV2Output{}
+		for _, key := range keys {
+			if key <= startAfter || !strings.HasPrefix(key, aws.StringValue(input.Prefix)) {
+				continue
+			}
+			if len(output.Contents) == maxKeys {
+				output.IsTruncated = aws.Bool(true)
+				output.NextContinuationToken = output.Contents[maxKeys-1].Key
+				break
+			}
+			output.Contents = append(output.Contents, &s3.Object{
+				Key:          aws.String(key),
+				Size:         aws.Int64(int64(len(key))),
+				LastModified: aws.Time(time.Now().Add(-time.Hour)),
+			})
+		}
+		*r.Data.(*s3.ListObjectsV2Output) = *output
+	})
+
+	return &S3{api: mockAPI, listPartitions: partitions}
+}
+
+func TestS3ListPartitioned(t *testing.T) {
+	t.Parallel()
+
+	var hexKeys, dateKeys []string
+	for i := 0; i < 64; i++ {
+		hexKeys = append(hexKeys, fmt.Sprintf("%08x", i*0x3f1a2b7))
+		dateKeys = append(dateKeys, fmt.Sprintf("2024-01-%02d/file-%d", i%28+1, i))
+	}
+
+	testcases := []struct {
+		name       string
+		keys       []string
+		src        string
+		partitions int
+	}{
+		{name: "flat bucket", keys: hexKeys, src: "s3://bucket/*", partitions: 4},
+		{name: "common prefix", keys: dateKeys, src: "s3://bucket/*", partitions: 8},
+		{name: "wildcard", keys: dateKeys, src: "s3://bucket/2024-01-1*/file-1*", partitions: 3},
+		{name: "more partitions than keys", keys: []string{"a", "b/c", "d"}, src: "s3://bucket/*", partitions: 16},
+		{name: "single key", keys: []string{"prefix/key"}, src: "s3://bucket/prefix/*", partitions: 4},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			src, err := url.New(tc.src)
+			assert.NilError(t, err)
+
+			var expected []string
+			for obj := range newFakeListS3(tc.keys, 0, new(int64)).List(context.Background(), src, false) {
+				assert.NilError(t, obj.Err)
+				expected = append(expected, obj.URL.Absolute()+" "+obj.URL.Relative())
+			}
+
+			var startAfterCount int64
+			var got []string
+			for obj := range newFakeListS3(tc.keys, tc.partitions, &startAfterCount).List(context.Background(), src, false) {
+				assert.NilError(t, obj.Err)
+				got = append(got, obj.URL.Absolute()+" "+obj.URL.Relative())
+			}
+
+			assert.DeepEqual(t, got, expected)
+			assert.Assert(t, startAfterCount > 0)
+		})
+	}
+}
+
+func TestPartitionBoundaries(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{"logs/2024/a", "logs/2024/b", "logs/2024/c", "logs/2024/d"}
+	src, err := url.New("s3://bucket/logs/*")
+	assert.NilError(t, err)
+
+	boundaries, err := newFakeListS3(keys, 2, new(int64)).partitionBoundaries(context.Background(), src)
+	assert.NilError(t, err)
+	assert.Equal(t, len(boundaries), 1)
+	assert.Equal(t, aws.StringValue(boundaries[0].Key), "logs/2024/c")
+}
+
+func TestS3ListPartitionedNoObjectFound(t *testing.T) {
+	t.Parallel()
+
+	src, err := url.New("s3://bucket/prefix/*")
+	assert.NilError(t, err)
+
+	var errs []error
+	for obj := range newFakeListS3([]string{"other/key"}, 4, new(int64)).List(context.Background(), src, false) {
+		errs = append(errs, obj.Err)
+	}
+	assert.Equal(t, len(errs), 1)
+	assert.Equal(t, errs[0], ErrNoObjectFound)
+}
@@ -3,6 +3,9 @@ package storage
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -12,7 +15,9 @@ import (
 	urlpkg "net/url"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -847,6 +852,168 @@ func TestS3PutEncryptionRequest(t *testing.T) {
 	}
 }
 
+func TestS3SSECustomerKeyRequest(t *testing.T) {
+	const (
+		key           = "0123456789abcdef0123456789abcdef"
+		copySourceKey = "fedcba9876543210fedcba9876543210"
+	)
+
+	encoded := func(key string) (string, string) {
+		sum := md5.Sum([]byte(key))
+		return base64.StdEncoding.EncodeToString([]byte(key)), base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	testcases := []struct {
+		name      string
+		operation string
+		run       func(s *S3) error
+
+		expectCopySource bool
+	}{
+		{
+			name:      "stat",
+			operation: "HeadObject",
+			run: func(s *S3) error {
+				_, err := s.Stat(context.Background(), u)
+				return err
+			},
+		},
+		{
+			name:      "read",
+			operation: "GetObject",
+			run: func(s *S3) error {
+				_, err := s.Read(context.Background(), u)
+				return err
+			},
+		},
+		{
+			name:      "get",
+			operation: "GetObject",
+			run: func(s *S3) error {
+				_, err := s.Get(context.Background(), u, aws.NewWriteAtBuffer(nil), 1, s3manager.DefaultDownloadPartSize)
+				return err
+			},
+		},
+		{
+			name:      "put",
+			operation: "PutObject",
+			run: func(s *S3) error {
+				return s.Put(context.Background(), strings.NewReader("content"), u, Metadata{}, 1, s3manager.DefaultUploadPartSize)
+			},
+		},
+		{
+			name:      "copy",
+			operation: "CopyObject",
+			run: func(s *S3) error {
+				return s.Copy(context.Background(), u, u, Metadata{})
+			},
+			expectCopySource: true,
+		},
+		{
+			name:      "multipart copy",
+			operation: "UploadPartCopy",
+			run: func(s *S3) error {
+				return s.MultipartCopy(context.Background(), &Object{URL: u, Size: 1}, u, Metadata{}, 1, s3manager.MinUploadPartSize)
+			},
+			expectCopySource: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockAPI := s3.New(unit.Session)
+
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.UnmarshalError.Clear()
+			mockAPI.Handlers.Send.Clear()
+
+			var requests int
+			mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader("")),
+				}
+
+				if r.Operation.Name != tc.operation {
+					return
+				}
+				requests++
+
+				header := r.HTTPRequest.Header
+				encodedKey, keyMD5 := encoded(key)
+				assert.Equal(t, header.Get("x-amz-server-side-encryption-customer-algorithm"), SSECustomerAlgorithm)
+				assert.Equal(t, header.Get("x-amz-server-side-encryption-customer-key"), encodedKey)
+				assert.Equal(t, header.Get("x-amz-server-side-encryption-customer-key-md5"), keyMD5)
+
+				if !tc.expectCopySource {
+					return
+				}
+				encodedKey, keyMD5 = encoded(copySourceKey)
+				assert.Equal(t, header.Get("x-amz-copy-source-server-side-encryption-customer-algorithm"), SSECustomerAlgorithm)
+				assert.Equal(t, header.Get("x-amz-copy-source-server-side-encryption-customer-key"), encodedKey)
+				assert.Equal(t, header.Get("x-amz-copy-source-server-side-encryption-customer-key-md5"), keyMD5)
+			})
+
+			mockAPI.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				if output, ok := r.Data.(*s3.GetObjectOutput); ok {
+					output.Body = r.HTTPResponse.Body
+					output.ContentRange = aws.String("bytes 0-0/0")
+				}
+				if output, ok := r.Data.(*s3.UploadPartCopyOutput); ok {
+					output.CopyPartResult = &s3.CopyPartResult{}
+				}
+				if awsErr, ok := r.Error.(awserr.Error); ok && awsErr.Code() == request.ErrCodeSerialization {
+					r.Error = nil
+				}
+			})
+
+			mockS3 := &S3{
+				api:                      mockAPI,
+				downloader:               s3manager.NewDownloaderWithClient(mockAPI),
+				uploader:                 s3manager.NewUploaderWithClient(mockAPI),
+				sseCustomerKey:           key,
+				copySourceSSECustomerKey: copySourceKey,
+			}
+
+			if err := tc.run(mockS3); err != nil {
+				t.Errorf("Expected %v, but received %q", nil, err)
+			}
+			assert.Equal(t, requests, 1)
+		})
+	}
+}
+
+func TestS3PresignSSECustomerKey(t *testing.T) {
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	mockS3 := &S3{
+		api:            s3.New(unit.Session),
+		sseCustomerKey: "0123456789abcdef0123456789abcdef",
+	}
+
+	presigned, err := mockS3.Presign(context.Background(), u, time.Hour)
+	assert.NilError(t, err)
+
+	parsed, err := urlpkg.Parse(presigned)
+	assert.NilError(t, err)
+
+	// the key must not leak into the url, it is sent with the headers.
+	signedHeaders := parsed.Query().Get("X-Amz-SignedHeaders")
+	assert.Assert(t, strings.Contains(signedHeaders, "x-amz-server-side-encryption-customer-key"))
+	assert.Assert(t, !strings.Contains(presigned, base64.StdEncoding.EncodeToString([]byte(mockS3.sseCustomerKey))))
+}
+
 func TestS3listObjectsV2(t *testing.T) {
 	const (
 		numObjectsToReturn = 10100
@@ -1341,3 +1508,690 @@ func (e tempError) Error() string { return e.err.Error() }
 func (e tempError) Temporary() bool { return e.temp }
 
 func (e *tempError) Unwrap() error { return e.err }
+
+func TestS3PutResumable(t *testing.T) {
+	log.Init("debug", false)
+
+	const (
+		partSize = 10
+		content  = "0123456789abcdefghijklmnopqrstu" // 3 full parts and a partial one
+	)
+
+	modTime := time.Now()
+
+	testcases := []struct {
+		name          string
+		state         *UploadState
+		stateModTime  time.Time
+		expectedOps   []string
+		expectedParts []int64
+	}{
+		{
+			name:          "no previous upload",
+			state:         nil,
+			expectedOps:   []string{"CreateMultipartUpload", "UploadPart", "UploadPart", "UploadPart", "UploadPart", "CompleteMultipartUpload"},
+			expectedParts: []int64{1, 2, 3, 4},
+		},
+		{
+			name: "resume previous upload",
+			state: &UploadState{
+				Bucket:   "bucket",
+				Key:      "key",
+				UploadID: "upload-id",
+				PartSize: partSize,
+				Parts: []UploadedPart{
+					{PartNumber: 1, ETag: "etag-1", Size: partSize},
+				},
+			},
+			stateModTime: modTime,
+			// part 2 is uploaded but not recorded, it is adopted from the
+			// listed parts.
+			expectedOps:   []string{"ListParts", "UploadPart", "UploadPart", "CompleteMultipartUpload"},
+			expectedParts: []int64{3, 4},
+		},
+		{
+			name: "source file has been changed",
+			state: &UploadState{
+				Bucket:   "bucket",
+				Key:      "key",
+				UploadID: "upload-id",
+				PartSize: partSize,
+				Parts: []UploadedPart{
+					{PartNumber: 1, ETag: "etag-1", Size: partSize},
+				},
+			},
+			stateModTime:  modTime.Add(-time.Hour),
+			expectedOps:   []string{"AbortMultipartUpload", "CreateMultipartUpload", "UploadPart", "UploadPart", "UploadPart", "UploadPart", "CompleteMultipartUpload"},
+			expectedParts: []int64{1, 2, 3, 4},
+		},
+	}
+
+	srcurl, err := url.New("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsturl, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &Object{URL: srcurl, Size: int64(len(content)), ModTime: &modTime}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			statePath := t.TempDir() + "/state.json"
+			if tc.state != nil {
+				tc.state.Source = srcurl.Absolute()
+				tc.state.Size = source.Size
+				tc.state.ModTime = tc.stateModTime
+				data, err := json.Marshal(tc.state)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(statePath, data, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mockAPI := s3.New(unit.Session)
+			mockS3 := &S3{api: mockAPI}
+
+			var (
+				mu            sync.Mutex
+				ops           []string
+				uploadedParts []int64
+				completed     []*s3.CompletedPart
+			)
+
+			mockAPI.Handlers.Send.Clear()
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.ValidateResponse.Clear()
+			mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("<CompleteMultipartUploadResult/>")),
+				}
+			})
+			mockAPI.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				ops = append(ops, r.Operation.Name)
+				switch input := r.Params.(type) {
+				case *s3.CreateMultipartUploadInput:
+					r.Data.(*s3.CreateMultipartUploadOutput).UploadId = aws.String("new-upload-id")
+				case *s3.ListPartsInput:
+					r.Data = &s3.ListPartsOutput{
+						Parts: []*s3.Part{
+							{PartNumber: aws.Int64(1), ETag: aws.String("etag-1"), Size: aws.Int64(partSize)},
+							{PartNumber: aws.Int64(2), ETag: aws.String("etag-2"), Size: aws.Int64(partSize)},
+						},
+					}
+				case *s3.UploadPartInput:
+					partNumber := aws.Int64Value(input.PartNumber)
+					uploadedParts = append(uploadedParts, partNumber)
+					r.Data.(*s3.UploadPartOutput).ETag = aws.String(fmt.Sprintf("etag-%d", partNumber))
+				case *s3.CompleteMultipartUploadInput:
+					completed = input.MultipartUpload.Parts
+				}
+			})
+
+			state, err := LoadUploadState(statePath, source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resumed int64
+			err = mockS3.PutResumable(context.Background(), strings.NewReader(content), dsturl, Metadata{}, 2, partSize, state, func(n int64) {
+				resumed = n
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sort.Strings(ops)
+			expectedOps := append([]string(nil), tc.expectedOps...)
+			sort.Strings(expectedOps)
+			assert.DeepEqual(t, expectedOps, ops)
+
+			sort.Slice(uploadedParts, func(i, j int) bool { return uploadedParts[i] < uploadedParts[j] })
+			assert.DeepEqual(t, tc.expectedParts, uploadedParts)
+
+			assert.Equal(t, int64(4-len(tc.expectedParts))*partSize, resumed)
+
+			assert.Equal(t, 4, len(completed))
+			for i, part := range completed {
+				assert.Equal(t, int64(i+1), aws.Int64Value(part.PartNumber))
+				assert.Equal(t, fmt.Sprintf("etag-%d", i+1), aws.StringValue(part.ETag))
+			}
+
+			if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+				t.Errorf("expected state file to be removed, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewObjectInput(t *testing.T) {
+	expires := "2024-01-02T03:04:05Z"
+	metadata := Metadata{
+		ACL:                "public-read",
+		CacheControl:       "no-cache",
+		ContentDisposition: "inline",
+		ContentEncoding:    "gzip",
+		ContentLanguage:    "en",
+		ContentType:        "text/plain",
+		Expires:            expires,
+		EncryptionMethod:   "aws:kms",
+		EncryptionKeyID:    "key-id",
+		StorageClass:       "STANDARD_IA",
+		Tags:               map[string]string{"a": "b"},
+		UserDefined:        map[string]string{"k": "v"},
+	}
+
+	in, err := newObjectInput(metadata)
+	assert.NilError(t, err)
+
+	expiresTime, err := time.Parse(time.RFC3339, expires)
+	assert.NilError(t, err)
+
+	want := &objectInput{
+		ACL:                  aws.String("public-read"),
+		CacheControl:         aws.String("no-cache"),
+		ContentDisposition:   aws.String("inline"),
+		ContentEncoding:      aws.String("gzip"),
+		ContentLanguage:      aws.String("en"),
+		ContentType:          aws.String("text/plain"),
+		Expires:              aws.Time(expiresTime),
+		Metadata:             map[string]*string{"k": aws.String("v")},
+		SSEKMSKeyId:          aws.String("key-id"),
+		ServerSideEncryption: aws.String("aws:kms"),
+		StorageClass:         aws.String("STANDARD_IA"),
+		Tagging:              aws.String("a=b"),
+	}
+	assert.DeepEqual(t, want, in)
+
+	empty, err := newObjectInput(Metadata{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &objectInput{Metadata: map[string]*string{}}, empty)
+
+	_, err = newObjectInput(Metadata{Expires: "tomorrow"})
+	assert.Assert(t, err != nil)
+}
+
+func TestS3GetResumable(t *testing.T) {
+	const (
+		partSize = 10
+		content  = "0123456789abcdefghijklmnopqrstu"
+		etag     = "0123456789abcdef0123456789abcdef"
+	)
+
+	testcases := []struct {
+		name           string
+		ranges         []ByteRange
+		err            error
+		expectedRanges []string
+		expectedErr    error
+	}{
+		{
+			name:           "no previous download",
+			expectedRanges: []string{"bytes=0-9", "bytes=10-19", "bytes=20-29", "bytes=30-30"},
+		},
+		{
+			name:           "resume previous download",
+			ranges:         []ByteRange{{Start: 0, End: 10}, {Start: 15, End: 20}},
+			expectedRanges: []string{"bytes=10-14", "bytes=20-29", "bytes=30-30"},
+		},
+		{
+			name:           "object has been modified",
+			ranges:         []ByteRange{{Start: 0, End: 30}},
+			err:            awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil),
+			expectedRanges: []string{"bytes=30-30"},
+			expectedErr:    ErrObjectModified,
+		},
+	}
+
+	srcurl, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			partPath := t.TempDir() + "/file.part"
+			if err := os.WriteFile(partPath, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if len(tc.ranges) > 0 {
+				data, err := json.Marshal(DownloadState{
+					Source: srcurl.Absolute(),
+					ETag:   etag,
+					Size:   int64(len(content)),
+					Ranges: tc.ranges,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(partPath+".json", data, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mockAPI := s3.New(unit.Session)
+			mockS3 := &S3{api: mockAPI}
+
+			var (
+				mu     sync.Mutex
+				ranges []string
+			)
+
+			mockAPI.Handlers.Send.Clear()
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.ValidateResponse.Clear()
+			mockAPI.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				input := r.Params.(*s3.GetObjectInput)
+				assert.Equal(t, `"`+etag+`"`, aws.StringValue(input.IfMatch))
+
+				rng := aws.StringValue(input.Range)
+				mu.Lock()
+				ranges = append(ranges, rng)
+				mu.Unlock()
+
+				if tc.err != nil {
+					r.Error = tc.err
+					return
+				}
+
+				var start, end int
+				fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+				r.Data.(*s3.GetObjectOutput).Body = io.NopCloser(strings.NewReader(content[start : end+1]))
+			})
+
+			object := &Object{URL: srcurl, Etag: etag, Size: int64(len(content))}
+			state, err := LoadDownloadState(partPath, object)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf := &writerAtBuffer{data: make([]byte, len(content))}
+			_, err = mockS3.GetResumable(context.Background(), srcurl, buf, 2, partSize, state, nil)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+
+			sort.Strings(ranges)
+			expectedRanges := append([]string(nil), tc.expectedRanges...)
+			sort.Strings(expectedRanges)
+			assert.DeepEqual(t, expectedRanges, ranges)
+
+			if tc.expectedErr != nil {
+				return
+			}
+
+			// only the missing ranges are written.
+			for _, r := range tc.ranges {
+				copy(buf.data[r.Start:r.End], content[r.Start:r.End])
+			}
+			assert.Equal(t, content, string(buf.data))
+
+			if _, err := os.Stat(partPath + ".json"); !os.IsNotExist(err) {
+				t.Errorf("expected state file to be removed, got: %v", err)
+			}
+		})
+	}
+}
+
+type writerAtBuffer struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (w *writerAtBuffer) WriteAt(p []byte, off int64) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return copy(w.data[off:], p), nil
+}
+
+func TestS3CopyTaggingRequest(t *testing.T) {
+	testcases := []struct {
+		name              string
+		tags              map[string]string
+		expectedTagging   interface{}
+		expectedDirective interface{}
+	}{
+		{
+			name: "no tags",
+		},
+		{
+			name:              "tags",
+			tags:              map[string]string{"project": "s5cmd", "team": "data & ml"},
+			expectedTagging:   "project=s5cmd&team=data+%26+ml",
+			expectedDirective: s3.TaggingDirectiveReplace,
+		},
+	}
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockAPI := s3.New(unit.Session)
+
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.UnmarshalError.Clear()
+			mockAPI.Handlers.Send.Clear()
+
+			mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("")),
+				}
+
+				assert.Equal(t, tc.expectedTagging, valueAtPath(r.Params, "Tagging"))
+				assert.Equal(t, tc.expectedDirective, valueAtPath(r.Params, "TaggingDirective"))
+			})
+			mockAPI.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				if awsErr, ok := r.Error.(awserr.Error); ok && awsErr.Code() == request.ErrCodeSerialization {
+					r.Error = nil
+				}
+			})
+
+			mockS3 := &S3{api: mockAPI}
+
+			err = mockS3.Copy(context.Background(), u, u, Metadata{Tags: tc.tags})
+			if err != nil {
+				t.Errorf("Expected %v, but received %q", nil, err)
+			}
+		})
+	}
+}
+
+func TestS3GetObjectTagging(t *testing.T) {
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mockAPI := s3.New(unit.Session)
+	mockS3 := &S3{api: mockAPI}
+
+	mockAPI.Handlers.Send.Clear()
+	mockAPI.Handlers.Unmarshal.Clear()
+	mockAPI.Handlers.UnmarshalMeta.Clear()
+	mockAPI.Handlers.ValidateResponse.Clear()
+	mockAPI.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		r.Data.(*s3.GetObjectTaggingOutput).TagSet = []*s3.Tag{
+			{Key: aws.String("project"), Value: aws.String("s5cmd")},
+			{Key: aws.String("team"), Value: aws.String("data")},
+		}
+	})
+
+	tags, err := mockS3.GetObjectTagging(context.Background(), u)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.DeepEqual(t, map[string]string{"project": "s5cmd", "team": "data"}, tags)
+}
+
+func TestS3MultipartCopy(t *testing.T) {
+	log.Init("debug", false)
+
+	const (
+		etag = "0123456789abcdef0123456789abcdef"
+		size = 2*s3manager.MinUploadPartSize + 1
+	)
+
+	testcases := []struct {
+		name           string
+		partSize       int64
+		err            error
+		expectedOps    []string
+		expectedRanges []string
+		expectedErr    error
+	}{
+		{
+			name:        "copy",
+			partSize:    s3manager.MinUploadPartSize,
+			expectedOps: []string{"CreateMultipartUpload", "UploadPartCopy", "UploadPartCopy", "UploadPartCopy", "CompleteMultipartUpload"},
+			expectedRanges: []string{
+				"bytes=0-5242879",
+				"bytes=5242880-10485759",
+				"bytes=10485760-10485760",
+			},
+		},
+		{
+			name:        "part size is increased to the minimum",
+			partSize:    1,
+			expectedOps: []string{"CreateMultipartUpload", "UploadPartCopy", "UploadPartCopy", "UploadPartCopy", "CompleteMultipartUpload"},
+			expectedRanges: []string{
+				"bytes=0-5242879",
+				"bytes=5242880-10485759",
+				"bytes=10485760-10485760",
+			},
+		},
+		{
+			name:           "source object has been modified",
+			partSize:       size,
+			err:            awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil),
+			expectedOps:    []string{"CreateMultipartUpload", "UploadPartCopy", "AbortMultipartUpload"},
+			expectedRanges: []string{"bytes=0-10485760"},
+			expectedErr:    ErrObjectModified,
+		},
+	}
+
+	srcurl, err := url.New("s3://source-bucket/source-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsturl, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &Object{URL: srcurl, Size: size, Etag: etag}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockAPI := s3.New(unit.Session)
+			mockS3 := &S3{api: mockAPI}
+
+			var (
+				mu        sync.Mutex
+				ops       []string
+				ranges    []string
+				completed []*s3.CompletedPart
+			)
+
+			mockAPI.Handlers.Send.Clear()
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.ValidateResponse.Clear()
+			mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				ops = append(ops, r.Operation.Name)
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader("<CompleteMultipartUploadResult/>")),
+				}
+
+				input, ok := r.Params.(*s3.UploadPartCopyInput)
+				if !ok {
+					return
+				}
+				assert.Equal(t, "source-bucket/source-key", aws.StringValue(input.CopySource))
+				assert.Equal(t, `"`+etag+`"`, aws.StringValue(input.CopySourceIfMatch))
+				assert.Equal(t, "upload-id", aws.StringValue(input.UploadId))
+				ranges = append(ranges, aws.StringValue(input.CopySourceRange))
+				if tc.err != nil {
+					r.Error = tc.err
+				}
+			})
+			mockAPI.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+				switch input := r.Params.(type) {
+				case *s3.CreateMultipartUploadInput:
+					r.Data.(*s3.CreateMultipartUploadOutput).UploadId = aws.String("upload-id")
+				case *s3.UploadPartCopyInput:
+					r.Data.(*s3.UploadPartCopyOutput).CopyPartResult = &s3.CopyPartResult{
+						ETag: aws.String(fmt.Sprintf("etag-%d", aws.Int64Value(input.PartNumber))),
+					}
+				case *s3.CompleteMultipartUploadInput:
+					completed = input.MultipartUpload.Parts
+				}
+			})
+
+			err := mockS3.MultipartCopy(context.Background(), source, dsturl, Metadata{}, 2, tc.partSize)
+			if err != tc.expectedErr {
+				t.Fatalf("expected error %v, got: %v", tc.expectedErr, err)
+			}
+
+			sort.Strings(ops)
+			expectedOps := append([]string(nil), tc.expectedOps...)
+			sort.Strings(expectedOps)
+			assert.DeepEqual(t, expectedOps, ops)
+
+			sort.Strings(ranges)
+			expectedRanges := append([]string(nil), tc.expectedRanges...)
+			sort.Strings(expectedRanges)
+			assert.DeepEqual(t, expectedRanges, ranges)
+
+			if tc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, len(tc.expectedRanges), len(completed))
+			for i, part := range completed {
+				assert.Equal(t, int64(i+1), aws.Int64Value(part.PartNumber))
+				assert.Equal(t, fmt.Sprintf("etag-%d", i+1), aws.StringValue(part.ETag))
+			}
+		})
+	}
+}
+
+func TestS3PutObjectTagging(t *testing.T) {
+	u, err := url.New("s3://bucket/key", url.WithVersion("1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mockAPI := s3.New(unit.Session)
+	mockS3 := &S3{api: mockAPI}
+
+	mockAPI.Handlers.Send.Clear()
+	mockAPI.Handlers.Unmarshal.Clear()
+	mockAPI.Handlers.UnmarshalMeta.Clear()
+	mockAPI.Handlers.ValidateResponse.Clear()
+	mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+		r.HTTPResponse = &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}
+
+		input := r.Params.(*s3.PutObjectTaggingInput)
+		assert.Equal(t, "1", aws.StringValue(input.VersionId))
+		assert.DeepEqual(t, []*s3.Tag{
+			{Key: aws.String("project"), Value: aws.String("s5cmd")},
+			{Key: aws.String("team"), Value: aws.String("data")},
+		}, input.Tagging.TagSet)
+	})
+
+	err = mockS3.PutObjectTagging(context.Background(), u, map[string]string{"team": "data", "project": "s5cmd"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseRestoreHeader(t *testing.T) {
+	expiry := time.Date(2012, time.December, 21, 0, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		name     string
+		header   string
+		expected *RestoreStatus
+	}{
+		{
+			name:     "not restored",
+			header:   "",
+			expected: nil,
+		},
+		{
+			name:     "in progress",
+			header:   `ongoing-request="true"`,
+			expected: &RestoreStatus{InProgress: true},
+		},
+		{
+			name:     "restored",
+			header:   `ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`,
+			expected: &RestoreStatus{InProgress: false, ExpiryDate: &expiry},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.DeepEqual(t, tc.expected, parseRestoreHeader(tc.header))
+		})
+	}
+}
+
+func TestS3RestoreObject(t *testing.T) {
+	testcases := []struct {
+		name        string
+		err         error
+		expectedErr bool
+	}{
+		{
+			name: "restore",
+		},
+		{
+			name: "restore is in progress already",
+			err:  awserr.New("RestoreAlreadyInProgress", "Object restore is already in progress", nil),
+		},
+		{
+			name:        "object is not archived",
+			err:         awserr.New("InvalidObjectState", "Restore is not allowed for the object's current storage class", nil),
+			expectedErr: true,
+		},
+	}
+
+	u, err := url.New("s3://bucket/key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mockAPI := s3.New(unit.Session)
+			mockS3 := &S3{api: mockAPI}
+
+			mockAPI.Handlers.Send.Clear()
+			mockAPI.Handlers.Unmarshal.Clear()
+			mockAPI.Handlers.UnmarshalMeta.Clear()
+			mockAPI.Handlers.ValidateResponse.Clear()
+			mockAPI.Handlers.Send.PushBack(func(r *request.Request) {
+				r.HTTPResponse = &http.Response{
+					StatusCode: http.StatusAccepted,
+					Body:       io.NopCloser(strings.NewReader("")),
+				}
+
+				input := r.Params.(*s3.RestoreObjectInput)
+				assert.Equal(t, int64(7), aws.Int64Value(input.RestoreRequest.Days))
+				assert.Equal(t, "Bulk", aws.StringValue(input.RestoreRequest.GlacierJobParameters.Tier))
+				r.Error = tc.err
+			})
+
+			err := mockS3.RestoreObject(context.Background(), u, 7, "Bulk")
+			if tc.expectedErr != (err != nil) {
+				t.Errorf("expected error: %v, got: %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,664 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	osuser "os/user"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+const (
+	sftpDefaultPort = "22"
+
+	// sftpHandshakeTimeout is the maximum duration to connect to a server
+	// and authenticate the user.
+	sftpHandshakeTimeout = 30 * time.Second
+)
+
+func init() {
+	Register("sftp", url.SchemeOptions{HostAuthority: true}, func(_ context.Context, _ *url.URL, opts Options) (Storage, error) {
+		return NewSFTPClient(opts)
+	})
+}
+
+// sftpClients caches the clients by their options, so that the SSH
+// connections are reused by the operations.
+var sftpClients sync.Map
+
+// SFTP is the Storage implementation of the SFTP servers, e.g.
+// sftp://user@host:port/path/to/file. The path of the URL is absolute, paths
+// starting with "~/" are relative to the home directory of the user.
+//
+// The user is authenticated with the keys of ssh-agent, the key file given
+// with SFTP_IDENTITY_FILE or the default keys in ~/.ssh. The host keys are
+// verified with ~/.ssh/known_hosts unless --no-verify-ssl flag is given.
+type SFTP struct {
+	config *ssh.ClientConfig
+	dryRun bool
+
+	// agentSock is the socket of ssh-agent, which is connected only while
+	// the connections are authenticated.
+	agentSock string
+
+	mu    sync.Mutex
+	conns map[string]*sftpConn
+}
+
+// sftpConn is the connection of a host which is shared by the operations.
+// ready is closed once the connection is opened or failed.
+type sftpConn struct {
+	ready  chan struct{}
+	client *sftp.Client
+	err    error
+}
+
+// NewSFTPClient returns a client of the SFTP servers which is configured
+// with the environment variables.
+func NewSFTPClient(opts Options) (*SFTP, error) {
+	// only the options used by the client are kept in the cache key.
+	key := Options{
+		NoVerifySSL: opts.NoVerifySSL,
+		DryRun:      opts.DryRun,
+	}
+	if client, ok := sftpClients.Load(key); ok {
+		return client.(*SFTP), nil
+	}
+
+	client, err := newSFTPClient(key, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	actual, _ := sftpClients.LoadOrStore(key, client)
+	return actual.(*SFTP), nil
+}
+
+func newSFTPClient(opts Options, getenv func(string) string) (*SFTP, error) {
+	home := getenv("HOME")
+	if home == "" {
+		home, _ = os.UserHomeDir()
+	}
+
+	auth, err := sftpAuthMethods(getenv, home)
+	if err != nil {
+		return nil, err
+	}
+
+	username := getenv("USER")
+	if username == "" {
+		if current, err := osuser.Current(); err == nil {
+			username = current.Username
+		}
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !opts.NoVerifySSL {
+		knownHosts := getenv("SFTP_KNOWN_HOSTS")
+		if knownHosts == "" {
+			knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+		}
+		hostKeyCallback, err = knownhosts.New(knownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read known hosts, use --no-verify-ssl flag to skip host key verification: %w", err)
+		}
+	}
+
+	return &SFTP{
+		config: &ssh.ClientConfig{
+			User:            username,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+		},
+		dryRun:    opts.DryRun,
+		agentSock: getenv("SSH_AUTH_SOCK"),
+		conns:     map[string]*sftpConn{},
+	}, nil
+}
+
+// sftpAuthMethods returns the authentication methods in the order of the key
+// files and the password. The keys of ssh-agent are tried before them, see
+// dial.
+func sftpAuthMethods(getenv func(string) string, home string) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+
+	var signers []ssh.Signer
+	if identity := getenv("SFTP_IDENTITY_FILE"); identity != "" {
+		signer, err := readSFTPKey(identity)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read identity file %q: %w", identity, err)
+		}
+		signers = append(signers, signer)
+	} else {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			keyfile := filepath.Join(home, ".ssh", name)
+			signer, err := readSFTPKey(keyfile)
+			if err != nil {
+				// encrypted keys can only be used with ssh-agent.
+				if !errors.Is(err, os.ErrNotExist) {
+					log.Debug(log.DebugMessage{Err: fmt.Sprintf("skipping identity file %q: %v", keyfile, err)})
+				}
+				continue
+			}
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+
+	if password := getenv("SFTP_PASSWORD"); password != "" {
+		methods = append(methods, ssh.Password(password))
+	}
+
+	return methods, nil
+}
+
+func readSFTPKey(keyfile string) (ssh.Signer, error) {
+	data, err := os.ReadFile(keyfile)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+// conn returns the SFTP connection of the host of the URL. The connections
+// are opened on first use and shared by the operations. The closed
+// connections are opened again on next use.
+func (s *SFTP) conn(ctx context.Context, u *url.URL) (*sftp.Client, error) {
+	user := s.config.User
+	if u.User() != "" {
+		user = u.User()
+	}
+
+	addr := u.Host()
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, sftpDefaultPort)
+	}
+
+	if user == "" {
+		return nil, fmt.Errorf("unable to find the user of %v, give it in the url, e.g. sftp://user@%v/", addr, u.Host())
+	}
+
+	key := user + "@" + addr
+
+	s.mu.Lock()
+	conn, ok := s.conns[key]
+	if !ok {
+		conn = &sftpConn{ready: make(chan struct{})}
+		s.conns[key] = conn
+	}
+	s.mu.Unlock()
+
+	// the connection is opened out of the lock, so a slow host doesn't
+	// block the operations on the other hosts.
+	if !ok {
+		conn.client, conn.err = s.dial(ctx, user, addr)
+		close(conn.ready)
+
+		if conn.err != nil {
+			s.evict(key, conn)
+		} else {
+			go func() {
+				conn.client.Wait()
+				s.evict(key, conn)
+			}()
+		}
+	}
+
+	select {
+	case <-conn.ready:
+		return conn.client, conn.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// evict removes the connection of the key, unless it is replaced already.
+func (s *SFTP) evict(key string, conn *sftpConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conns[key] == conn {
+		delete(s.conns, key)
+	}
+}
+
+// dial connects to the server at addr and starts an SFTP session as the
+// user. The connection is aborted if ctx is canceled or the handshake takes
+// longer than sftpHandshakeTimeout.
+func (s *SFTP) dial(ctx context.Context, user, addr string) (*sftp.Client, error) {
+	config := *s.config
+	config.User = user
+
+	key := user + "@" + addr
+
+	if s.agentSock != "" {
+		agentConn, err := net.Dial("unix", s.agentSock)
+		if err != nil {
+			log.Debug(log.DebugMessage{Err: fmt.Sprintf("unable to connect to ssh-agent: %v", err)})
+		} else {
+			defer agentConn.Close()
+			config.Auth = append([]ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers)}, config.Auth...)
+		}
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, sftpHandshakeTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// the handshake doesn't take a context, the connection is closed to
+	// abort it.
+	deadline, _ := dialCtx.Deadline()
+	netConn.SetDeadline(deadline)
+
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			netConn.Close()
+		case <-stop:
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, &config)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("unable to connect to %v: %w", key, err)
+	}
+
+	client, err := sftp.NewClient(ssh.NewClient(sshConn, chans, reqs), sftp.UseConcurrentWrites(true))
+	if err != nil {
+		sshConn.Close()
+		return nil, fmt.Errorf("unable to start sftp session on %v: %w", key, err)
+	}
+
+	netConn.SetDeadline(time.Time{})
+	return client, nil
+}
+
+// sftpPath returns the path of the key on the server.
+func sftpPath(key string) string {
+	if key == "~" || strings.HasPrefix(key, "~/") {
+		p := strings.TrimPrefix(key[1:], "/")
+		if p == "" {
+			return "."
+		}
+		return p
+	}
+	return "/" + key
+}
+
+// sftpKey is the inverse of sftpPath, home is true if the path is relative to
+// the home directory.
+func sftpKey(p string, home bool) string {
+	if home {
+		if p == "." {
+			return "~/"
+		}
+		return "~/" + strings.TrimPrefix(p, "./")
+	}
+	return strings.TrimPrefix(p, "/")
+}
+
+func sftpObject(u *url.URL, fi os.FileInfo) *Object {
+	mod := fi.ModTime().UTC()
+	return &Object{
+		URL:     u,
+		Type:    ObjectType{fi.Mode()},
+		Size:    fi.Size(),
+		ModTime: &mod,
+	}
+}
+
+// Stat returns the Object structure describing the file.
+func (s *SFTP) Stat(ctx context.Context, u *url.URL) (*Object, error) {
+	client, err := s.conn(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := client.Stat(sftpPath(u.Path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &ErrGivenObjectNotFound{ObjectAbsPath: u.Absolute()}
+		}
+		return nil, err
+	}
+	return sftpObject(u, fi), nil
+}
+
+// HeadObject returns the Object structure of the file, SFTP doesn't keep any
+// metadata of the files.
+func (s *SFTP) HeadObject(ctx context.Context, u *url.URL) (*Object, *Metadata, error) {
+	obj, err := s.Stat(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	return obj, &Metadata{}, nil
+}
+
+// List lists the files in the same manner as S3. The directories are listed
+// as prefixes with the delimiter, the whole tree under the deepest directory
+// without wildcards is walked otherwise.
+func (s *SFTP) List(ctx context.Context, src *url.URL, followSymlinks bool) <-chan *Object {
+	objCh := make(chan *Object)
+
+	go func() {
+		defer close(objCh)
+
+		client, err := s.conn(ctx, src)
+		if err != nil {
+			sendError(ctx, err, objCh)
+			return
+		}
+
+		dir := src.Prefix[:strings.LastIndex(src.Prefix, "/")+1]
+		home := dir == "~/" || strings.HasPrefix(dir, "~/")
+
+		var objectFound bool
+		if src.Delimiter != "" {
+			objectFound = s.listDir(ctx, client, src, dir, followSymlinks, objCh)
+		} else {
+			objectFound = s.walk(ctx, client, src, dir, home, followSymlinks, objCh)
+		}
+
+		if !objectFound && !src.IsBucket() {
+			sendError(ctx, ErrNoObjectFound, objCh)
+		}
+	}()
+
+	return objCh
+}
+
+// listDir lists the direct children of the directory.
+func (s *SFTP) listDir(
+	ctx context.Context,
+	client *sftp.Client,
+	src *url.URL,
+	dir string,
+	followSymlinks bool,
+	objCh chan *Object,
+) bool {
+	entries, err := client.ReadDir(sftpPath(strings.TrimSuffix(dir, "/")))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			sendError(ctx, err, objCh)
+		}
+		return false
+	}
+
+	var objectFound bool
+	for _, fi := range entries {
+		key := dir + fi.Name()
+		if !strings.HasPrefix(key, src.Prefix) {
+			continue
+		}
+
+		fi, ok := s.resolve(client, sftpPath(key), fi, followSymlinks)
+		if !ok {
+			continue
+		}
+
+		if fi.IsDir() {
+			key += "/"
+		}
+		if !src.Match(key) {
+			continue
+		}
+
+		newurl := src.Clone()
+		newurl.Path = key
+		obj := sftpObject(newurl, fi)
+		if fi.IsDir() {
+			obj = &Object{URL: newurl, Type: ObjectType{os.ModeDir}}
+		}
+		sendObject(ctx, obj, objCh)
+		objectFound = true
+	}
+	return objectFound
+}
+
+// walk lists the files of the tree under the directory which match the URL.
+func (s *SFTP) walk(
+	ctx context.Context,
+	client *sftp.Client,
+	src *url.URL,
+	dir string,
+	home bool,
+	followSymlinks bool,
+	objCh chan *Object,
+) bool {
+	root := sftpPath(strings.TrimSuffix(dir, "/"))
+	if dir == "" {
+		root = "/"
+	}
+
+	var objectFound bool
+	walker := client.Walk(root)
+	for walker.Step() {
+		if ctx.Err() != nil {
+			return objectFound
+		}
+
+		if err := walker.Err(); err != nil {
+			if walker.Path() == root && errors.Is(err, os.ErrNotExist) {
+				return false
+			}
+			sendError(ctx, err, objCh)
+			continue
+		}
+
+		key := sftpKey(walker.Path(), home)
+		if walker.Stat().IsDir() {
+			// skip the directories which can't have any matching file.
+			if walker.Path() != root &&
+				!strings.HasPrefix(key+"/", src.Prefix) &&
+				!strings.HasPrefix(src.Prefix, key+"/") {
+				walker.SkipDir()
+			}
+			continue
+		}
+
+		fi, ok := s.resolve(client, walker.Path(), walker.Stat(), followSymlinks)
+		// symlinked directories are not followed to avoid cycles.
+		if !ok || fi.IsDir() {
+			continue
+		}
+
+		if !src.Match(key) {
+			continue
+		}
+
+		newurl := src.Clone()
+		newurl.Path = key
+		sendObject(ctx, sftpObject(newurl, fi), objCh)
+		objectFound = true
+	}
+	return objectFound
+}
+
+// resolve returns the file info of the target of the symlink. It returns
+// false if the symlinks are not followed or the link is broken.
+func (s *SFTP) resolve(client *sftp.Client, p string, fi os.FileInfo, followSymlinks bool) (os.FileInfo, bool) {
+	if fi.Mode()&os.ModeSymlink == 0 {
+		return fi, true
+	}
+	if !followSymlinks {
+		return nil, false
+	}
+
+	target, err := client.Stat(p)
+	if err != nil {
+		return nil, false
+	}
+	return target, true
+}
+
+// Delete deletes the file. Deleting a missing file is not an error, as in S3.
+func (s *SFTP) Delete(ctx context.Context, u *url.URL) error {
+	if s.dryRun {
+		return nil
+	}
+
+	client, err := s.conn(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	err = client.Remove(sftpPath(u.Path))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// MultiDelete deletes all the files read from the channel.
+func (s *SFTP) MultiDelete(ctx context.Context, urlch <-chan *url.URL) <-chan *Object {
+	resultch := make(chan *Object)
+
+	go func() {
+		defer close(resultch)
+
+		for u := range urlch {
+			sendObject(ctx, &Object{URL: u, Err: s.Delete(ctx, u)}, resultch)
+		}
+	}()
+
+	return resultch
+}
+
+// Copy copies the file by streaming its contents through the client, the
+// files may be on different hosts.
+func (s *SFTP) Copy(ctx context.Context, from, to *url.URL, metadata Metadata) error {
+	if s.dryRun {
+		return nil
+	}
+
+	reader, err := s.Read(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return s.Put(ctx, reader, to, metadata, 0, 0)
+}
+
+// Read opens the file for reading.
+func (s *SFTP) Read(ctx context.Context, src *url.URL) (io.ReadCloser, error) {
+	client, err := s.conn(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := client.Open(sftpPath(src.Path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, &ErrGivenObjectNotFound{ObjectAbsPath: src.Absolute()}
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// Get writes the contents of the file to the destination. The file is read
+// with concurrent requests by the SFTP client.
+func (s *SFTP) Get(ctx context.Context, from *url.URL, to io.WriterAt, _ int, _ int64) (int64, error) {
+	if s.dryRun {
+		return 0, nil
+	}
+
+	f, err := s.Read(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return io.Copy(io.NewOffsetWriter(to, 0), f)
+}
+
+// Put writes the contents of the reader to a temporary file next to the
+// destination and renames it once the upload is completed, so that partial
+// files are never visible. The parent directories are created if missing.
+func (s *SFTP) Put(
+	ctx context.Context,
+	reader io.Reader,
+	to *url.URL,
+	metadata Metadata,
+	_ int,
+	_ int64,
+) error {
+	if len(metadata.Tags) > 0 {
+		return NotSupported(to, "object tagging")
+	}
+	if s.dryRun {
+		return nil
+	}
+
+	client, err := s.conn(ctx, to)
+	if err != nil {
+		return err
+	}
+
+	p := sftpPath(to.Path)
+	if err := client.MkdirAll(path.Dir(p)); err != nil {
+		return err
+	}
+
+	tmp := p + ".s5cmd-" + randomHex(8)
+	f, err := client.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.ReadFrom(reader)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = sftpRename(client, tmp, p)
+	}
+	if err != nil {
+		client.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// sftpRename replaces the destination with the file. The servers which don't
+// support the posix-rename extension can't rename onto an existing file.
+func sftpRename(client *sftp.Client, from, to string) error {
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+		return client.PosixRename(from, to)
+	}
+
+	if err := client.Remove(to); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return client.Rename(from, to)
+}
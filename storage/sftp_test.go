@@ -0,0 +1,376 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gotest.tools/v3/assert"
+
+	"github.com/peak/s5cmd/v2/log"
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// newFakeSFTP starts an in-process SSH server with an in-memory SFTP
+// subsystem. It returns the address of the server and the environment which
+// authenticates the client with a key file and trusts the host key.
+func newFakeSFTP(t *testing.T) (string, map[string]string) {
+	t.Helper()
+	log.Init("error", false)
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	assert.NilError(t, err)
+
+	userPub, userKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	authorized, err := ssh.NewPublicKey(userPub)
+	assert.NilError(t, err)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "s5cmd" && bytes.Equal(key.Marshal(), authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, os.ErrPermission
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	handlers := sftp.InMemHandler()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeSFTP(conn, config, handlers)
+		}
+	}()
+
+	dir := t.TempDir()
+
+	block, err := ssh.MarshalPrivateKey(userKey, "")
+	assert.NilError(t, err)
+	identity := filepath.Join(dir, "id_ed25519")
+	assert.NilError(t, os.WriteFile(identity, pem.EncodeToMemory(block), 0o600))
+
+	addr := listener.Addr().String()
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, hostSigner.PublicKey())
+	knownHosts := filepath.Join(dir, "known_hosts")
+	assert.NilError(t, os.WriteFile(knownHosts, []byte(line+"\n"), 0o600))
+
+	return addr, map[string]string{
+		"HOME":               dir,
+		"USER":               "s5cmd",
+		"SFTP_IDENTITY_FILE": identity,
+		"SFTP_KNOWN_HOSTS":   knownHosts,
+	}
+}
+
+func serveFakeSFTP(conn net.Conn, config *ssh.ServerConfig, handlers sftp.Handlers) {
+	defer conn.Close()
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if ok {
+					server := sftp.NewRequestServer(channel, handlers)
+					server.Serve()
+					server.Close()
+				}
+			}
+		}()
+	}
+}
+
+func newTestSFTPClient(t *testing.T, opts Options) (*SFTP, string) {
+	t.Helper()
+
+	addr, env := newFakeSFTP(t)
+	client, err := newSFTPClient(opts, func(key string) string { return env[key] })
+	assert.NilError(t, err)
+	return client, addr
+}
+
+func TestSFTPImplementsCapabilities(t *testing.T) {
+	var s Storage = &SFTP{}
+
+	_, ok := s.(Getter)
+	assert.Assert(t, ok)
+	_, ok = s.(Putter)
+	assert.Assert(t, ok)
+	_, ok = s.(Reader)
+	assert.Assert(t, ok)
+	_, ok = s.(MetadataReader)
+	assert.Assert(t, ok)
+	_, ok = s.(Presigner)
+	assert.Assert(t, !ok)
+}
+
+func TestSFTPPutAndGet(t *testing.T) {
+	t.Parallel()
+
+	client, addr := newTestSFTPClient(t, Options{})
+	ctx := context.Background()
+
+	content := strings.Repeat("s5cmd", 100000)
+	u := mustNewURL(t, "sftp://"+addr+"/dir/sub/file.txt")
+
+	err := client.Put(ctx, strings.NewReader(content), u, Metadata{}, 1, 0)
+	assert.NilError(t, err)
+
+	// overwrite the file.
+	err = client.Put(ctx, strings.NewReader(content+"!"), u, Metadata{}, 1, 0)
+	assert.NilError(t, err)
+
+	obj, err := client.Stat(ctx, u)
+	assert.NilError(t, err)
+	assert.Equal(t, obj.Size, int64(len(content)+1))
+	assert.Assert(t, obj.ModTime != nil)
+
+	buf := aws.NewWriteAtBuffer(nil)
+	n, err := client.Get(ctx, u, buf, 4, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, n, int64(len(content)+1))
+	assert.Equal(t, string(buf.Bytes()), content+"!")
+
+	reader, err := client.Read(ctx, u)
+	assert.NilError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NilError(t, err)
+	assert.NilError(t, reader.Close())
+	assert.Equal(t, string(data), content+"!")
+
+	// the temporary files are not left behind.
+	var keys []string
+	for obj := range client.List(ctx, mustNewURL(t, "sftp://"+addr+"/dir/sub/"), false) {
+		assert.NilError(t, obj.Err)
+		keys = append(keys, obj.URL.Path)
+	}
+	assert.DeepEqual(t, keys, []string{"dir/sub/file.txt"})
+
+	_, err = client.Stat(ctx, mustNewURL(t, "sftp://"+addr+"/dir/missing"))
+	assert.ErrorType(t, err, &ErrGivenObjectNotFound{})
+
+	_, err = client.Read(ctx, mustNewURL(t, "sftp://"+addr+"/dir/missing"))
+	assert.ErrorType(t, err, &ErrGivenObjectNotFound{})
+
+	err = client.Put(ctx, strings.NewReader(""), u, Metadata{Tags: map[string]string{"k": "v"}}, 1, 0)
+	assert.ErrorContains(t, err, "object tagging is not supported by sftp:// storage")
+}
+
+func TestSFTPList(t *testing.T) {
+	t.Parallel()
+
+	client, addr := newTestSFTPClient(t, Options{})
+	ctx := context.Background()
+
+	for _, key := range []string{"a.txt", "b.csv", "dir/c.txt", "dir/sub/d.txt", "e.txt"} {
+		err := client.Put(ctx, strings.NewReader(key), mustNewURL(t, "sftp://"+addr+"/data/"+key), Metadata{}, 1, 0)
+		assert.NilError(t, err)
+	}
+
+	testcases := []struct {
+		src      string
+		expected []string
+	}{
+		{src: "data/", expected: []string{"data/dir/", "data/a.txt", "data/b.csv", "data/e.txt"}},
+		{src: "data/*", expected: []string{"data/a.txt", "data/b.csv", "data/dir/c.txt", "data/dir/sub/d.txt", "data/e.txt"}},
+		{src: "data/*.txt", expected: []string{"data/a.txt", "data/dir/c.txt", "data/dir/sub/d.txt", "data/e.txt"}},
+		{src: "data/d*/*.txt", expected: []string{"data/dir/c.txt", "data/dir/sub/d.txt"}},
+		{src: "data/dir/", expected: []string{"data/dir/sub/", "data/dir/c.txt"}},
+		{src: "~/data/dir/*", expected: []string{"~/data/dir/c.txt", "~/data/dir/sub/d.txt"}},
+	}
+
+	for _, tc := range testcases {
+		var keys []string
+		for obj := range client.List(ctx, mustNewURL(t, "sftp://s5cmd@"+addr+"/"+tc.src), false) {
+			assert.NilError(t, obj.Err, tc.src)
+			keys = append(keys, obj.URL.Path)
+		}
+		sort.Strings(keys)
+		sort.SliceStable(keys, func(i, j int) bool {
+			return strings.HasSuffix(keys[i], "/") && !strings.HasSuffix(keys[j], "/")
+		})
+		assert.DeepEqual(t, keys, tc.expected)
+	}
+
+	for _, src := range []string{"data/missing/", "data/missing/*", "missing/*.txt"} {
+		for obj := range client.List(ctx, mustNewURL(t, "sftp://"+addr+"/"+src), false) {
+			assert.Equal(t, obj.Err, ErrNoObjectFound, src)
+		}
+	}
+}
+
+func TestSFTPCopyAndDelete(t *testing.T) {
+	t.Parallel()
+
+	client, addr := newTestSFTPClient(t, Options{})
+	ctx := context.Background()
+
+	src := mustNewURL(t, "sftp://"+addr+"/src.txt")
+	dst := mustNewURL(t, "sftp://"+addr+"/copy/dst.txt")
+
+	assert.NilError(t, client.Put(ctx, strings.NewReader("content"), src, Metadata{}, 1, 0))
+	assert.NilError(t, client.Copy(ctx, src, dst, Metadata{}))
+
+	buf := aws.NewWriteAtBuffer(nil)
+	_, err := client.Get(ctx, dst, buf, 1, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, string(buf.Bytes()), "content")
+
+	urlch := make(chan *url.URL, 3)
+	urlch <- src
+	urlch <- dst
+	urlch <- mustNewURL(t, "sftp://"+addr+"/missing.txt")
+	close(urlch)
+
+	var deleted int
+	for obj := range client.MultiDelete(ctx, urlch) {
+		assert.NilError(t, obj.Err)
+		deleted++
+	}
+	assert.Equal(t, deleted, 3)
+
+	_, err = client.Stat(ctx, src)
+	assert.ErrorType(t, err, &ErrGivenObjectNotFound{})
+	_, err = client.Stat(ctx, dst)
+	assert.ErrorType(t, err, &ErrGivenObjectNotFound{})
+}
+
+func TestSFTPHostKeyVerification(t *testing.T) {
+	t.Parallel()
+
+	addr, env := newFakeSFTP(t)
+	ctx := context.Background()
+	u := mustNewURL(t, "sftp://"+addr+"/file")
+
+	// the host key of another server.
+	_, otherEnv := newFakeSFTP(t)
+	env["SFTP_KNOWN_HOSTS"] = otherEnv["SFTP_KNOWN_HOSTS"]
+	getenv := func(key string) string { return env[key] }
+
+	client, err := newSFTPClient(Options{}, getenv)
+	assert.NilError(t, err)
+	_, err = client.Stat(ctx, u)
+	assert.ErrorContains(t, err, "knownhosts: key is unknown")
+
+	client, err = newSFTPClient(Options{NoVerifySSL: true}, getenv)
+	assert.NilError(t, err)
+	_, err = client.Stat(ctx, u)
+	assert.ErrorType(t, err, &ErrGivenObjectNotFound{})
+
+	env["SFTP_KNOWN_HOSTS"] = filepath.Join(env["HOME"], "missing")
+	_, err = newSFTPClient(Options{}, getenv)
+	assert.ErrorContains(t, err, "--no-verify-ssl")
+
+	// another user is not authorized.
+	client, err = newSFTPClient(Options{NoVerifySSL: true}, getenv)
+	assert.NilError(t, err)
+	_, err = client.Stat(ctx, mustNewURL(t, "sftp://other@"+addr+"/file"))
+	assert.ErrorContains(t, err, "unable to authenticate")
+}
+
+func TestSFTPReconnect(t *testing.T) {
+	t.Parallel()
+
+	client, addr := newTestSFTPClient(t, Options{})
+	ctx := context.Background()
+	u := mustNewURL(t, "sftp://"+addr+"/file.txt")
+
+	assert.NilError(t, client.Put(ctx, strings.NewReader("content"), u, Metadata{}, 1, 0))
+
+	conn, err := client.conn(ctx, u)
+	assert.NilError(t, err)
+	assert.NilError(t, conn.Close())
+
+	// the closed connection is evicted once its session is shut down.
+	for i := 0; ; i++ {
+		client.mu.Lock()
+		n := len(client.conns)
+		client.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		assert.Assert(t, i < 100, "closed connection is not evicted")
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, err = client.Stat(ctx, u)
+	assert.NilError(t, err)
+}
+
+func TestSFTPUnresponsiveHost(t *testing.T) {
+	t.Parallel()
+
+	client, addr := newTestSFTPClient(t, Options{})
+
+	// the host accepts the connections but never completes the handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		_, err := client.Stat(ctx, mustNewURL(t, "sftp://"+listener.Addr().String()+"/file.txt"))
+		errCh <- err
+	}()
+
+	conn := <-accepted
+	defer conn.Close()
+
+	// the operations on the other hosts are not blocked by the handshake.
+	_, err = client.Stat(context.Background(), mustNewURL(t, "sftp://"+addr+"/file.txt"))
+	assert.ErrorType(t, err, &ErrGivenObjectNotFound{})
+
+	// the handshake is aborted once the context is canceled.
+	cancel()
+	assert.Assert(t, <-errCh != nil)
+}
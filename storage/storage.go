@@ -21,6 +21,10 @@ import (
 // ErrNoObjectFound indicates there are no objects found from a given directory.
 var ErrNoObjectFound = fmt.Errorf("no object found")
 
+// ErrObjectModified indicates that the object has been modified since a
+// resumable download or a multipart copy is started.
+var ErrObjectModified = fmt.Errorf("object has been modified")
+
 // ErrGivenObjectNotFound indicates a specified object is not found.
 type ErrGivenObjectNotFound struct {
 	ObjectAbsPath string
@@ -53,10 +57,22 @@ type Storage interface {
 }
 
 func NewLocalClient(opts Options) *Filesystem {
-	return &Filesystem{dryRun: opts.DryRun}
+	return &Filesystem{dryRun: opts.DryRun, ignoreFile: opts.IgnoreFile}
 }
 
+func init() {
+	Register("s3", url.SchemeOptions{}, func(ctx context.Context, url *url.URL, opts Options) (Storage, error) {
+		return NewRemoteClient(ctx, url, opts)
+	})
+}
+
+// NewRemoteClient creates an S3 client. It is used by the operations which
+// are specific to S3.
 func NewRemoteClient(ctx context.Context, url *url.URL, opts Options) (*S3, error) {
+	if url.Scheme != "" && url.Scheme != "s3" {
+		return nil, fmt.Errorf("the operation is only supported for s3 urls: %v", url)
+	}
+
 	newOpts := Options{
 		MaxRetries:             opts.MaxRetries,
 		NoSuchUploadRetryCount: opts.NoSuchUploadRetryCount,
@@ -65,21 +81,40 @@ func NewRemoteClient(ctx context.Context, url *url.URL, opts Options) (*S3, erro
 		DryRun:                 opts.DryRun,
 		NoSignRequest:          opts.NoSignRequest,
 		UseListObjectsV1:       opts.UseListObjectsV1,
+		ListRestoreStatus:      opts.ListRestoreStatus,
+		ListPartitions:         opts.ListPartitions,
+		ListingCache:           opts.ListingCache,
+		CacheListings:          opts.CacheListings,
 		RequestPayer:           opts.RequestPayer,
 		Profile:                opts.Profile,
 		CredentialFile:         opts.CredentialFile,
 		LogLevel:               opts.LogLevel,
 		bucket:                 url.Bucket,
 		region:                 opts.region,
+
+		SSECustomerKey:           opts.SSECustomerKey,
+		CopySourceSSECustomerKey: opts.CopySourceSSECustomerKey,
 	}
 	return newS3Storage(ctx, newOpts)
 }
 
+// NewClient creates the client of the storage of the URL. Remote storages are
+// looked up by the scheme of the URL.
 func NewClient(ctx context.Context, url *url.URL, opts Options) (Storage, error) {
-	if url.IsRemote() {
-		return NewRemoteClient(ctx, url, opts)
+	if !url.IsRemote() {
+		return NewLocalClient(opts), nil
 	}
-	return NewLocalClient(opts), nil
+
+	scheme := url.Scheme
+	if scheme == "" {
+		scheme = "s3"
+	}
+
+	backend, ok := lookupBackend(scheme)
+	if !ok {
+		return nil, fmt.Errorf("unsupported url scheme %q", scheme)
+	}
+	return backend(ctx, url, opts)
 }
 
 // Options stores configuration for storage.
@@ -91,18 +126,43 @@ type Options struct {
 	DryRun                 bool
 	NoSignRequest          bool
 	UseListObjectsV1       bool
+	ListRestoreStatus      bool
+	ListPartitions         int
 	LogLevel               log.LogLevel
 	RequestPayer           string
 	Profile                string
 	CredentialFile         string
 	bucket                 string
 	region                 string
+
+	// ListingCache is invalidated on the writes of the client. The listings
+	// are served from it only if CacheListings is set.
+	ListingCache  *ListingCache
+	CacheListings bool
+
+	// SSECustomerKey is the customer-provided key of the objects encrypted
+	// with SSE-C. CopySourceSSECustomerKey is the key of the source objects
+	// of the copies between remote storages.
+	SSECustomerKey           string
+	CopySourceSSECustomerKey string
+
+	// IgnoreFile is the name of the files which list the patterns of the
+	// files to skip in gitignore syntax while local directories are walked.
+	// The ignore files are not looked up if it is empty.
+	IgnoreFile string
 }
 
 func (o *Options) SetRegion(region string) {
 	o.region = region
 }
 
+// CopySourceOptions returns the options to access the source objects of the
+// copies between remote storages, which are read with the copy source key.
+func (o Options) CopySourceOptions() Options {
+	o.SSECustomerKey = o.CopySourceSSECustomerKey
+	return o
+}
+
 // Object is a generic type which contains metadata for storage items.
 type Object struct {
 	URL          *url.URL     `json:"key,omitempty"`
@@ -114,9 +174,21 @@ type Object struct {
 	Err          error        `json:"error,omitempty"`
 	retryID      string
 
+	// Restore is the restore status of an archived object. It is nil if the
+	// object is not restored or the status is not requested.
+	Restore *RestoreStatus `json:"restore,omitempty"`
+
+	// CRC32C is the base64 encoded CRC32C checksum of the object. It is only
+	// reported by the storages which support it, e.g. Google Cloud Storage.
+	CRC32C string `json:"crc32c,omitempty"`
+
 	// the VersionID field exist only for JSON Marshall, it must not be used for
 	// any other purpose. URL.VersionID must be used instead.
 	VersionID string `json:"version_id,omitempty"`
+
+	// sizeUnknown is set for the objects whose size is not known, such as
+	// the objects which are read from the plain key lists.
+	sizeUnknown bool
 }
 
 // String returns the string representation of Object.
@@ -124,6 +196,12 @@ func (o *Object) String() string {
 	return o.URL.String()
 }
 
+// SizeUnknown reports whether the size of the object is unknown. Size is zero
+// for such objects, it must be retrieved with Stat if it is needed.
+func (o *Object) SizeUnknown() bool {
+	return o.sizeUnknown
+}
+
 // JSON returns the JSON representation of Object.
 func (o *Object) JSON() string {
 	if o.URL != nil {
@@ -197,11 +275,18 @@ const dateFormat = "2006/01/02 15:04:05"
 type Bucket struct {
 	CreationDate time.Time `json:"created_at"`
 	Name         string    `json:"name"`
+
+	// Scheme is the URL scheme of the storage of the bucket, s3 if empty.
+	Scheme string `json:"-"`
 }
 
 // String returns the string representation of Bucket.
 func (b Bucket) String() string {
-	return fmt.Sprintf("%s  s3://%s", b.CreationDate.Format(dateFormat), b.Name)
+	scheme := b.Scheme
+	if scheme == "" {
+		scheme = "s3"
+	}
+	return fmt.Sprintf("%s  %s://%s", b.CreationDate.Format(dateFormat), scheme, b.Name)
 }
 
 // JSON returns the JSON representation of Bucket.
@@ -216,6 +301,29 @@ func (s StorageClass) IsGlacier() bool {
 	return s == "GLACIER"
 }
 
+// IsArchived reports whether the objects of the storage class must be
+// restored before they can be accessed.
+func (s StorageClass) IsArchived() bool {
+	return s == "GLACIER" || s == "DEEP_ARCHIVE"
+}
+
+// RestoreStatus is the restore status of an archived object.
+type RestoreStatus struct {
+	// InProgress reports whether the object is being restored.
+	InProgress bool `json:"in_progress"`
+	// ExpiryDate is the date when the restored copy of the object expires.
+	// It is only set once the restore is completed.
+	ExpiryDate *time.Time `json:"expiry_date,omitempty"`
+}
+
+// String returns the string representation of RestoreStatus.
+func (r RestoreStatus) String() string {
+	if r.InProgress {
+		return "RESTORING"
+	}
+	return "RESTORED"
+}
+
 type Metadata struct {
 	ACL                string
 	CacheControl       string
@@ -224,11 +332,20 @@ type Metadata struct {
 	ContentType        string
 	ContentEncoding    string
 	ContentDisposition string
+	ContentLanguage    string
 	EncryptionMethod   string
 	EncryptionKeyID    string
 
 	UserDefined map[string]string
 
+	// Tags is the tag set of the object. If it is not empty, it replaces the
+	// tags of the destination object on copy operations.
+	Tags map[string]string
+
+	// TagCount is the number of tags of the object. It is only reported by
+	// HeadObject, the tags must be fetched separately.
+	TagCount int64
+
 	// MetadataDirective is used to specify whether the metadata is copied from
 	// the source object or replaced with metadata provided when copying S3
 	// objects. If MetadataDirective is not set, it defaults to "COPY".
@@ -242,6 +359,8 @@ func (o Object) ToBytes() []byte {
 	enc.Encode(o.ModTime.Format(time.RFC3339Nano))
 	enc.Encode(o.Type.mode)
 	enc.Encode(o.Size)
+	enc.Encode(o.Etag)
+	enc.Encode(o.CRC32C)
 
 	return buf.Bytes()
 }
@@ -260,6 +379,8 @@ func FromBytes(data []byte) extsort.SortType {
 	o.ModTime = &tmp
 	dec.Decode(&o.Type.mode)
 	dec.Decode(&o.Size)
+	dec.Decode(&o.Etag)
+	dec.Decode(&o.CRC32C)
 	return o
 }
 
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/lanrat/extsort"
 	"github.com/peak/s5cmd/v2/strutil"
@@ -75,6 +76,40 @@ func WithAllVersions(isAllVersions bool) Option {
 	}
 }
 
+// SchemeOptions describes how the URLs of a remote storage scheme are parsed.
+type SchemeOptions struct {
+	// HostAuthority reports that the authority of the URLs is a network
+	// location, i.e. [user@]host[:port], instead of a bucket name. The
+	// authority is kept in the Bucket field as is.
+	HostAuthority bool
+
+	// NoWildcard disables the wildcard operations on the URLs, for the
+	// storages which can not list objects. Glob characters are treated as
+	// part of the path.
+	NoWildcard bool
+}
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]SchemeOptions{
+		"s3": {},
+	}
+)
+
+// RegisterScheme makes the URLs of the given scheme parsable as remote URLs.
+func RegisterScheme(scheme string, opts SchemeOptions) {
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[scheme] = opts
+}
+
+func lookupScheme(scheme string) (SchemeOptions, bool) {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+	opts, ok := schemes[scheme]
+	return opts, ok
+}
+
 // New creates a new URL from given path string.
 func New(s string, opts ...Option) (*URL, error) {
 	scheme, rest, isFound := strings.Cut(s, "://")
@@ -99,8 +134,9 @@ func New(s string, opts ...Option) (*URL, error) {
 		return url, nil
 	}
 
-	if scheme != "s3" {
-		return nil, fmt.Errorf("s3 url should start with %q", s3Scheme)
+	schemeOpts, ok := lookupScheme(scheme)
+	if !ok {
+		return nil, fmt.Errorf("unsupported url scheme %q", scheme)
 	}
 
 	parts := strings.SplitN(rest, s3Separator, 2)
@@ -111,17 +147,23 @@ func New(s string, opts ...Option) (*URL, error) {
 		key = parts[1]
 	}
 
-	if bucket == "" {
-		return nil, fmt.Errorf("s3 url should have a bucket")
-	}
+	if schemeOpts.HostAuthority {
+		if err := validateHost(scheme, bucket); err != nil {
+			return nil, err
+		}
+	} else {
+		if bucket == "" {
+			return nil, fmt.Errorf("%v url should have a bucket", scheme)
+		}
 
-	if hasGlobCharacter(bucket) {
-		return nil, fmt.Errorf("bucket name cannot contain wildcards")
+		if hasGlobCharacter(bucket) {
+			return nil, fmt.Errorf("bucket name cannot contain wildcards")
+		}
 	}
 
 	url := &URL{
 		Type:   remoteObject,
-		Scheme: "s3",
+		Scheme: scheme,
 		Bucket: bucket,
 		Path:   key,
 	}
@@ -130,12 +172,46 @@ func New(s string, opts ...Option) (*URL, error) {
 		opt(url)
 	}
 
+	if schemeOpts.NoWildcard {
+		url.raw = true
+	}
+
 	if err := url.setPrefixAndFilter(); err != nil {
 		return nil, err
 	}
 	return url, nil
 }
 
+// validateHost checks the authority of the URLs whose authority is a network
+// location.
+func validateHost(scheme, authority string) error {
+	if authority == "" {
+		return fmt.Errorf("%v url should have a host", scheme)
+	}
+
+	u, err := url.Parse(scheme + "://" + authority)
+	if err != nil || u.Host != authority[strings.LastIndex(authority, "@")+1:] {
+		return fmt.Errorf("invalid host %q in %v url", authority, scheme)
+	}
+	return nil
+}
+
+// Host returns the host, with the port if given, of the URLs whose authority
+// is a network location.
+func (u *URL) Host() string {
+	return u.Bucket[strings.LastIndex(u.Bucket, "@")+1:]
+}
+
+// User returns the user name of the URLs whose authority is a network
+// location, if given.
+func (u *URL) User() string {
+	i := strings.LastIndex(u.Bucket, "@")
+	if i < 0 {
+		return ""
+	}
+	return u.Bucket[:i]
+}
+
 // IsRemote reports whether the object is stored on a remote storage system.
 func (u *URL) IsRemote() bool {
 	return u.Type == remoteObject
@@ -119,6 +119,81 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewWithRegisteredScheme(t *testing.T) {
+	RegisterScheme("bucketscheme", SchemeOptions{})
+	RegisterScheme("hostscheme", SchemeOptions{HostAuthority: true, NoWildcard: true})
+
+	tests := []struct {
+		name     string
+		object   string
+		want     *URL
+		wantUser string
+		wantHost string
+		wantErr  string
+	}{
+		{
+			name:    "error_if_scheme_is_not_registered",
+			object:  "unknown://bucket/key",
+			wantErr: `unsupported url scheme "unknown"`,
+		},
+		{
+			name:    "error_if_does_not_have_bucket",
+			object:  "bucketscheme://",
+			wantErr: "bucketscheme url should have a bucket",
+		},
+		{
+			name:   "bucket_authority",
+			object: "bucketscheme://bucket/key/*",
+			want: &URL{
+				Scheme: "bucketscheme",
+				Bucket: "bucket",
+				Path:   "key/*",
+				Prefix: "key/",
+			},
+		},
+		{
+			name:    "error_if_does_not_have_host",
+			object:  "hostscheme:///key",
+			wantErr: "hostscheme url should have a host",
+		},
+		{
+			name:   "host_authority",
+			object: "hostscheme://user@example.com:8080/dir/file?.txt",
+			want: &URL{
+				Scheme: "hostscheme",
+				Bucket: "user@example.com:8080",
+				Path:   "dir/file?.txt",
+			},
+			wantUser: "user",
+			wantHost: "example.com:8080",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := New(tc.object)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.IgnoreUnexported(URL{})); diff != "" {
+				t.Errorf("URL mismatch (-want +got):\n%v", diff)
+			}
+			if got.User() != tc.wantUser || got.Host() != tc.wantHost && tc.wantHost != "" {
+				t.Errorf("expected %q@%q, got %q@%q", tc.wantUser, tc.wantHost, got.User(), got.Host())
+			}
+			if got.Absolute() != tc.object {
+				t.Errorf("expected %q, got %q", tc.object, got.Absolute())
+			}
+		})
+	}
+}
+
 func TestJoin(t *testing.T) {
 	tests := []struct {
 		name       string